@@ -0,0 +1,330 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Access modes understood by the server
+const (
+	AccessPublic    = "public"
+	AccessPassword  = "password"
+	AccessAllowList = "allowlist"
+)
+
+// generatedPasswordLength is long enough to resist guessing while still
+// being easy to paste into a share message
+const generatedPasswordLength = 20
+
+// AccessCmd controls who may view an efmrl
+type AccessCmd struct {
+	Show     AccessShowCmd     `cmd:"" default:"1" help:"Show how access to the efmrl is restricted"`
+	Password AccessPasswordCmd `cmd:"" help:"Set or rotate the password protecting the efmrl"`
+	Allow    AccessAllowCmd    `cmd:"" help:"Allow one or more email addresses to view the efmrl"`
+	Revoke   AccessRevokeCmd   `cmd:"" help:"Remove one or more email addresses from the allow-list"`
+	Disable  AccessDisableCmd  `cmd:"" help:"Remove all access restrictions and make the efmrl public"`
+}
+
+// AccessSettings describes the viewer restrictions on an efmrl
+type AccessSettings struct {
+	Mode          string   `json:"mode"`
+	Emails        []string `json:"emails,omitempty"`
+	PasswordSetAt string   `json:"passwordSetAt,omitempty"`
+}
+
+// AccessShowCmd prints the current access mode and allow-list
+type AccessShowCmd struct{}
+
+func (a *AccessShowCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	var access AccessSettings
+	if err := getJSON(apiClient, fmt.Sprintf("/admin/efmrls/%s/access", config.Site.SiteID), &access); err != nil {
+		return fmt.Errorf("failed to fetch access settings: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(access)
+	}
+
+	switch access.Mode {
+	case AccessPassword:
+		outln("Access: password protected")
+		if access.PasswordSetAt != "" {
+			outf("  Password set: %s\n", access.PasswordSetAt)
+		}
+	case AccessAllowList:
+		outln("Access: restricted to allowed viewers")
+	default:
+		outln("Access: public")
+	}
+
+	if len(access.Emails) > 0 {
+		outf("Allowed viewers (%d):\n", len(access.Emails))
+		for _, email := range access.Emails {
+			outf("  %s\n", email)
+		}
+	}
+
+	return nil
+}
+
+// AccessPasswordCmd enables password protection, replacing any existing password
+type AccessPasswordCmd struct {
+	Generate bool `help:"Generate a random password instead of prompting for one"`
+}
+
+func (a *AccessPasswordCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	var password string
+	if a.Generate {
+		password = generatePassword()
+	} else {
+		password, err = askSecret("New password: ")
+		if err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		if password == "" {
+			return fmt.Errorf("password must not be empty")
+		}
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	body := map[string]string{"mode": AccessPassword, "password": password}
+	if err := putAccess(apiClient, config.Site.SiteID, body); err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+
+	if jsonOutput {
+		result := map[string]string{"mode": AccessPassword}
+		if a.Generate {
+			result["password"] = password
+		}
+		return printJSON(result)
+	}
+
+	outf("%s Password protection enabled\n", green("✓"))
+	if a.Generate {
+		outf("  Password: %s\n", password)
+	}
+
+	return nil
+}
+
+// generatePassword returns a random password drawn from an alphabet without
+// easily confused characters, each character equally likely
+func generatePassword() string {
+	const alphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	buf := make([]byte, generatedPasswordLength)
+	size := big.NewInt(int64(len(alphabet)))
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			panic(err) // crypto/rand doesn't fail on supported platforms
+		}
+		buf[i] = alphabet[n.Int64()]
+	}
+	return string(buf)
+}
+
+// AccessAllowCmd adds viewers to the allow-list and switches the efmrl to
+// allow-list mode
+type AccessAllowCmd struct {
+	Emails []string `arg:"" name:"email" help:"Email address(es) to allow" required:""`
+}
+
+func (a *AccessAllowCmd) Run() error {
+	for _, email := range a.Emails {
+		if !strings.Contains(email, "@") {
+			return fmt.Errorf("invalid email address: %s", email)
+		}
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	for _, email := range a.Emails {
+		outf("Allowing %s... ", email)
+
+		body := map[string]string{"email": email}
+		resp, err := apiClient.Post(fmt.Sprintf("/admin/efmrls/%s/access/emails", config.Site.SiteID), body)
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to allow %s: %w", email, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		outf("%s\n", green("OK"))
+	}
+
+	var access AccessSettings
+	if err := getJSON(apiClient, fmt.Sprintf("/admin/efmrls/%s/access", config.Site.SiteID), &access); err != nil {
+		return fmt.Errorf("failed to fetch access settings: %w", err)
+	}
+
+	if access.Mode != AccessAllowList {
+		if err := putAccess(apiClient, config.Site.SiteID, map[string]string{"mode": AccessAllowList}); err != nil {
+			return fmt.Errorf("failed to enable allow-list: %w", err)
+		}
+		if access.Mode == AccessPassword {
+			outf("%s Viewer allow-list enabled (replaces password protection)\n", green("✓"))
+		} else {
+			outf("%s Viewer allow-list enabled\n", green("✓"))
+		}
+	}
+
+	if jsonOutput {
+		return printJSON(map[string]any{"mode": AccessAllowList, "allowed": a.Emails})
+	}
+	return nil
+}
+
+// AccessRevokeCmd removes viewers from the allow-list
+type AccessRevokeCmd struct {
+	Emails []string `arg:"" name:"email" help:"Email address(es) to remove" required:""`
+}
+
+func (a *AccessRevokeCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	revoked := []string{}
+	for _, email := range a.Emails {
+		outf("Revoking %s... ", email)
+
+		resp, err := apiClient.Delete(fmt.Sprintf("/admin/efmrls/%s/access/emails/%s", config.Site.SiteID, url.PathEscape(email)))
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to revoke %s: %w", email, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			outf("not on allow-list\n")
+			continue
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		outf("%s\n", green("OK"))
+		revoked = append(revoked, email)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]string{"revoked": revoked})
+	}
+
+	return nil
+}
+
+// AccessDisableCmd makes the efmrl public again
+type AccessDisableCmd struct{}
+
+func (a *AccessDisableCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	if err := putAccess(apiClient, config.Site.SiteID, map[string]string{"mode": AccessPublic}); err != nil {
+		return fmt.Errorf("failed to update access settings: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string]string{"mode": AccessPublic})
+	}
+
+	outf("%s Access restrictions removed; the efmrl is public\n", green("✓"))
+	return nil
+}
+
+// putAccess replaces the access settings of an efmrl
+func putAccess(client *APIClient, siteID string, body map[string]string) error {
+	resp, err := client.Put(fmt.Sprintf("/admin/efmrls/%s/access", siteID), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	first := generatePassword()
+	if len(first) != generatedPasswordLength {
+		t.Errorf("Expected length %d, got %d", generatedPasswordLength, len(first))
+	}
+	if strings.ContainsAny(first, "0O1lI") {
+		t.Errorf("Expected no ambiguous characters, got %q", first)
+	}
+	if second := generatePassword(); second == first {
+		t.Errorf("Expected distinct passwords, got %q twice", first)
+	}
+}
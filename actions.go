@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// githubActions reports whether efmrl3 is running as a GitHub Actions step.
+// Output then includes workflow commands, which the runner turns into
+// collapsible log groups, annotations, and a job summary.
+func githubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// actionsGroupOpen is set while a log group is open, since groups can't nest.
+// sync --all --parallel syncs several sites at once, so it's guarded.
+var (
+	actionsGroupOpen bool
+	actionsGroupMu   sync.Mutex
+)
+
+// startGroup starts a collapsible log group for a phase, ending any group
+// that's still open
+func startGroup(title string) {
+	if !githubActions() {
+		return
+	}
+	actionsGroupMu.Lock()
+	defer actionsGroupMu.Unlock()
+	endGroupLocked()
+	fmt.Fprintf(humanOutput(), "::group::%s\n", escapeWorkflowData(title))
+	actionsGroupOpen = true
+}
+
+// endGroup ends the open log group, if there is one. main calls it before
+// printing an error, so the error isn't hidden in a collapsed group.
+func endGroup() {
+	actionsGroupMu.Lock()
+	defer actionsGroupMu.Unlock()
+	endGroupLocked()
+}
+
+// endGroupLocked is endGroup for callers already holding actionsGroupMu
+func endGroupLocked() {
+	if !actionsGroupOpen {
+		return
+	}
+	fmt.Fprintln(humanOutput(), "::endgroup::")
+	actionsGroupOpen = false
+}
+
+// annotateError adds an error annotation to the workflow run. file is a
+// local path, or "" when the error isn't about a local file.
+func annotateError(title, file string, err error) {
+	if !githubActions() {
+		return
+	}
+
+	props := []string{"title=" + escapeWorkflowProperty(title)}
+	if file != "" {
+		// Annotations point at files relative to the workspace
+		if wd, werr := os.Getwd(); werr == nil {
+			if rel, rerr := filepath.Rel(wd, file); rerr == nil {
+				file = rel
+			}
+		}
+		props = append(props, "file="+escapeWorkflowProperty(filepath.ToSlash(file)))
+	}
+
+	// Always stderr, so annotations neither break --json nor vanish with
+	// --quiet; the runner reads workflow commands from both streams
+	fmt.Fprintf(os.Stderr, "::error %s::%s\n", strings.Join(props, ","), escapeWorkflowData(err.Error()))
+}
+
+// escapeWorkflowData escapes the message part of a workflow command
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeWorkflowProperty escapes a property value of a workflow command
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// maxSummaryFiles is how many uploaded or deleted files the job summary
+// lists before eliding the rest
+const maxSummaryFiles = 50
+
+// writeStepSummary appends a markdown summary of the sync to the job
+// summary, when GitHub Actions provides one
+func writeStepSummary(result *SyncResult) error {
+	summaryFile := os.Getenv("GITHUB_STEP_SUMMARY")
+	if !githubActions() || summaryFile == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(summaryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open job summary: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(stepSummary(result)); err != nil {
+		return fmt.Errorf("failed to write job summary: %w", err)
+	}
+	return nil
+}
+
+// stepSummary renders the markdown written to the job summary
+func stepSummary(result *SyncResult) string {
+	var b strings.Builder
+
+	title := "efmrl sync"
+	if result.DeployID != "" {
+		title = "efmrl deploy"
+	}
+	if result.DryRun {
+		title += " (dry run)"
+	}
+	fmt.Fprintf(&b, "### %s: `%s`\n\n", title, result.SiteID)
+
+	b.WriteString("| Result | Files |\n")
+	b.WriteString("| --- | ---: |\n")
+	fmt.Fprintf(&b, "| Uploaded | %d |\n", len(result.Uploaded))
+	fmt.Fprintf(&b, "| Deleted | %d |\n", len(result.Deleted))
+	fmt.Fprintf(&b, "| Unchanged | %d |\n", result.Unchanged)
+	b.WriteString("\n")
+
+	if result.DeployID != "" {
+		fmt.Fprintf(&b, "Deploy ID: `%s`\n\n", result.DeployID)
+		if result.DeployURL != "" {
+			fmt.Fprintf(&b, "Permanent URL: %s\n\n", result.DeployURL)
+		}
+	}
+
+	writeSummaryFiles(&b, "Uploaded files", result.Uploaded)
+	writeSummaryFiles(&b, "Deleted files", result.Deleted)
+	return b.String()
+}
+
+// writeSummaryFiles lists files in a collapsed section of the job summary
+func writeSummaryFiles(b *strings.Builder, heading string, files []string) {
+	if len(files) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "<details><summary>%s (%d)</summary>\n\n", heading, len(files))
+	for i, file := range files {
+		if i == maxSummaryFiles {
+			fmt.Fprintf(b, "- ... and %d more\n", len(files)-maxSummaryFiles)
+			break
+		}
+		fmt.Fprintf(b, "- `%s`\n", file)
+	}
+	b.WriteString("\n</details>\n\n")
+}
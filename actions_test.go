@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEscapeWorkflow tests escaping of workflow command data and properties
+func TestEscapeWorkflow(t *testing.T) {
+	tests := []struct {
+		input    string
+		data     string
+		property string
+	}{
+		{"plain", "plain", "plain"},
+		{"100%", "100%25", "100%25"},
+		{"line one\nline two\r", "line one%0Aline two%0D", "line one%0Aline two%0D"},
+		{"a:b,c", "a:b,c", "a%3Ab%2Cc"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeWorkflowData(tt.input); got != tt.data {
+			t.Errorf("escapeWorkflowData(%q): Expected %q, got %q", tt.input, tt.data, got)
+		}
+		if got := escapeWorkflowProperty(tt.input); got != tt.property {
+			t.Errorf("escapeWorkflowProperty(%q): Expected %q, got %q", tt.input, tt.property, got)
+		}
+	}
+}
+
+// TestGroups tests that groups are only written under GitHub Actions, and
+// that starting a group ends the previous one
+func TestGroups(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	out := captureStdout(t, func() {
+		startGroup("Scan")
+		endGroup()
+	})
+	if out != "" {
+		t.Errorf("Expected no output outside GitHub Actions, got %q", out)
+	}
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	out = captureStdout(t, func() {
+		startGroup("Scan")
+		startGroup("Plan")
+		endGroup()
+		endGroup()
+	})
+	expected := "::group::Scan\n::endgroup::\n::group::Plan\n::endgroup::\n"
+	if out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+}
+
+// TestAnnotateError tests the error annotation for a failed upload
+func TestAnnotateError(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("GITHUB_ACTIONS", "true")
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	annotateError("Failed to upload /a.html", filepath.Join(dir, "public", "a.html"), errors.New("server said: no\nreally"))
+	os.Stderr = stderr
+	w.Close()
+
+	buf := make([]byte, 1024)
+	n, _ := r.Read(buf)
+	expected := "::error title=Failed to upload /a.html,file=public/a.html::server said: no%0Areally\n"
+	if got := string(buf[:n]); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+// TestWriteStepSummary tests the job summary written for a deploy
+func TestWriteStepSummary(t *testing.T) {
+	summaryFile := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_STEP_SUMMARY", summaryFile)
+
+	result := &SyncResult{
+		SiteID:    "abc",
+		DeployID:  "d1",
+		DeployURL: "https://deploy-d1.abc.efmrl.net",
+		Uploaded:  []string{"/index.html", "/style.css"},
+		Deleted:   []string{},
+		Unchanged: 3,
+	}
+	if err := writeStepSummary(result); err != nil {
+		t.Fatalf("Failed to write summary: %v", err)
+	}
+
+	data, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatalf("Failed to read summary: %v", err)
+	}
+	summary := string(data)
+	for _, want := range []string{
+		"### efmrl deploy: `abc`",
+		"| Uploaded | 2 |",
+		"| Deleted | 0 |",
+		"| Unchanged | 3 |",
+		"Deploy ID: `d1`",
+		"Permanent URL: https://deploy-d1.abc.efmrl.net",
+		"<details><summary>Uploaded files (2)</summary>",
+		"- `/style.css`",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", want, summary)
+		}
+	}
+	if strings.Contains(summary, "Deleted files") {
+		t.Errorf("Expected no deleted files section, got:\n%s", summary)
+	}
+}
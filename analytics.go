@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// AnalyticsCmd summarizes traffic to the site
+type AnalyticsCmd struct {
+	Since string `help:"How far back to look, e.g. 24h, 7d, 30d" default:"7d"`
+	Top   int    `help:"Number of top paths and referrers to show" default:"10"`
+}
+
+// AnalyticsSummary is the server's traffic summary for a period
+type AnalyticsSummary struct {
+	Since          string       `json:"since"`
+	PageViews      int64        `json:"pageViews"`
+	UniqueVisitors int64        `json:"uniqueVisitors"`
+	TopPaths       []CountByKey `json:"topPaths"`
+	TopReferrers   []CountByKey `json:"topReferrers"`
+}
+
+// CountByKey is one row of a ranked list, such as views per path
+type CountByKey struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+func (a *AnalyticsCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	since, err := parseSince(a.Since)
+	if err != nil {
+		return err
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("since", serverNow().Add(-since).UTC().Format(time.RFC3339))
+	query.Set("top", strconv.Itoa(a.Top))
+
+	var summary AnalyticsSummary
+	if err := getJSON(apiClient, fmt.Sprintf("/admin/efmrls/%s/analytics?%s", config.Site.SiteID, query.Encode()), &summary); err != nil {
+		return fmt.Errorf("failed to fetch analytics: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(summary)
+	}
+
+	outf("Analytics (last %s)\n", a.Since)
+	outln("==================")
+	outf("Page views:      %d\n", summary.PageViews)
+	outf("Unique visitors: %d\n", summary.UniqueVisitors)
+
+	printRanking("Top paths", "PATH", summary.TopPaths, a.Top)
+	printRanking("Top referrers", "REFERRER", summary.TopReferrers, a.Top)
+	return nil
+}
+
+// printRanking prints a ranked list as a two-column table
+func printRanking(title, column string, rows []CountByKey, limit int) {
+	outf("\n%s:\n", title)
+	if len(rows) == 0 {
+		outln("  (none)")
+		return
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	w := tabwriter.NewWriter(humanOutput(), 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  %s\tVIEWS\n", column)
+	for _, row := range rows {
+		fmt.Fprintf(w, "  %s\t%d\n", orDash(row.Key), row.Count)
+	}
+	w.Flush()
+}
+
+// parseSince parses a lookback period. It accepts Go durations ("36h") plus
+// whole days ("7d") and weeks ("2w").
+func parseSince(since string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(since, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count <= 0 {
+				return 0, fmt.Errorf("invalid period %q (use e.g. 24h, 7d, 2w)", since)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(since)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid period %q (use e.g. 24h, 7d, 2w)", since)
+	}
+	return d, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseSince tests parsing of lookback periods
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		since    string
+		expected time.Duration
+	}{
+		{"24h", 24 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSince(tt.since)
+		if err != nil {
+			t.Errorf("parseSince(%s) failed: %v", tt.since, err)
+		} else if got != tt.expected {
+			t.Errorf("parseSince(%s) = %v, expected %v", tt.since, got, tt.expected)
+		}
+	}
+
+	for _, bad := range []string{"", "d", "0d", "-1d", "7x", "-5h"} {
+		if _, err := parseSince(bad); err == nil {
+			t.Errorf("Expected error for %q, got nil", bad)
+		}
+	}
+}
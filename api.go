@@ -2,22 +2,36 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"sync"
 )
 
+// errDeployKeyRejected is returned when the server refuses the deploy key in
+// TokenEnvVar
+var errDeployKeyRejected = &AuthError{Err: fmt.Errorf("the deploy key in %s was rejected (revoked, expired, or for a different efmrl)", TokenEnvVar)}
+
 // APIClient handles authenticated API requests to the efmrl server
 type APIClient struct {
-	BaseURL       string
-	host          string
+	BaseURL string
+	host    string
+
+	// mu guards the refresh state below, and is held through a refresh, so
+	// requests made concurrently share one refresh rather than each
+	// starting their own
+	mu            sync.Mutex
 	refreshFailed bool // true after a failed token refresh; prevents repeated attempts
+	refreshedSoon bool // true after refreshing a token about to expire; prevents a loop if the clock is off
 }
 
 // AuthFailed reports whether a token refresh was attempted and failed.
 func (c *APIClient) AuthFailed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.refreshFailed
 }
 
@@ -38,59 +52,122 @@ func NewAPIClient(baseURL string) (*APIClient, error) {
 	}, nil
 }
 
-// getAccessToken retrieves the access token from global config
+// getAccessToken retrieves the access token: a deploy key from the
+// environment if one is set, otherwise the credentials saved by login
 func (c *APIClient) getAccessToken() (string, error) {
-	config, err := LoadGlobalConfig()
-	if err != nil {
-		return "", fmt.Errorf("failed to load credentials: %w", err)
+	if token := os.Getenv(TokenEnvVar); token != "" {
+		return token, nil
 	}
 
-	creds, ok := config.GetHostCredentials(c.host)
-	if !ok || creds.AccessToken == "" {
-		return "", fmt.Errorf("not logged in to %s (run 'efmrl3 login' first)", c.host)
+	creds, err := c.savedCredentials()
+	if err != nil {
+		return "", err
+	}
+	if creds.RefreshToken != "" && tokenExpiring(creds.AccessToken) && c.refreshExpiring(creds.AccessToken) {
+		if creds, err = c.savedCredentials(); err != nil {
+			return "", err
+		}
 	}
-
 	return creds.AccessToken, nil
 }
 
-// refreshTokenIfNeeded attempts to refresh the access token using the refresh token.
-// It branches on creds.Provider to use the correct refresh mechanism.
-func (c *APIClient) refreshTokenIfNeeded() error {
+// savedCredentials loads the credentials login saved for the client's host
+func (c *APIClient) savedCredentials() (HostCredentials, error) {
 	config, err := LoadGlobalConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load credentials: %w", err)
+		return HostCredentials{}, fmt.Errorf("failed to load credentials: %w", err)
 	}
-
 	creds, ok := config.GetHostCredentials(c.host)
-	if !ok || creds.RefreshToken == "" {
-		return fmt.Errorf("no refresh token available (run 'efmrl3 login' again)")
+	if !ok || creds.AccessToken == "" {
+		return HostCredentials{}, &AuthError{Err: fmt.Errorf("not logged in to %s (run 'efmrl3 login' first)", c.host)}
 	}
+	return creds, nil
+}
 
-	clientID := getGoogleClientID()
-	clientSecret := getGoogleClientSecret()
-	tokenResp, err := RefreshGoogleToken(clientID, clientSecret, creds.RefreshToken)
-	if err != nil {
-		return fmt.Errorf("failed to refresh Google token: %w", err)
+// refreshExpiring refreshes an access token that's about to expire rather
+// than wait for a 401, but only once: if it still looks expired, the clock
+// is off, and the server has the final say. A failed refresh is left to the
+// 401 too. It reports whether the saved token may have changed, including
+// by a refresh another request made while this one waited for it.
+func (c *APIClient) refreshExpiring(token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.refreshFailed {
+		return false
 	}
-	// Google may not return a new refresh_token; keep the old one if absent
-	newRefreshToken := tokenResp.RefreshToken
-	if newRefreshToken == "" {
-		newRefreshToken = creds.RefreshToken
+	if c.refreshedSoon {
+		return true
 	}
-	newCreds := HostCredentials{
-		AccessToken:  tokenResp.IDToken,
-		RefreshToken: newRefreshToken,
-		Provider:     "google",
+	c.refreshedSoon = true
+	return c.refreshTokenIfNeeded(token) == nil
+}
+
+// refreshAfter401 gets a new access token after the server rejected token.
+// Requests rejected at the same time wait for one refresh between them,
+// and after one fails, no more are tried.
+func (c *APIClient) refreshAfter401(token string) error {
+	if os.Getenv(TokenEnvVar) != "" {
+		return errDeployKeyRejected
 	}
 
-	config.SetHostCredentials(c.host, newCreds)
-	if err := SaveGlobalConfig(config); err != nil {
-		return fmt.Errorf("failed to save refreshed credentials: %w", err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.refreshFailed {
+		return errSessionExpired
+	}
+	// Another request may have refreshed it while this one waited
+	if creds, err := c.savedCredentials(); err == nil && creds.AccessToken != token && !tokenExpiring(creds.AccessToken) {
+		return nil
 	}
 
+	fmt.Fprintln(os.Stderr, "Access token expired, refreshing...")
+	if err := c.refreshTokenIfNeeded(token); err != nil {
+		c.refreshFailed = true
+		return errSessionExpired
+	}
 	return nil
 }
 
+// refreshTokenIfNeeded attempts to refresh the access token using the refresh token,
+// unless the saved one is no longer stale. Callers hold c.mu.
+func (c *APIClient) refreshTokenIfNeeded(stale string) error {
+	// Deploy keys don't expire mid-session and can't be refreshed
+	if os.Getenv(TokenEnvVar) != "" {
+		return errDeployKeyRejected
+	}
+
+	// Refresh under the global config's lock, so a refresh token another
+	// command was issued meanwhile isn't overwritten
+	return updateGlobalConfig(func(config *GlobalConfig) error {
+		creds, ok := config.GetHostCredentials(c.host)
+		if !ok || creds.RefreshToken == "" {
+			return fmt.Errorf("no refresh token available (run 'efmrl3 login' again)")
+		}
+		// Another command may have refreshed it while this one waited
+		if creds.AccessToken != stale && !tokenExpiring(creds.AccessToken) {
+			return nil
+		}
+
+		clientID := getGoogleClientID()
+		clientSecret := getGoogleClientSecret()
+		tokenResp, err := RefreshGoogleToken(clientID, clientSecret, creds.RefreshToken)
+		if err != nil {
+			return fmt.Errorf("failed to refresh Google token: %w", err)
+		}
+		// Google may not return a new refresh_token; keep the old one if absent
+		newRefreshToken := tokenResp.RefreshToken
+		if newRefreshToken == "" {
+			newRefreshToken = creds.RefreshToken
+		}
+		config.SetHostCredentials(c.host, HostCredentials{
+			AccessToken:  tokenResp.IDToken,
+			RefreshToken: newRefreshToken,
+			Provider:     "google",
+		})
+		return nil
+	})
+}
+
 // doRequest performs an HTTP request with authentication
 func (c *APIClient) doRequest(method, path string, body interface{}) (*http.Response, error) {
 	url := c.BaseURL + path
@@ -123,7 +200,7 @@ func (c *APIClient) doRequest(method, path string, body interface{}) (*http.Resp
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
 
 	// Send request
-	client := &http.Client{}
+	client := newHTTPClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
@@ -133,15 +210,8 @@ func (c *APIClient) doRequest(method, path string, body interface{}) (*http.Resp
 	if resp.StatusCode == http.StatusUnauthorized {
 		resp.Body.Close()
 
-		if c.refreshFailed {
-			return nil, fmt.Errorf("session expired — run 'efmrl3 login' to re-authenticate")
-		}
-
-		fmt.Fprintln(os.Stderr, "Access token expired, refreshing...")
-
-		if err := c.refreshTokenIfNeeded(); err != nil {
-			c.refreshFailed = true
-			return nil, fmt.Errorf("session expired — run 'efmrl3 login' to re-authenticate")
+		if err := c.refreshAfter401(accessToken); err != nil {
+			return nil, err
 		}
 
 		// Retry the request with the new token
@@ -150,6 +220,10 @@ func (c *APIClient) doRequest(method, path string, body interface{}) (*http.Resp
 			return nil, err
 		}
 
+		// The first attempt consumed the body
+		if err := rewindBody(req); err != nil {
+			return nil, fmt.Errorf("failed to create retry request: %w", err)
+		}
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
 
 		resp, err = client.Do(req)
@@ -161,6 +235,20 @@ func (c *APIClient) doRequest(method, path string, body interface{}) (*http.Resp
 	return resp, nil
 }
 
+// rewindBody gives req a fresh copy of its body from req.GetBody, so it can
+// be sent again. A request without a body is left alone.
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
 // Get performs a GET request
 func (c *APIClient) Get(path string) (*http.Response, error) {
 	return c.doRequest("GET", path, nil)
@@ -176,6 +264,11 @@ func (c *APIClient) Patch(path string, body interface{}) (*http.Response, error)
 	return c.doRequest("PATCH", path, body)
 }
 
+// Put performs a PUT request
+func (c *APIClient) Put(path string, body interface{}) (*http.Response, error) {
+	return c.doRequest("PUT", path, body)
+}
+
 // Delete performs a DELETE request
 func (c *APIClient) Delete(path string) (*http.Response, error) {
 	return c.doRequest("DELETE", path, nil)
@@ -183,11 +276,23 @@ func (c *APIClient) Delete(path string) (*http.Response, error) {
 
 // doBinaryRequest performs an HTTP request with a raw binary body and custom headers.
 // Used for multipart part uploads where the body is raw bytes, not JSON.
+// The request, reading the response included, has a deadline for the body's size.
 func (c *APIClient) doBinaryRequest(method, path string, headers map[string]string, body []byte) (*http.Response, error) {
+	ctx, cancel := uploadContext(int64(len(body)))
+	resp, err := c.doBinaryRequestContext(ctx, method, path, headers, body)
+	if err != nil {
+		cancel()
+		return nil, uploadTimeoutError(ctx, int64(len(body)), err)
+	}
+	resp.Body = cancelOnClose{resp.Body, cancel}
+	return resp, nil
+}
+
+func (c *APIClient) doBinaryRequestContext(ctx context.Context, method, path string, headers map[string]string, body []byte) (*http.Response, error) {
 	url := c.BaseURL + path
 
 	makeReq := func(token string) (*http.Request, error) {
-		req, err := http.NewRequest(method, url, bytes.NewReader(body))
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
 		if err != nil {
 			return nil, err
 		}
@@ -209,7 +314,7 @@ func (c *APIClient) doBinaryRequest(method, path string, headers map[string]stri
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpClient := &http.Client{}
+	httpClient := newHTTPClient()
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
@@ -218,15 +323,8 @@ func (c *APIClient) doBinaryRequest(method, path string, headers map[string]stri
 	if resp.StatusCode == http.StatusUnauthorized {
 		resp.Body.Close()
 
-		if c.refreshFailed {
-			return nil, fmt.Errorf("session expired — run 'efmrl3 login' to re-authenticate")
-		}
-
-		fmt.Fprintln(os.Stderr, "Access token expired, refreshing...")
-
-		if err := c.refreshTokenIfNeeded(); err != nil {
-			c.refreshFailed = true
-			return nil, fmt.Errorf("session expired — run 'efmrl3 login' to re-authenticate")
+		if err := c.refreshAfter401(accessToken); err != nil {
+			return nil, err
 		}
 
 		accessToken, err = c.getAccessToken()
@@ -248,3 +346,53 @@ func (c *APIClient) doBinaryRequest(method, path string, headers map[string]stri
 	return resp, nil
 }
 
+// getJSON performs a GET request and decodes a JSON response into v
+func getJSON(client *APIClient, path string, v any) error {
+	resp, err := client.Get(path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// postJSON performs a POST request and checks that it succeeded, discarding
+// the response body
+func postJSON(client *APIClient, path string, body any) error {
+	resp, err := client.Post(path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// putJSON performs a PUT request and checks that it succeeded, discarding
+// the response body
+func putJSON(client *APIClient, path string, body any) error {
+	resp, err := client.Put(path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
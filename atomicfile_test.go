@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeString(s string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+
+	if err := writeFileAtomic(path, 0600, writeString("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, 0644, writeString("second")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "second" {
+		t.Errorf("Expected the file replaced, got %q", data)
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file to keep mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// A failed write leaves the old file, and nothing else
+	err := writeFileAtomic(path, 0600, func(w io.Writer) error {
+		io.WriteString(w, "half")
+		return errors.New("crashed")
+	})
+	if err == nil {
+		t.Fatalf("Expected the write to fail")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "second" {
+		t.Errorf("Expected the old file kept, got %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected the temporary file removed, got %d file(s)", len(entries))
+	}
+}
+
+func TestWriteFileAtomicSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "config.toml")
+	link := filepath.Join(dir, "config.toml")
+	os.MkdirAll(filepath.Dir(target), 0755)
+	os.WriteFile(target, []byte("old"), 0600)
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("can't make a symlink: %v", err)
+	}
+
+	if err := writeFileAtomic(link, 0600, writeString("new")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the symlink kept")
+	}
+	if data, _ := os.ReadFile(target); string(data) != "new" {
+		t.Errorf("Expected the symlink's target replaced, got %q", data)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// caseConflictError lists groups of site paths that differ only in case.
+// Such files can't all exist on a case-insensitive filesystem (macOS,
+// Windows), so syncs from there and from elsewhere would keep deleting and
+// re-uploading them.
+type caseConflictError struct {
+	Groups [][]string // each path is followed by where it is, e.g. "/Logo.png (local)"
+}
+
+func (e *caseConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d set(s) of files differ only in case:\n", len(e.Groups))
+	for _, group := range e.Groups {
+		fmt.Fprintf(&b, "  %s\n", strings.Join(group, ", "))
+	}
+	b.WriteString("Case-insensitive filesystems (macOS, Windows) can only hold one of each, so syncs from different machines would flip-flop between them. ")
+	b.WriteString("Rename files so they differ by more than case, or sync with --delete to replace the copy on the site.")
+	return b.String()
+}
+
+// checkCaseConflicts fails if local files differ only in case from each
+// other, or from a remote file that would stay on the site. Remote files
+// being deleted don't count, so a rename that only changes case works with
+// --delete.
+func checkCaseConflicts(local []LocalFile, remote []RemoteFile, deleting bool) error {
+	localPaths := make(map[string]bool, len(local))
+	groups := make(map[string][]string)
+	for _, lf := range local {
+		localPaths[lf.Path] = true
+		key := strings.ToLower(lf.Path)
+		groups[key] = append(groups[key], lf.Path+" (local)")
+	}
+	if !deleting {
+		for _, rf := range remote {
+			key := strings.ToLower(rf.Path)
+			if !localPaths[rf.Path] && groups[key] != nil {
+				groups[key] = append(groups[key], rf.Path+" (on the site)")
+			}
+		}
+	}
+
+	var conflicts [][]string
+	for _, group := range groups {
+		if len(group) > 1 {
+			sort.Strings(group)
+			conflicts = append(conflicts, group)
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i][0] < conflicts[j][0] })
+	return &caseConflictError{Groups: conflicts}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCheckCaseConflicts(t *testing.T) {
+	local := func(paths ...string) []LocalFile {
+		var files []LocalFile
+		for _, p := range paths {
+			files = append(files, LocalFile{Path: p})
+		}
+		return files
+	}
+	remote := func(paths ...string) []RemoteFile {
+		var files []RemoteFile
+		for _, p := range paths {
+			files = append(files, RemoteFile{Path: p})
+		}
+		return files
+	}
+
+	tests := []struct {
+		name     string
+		local    []LocalFile
+		remote   []RemoteFile
+		deleting bool
+		want     string
+	}{
+		{
+			name:   "no conflicts",
+			local:  local("/logo.png", "/index.html"),
+			remote: remote("/logo.png", "/old.html"),
+		},
+		{
+			name:  "local files differing in case",
+			local: local("/logo.png", "/Logo.png", "/img/A.svg", "/img/a.svg"),
+			want:  "[[/Logo.png (local) /logo.png (local)] [/img/A.svg (local) /img/a.svg (local)]]",
+		},
+		{
+			name:   "a remote file that would stay",
+			local:  local("/logo.png"),
+			remote: remote("/Logo.png"),
+			want:   "[[/Logo.png (on the site) /logo.png (local)]]",
+		},
+		{
+			name:     "a remote file being deleted",
+			local:    local("/logo.png"),
+			remote:   remote("/Logo.png"),
+			deleting: true,
+		},
+		{
+			name:   "remote files differing in case with no local file",
+			local:  local("/index.html"),
+			remote: remote("/Logo.png", "/logo.png"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCaseConflicts(tt.local, tt.remote, tt.deleting)
+			var conflict *caseConflictError
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tt.want != "" && !errors.As(err, &conflict):
+				t.Errorf("Expected a case conflict, got %v", err)
+			case tt.want != "" && fmt.Sprint(conflict.Groups) != tt.want:
+				t.Errorf("Expected %s, got %v", tt.want, conflict.Groups)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// ciEnvironment is set by main to the detected CI service, if any
+var ciEnvironment *CIInfo
+
+// CIInfo describes the CI service efmrl3 is running under
+type CIInfo struct {
+	Provider string // e.g. "github-actions", or "ci" when only CI=true is set
+	RunURL   string // link to the job or workflow run, when the provider has one
+}
+
+// detectCI returns the CI service efmrl3 is running under, or nil when it
+// isn't running in CI. Nobody is watching a CI job, so main then turns off
+// prompts, color, and opening the browser.
+func detectCI() *CIInfo {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		info := &CIInfo{Provider: "github-actions"}
+		server, repo, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+		if server != "" && repo != "" && runID != "" {
+			info.RunURL = server + "/" + repo + "/actions/runs/" + runID
+		}
+		return info
+	case os.Getenv("GITLAB_CI") == "true":
+		return &CIInfo{Provider: "gitlab", RunURL: os.Getenv("CI_JOB_URL")}
+	case os.Getenv("CIRCLECI") == "true":
+		return &CIInfo{Provider: "circleci", RunURL: os.Getenv("CIRCLE_BUILD_URL")}
+	}
+
+	if ci, _ := strconv.ParseBool(os.Getenv("CI")); ci {
+		return &CIInfo{Provider: "ci"}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+// TestDetectCI tests provider detection and run URLs
+func TestDetectCI(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected *CIInfo
+	}{
+		{"none", nil, nil},
+		{"CI=false", map[string]string{"CI": "false"}, nil},
+		{"generic", map[string]string{"CI": "true"}, &CIInfo{Provider: "ci"}},
+		{"github", map[string]string{
+			"CI":                "true",
+			"GITHUB_ACTIONS":    "true",
+			"GITHUB_SERVER_URL": "https://github.com",
+			"GITHUB_REPOSITORY": "efmrl/site",
+			"GITHUB_RUN_ID":     "42",
+		}, &CIInfo{Provider: "github-actions", RunURL: "https://github.com/efmrl/site/actions/runs/42"}},
+		{"github without run", map[string]string{"GITHUB_ACTIONS": "true"}, &CIInfo{Provider: "github-actions"}},
+		{"gitlab", map[string]string{"GITLAB_CI": "true", "CI_JOB_URL": "https://gitlab.com/j/1"},
+			&CIInfo{Provider: "gitlab", RunURL: "https://gitlab.com/j/1"}},
+		{"circleci", map[string]string{"CIRCLECI": "true", "CIRCLE_BUILD_URL": "https://circleci.com/b/2"},
+			&CIInfo{Provider: "circleci", RunURL: "https://circleci.com/b/2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"CI", "GITHUB_ACTIONS", "GITHUB_SERVER_URL", "GITHUB_REPOSITORY",
+				"GITHUB_RUN_ID", "GITLAB_CI", "CI_JOB_URL", "CIRCLECI", "CIRCLE_BUILD_URL"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			got := detectCI()
+			switch {
+			case tt.expected == nil && got != nil:
+				t.Errorf("Expected no CI, got %+v", *got)
+			case tt.expected != nil && got == nil:
+				t.Errorf("Expected %+v, got no CI", *tt.expected)
+			case tt.expected != nil && *got != *tt.expected:
+				t.Errorf("Expected %+v, got %+v", *tt.expected, *got)
+			}
+		})
+	}
+}
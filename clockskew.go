@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// clockSkewWarning is how far the local clock can be from the server's
+	// before it's worth telling the user
+	clockSkewWarning = 2 * time.Minute
+
+	// clockSkewResolution is the least skew that's believed: Date headers
+	// are only to the second, and a request takes time
+	clockSkewResolution = 2 * time.Second
+
+	// tokenRefreshMargin is how long before its expiry an access token is
+	// refreshed, so it doesn't expire between being checked and being used
+	tokenRefreshMargin = time.Minute
+)
+
+var (
+	clockSkewMu     sync.Mutex
+	clockSkew       time.Duration // how far the server's clock is ahead of the local one
+	clockSkewWarned bool
+)
+
+// observeServerDate estimates the clock skew from the Date header of a
+// response to a request sent at sent and answered at received, and warns,
+// once, if the local clock is far off
+func observeServerDate(date string, sent, received time.Time) {
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return
+	}
+	skew := serverTime.Sub(sent.Add(received.Sub(sent) / 2))
+	if skew.Abs() < clockSkewResolution {
+		skew = 0
+	}
+
+	clockSkewMu.Lock()
+	clockSkew = skew
+	warn := skew.Abs() > clockSkewWarning && !clockSkewWarned
+	if warn {
+		clockSkewWarned = true
+	}
+	clockSkewMu.Unlock()
+
+	if warn {
+		direction := "behind"
+		if skew < 0 {
+			direction = "ahead of"
+		}
+		warnf("this computer's clock is %s %s the server's. Expiry times are adjusted for it,\n"+
+			"         but set the clock right (e.g. turn on network time) to avoid surprises.\n",
+			skew.Abs().Round(time.Second), direction)
+	}
+}
+
+// serverNow is the current time by the server's clock, as far as it's known
+// from the responses so far; before any, it's the local time
+func serverNow() time.Time {
+	return toServerTime(time.Now())
+}
+
+// toServerTime converts a time by the local clock to the server's
+func toServerTime(t time.Time) time.Time {
+	clockSkewMu.Lock()
+	defer clockSkewMu.Unlock()
+	return t.Add(clockSkew)
+}
+
+// tokenExpiring reports whether an access token has expired by the
+// server's clock, or will within tokenRefreshMargin. A token whose expiry
+// can't be read is assumed to be good.
+func tokenExpiring(token string) bool {
+	exp, ok := tokenExpiry(token)
+	return ok && !serverNow().Add(tokenRefreshMargin).Before(exp)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetClockSkew forgets the skew a test observes once it's done
+func resetClockSkew(t *testing.T) {
+	t.Cleanup(func() {
+		clockSkewMu.Lock()
+		clockSkew, clockSkewWarned = 0, false
+		clockSkewMu.Unlock()
+	})
+}
+
+func TestObserveServerDate(t *testing.T) {
+	resetClockSkew(t)
+	// Date headers only have whole seconds
+	now := time.Now().Truncate(time.Second)
+
+	tests := []struct {
+		name     string
+		date     string
+		wantSkew time.Duration
+		wantWarn bool
+	}{
+		{"in sync", now.UTC().Format(http.TimeFormat), 0, false},
+		{"unparsable", "yesterday", 0, false},
+		{"server ahead", now.Add(5 * time.Minute).UTC().Format(http.TimeFormat), 5 * time.Minute, true},
+		{"warned once", now.Add(-10 * time.Minute).UTC().Format(http.TimeFormat), -10 * time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		stderr := captureStderr(t, func() { observeServerDate(tt.date, now, now) })
+		clockSkewMu.Lock()
+		skew := clockSkew
+		clockSkewMu.Unlock()
+		if (skew - tt.wantSkew).Abs() > time.Second {
+			t.Errorf("%s: expected skew %v, got %v", tt.name, tt.wantSkew, skew)
+		}
+		if warned := strings.Contains(stderr, "clock is 5m0s behind the server's"); warned != tt.wantWarn {
+			t.Errorf("%s: expected warning %v, got %q", tt.name, tt.wantWarn, stderr)
+		}
+	}
+}
+
+func TestTokenExpiring(t *testing.T) {
+	resetClockSkew(t)
+	now := time.Now()
+	token := testJWT(now.Add(10 * time.Minute).Unix())
+
+	if tokenExpiring(token) {
+		t.Errorf("Expected a token with 10 minutes left not to be expiring")
+	}
+	if tokenExpiring("efk_opaque") {
+		t.Errorf("Expected a token without an expiry not to be expiring")
+	}
+	if !tokenExpiring(testJWT(now.Add(30 * time.Second).Unix())) {
+		t.Errorf("Expected a token within the refresh margin to be expiring")
+	}
+
+	// With the server's clock 15 minutes ahead, the token has expired
+	captureStderr(t, func() { observeServerDate(now.Add(15*time.Minute).UTC().Format(http.TimeFormat), now, now) })
+	if !tokenExpiring(token) {
+		t.Errorf("Expected the token to be expiring by the server's clock")
+	}
+}
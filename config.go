@@ -1,125 +1,788 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 const ConfigFileName = "efmrl.toml"
+const ConfigFileNameJSON = "efmrl.json"
+const ConfigFileNameYAML = "efmrl.yaml"
+const ConfigFileNameYML = "efmrl.yml"
+
+// LocalConfigFileName is an optional, per-developer file merged over the
+// shared config. It should be listed in .gitignore.
+const LocalConfigFileName = "efmrl.local.toml"
 const DefaultBaseHost = "efmrl.work"
 
+// ConfigFileNames lists the supported project config files in lookup order.
+// All of them share the same schema; only the encoding differs.
+var ConfigFileNames = []string{ConfigFileName, ConfigFileNameJSON, ConfigFileNameYAML, ConfigFileNameYML}
+
 type Config struct {
-	BaseHost string     `toml:"base_host,omitempty"`
-	Site     SiteConfig `toml:"site"`
+	// BaseHost is the legacy top-level server setting; site.base_host wins if both are set
+	BaseHost string     `toml:"base_host,omitempty" json:"base_host,omitempty" yaml:"base_host,omitempty"`
+	Site     SiteConfig `toml:"site" json:"site" yaml:"site"`
+
+	// Sites holds named site profiles ([sites.<name>]) selected with --site
+	Sites map[string]SiteConfig `toml:"sites,omitempty" json:"sites,omitempty" yaml:"sites,omitempty"`
+
+	Build  BuildConfig  `toml:"build,omitempty" json:"build,omitempty" yaml:"build,omitempty"`
+	Sync   SyncConfig   `toml:"sync,omitempty" json:"sync,omitempty" yaml:"sync,omitempty"`
+	Deploy DeployConfig `toml:"deploy,omitempty" json:"deploy,omitempty" yaml:"deploy,omitempty"`
+	Verify VerifyConfig `toml:"verify,omitempty" json:"verify,omitempty" yaml:"verify,omitempty"`
+
+	Notifications NotificationsConfig `toml:"notifications,omitempty" json:"notifications,omitempty" yaml:"notifications,omitempty"`
+	Workspace     WorkspaceConfig     `toml:"workspace,omitempty" json:"workspace,omitempty" yaml:"workspace,omitempty"`
+	Performance   PerformanceConfig   `toml:"performance,omitempty" json:"performance,omitempty" yaml:"performance,omitempty"`
+
+	fileName    string     // config file this was loaded from; empty for defaults
+	siteName    string     // selected profile name; empty when using [site]
+	defaultSite SiteConfig // the [site] table as written, while a profile is selected
+	local       *Config    // overrides from efmrl.local.toml, if it exists
 }
 
 type SiteConfig struct {
-	SiteID string `toml:"site_id"`
-	Dir    string `toml:"dir,omitempty"`
+	SiteID   string `toml:"site_id" json:"site_id" yaml:"site_id"`
+	Dir      string `toml:"dir,omitempty" json:"dir,omitempty" yaml:"dir,omitempty"`
+	BaseHost string `toml:"base_host,omitempty" json:"base_host,omitempty" yaml:"base_host,omitempty"`
+}
+
+// BuildConfig describes how 'efmrl3 deploy' builds the site before syncing
+type BuildConfig struct {
+	Command   string `toml:"command,omitempty" json:"command,omitempty" yaml:"command,omitempty"`
+	OutputDir string `toml:"output_dir,omitempty" json:"output_dir,omitempty" yaml:"output_dir,omitempty"`
+}
+
+// SyncConfig tunes how sync and deploy behave
+type SyncConfig struct {
+	// ConfirmDeletes is how many remote deletions a sync may make before it
+	// asks for confirmation. Zero means the default; -1 never asks.
+	ConfirmDeletes int `toml:"confirm_deletes,omitempty" json:"confirm_deletes,omitempty" yaml:"confirm_deletes,omitempty"`
+
+	// ExpiryWarningDays is how many days before the site expires sync,
+	// deploy, and status warn about it. Zero means the default; -1 never
+	// warns.
+	ExpiryWarningDays int `toml:"expiry_warning_days,omitempty" json:"expiry_warning_days,omitempty" yaml:"expiry_warning_days,omitempty"`
+
+	// Sitemap generates and uploads a sitemap.xml of the HTML pages on
+	// every sync, leaving out paths matching SitemapExclude (which may end
+	// in "*")
+	Sitemap        bool     `toml:"sitemap,omitempty" json:"sitemap,omitempty" yaml:"sitemap,omitempty"`
+	SitemapExclude []string `toml:"sitemap_exclude,omitempty" json:"sitemap_exclude,omitempty" yaml:"sitemap_exclude,omitempty"`
+
+	// Fingerprint renames the assets HTML and CSS refer to after a hash of
+	// their content and caches them as immutable. FingerprintExtensions
+	// picks which assets, defaulting to scripts, styles, images, and fonts.
+	Fingerprint           bool     `toml:"fingerprint,omitempty" json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	FingerprintExtensions []string `toml:"fingerprint_extensions,omitempty" json:"fingerprint_extensions,omitempty" yaml:"fingerprint_extensions,omitempty"`
+
+	// OptimizeImages losslessly recompresses PNG and JPEG images before
+	// they're uploaded. ImageVariants adds a smaller copy of each image in
+	// the listed formats ("webp", "avif") next to it.
+	OptimizeImages bool     `toml:"optimize_images,omitempty" json:"optimize_images,omitempty" yaml:"optimize_images,omitempty"`
+	ImageVariants  []string `toml:"image_variants,omitempty" json:"image_variants,omitempty" yaml:"image_variants,omitempty"`
+
+	// Minify lists the kinds of file ("html", "css", "js") minified before
+	// they're uploaded. ETags are of the minified content, so an unchanged
+	// file isn't uploaded again.
+	Minify []string `toml:"minify,omitempty" json:"minify,omitempty" yaml:"minify,omitempty"`
+
+	// Stream syncs in one pass, uploading files as they're scanned rather
+	// than planning the whole sync first. It's for very large sites, and
+	// can't be combined with the settings that need every file up front.
+	Stream bool `toml:"stream,omitempty" json:"stream,omitempty" yaml:"stream,omitempty"`
+
+	// Integrity adds a Subresource Integrity hash to every script and
+	// stylesheet a page loads from the site, and keeps it up to date
+	Integrity bool `toml:"integrity,omitempty" json:"integrity,omitempty" yaml:"integrity,omitempty"`
+
+	// CheckLinks checks the site's internal links before every sync: "fail"
+	// stops the sync if any are broken, "warn" only reports them. Targets
+	// matching CheckLinksIgnore (which may end in "*") aren't checked.
+	CheckLinks       string   `toml:"check_links,omitempty" json:"check_links,omitempty" yaml:"check_links,omitempty"`
+	CheckLinksIgnore []string `toml:"check_links_ignore,omitempty" json:"check_links_ignore,omitempty" yaml:"check_links_ignore,omitempty"`
+
+	// UnicodeNormalization is the Unicode form file names are uploaded in:
+	// "nfc" (the default), which is how links and URLs spell them, or
+	// "none" to upload names exactly as the filesystem stores them
+	UnicodeNormalization string `toml:"unicode_normalization,omitempty" json:"unicode_normalization,omitempty" yaml:"unicode_normalization,omitempty"`
+
+	// EmptyDirs is what sync does about directories with nothing to sync
+	// inside, which the site can't hold: "warn" (the default), "keep" to
+	// upload a .keep file into each, or "ignore"
+	EmptyDirs string `toml:"empty_dirs,omitempty" json:"empty_dirs,omitempty" yaml:"empty_dirs,omitempty"`
+
+	// MinUploadSpeed, in KB/s, and UploadGraceSeconds set how long an
+	// upload may take before it's given up on: the grace period, plus as
+	// long as the file's size takes at the minimum speed. Zero means the
+	// default; a MinUploadSpeed of -1 never gives up.
+	MinUploadSpeed     int `toml:"min_upload_speed,omitempty" json:"min_upload_speed,omitempty" yaml:"min_upload_speed,omitempty"`
+	UploadGraceSeconds int `toml:"upload_grace_seconds,omitempty" json:"upload_grace_seconds,omitempty" yaml:"upload_grace_seconds,omitempty"`
+
+	// Compare is how sync tells whether a file on the site is up to date:
+	// "etag" (the default) by checksum; "auto" by size and modification
+	// time where the site's ETag isn't a checksum this client computes,
+	// as after some multipart uploads; or "metadata" that way for every
+	// file, for servers whose ETags never are
+	Compare string `toml:"compare,omitempty" json:"compare,omitempty" yaml:"compare,omitempty"`
+}
+
+// DeployConfig tunes how deploy behaves
+type DeployConfig struct {
+	// AllowDirty lets deploy run from a git tree with uncommitted changes
+	// without passing --allow-dirty every time
+	AllowDirty bool `toml:"allow_dirty,omitempty" json:"allow_dirty,omitempty" yaml:"allow_dirty,omitempty"`
+}
+
+// VerifyConfig picks what verify-deploy fetches from the live site
+type VerifyConfig struct {
+	// Paths are fetched on top of /, and the uploaded files after a sync
+	Paths []string `toml:"paths,omitempty" json:"paths,omitempty" yaml:"paths,omitempty"`
+
+	// AfterSync verifies the live site after every sync and deploy, as if
+	// --verify were passed
+	AfterSync bool `toml:"after_sync,omitempty" json:"after_sync,omitempty" yaml:"after_sync,omitempty"`
+}
+
+// NotificationsConfig posts a message to a chat webhook after each sync and
+// deploy
+type NotificationsConfig struct {
+	// WebhookURL is a Slack or Discord incoming webhook, or anything else
+	// that accepts Slack's {"text": ...}. $VARIABLES in it are expanded,
+	// so the secret part can stay out of the config file.
+	WebhookURL string `toml:"webhook_url,omitempty" json:"webhook_url,omitempty" yaml:"webhook_url,omitempty"`
+
+	// Events picks the outcomes posted, "success" and "failure"; both by
+	// default
+	Events []string `toml:"events,omitempty" json:"events,omitempty" yaml:"events,omitempty"`
+}
+
+// WorkspaceConfig, in the config file at the root of a monorepo, lists the
+// directories holding its sites' own config files
+type WorkspaceConfig struct {
+	// Packages are directories relative to the root, which may use glob
+	// patterns like "sites/*"
+	Packages []string `toml:"packages,omitempty" json:"packages,omitempty" yaml:"packages,omitempty"`
+}
+
+// DefaultConfirmDeletes is the deletion count above which sync asks first
+const DefaultConfirmDeletes = 50
+
+// DefaultExpiryWarningDays is how close to its expiry a site has to be
+// before sync, deploy, and status warn about it
+const DefaultExpiryWarningDays = 7
+
+// hostOverride is set from the global --host flag (or EFMRL_HOST) and takes
+// precedence over anything in the config file
+var hostOverride string
+
+// siteOverride is set from the global --site flag (or EFMRL_SITE) and names
+// the [sites.<name>] profile to use
+var siteOverride string
+
+// siteIDOverride is set from the global --site-id flag (or EFMRL_SITE_ID).
+// It replaces the configured site ID, and makes the config file optional.
+var siteIDOverride string
+
+// FileName returns the name of the config file this config was loaded from,
+// or the default efmrl.toml if it wasn't loaded from disk.
+func (c *Config) FileName() string {
+	if c.fileName == "" {
+		return ConfigFileName
+	}
+	return c.fileName
+}
+
+// findConfigFile returns the name of the project config file in the current
+// directory, or "" if there is none. It's an error for more than one
+// supported config file to exist, since it would be ambiguous which one wins.
+func findConfigFile() (string, error) {
+	return findConfigFileIn(".")
+}
+
+// findConfigFileIn is findConfigFile for another directory
+func findConfigFileIn(dir string) (string, error) {
+	var found []string
+	for _, name := range ConfigFileNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			found = append(found, name)
+		}
+	}
+
+	where := "current directory"
+	if dir != "." {
+		where = dir
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("multiple config files found in %s (%s and %s); keep only one",
+			where, found[0], found[1])
+	}
+}
+
+// decodeConfigFile parses a config file, choosing the decoder by extension.
+// Unknown keys are rejected, and errors carry the line and column.
+func decodeConfigFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch filepath.Ext(path) {
+	case ".json":
+		return decodeJSONStrict(data, config)
+	case ".yaml", ".yml":
+		return decodeYAMLStrict(data, config)
+	default:
+		return decodeTOMLStrict(data, config)
+	}
 }
 
-// LoadConfig loads the efmrl.toml config file from the current directory
+// LoadConfig loads the project config file (efmrl.toml, efmrl.json, or
+// efmrl.yaml or .yml) from the current directory, with the --site profile
+// selected and --site-id applied. The file may be absent if --site-id is
+// given.
 func LoadConfig() (*Config, error) {
-	configPath := filepath.Join(".", ConfigFileName)
+	fileName, err := findConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// With --site-id, everything else can come from flags and defaults
+	config := &Config{}
+	if fileName != "" {
+		if config, err = loadConfigFile(fileName); err != nil {
+			return nil, err
+		}
+	} else if siteIDOverride == "" {
+		return nil, &MissingConfigError{Err: fmt.Errorf("no %s, %s, or %s file found in current directory (or pass --site-id)",
+			ConfigFileName, ConfigFileNameJSON, ConfigFileNameYAML)}
+	}
+
+	if err := config.applyLocalOverrides(); err != nil {
+		return nil, err
+	}
+
+	if err := config.selectSite(siteOverride, false); err != nil {
+		return nil, err
+	}
+
+	if siteIDOverride != "" {
+		config.Site.SiteID = siteIDOverride
+	}
+
+	for _, warning := range config.validate() {
+		warnf("%s: %s\n", config.FileName(), warning)
+	}
+
+	return config, nil
+}
+
+// LoadSiteConfigs loads the project config once for each site it
+// configures: [site], if it has a site_id, then every [sites.<name>]
+// profile in name order. It's for commands that act on all of them, so
+// --site and --site-id don't apply.
+func LoadSiteConfigs() ([]*Config, error) {
+	fileName, err := findConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if fileName == "" {
+		return nil, &MissingConfigError{Err: fmt.Errorf("no %s, %s, or %s file found in current directory",
+			ConfigFileName, ConfigFileNameJSON, ConfigFileNameYAML)}
+	}
+
+	base, err := loadConfigFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := base.applyLocalOverrides(); err != nil {
+		return nil, err
+	}
+	for _, warning := range base.validate() {
+		warnf("%s: %s\n", base.FileName(), warning)
+	}
 
-	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("no %s file found in current directory", ConfigFileName)
+	var configs []*Config
+	if base.Site.SiteID != "" {
+		configs = append(configs, base)
+	}
+	for _, name := range base.SiteNames() {
+		config := *base
+		if err := config.selectSite(name, false); err != nil {
+			return nil, err
+		}
+		configs = append(configs, &config)
 	}
+	if len(configs) == 0 {
+		return nil, errNoSiteID
+	}
+	return configs, nil
+}
 
+// loadConfigFile decodes the named config file without selecting a profile
+func loadConfigFile(fileName string) (*Config, error) {
 	var config Config
-	if _, err := toml.DecodeFile(configPath, &config); err != nil {
-		return nil, fmt.Errorf("error parsing %s: %w", ConfigFileName, err)
+	if err := decodeConfigFile(filepath.Join(".", fileName), &config); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", fileName, err)
 	}
+	config.fileName = fileName
 
 	return &config, nil
 }
 
-// LoadConfigOrDefault loads the config file, or returns a default config if it doesn't exist
+// LoadConfigOrDefault loads the config file, or returns a default config if
+// it doesn't exist. Unlike LoadConfig, a --site profile that doesn't exist
+// yet is created (empty) rather than being an error.
 func LoadConfigOrDefault() (*Config, error) {
-	config, err := LoadConfig()
+	config, err := loadConfigForEdit(false)
 	if err != nil {
-		// Return default config
-		return &Config{
-			BaseHost: DefaultBaseHost,
-			Site:     SiteConfig{},
-		}, nil
+		return nil, err
 	}
+
+	if err := config.applyLocalOverrides(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
-// SaveConfig saves the config to the efmrl.toml file in the current directory
-func SaveConfig(config *Config) error {
-	configPath := filepath.Join(".", ConfigFileName)
+// loadConfigForEdit loads the shared config, or efmrl.local.toml if local is
+// set, without merging the two, so that it can be modified and saved
+func loadConfigForEdit(local bool) (*Config, error) {
+	fileName := LocalConfigFileName
+	if !local {
+		var err error
+		if fileName, err = findConfigFile(); err != nil {
+			return nil, err
+		}
+	}
 
-	file, err := os.Create(configPath)
-	if err != nil {
-		return fmt.Errorf("error creating %s: %w", ConfigFileName, err)
+	config := &Config{fileName: fileName}
+	if fileName != "" {
+		if _, err := os.Stat(filepath.Join(".", fileName)); err == nil {
+			if config, err = loadConfigFile(fileName); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := config.selectSite(siteOverride, true); err != nil {
+		return nil, err
 	}
-	defer file.Close()
 
-	encoder := toml.NewEncoder(file)
-	if err := encoder.Encode(config); err != nil {
-		return fmt.Errorf("error writing %s: %w", ConfigFileName, err)
+	return config, nil
+}
+
+// applyLocalOverrides merges efmrl.local.toml, if present, over the config.
+// Only values that are set in the local file replace shared ones.
+func (c *Config) applyLocalOverrides() error {
+	localPath := filepath.Join(".", LocalConfigFileName)
+	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+		return nil
 	}
 
+	var local Config
+	if err := decodeConfigFile(localPath, &local); err != nil {
+		return fmt.Errorf("error parsing %s: %w", LocalConfigFileName, err)
+	}
+
+	if local.BaseHost != "" {
+		c.BaseHost = local.BaseHost
+	}
+	if local.Build.Command != "" {
+		c.Build.Command = local.Build.Command
+	}
+	if local.Build.OutputDir != "" {
+		c.Build.OutputDir = local.Build.OutputDir
+	}
+	if local.Sync.ConfirmDeletes != 0 {
+		c.Sync.ConfirmDeletes = local.Sync.ConfirmDeletes
+	}
+	if local.Sync.ExpiryWarningDays != 0 {
+		c.Sync.ExpiryWarningDays = local.Sync.ExpiryWarningDays
+	}
+	if local.Sync.Sitemap {
+		c.Sync.Sitemap = true
+	}
+	if len(local.Sync.SitemapExclude) > 0 {
+		c.Sync.SitemapExclude = local.Sync.SitemapExclude
+	}
+	if local.Sync.Fingerprint {
+		c.Sync.Fingerprint = true
+	}
+	if len(local.Sync.FingerprintExtensions) > 0 {
+		c.Sync.FingerprintExtensions = local.Sync.FingerprintExtensions
+	}
+	if local.Sync.OptimizeImages {
+		c.Sync.OptimizeImages = true
+	}
+	if len(local.Sync.ImageVariants) > 0 {
+		c.Sync.ImageVariants = local.Sync.ImageVariants
+	}
+	if len(local.Sync.Minify) > 0 {
+		c.Sync.Minify = local.Sync.Minify
+	}
+	if local.Sync.Stream {
+		c.Sync.Stream = true
+	}
+	if local.Sync.Integrity {
+		c.Sync.Integrity = true
+	}
+	if local.Sync.CheckLinks != "" {
+		c.Sync.CheckLinks = local.Sync.CheckLinks
+	}
+	if len(local.Sync.CheckLinksIgnore) > 0 {
+		c.Sync.CheckLinksIgnore = local.Sync.CheckLinksIgnore
+	}
+	if local.Sync.UnicodeNormalization != "" {
+		c.Sync.UnicodeNormalization = local.Sync.UnicodeNormalization
+	}
+	if local.Sync.EmptyDirs != "" {
+		c.Sync.EmptyDirs = local.Sync.EmptyDirs
+	}
+	if local.Sync.MinUploadSpeed != 0 {
+		c.Sync.MinUploadSpeed = local.Sync.MinUploadSpeed
+	}
+	if local.Sync.UploadGraceSeconds != 0 {
+		c.Sync.UploadGraceSeconds = local.Sync.UploadGraceSeconds
+	}
+	if local.Sync.Compare != "" {
+		c.Sync.Compare = local.Sync.Compare
+	}
+	if local.Deploy.AllowDirty {
+		c.Deploy.AllowDirty = true
+	}
+	c.Performance = c.Performance.overlay(local.Performance)
+	if len(local.Verify.Paths) > 0 {
+		c.Verify.Paths = local.Verify.Paths
+	}
+	if local.Verify.AfterSync {
+		c.Verify.AfterSync = true
+	}
+	if local.Notifications.WebhookURL != "" {
+		c.Notifications.WebhookURL = local.Notifications.WebhookURL
+	}
+	if len(local.Notifications.Events) > 0 {
+		c.Notifications.Events = local.Notifications.Events
+	}
+
+	// The active site may already have been selected (LoadConfigOrDefault)
+	if c.siteName == "" {
+		mergeSite(&c.Site, local.Site)
+	} else {
+		mergeSite(&c.defaultSite, local.Site)
+		mergeSite(&c.Site, local.Sites[c.siteName])
+	}
+
+	for name, site := range local.Sites {
+		if c.Sites == nil {
+			c.Sites = make(map[string]SiteConfig)
+		}
+		merged := c.Sites[name]
+		mergeSite(&merged, site)
+		c.Sites[name] = merged
+	}
+
+	c.local = &local
 	return nil
 }
 
-// GetBaseHost returns the base host from config, or the default if not set
+// mergeSite copies the values that are set in src over dst
+func mergeSite(dst *SiteConfig, src SiteConfig) {
+	if src.SiteID != "" {
+		dst.SiteID = src.SiteID
+	}
+	if src.Dir != "" {
+		dst.Dir = src.Dir
+	}
+	if src.BaseHost != "" {
+		dst.BaseHost = src.BaseHost
+	}
+}
+
+// selectSite makes the named [sites.<name>] profile the active Site. With no
+// name, [site] is used, unless it's empty and there is exactly one profile.
+func (c *Config) selectSite(name string, create bool) error {
+	if name == "" {
+		if c.Site.SiteID != "" || len(c.Sites) == 0 {
+			return nil
+		}
+		if len(c.Sites) > 1 {
+			if create {
+				return nil
+			}
+			return fmt.Errorf("multiple sites configured (%s); choose one with --site",
+				strings.Join(c.SiteNames(), ", "))
+		}
+		name = c.SiteNames()[0]
+	}
+
+	site, ok := c.Sites[name]
+	if !ok && !create {
+		if len(c.Sites) == 0 {
+			return fmt.Errorf("no site named %q in %s", name, c.FileName())
+		}
+		return fmt.Errorf("no site named %q in %s (have: %s)",
+			name, c.FileName(), strings.Join(c.SiteNames(), ", "))
+	}
+
+	c.defaultSite = c.Site
+	c.Site = site
+	c.siteName = name
+	return nil
+}
+
+// SiteName returns the selected profile name, or "" when using [site]
+func (c *Config) SiteName() string {
+	return c.siteName
+}
+
+// SiteNames returns the names of all configured site profiles, sorted
+func (c *Config) SiteNames() []string {
+	names := make([]string, 0, len(c.Sites))
+	for name := range c.Sites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fileView returns the config as it should be written to disk, with the
+// active profile stored back under [sites.<name>]
+func (c *Config) fileView() *Config {
+	if c.siteName == "" {
+		return c
+	}
+
+	view := *c
+	view.Site = c.defaultSite
+	view.Sites = make(map[string]SiteConfig, len(c.Sites)+1)
+	for name, site := range c.Sites {
+		view.Sites[name] = site
+	}
+	view.Sites[c.siteName] = c.Site
+	return &view
+}
+
+// SaveConfig saves the config to the file it was loaded from, or to
+// efmrl.toml in the current directory for a new config
+func SaveConfig(config *Config) error {
+	if config.local != nil {
+		return fmt.Errorf("refusing to save a config merged with %s", LocalConfigFileName)
+	}
+
+	fileName := config.FileName()
+	configPath := filepath.Join(".", fileName)
+
+	view := config.fileView()
+	err := writeFileAtomic(configPath, 0644, func(w io.Writer) error {
+		switch filepath.Ext(fileName) {
+		case ".json":
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(view)
+		case ".yaml", ".yml":
+			encoder := yaml.NewEncoder(w)
+			encoder.SetIndent(2)
+			return encoder.Encode(view)
+		default:
+			return toml.NewEncoder(w).Encode(view)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", fileName, err)
+	}
+
+	return nil
+}
+
+// GetBaseHost returns the efmrl server host. In order of precedence: the
+// --host flag or EFMRL_HOST, the active site's base_host, [site].base_host
+// when a profile is active, the top-level base_host, and finally the default.
 func (c *Config) GetBaseHost() string {
-	if c.BaseHost == "" {
-		return DefaultBaseHost
+	host, _ := c.resolveBaseHost()
+	return host
+}
+
+// resolveBaseHost returns the base host along with a description of where it
+// came from, for GetBaseHost and config show
+func (c *Config) resolveBaseHost() (string, string) {
+	switch {
+	case hostOverride != "":
+		return hostOverride, flagSource("host", "EFMRL_HOST")
+	case c.Site.BaseHost != "":
+		return c.Site.BaseHost, c.fieldSource(func(s SiteConfig) string { return s.BaseHost })
+	case c.defaultSite.BaseHost != "":
+		if c.local != nil && c.local.Site.BaseHost != "" {
+			return c.defaultSite.BaseHost, LocalConfigFileName + " [site]"
+		}
+		return c.defaultSite.BaseHost, c.FileName() + " [site]"
+	case c.BaseHost != "":
+		if c.local != nil && c.local.BaseHost != "" {
+			return c.BaseHost, LocalConfigFileName + " (top level)"
+		}
+		return c.BaseHost, c.FileName() + " (top level)"
+	default:
+		return DefaultBaseHost, "default"
 	}
-	return c.BaseHost
 }
 
+// siteSource describes the config table the active site was read from
+func (c *Config) siteSource() string {
+	return siteTable(c.FileName(), c.siteName)
+}
+
+// fieldSource describes where one field of the active site came from,
+// taking efmrl.local.toml overrides into account
+func (c *Config) fieldSource(field func(SiteConfig) string) string {
+	if c.local != nil {
+		localSite := c.local.Site
+		if c.siteName != "" {
+			localSite = c.local.Sites[c.siteName]
+		}
+		if field(localSite) != "" {
+			return siteTable(LocalConfigFileName, c.siteName)
+		}
+	}
+	return c.siteSource()
+}
+
+func siteTable(fileName, siteName string) string {
+	if siteName != "" {
+		return fmt.Sprintf("%s [sites.%s]", fileName, siteName)
+	}
+	return fileName + " [site]"
+}
+
+// SyncDir returns the directory to sync: the site's dir, else the build
+// output directory, else the current directory
+func (c *Config) SyncDir() string {
+	switch {
+	case c.Site.Dir != "":
+		return c.Site.Dir
+	case c.Build.OutputDir != "":
+		return c.Build.OutputDir
+	default:
+		return "."
+	}
+}
+
+// ConfirmDeletes returns how many deletions a sync may make without asking,
+// or -1 if it should never ask
+func (c *Config) ConfirmDeletes() int {
+	if c.Sync.ConfirmDeletes == 0 {
+		return DefaultConfirmDeletes
+	}
+	return c.Sync.ConfirmDeletes
+}
+
+// ExpiryWarningDays returns how many days before the site expires sync,
+// deploy, and status start warning about it, or -1 to never warn
+func (c *Config) ExpiryWarningDays() int {
+	if c.Sync.ExpiryWarningDays == 0 {
+		return DefaultExpiryWarningDays
+	}
+	return c.Sync.ExpiryWarningDays
+}
+
+// UnicodeNormalization returns the Unicode form file names are uploaded in:
+// normalizeNFC or normalizeNone
+func (c *Config) UnicodeNormalization() string {
+	if c.Sync.UnicodeNormalization == "" {
+		return normalizeNFC
+	}
+	return c.Sync.UnicodeNormalization
+}
+
+// BaseURL returns the URL that all API requests for this config are built on
+func (c *Config) BaseURL() string {
+	return hostToBaseURL(c.GetBaseHost())
+}
+
+// resolveHost returns the server host for commands that don't require a
+// project config, such as login and logout
+func resolveHost() string {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		config = &Config{}
+	}
+	return config.GetBaseHost()
+}
+
+// ConfigCmd views or modifies the project configuration
 type ConfigCmd struct {
+	Set  ConfigSetCmd  `cmd:"" default:"withargs" help:"Set configuration values, or display them with no flags (default)"`
+	Show ConfigShowCmd `cmd:"" help:"Show the effective configuration and where each value comes from"`
+	Edit ConfigEditCmd `cmd:"" help:"Open the config file in $EDITOR, saving only if it's valid"`
+}
+
+// ConfigSetCmd updates efmrl.toml, or displays it when no flags are given
+type ConfigSetCmd struct {
 	ID       string `help:"Set the site ID"`
 	Dir      string `help:"Set the directory to sync"`
-	BaseHost string `hidden:"" help:"Set the base host for the efmrl server"`
+	BaseHost string `help:"Set the base host for the efmrl server"`
+	Local    bool   `help:"Write to efmrl.local.toml instead of the shared config"`
 }
 
-func (c *ConfigCmd) Run() error {
-	// Load existing config or create default
-	config, err := LoadConfigOrDefault()
+func (c *ConfigSetCmd) Run() error {
+	// If no flags were provided, just display current config
+	if c.ID == "" && c.Dir == "" && c.BaseHost == "" {
+		config, err := LoadConfigOrDefault()
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+
+		if jsonOutput {
+			return printJSON(config.summary())
+		}
+
+		outln("Current Configuration")
+		outln("=====================")
+		if config.SiteName() != "" {
+			outf("Site:      %s\n", config.SiteName())
+		}
+		outf("Site ID:   %s\n", config.Site.SiteID)
+		outf("Dir:       %s\n", config.Site.Dir)
+		outf("Base Host: %s\n", config.GetBaseHost())
+		if len(config.Sites) > 0 {
+			outf("\nSites:     %s\n", strings.Join(config.SiteNames(), ", "))
+		}
+		outf("\nConfig file: %s\n", config.FileName())
+		if config.local != nil {
+			outf("Overrides:   %s\n", LocalConfigFileName)
+		}
+		return nil
+	}
+
+	// Load the file being edited on its own, so local overrides never leak
+	// into the shared config (or vice versa)
+	config, err := loadConfigForEdit(c.Local)
 	if err != nil {
 		return fmt.Errorf("error loading config: %w", err)
 	}
 
-	// Track if any changes were made
-	changed := false
-
-	// Update ID if provided
 	if c.ID != "" {
 		config.Site.SiteID = c.ID
-		changed = true
 	}
-
-	// Update Dir if provided
 	if c.Dir != "" {
 		config.Site.Dir = c.Dir
-		changed = true
 	}
-
-	// Update BaseHost if provided
 	if c.BaseHost != "" {
-		config.BaseHost = c.BaseHost
-		changed = true
-	}
-
-	// If no flags were provided, just display current config
-	if !changed {
-		fmt.Println("Current Configuration")
-		fmt.Println("=====================")
-		fmt.Printf("Site ID:   %s\n", config.Site.SiteID)
-		fmt.Printf("Dir:       %s\n", config.Site.Dir)
-		fmt.Printf("Base Host: %s\n", config.GetBaseHost())
-		fmt.Printf("\nConfig file: %s\n", ConfigFileName)
-		return nil
+		config.Site.BaseHost = c.BaseHost
 	}
 
 	// Save the updated config
@@ -127,16 +790,68 @@ func (c *ConfigCmd) Run() error {
 		return err
 	}
 
-	fmt.Printf("Configuration saved to %s\n", ConfigFileName)
+	outf("Configuration saved to %s\n", config.FileName())
+	if config.SiteName() != "" {
+		outf("  Site: %s\n", config.SiteName())
+	}
 	if c.ID != "" {
-		fmt.Printf("  Site ID set to: %s\n", c.ID)
+		outf("  Site ID set to: %s\n", c.ID)
 	}
 	if c.Dir != "" {
-		fmt.Printf("  Dir set to: %s\n", c.Dir)
+		outf("  Dir set to: %s\n", c.Dir)
 	}
 	if c.BaseHost != "" {
-		fmt.Printf("  Base host set to: %s\n", c.BaseHost)
+		outf("  Base host set to: %s\n", c.BaseHost)
+	}
+	if c.Local && !isGitIgnored(LocalConfigFileName) {
+		outf("\nNote: add %s to .gitignore so it isn't committed\n", LocalConfigFileName)
+	}
+
+	if jsonOutput {
+		return printJSON(config.summary())
 	}
 
 	return nil
 }
+
+// ConfigSummary is the JSON form of 'efmrl3 config set'
+type ConfigSummary struct {
+	File      string   `json:"file"`
+	Overrides string   `json:"overrides,omitempty"`
+	Site      string   `json:"site,omitempty"`
+	SiteID    string   `json:"siteId"`
+	Dir       string   `json:"dir"`
+	BaseHost  string   `json:"baseHost"`
+	Sites     []string `json:"sites,omitempty"`
+}
+
+// summary describes the selected site for JSON output
+func (c *Config) summary() ConfigSummary {
+	summary := ConfigSummary{
+		File:     c.FileName(),
+		Site:     c.SiteName(),
+		SiteID:   c.Site.SiteID,
+		Dir:      c.Site.Dir,
+		BaseHost: c.GetBaseHost(),
+		Sites:    c.SiteNames(),
+	}
+	if c.local != nil {
+		summary.Overrides = LocalConfigFileName
+	}
+	return summary
+}
+
+// isGitIgnored reports whether name is listed verbatim in ./.gitignore
+func isGitIgnored(name string) bool {
+	data, err := os.ReadFile(".gitignore")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == name || line == "/"+name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestLoadConfigFormats tests that every supported config format decodes to the same schema
+func TestLoadConfigFormats(t *testing.T) {
+	tests := []struct {
+		fileName string
+		content  string
+	}{
+		{ConfigFileName, "base_host = \"example.test\"\n[site]\nsite_id = \"abc\"\ndir = \"public\"\n"},
+		{ConfigFileNameJSON, `{"base_host": "example.test", "site": {"site_id": "abc", "dir": "public"}}`},
+		{ConfigFileNameYAML, "base_host: example.test\nsite:\n  site_id: abc\n  dir: public\n"},
+		{ConfigFileNameYML, "base_host: example.test\nsite:\n  site_id: abc\n  dir: public\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if err := os.WriteFile(tt.fileName, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", tt.fileName, err)
+			}
+
+			config, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if config.BaseHost != "example.test" || config.Site.SiteID != "abc" || config.Site.Dir != "public" {
+				t.Errorf("Unexpected config: %+v", config)
+			}
+			if config.FileName() != tt.fileName {
+				t.Errorf("Expected FileName %s, got %s", tt.fileName, config.FileName())
+			}
+
+			// Saving should round-trip through the same format
+			config.Site.SiteID = "def"
+			if err := SaveConfig(config); err != nil {
+				t.Fatalf("SaveConfig failed: %v", err)
+			}
+			reloaded, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig after save failed: %v", err)
+			}
+			if reloaded.Site.SiteID != "def" {
+				t.Errorf("Expected site_id 'def' after save, got '%s'", reloaded.Site.SiteID)
+			}
+		})
+	}
+}
+
+// TestLoadConfigAmbiguous tests that multiple config files are rejected
+func TestLoadConfigAmbiguous(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile(ConfigFileName, []byte("[site]\nsite_id = \"a\"\n"), 0644)
+	os.WriteFile(ConfigFileNameJSON, []byte(`{"site": {"site_id": "b"}}`), 0644)
+
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected error with both efmrl.toml and efmrl.json present, got nil")
+	}
+}
+
+// TestGetBaseHost tests base host precedence
+func TestGetBaseHost(t *testing.T) {
+	defer func() { hostOverride = "" }()
+
+	config := &Config{}
+	if got := config.GetBaseHost(); got != DefaultBaseHost {
+		t.Errorf("Expected default %s, got %s", DefaultBaseHost, got)
+	}
+
+	config.BaseHost = "legacy.test"
+	if got := config.GetBaseHost(); got != "legacy.test" {
+		t.Errorf("Expected top-level base_host, got %s", got)
+	}
+
+	config.Site.BaseHost = "site.test"
+	if got := config.GetBaseHost(); got != "site.test" {
+		t.Errorf("Expected site.base_host to win over top-level, got %s", got)
+	}
+
+	hostOverride = "localhost:8787"
+	if got := config.GetBaseHost(); got != "localhost:8787" {
+		t.Errorf("Expected --host override to win, got %s", got)
+	}
+	if got := config.BaseURL(); got != "http://localhost:8787" {
+		t.Errorf("Expected http URL for localhost, got %s", got)
+	}
+}
+
+// TestSiteProfiles tests selecting and saving [sites.<name>] profiles
+func TestSiteProfiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	defer func() { siteOverride = "" }()
+
+	content := `
+[site]
+base_host = "shared.test"
+
+[sites.blog]
+site_id = "blog-id"
+dir = "blog/public"
+
+[sites.docs]
+site_id = "docs-id"
+dir = "docs/public"
+`
+	if err := os.WriteFile(ConfigFileName, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	// Without --site, multiple profiles are ambiguous
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected error choosing between multiple sites, got nil")
+	}
+
+	siteOverride = "docs"
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Site.SiteID != "docs-id" || config.Site.Dir != "docs/public" {
+		t.Errorf("Expected docs profile, got %+v", config.Site)
+	}
+	if config.GetBaseHost() != "shared.test" {
+		t.Errorf("Expected profile to fall back to [site].base_host, got %s", config.GetBaseHost())
+	}
+
+	// Saving writes the profile back under [sites.docs]
+	config.Site.SiteID = "docs-id-2"
+	if err := SaveConfig(config); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	siteOverride = ""
+	reloaded, err := LoadConfigOrDefault()
+	if err != nil {
+		t.Fatalf("LoadConfigOrDefault failed: %v", err)
+	}
+	if reloaded.Sites["docs"].SiteID != "docs-id-2" || reloaded.Sites["blog"].SiteID != "blog-id" {
+		t.Errorf("Unexpected profiles after save: %+v", reloaded.Sites)
+	}
+	if reloaded.Site.SiteID != "" || reloaded.Site.BaseHost != "shared.test" {
+		t.Errorf("Expected [site] to be unchanged, got %+v", reloaded.Site)
+	}
+
+	siteOverride = "missing"
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected error for unknown site, got nil")
+	}
+}
+
+// TestRedaction tests that credential-like values are never printed in full
+func TestRedaction(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"abc123", "abc123"},
+		{"efmrl.work", "efmrl.work"},
+		{"eyJhbGciOiJSUzI1NiJ9.payload.sig", "eyJh…[redacted]"},
+		{"ya29.a0AfH6SMBx", "ya29…[redacted]"},
+		{"1//0gLongRefreshToken", "1//0…[redacted]"},
+	}
+
+	for _, tt := range tests {
+		if got := redactIfTokenLike(tt.value); got != tt.expected {
+			t.Errorf("redactIfTokenLike(%s) = %s, expected %s", tt.value, got, tt.expected)
+		}
+	}
+
+	if got := redactSecret("short"); got != "[redacted]" {
+		t.Errorf("Expected short secrets to be fully redacted, got %s", got)
+	}
+}
+
+// TestLocalOverrides tests that efmrl.local.toml is merged over the shared config
+func TestLocalOverrides(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	os.WriteFile(ConfigFileName, []byte("[site]\nsite_id = \"shared\"\ndir = \"public\"\n"), 0644)
+	os.WriteFile(LocalConfigFileName, []byte("[site]\nsite_id = \"mine\"\nbase_host = \"localhost:8787\"\n"), 0644)
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Site.SiteID != "mine" {
+		t.Errorf("Expected local site_id 'mine', got '%s'", config.Site.SiteID)
+	}
+	if config.Site.Dir != "public" {
+		t.Errorf("Expected shared dir 'public' to be kept, got '%s'", config.Site.Dir)
+	}
+	if config.GetBaseHost() != "localhost:8787" {
+		t.Errorf("Expected local base_host, got '%s'", config.GetBaseHost())
+	}
+
+	// A merged config must never be written back over the shared file
+	if err := SaveConfig(config); err == nil {
+		t.Error("Expected SaveConfig to refuse a merged config, got nil")
+	}
+}
+
+// TestConfiglessOperation tests that --site-id makes the config file optional
+func TestConfiglessOperation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	defer func() { siteIDOverride = "" }()
+
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected error without config file or --site-id, got nil")
+	}
+
+	siteIDOverride = "abc"
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig with --site-id failed: %v", err)
+	}
+	if config.Site.SiteID != "abc" || config.SyncDir() != "." {
+		t.Errorf("Unexpected configless config: %+v", config.Site)
+	}
+
+	// --site-id also overrides a config file
+	os.WriteFile(ConfigFileName, []byte("[site]\nsite_id = \"from-file\"\ndir = \"public\"\n"), 0644)
+	config, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Site.SiteID != "abc" || config.Site.Dir != "public" {
+		t.Errorf("Expected --site-id to override only site_id, got %+v", config.Site)
+	}
+}
+
+// TestStrictConfigDecoding tests that unknown keys are rejected with their position
+func TestStrictConfigDecoding(t *testing.T) {
+	tests := []struct {
+		fileName string
+		content  string
+		expected string
+	}{
+		{ConfigFileName, "[site]\nsite_id = \"a\"\n  site_idd = \"b\"\n", `line 3, column 3: unknown key "site.site_idd"`},
+		{ConfigFileName, "base_hots = \"x\"\n", `line 1, column 1: unknown key "base_hots"`},
+		{ConfigFileName, "[site]\nsite_id = \n", "line 2, column 11"},
+		{ConfigFileNameJSON, "{\n  \"site\": {\n    \"site_idd\": \"b\"\n  }\n}\n", `line 3, column 5: unknown key "site_idd"`},
+		{ConfigFileNameYAML, "site:\n  site_idd: b\n", "line 2: field site_idd not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			os.WriteFile(tt.fileName, []byte(tt.content), 0644)
+
+			_, err := LoadConfig()
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
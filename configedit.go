@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// newConfigSkeleton is the starting point when editing a project that has
+// no config file yet
+const newConfigSkeleton = `# efmrl project configuration
+[site]
+site_id = ""
+# dir = "public"
+`
+
+// ConfigEditCmd opens the project (or global) config in $EDITOR and only
+// saves the result if it still parses
+type ConfigEditCmd struct {
+	Global bool `help:"Edit the global credentials file instead of the project config"`
+}
+
+func (c *ConfigEditCmd) Run() error {
+	if nonInteractive {
+		return fmt.Errorf("config edit opens an editor and can't run in non-interactive mode (use 'efmrl3 config' flags instead)")
+	}
+
+	var path string
+	var validate func([]byte) error
+	perm := os.FileMode(0644)
+
+	if c.Global {
+		var err error
+		if path, err = GetGlobalConfigPath(); err != nil {
+			return err
+		}
+		validate = func(data []byte) error {
+			var config GlobalConfig
+			return decodeTOMLStrict(data, &config)
+		}
+		perm = 0600
+	} else {
+		fileName, err := findConfigFile()
+		if err != nil {
+			return err
+		}
+		if fileName == "" {
+			fileName = ConfigFileName
+		}
+		path = filepath.Join(".", fileName)
+		validate = func(data []byte) error {
+			var config Config
+			switch filepath.Ext(path) {
+			case ".json":
+				return decodeJSONStrict(data, &config)
+			case ".yaml", ".yml":
+				return decodeYAMLStrict(data, &config)
+			default:
+				return decodeTOMLStrict(data, &config)
+			}
+		}
+	}
+
+	original, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if !c.Global {
+			original = []byte(newConfigSkeleton)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// Edit a temporary copy so a broken config never replaces a working one
+	tmp, err := os.CreateTemp("", "efmrl3-edit-*"+filepath.Ext(path))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	_, err = tmp.Write(original)
+	tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	for {
+		if err := runEditor(tmp.Name()); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read edited file: %w", err)
+		}
+
+		if bytes.Equal(edited, original) {
+			outln("No changes made")
+			return nil
+		}
+
+		if err := validate(edited); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s is not valid: %v\n", filepath.Base(path), err)
+			if again, _ := askYesNo("Edit again? (changes are discarded otherwise)"); !again {
+				return fmt.Errorf("invalid config not saved")
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		err = writeFileAtomic(path, perm, func(w io.Writer) error {
+			_, err := w.Write(edited)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save %s: %w", path, err)
+		}
+		outf("%s Saved %s\n", green("✓"), path)
+		return nil
+	}
+}
+
+// runEditor opens path in $VISUAL or $EDITOR (falling back to vi) and waits
+// for it to exit. The variable may include arguments, e.g. "code --wait".
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
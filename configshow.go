@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ConfigShowCmd prints the fully resolved configuration with the source of
+// each value, redacting anything that looks like a credential
+type ConfigShowCmd struct{}
+
+// Setting is one resolved configuration value and where it came from
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// ResolvedConfig is the JSON form of 'efmrl3 config show'
+type ResolvedConfig struct {
+	ConfigFile      string    `json:"configFile,omitempty"`
+	Overrides       string    `json:"overrides,omitempty"`
+	Settings        []Setting `json:"settings"`
+	CredentialsFile string    `json:"credentialsFile"`
+	Credentials     []Setting `json:"credentials"`
+}
+
+// addSetting appends a setting, redacting values that look like credentials
+func addSetting(settings *[]Setting, name, value, source string) {
+	*settings = append(*settings, Setting{Name: name, Value: redactIfTokenLike(value), Source: source})
+}
+
+func (c *ConfigShowCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	resolved := ResolvedConfig{Settings: []Setting{}, Credentials: []Setting{}}
+	if config.fileName != "" {
+		resolved.ConfigFile = config.FileName()
+	}
+	if config.local != nil {
+		resolved.Overrides = LocalConfigFileName
+	}
+
+	if config.SiteName() != "" {
+		source := flagSource("site", "EFMRL_SITE")
+		if siteOverride == "" {
+			source = "only profile in " + config.FileName()
+		}
+		addSetting(&resolved.Settings, "site", config.SiteName(), source)
+	}
+
+	siteIDSource := config.fieldSource(func(s SiteConfig) string { return s.SiteID })
+	if siteIDOverride != "" {
+		siteIDSource = flagSource("site-id", "EFMRL_SITE_ID")
+	} else if config.Site.SiteID == "" {
+		siteIDSource = "not set"
+	}
+	addSetting(&resolved.Settings, "site_id", config.Site.SiteID, siteIDSource)
+
+	dir, dirSource := config.Site.Dir, config.fieldSource(func(s SiteConfig) string { return s.Dir })
+	if dir == "" {
+		dir, dirSource = ".", "default"
+	}
+	addSetting(&resolved.Settings, "dir", dir, dirSource)
+
+	host, hostSource := config.resolveBaseHost()
+	addSetting(&resolved.Settings, "base_host", host, hostSource)
+	addSetting(&resolved.Settings, "base_url", config.BaseURL(), "derived from base_host")
+
+	// Credentials from the global config
+	resolved.CredentialsFile, err = GetGlobalConfigPath()
+	if err != nil {
+		return err
+	}
+
+	loggedIn := true
+	if token := os.Getenv(TokenEnvVar); token != "" {
+		addSetting(&resolved.Credentials, "deploy_key", redactSecret(token), "$"+TokenEnvVar)
+	} else if globalConfig, err := LoadGlobalConfig(); err != nil {
+		warnf("Could not load credentials: %v\n", err)
+	} else if creds, ok := globalConfig.GetHostCredentials(host); !ok {
+		loggedIn = false
+	} else {
+		addSetting(&resolved.Credentials, "provider", creds.Provider, "credentials")
+		addSetting(&resolved.Credentials, "access_token", redactSecret(creds.AccessToken), "credentials")
+		addSetting(&resolved.Credentials, "refresh_token", redactSecret(creds.RefreshToken), "credentials")
+
+		if id := os.Getenv("GOOGLE_DEVICE_CLIENT_ID"); id != "" {
+			addSetting(&resolved.Credentials, "google_client_id", id, "$GOOGLE_DEVICE_CLIENT_ID")
+		}
+		if secret := os.Getenv("GOOGLE_DEVICE_CLIENT_SECRET"); secret != "" {
+			addSetting(&resolved.Credentials, "google_client_secret", redactSecret(secret), "$GOOGLE_DEVICE_CLIENT_SECRET")
+		}
+	}
+
+	if jsonOutput {
+		return printJSON(resolved)
+	}
+
+	outln("Effective Configuration")
+	outln("=======================")
+	if resolved.ConfigFile != "" {
+		outf("Config file: %s\n", resolved.ConfigFile)
+	} else {
+		outln("Config file: none (configured by flags)")
+	}
+	if resolved.Overrides != "" {
+		outf("Overrides:   %s\n", resolved.Overrides)
+	}
+	outln()
+	for _, setting := range resolved.Settings {
+		printSetting(setting)
+	}
+
+	outf("\nCredentials (%s)\n", resolved.CredentialsFile)
+	if !loggedIn {
+		outf("  no credentials for %s (run 'efmrl3 login')\n", host)
+	}
+	for _, setting := range resolved.Credentials {
+		printSetting(setting)
+	}
+
+	return nil
+}
+
+// printSetting prints one resolved value and its provenance
+func printSetting(setting Setting) {
+	outf("  %-14s %-32s (%s)\n", setting.Name, orDash(setting.Value), setting.Source)
+}
+
+// flagSource reports whether a global flag's value came from the command
+// line or from its environment variable
+func flagSource(flag, env string) string {
+	for _, arg := range os.Args[1:] {
+		if arg == "--"+flag || strings.HasPrefix(arg, "--"+flag+"=") {
+			return "--" + flag
+		}
+	}
+	if os.Getenv(env) != "" {
+		return "$" + env
+	}
+	return "--" + flag
+}
+
+// redactSecret hides all but the first few characters of a secret
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return "[redacted]"
+	}
+	return secret[:4] + "…[redacted]"
+}
+
+// tokenPrefixes are prefixes of credentials we know about: JWTs, Google
+// access and refresh tokens, and Google client secrets
+var tokenPrefixes = []string{"eyJ", "ya29.", "1//", "GOCSPX-"}
+
+// redactIfTokenLike redacts values that look like credentials even when they
+// show up somewhere unexpected, such as a misplaced config value
+func redactIfTokenLike(value string) string {
+	if strings.HasSuffix(value, "[redacted]") {
+		return value
+	}
+	for _, prefix := range tokenPrefixes {
+		if strings.HasPrefix(value, prefix) {
+			return redactSecret(value)
+		}
+	}
+	return value
+}
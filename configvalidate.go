@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigError is a problem at a specific position in a config file. Line and
+// Col are 1-based; zero means unknown.
+type ConfigError struct {
+	Line int
+	Col  int
+	Msg  string
+}
+
+func (e ConfigError) Error() string {
+	switch {
+	case e.Line > 0 && e.Col > 0:
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Col, e.Msg)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	default:
+		return e.Msg
+	}
+}
+
+// ConfigErrors collects every problem found in a config file, so a file with
+// several typos can be fixed in one go
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = "\n  " + err.Error()
+	}
+	return fmt.Sprintf("%d problems:%s", len(e), strings.Join(msgs, ""))
+}
+
+// decodeTOMLStrict decodes TOML, rejecting any keys that aren't part of the
+// config schema
+func decodeTOMLStrict(data []byte, v any) error {
+	md, err := toml.Decode(string(data), v)
+	if err != nil {
+		var pe toml.ParseError
+		if errors.As(err, &pe) {
+			return ConfigError{Line: pe.Position.Line, Col: pe.Position.Col, Msg: pe.Message}
+		}
+		return err
+	}
+
+	undecoded := md.Undecoded()
+	if len(undecoded) == 0 {
+		return nil
+	}
+
+	// Only report the outermost unknown key; the keys inside an unknown
+	// table are implied
+	unknown := make(map[string]bool)
+	var errs ConfigErrors
+	for _, key := range undecoded {
+		if len(key) > 1 && unknown[key[:len(key)-1].String()] {
+			unknown[key.String()] = true
+			continue
+		}
+		unknown[key.String()] = true
+
+		line, col := locateTOMLKey(data, key)
+		errs = append(errs, ConfigError{Line: line, Col: col, Msg: fmt.Sprintf("unknown key %q", key.String())})
+	}
+	return errs
+}
+
+// locateTOMLKey finds the line and column where key is defined, either as a
+// table header or as a key = value line inside its parent table
+func locateTOMLKey(data []byte, key toml.Key) (int, int) {
+	table := ""
+	parent := ""
+	if len(key) > 1 {
+		parent = key[:len(key)-1].String()
+	}
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(raw)
+		col := len(raw) - len(strings.TrimLeft(raw, " \t")) + 1
+
+		if strings.HasPrefix(line, "[") {
+			table = strings.Trim(line, "[] ")
+			if table == key.String() || strings.HasPrefix(table, key.String()+".") {
+				return i + 1, col
+			}
+			continue
+		}
+
+		name, _, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		name = strings.Trim(strings.TrimSpace(name), `"'`)
+
+		full := name
+		if table != "" {
+			full = table + "." + name
+		}
+		if table == parent && name == key[len(key)-1] || full == key.String() {
+			return i + 1, col
+		}
+	}
+	return 0, 0
+}
+
+// decodeJSONStrict decodes JSON, rejecting unknown fields
+func decodeJSONStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		line, col := offsetToPosition(data, syntaxErr.Offset)
+		return ConfigError{Line: line, Col: col, Msg: syntaxErr.Error()}
+	case errors.As(err, &typeErr):
+		line, col := offsetToPosition(data, typeErr.Offset)
+		return ConfigError{Line: line, Col: col,
+			Msg: fmt.Sprintf("%s should be %s, not %s", typeErr.Field, typeErr.Type, typeErr.Value)}
+	}
+
+	// encoding/json doesn't report where unknown fields are, so find the key
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		name, _ := strconv.Unquote(field)
+		msg := fmt.Sprintf("unknown key %q", name)
+		re := regexp.MustCompile(regexp.QuoteMeta(strconv.Quote(name)) + `\s*:`)
+		if loc := re.FindIndex(data); loc != nil {
+			line, col := offsetToPosition(data, int64(loc[0]))
+			return ConfigError{Line: line, Col: col, Msg: msg}
+		}
+		return ConfigError{Msg: msg}
+	}
+
+	return err
+}
+
+var (
+	yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	yamlTypeRe = regexp.MustCompile(` in type [\w.]+$`) // Go type names mean nothing to users
+)
+
+// decodeYAMLStrict decodes YAML, rejecting unknown fields
+func decodeYAMLStrict(data []byte, v any) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(v)
+	if err == nil || errors.Is(err, io.EOF) { // io.EOF: empty document
+		return nil
+	}
+
+	var msgs []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	} else {
+		msgs = []string{err.Error()}
+	}
+
+	var errs ConfigErrors
+	for _, msg := range msgs {
+		if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			errs = append(errs, ConfigError{Line: line, Msg: yamlTypeRe.ReplaceAllString(m[2], "")})
+		} else {
+			errs = append(errs, ConfigError{Msg: msg})
+		}
+	}
+	return errs
+}
+
+// offsetToPosition converts a byte offset to a 1-based line and column
+func offsetToPosition(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// validate returns warnings about settings that are present but unusable.
+// These are reported before any network call is made.
+func (c *Config) validate() []string {
+	var warnings []string
+
+	checkHost := func(where, host string) {
+		if strings.Contains(host, "://") || strings.Contains(host, "/") {
+			warnings = append(warnings, fmt.Sprintf("%s base_host %q should be a host name, not a URL", where, host))
+		}
+	}
+
+	checkHost("top-level", c.BaseHost)
+	if c.siteName == "" {
+		checkHost("[site]", c.Site.BaseHost)
+	} else {
+		checkHost("[site]", c.defaultSite.BaseHost)
+		checkHost(fmt.Sprintf("[sites.%s]", c.siteName), c.Site.BaseHost)
+	}
+
+	for _, name := range c.SiteNames() {
+		site := c.Sites[name]
+		if site.SiteID == "" {
+			warnings = append(warnings, fmt.Sprintf("[sites.%s] is missing site_id", name))
+		}
+		if name != c.siteName {
+			checkHost(fmt.Sprintf("[sites.%s]", name), site.BaseHost)
+		}
+	}
+
+	checkDir := func(where, dir string) {
+		if isDriveRelative(dir) {
+			warnings = append(warnings, fmt.Sprintf("%s %q is relative to the current directory of drive %s, which depends on where efmrl3 runs; use a path relative to the project, or %s\\%s",
+				where, dir, dir[:2], dir[:2], dir[2:]))
+		}
+	}
+	if c.siteName == "" {
+		checkDir("[site] dir", c.Site.Dir)
+	} else {
+		checkDir("[site] dir", c.defaultSite.Dir)
+		checkDir(fmt.Sprintf("[sites.%s] dir", c.siteName), c.Site.Dir)
+	}
+	for _, name := range c.SiteNames() {
+		if name != c.siteName {
+			checkDir(fmt.Sprintf("[sites.%s] dir", name), c.Sites[name].Dir)
+		}
+	}
+	checkDir("[build] output_dir", c.Build.OutputDir)
+
+	if c.Build.OutputDir != "" && c.Build.Command == "" {
+		warnings = append(warnings, "[build] has output_dir but no command")
+	}
+	if c.Sync.ConfirmDeletes < -1 {
+		warnings = append(warnings, "[sync] confirm_deletes should be a count, or -1 to never ask")
+	}
+	if c.Sync.ExpiryWarningDays < -1 {
+		warnings = append(warnings, "[sync] expiry_warning_days should be a number of days, or -1 to never warn")
+	}
+	for _, pattern := range c.Sync.SitemapExclude {
+		if !strings.HasPrefix(pattern, "/") {
+			warnings = append(warnings, fmt.Sprintf("[sync] sitemap_exclude path %q should start with /", pattern))
+		}
+	}
+	for _, ext := range c.Sync.FingerprintExtensions {
+		if !strings.HasPrefix(ext, ".") {
+			warnings = append(warnings, fmt.Sprintf("[sync] fingerprint_extensions entry %q should start with a dot", ext))
+		}
+	}
+	for _, format := range c.Sync.ImageVariants {
+		if _, ok := imageVariantFormats[format]; !ok {
+			warnings = append(warnings, fmt.Sprintf("[sync] image_variants entry %q should be webp or avif", format))
+		}
+	}
+	for _, kind := range c.Sync.Minify {
+		if kind != "html" && kind != "css" && kind != "js" {
+			warnings = append(warnings, fmt.Sprintf("[sync] minify entry %q should be html, css, or js", kind))
+		}
+	}
+	if form := c.Sync.UnicodeNormalization; form != "" && form != normalizeNFC && form != normalizeNone {
+		warnings = append(warnings, fmt.Sprintf("[sync] unicode_normalization %q should be nfc or none", form))
+	}
+	if mode := c.Sync.EmptyDirs; mode != "" && mode != emptyDirsWarn && mode != emptyDirsKeep && mode != emptyDirsIgnore {
+		warnings = append(warnings, fmt.Sprintf("[sync] empty_dirs %q should be warn, keep, or ignore", mode))
+	}
+	if c.Sync.MinUploadSpeed < -1 {
+		warnings = append(warnings, fmt.Sprintf("[sync] min_upload_speed %d should be a speed in KB/s, or -1 for no limit", c.Sync.MinUploadSpeed))
+	}
+	if c.Sync.UploadGraceSeconds < 0 {
+		warnings = append(warnings, fmt.Sprintf("[sync] upload_grace_seconds %d should be a number of seconds", c.Sync.UploadGraceSeconds))
+	}
+	if mode := c.Sync.Compare; mode != "" && mode != compareETag && mode != compareAuto && mode != compareMetadata {
+		warnings = append(warnings, fmt.Sprintf("[sync] compare %q should be etag, auto, or metadata", mode))
+	}
+	if c.Sync.CheckLinks != "" && c.Sync.CheckLinks != "warn" && c.Sync.CheckLinks != "fail" {
+		warnings = append(warnings, fmt.Sprintf("[sync] check_links %q should be warn or fail", c.Sync.CheckLinks))
+	}
+	for _, event := range c.Notifications.Events {
+		if !slices.Contains(notificationEvents, event) {
+			warnings = append(warnings, fmt.Sprintf("[notifications] event %q should be success or failure", event))
+		}
+	}
+	if len(c.Notifications.Events) > 0 && c.Notifications.WebhookURL == "" {
+		warnings = append(warnings, "[notifications] has events but no webhook_url")
+	}
+	for _, p := range c.Verify.Paths {
+		if !strings.HasPrefix(p, "/") {
+			warnings = append(warnings, fmt.Sprintf("[verify] path %q should start with /", p))
+		}
+	}
+	for _, pattern := range c.Sync.CheckLinksIgnore {
+		if !strings.HasPrefix(pattern, "/") {
+			warnings = append(warnings, fmt.Sprintf("[sync] check_links_ignore path %q should start with /", pattern))
+		}
+	}
+	for _, setting := range []struct {
+		name  string
+		value int
+	}{
+		{"hash_workers", c.Performance.HashWorkers},
+		{"upload_concurrency", c.Performance.UploadConcurrency},
+		{"delete_concurrency", c.Performance.DeleteConcurrency},
+		{"stream_buffer", c.Performance.StreamBuffer},
+	} {
+		if setting.value < 0 {
+			warnings = append(warnings, fmt.Sprintf("[performance] %s %d should be at least 1, or 0 for the default", setting.name, setting.value))
+		}
+	}
+	for _, pattern := range c.Workspace.Packages {
+		if _, err := path.Match(pattern, ""); err != nil {
+			warnings = append(warnings, fmt.Sprintf("[workspace] package %q is not a valid pattern", pattern))
+		} else if path.IsAbs(pattern) || strings.HasPrefix(path.Clean(pattern), "..") {
+			warnings = append(warnings, fmt.Sprintf("[workspace] package %q should be relative to the workspace root, inside it", pattern))
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// DeployCmd builds the site, syncs the build output, and records the deploy
+// in the site's history. Use sync to mirror files without a build or record.
+type DeployCmd struct {
+	SyncCmd `embed:""`
+
+	Build      bool `help:"Require a build: fail if no build command is configured or detected" xor:"build"`
+	SkipBuild  bool `help:"Sync without running the build command first" xor:"build"`
+	AllowDirty bool `help:"Deploy even if the git working tree has uncommitted changes"`
+}
+
+// Examples are shown in 'efmrl3 deploy --help'
+func (d *DeployCmd) Examples() []Example {
+	return []Example{
+		{"Build with the detected framework and publish", "efmrl3 deploy"},
+		{"Run the [build] command from efmrl.toml, failing if there isn't one", "efmrl3 deploy --build"},
+		{"Publish an existing build without rebuilding", "efmrl3 deploy --skip-build --dir dist"},
+		{"Deploy a named site profile from efmrl.toml", "efmrl3 --site staging deploy"},
+		{"Deploy from CI and print the result as JSON", "EFMRL_TOKEN=$DEPLOY_KEY efmrl3 --json deploy --yes"},
+	}
+}
+
+func (d *DeployCmd) Run() (err error) {
+	start := time.Now()
+
+	// These come with the embedded sync flags, but a deploy is of one site
+	if d.All {
+		return fmt.Errorf("deploy can't do --all; deploy each site with --site <name>, or use 'efmrl3 sync --all' to sync them all without building")
+	}
+	if d.Parallel > 1 {
+		return fmt.Errorf("--parallel only applies to 'efmrl3 sync --all'")
+	}
+
+	if d.ProgressJSON {
+		if err := enableProgressEvents(); err != nil {
+			return err
+		}
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Check before anything here writes to the tree
+	dirty := gitDirtyFiles()
+	if err := d.checkDirty(config, dirty); err != nil {
+		return err
+	}
+
+	// Hold the lock across both build and sync
+	unlock, err := acquireSyncLock(d.ForceUnlock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer watchInterrupts()()
+	setUploadTimeouts(config)
+	setPerformance(config)
+
+	// From here on, a failure is a failed deploy worth announcing
+	var result *SyncResult
+	if !d.DryRun {
+		defer func() { notifySync(config, "Deploy", result, err) }()
+	}
+
+	build, framework := resolveBuild(config)
+	if framework != nil {
+		outf("Detected %s project\n", framework.Name)
+		if err := offerSaveBuild(config, framework); err != nil {
+			return err
+		}
+	}
+
+	if d.Build && build.Command == "" {
+		return fmt.Errorf("--build needs a build command: set [build] command in %s", config.FileName())
+	}
+
+	built := false
+	if !d.SkipBuild {
+		if build.Command == "" {
+			outln("No build command configured or detected; syncing as-is")
+		} else if err := runBuild(build.Command); err != nil {
+			return err
+		} else {
+			built = true
+		}
+		outln()
+	}
+
+	// An explicit dir always wins; otherwise sync what the build produced
+	syncDir := d.Dir
+	if syncDir == "" {
+		syncDir = config.Site.Dir
+	}
+	if syncDir == "" {
+		syncDir = build.OutputDir
+	}
+	if syncDir == "" {
+		syncDir = "."
+	}
+
+	// A build that "succeeds" without producing its output would otherwise
+	// sync the wrong thing, or delete the whole site
+	if built {
+		if info, err := os.Stat(syncDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("build finished but its output directory %s does not exist", syncDir)
+		}
+	}
+
+	result, err = d.syncDir(config, syncDir)
+	if err != nil {
+		return err
+	}
+
+	if !d.DryRun {
+		result.DeployID, result.DeployURL = d.record(config, result, time.Since(start), len(dirty) > 0)
+	}
+	return printSyncResult(result)
+}
+
+// maxDirtyListed is how many uncommitted changes are listed before eliding
+const maxDirtyListed = 10
+
+// checkDirty refuses to deploy uncommitted changes, which can't be traced
+// back to a commit later, unless they're explicitly allowed
+func (d *DeployCmd) checkDirty(config *Config, dirty []string) error {
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	warnf("the git working tree has %d uncommitted change(s):\n", len(dirty))
+	for i, line := range dirty {
+		if i == maxDirtyListed {
+			fmt.Fprintf(os.Stderr, "  ... and %d more\n", len(dirty)-maxDirtyListed)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
+	}
+
+	if d.AllowDirty || config.Deploy.AllowDirty {
+		return nil
+	}
+	return fmt.Errorf("refusing to deploy uncommitted changes (commit them, pass --allow-dirty, or set allow_dirty under [deploy] in %s)",
+		config.FileName())
+}
+
+// record saves the deploy in the site's deploy history and returns its ID
+// and permanent URL, if the server gives it one. A deploy that can't be
+// recorded has still succeeded, so failures are only warnings.
+func (d *DeployCmd) record(config *Config, result *SyncResult, duration time.Duration, dirty bool) (string, string) {
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		warnf("failed to record deploy: %v\n", err)
+		return "", ""
+	}
+
+	deploy := Deploy{
+		Uploaded:   len(result.Uploaded),
+		Deleted:    len(result.Deleted),
+		Unchanged:  result.Unchanged,
+		Commit:     gitCommit(),
+		DurationMs: duration.Milliseconds(),
+		CLIVersion: version,
+		Dirty:      dirty,
+	}
+	if ciEnvironment != nil {
+		deploy.CIProvider = ciEnvironment.Provider
+		deploy.CIRunURL = ciEnvironment.RunURL
+	}
+
+	recorded, err := recordDeploy(apiClient, config.Site.SiteID, deploy)
+	switch {
+	case errors.Is(err, errDeploysUnsupported):
+		return "", ""
+	case err != nil:
+		warnf("failed to record deploy: %v\n", err)
+		return "", ""
+	}
+
+	outf("Recorded deploy %s\n", recorded.ID)
+	if recorded.URL != "" {
+		outf("Permanent URL for this deploy: %s\n", recorded.URL)
+	}
+	return recorded.ID, recorded.URL
+}
+
+// runBuild runs the build command through the shell, streaming its output
+func runBuild(command string) error {
+	startGroup("Build")
+	outf("Building: %s\n", command)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = humanOutput()
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+	endGroup()
+
+	outf("%s Build complete\n", green("✓"))
+	return nil
+}
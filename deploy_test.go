@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestDeployBuildRequiresCommand tests that --build fails without a build
+// command instead of syncing unbuilt files
+func TestDeployBuildRequiresCommand(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(ConfigFileName, []byte("[site]\nsite_id = \"abc\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	err := (&DeployCmd{Build: true}).Run()
+	if err == nil || !strings.Contains(err.Error(), "--build needs a build command") {
+		t.Errorf("Expected a missing build command error, got %v", err)
+	}
+}
+
+// TestDeployAllRefused tests that deploy refuses --all rather than deploying
+// only [site] of several configured sites
+func TestDeployAllRefused(t *testing.T) {
+	t.Chdir(t.TempDir())
+	config := "[site]\nsite_id = \"abc\"\ndir = \"public\"\n\n[sites.blog]\nsite_id = \"blog\"\ndir = \"blog\"\n"
+	if err := os.WriteFile(ConfigFileName, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		cmd      DeployCmd
+		expected string
+	}{
+		{DeployCmd{SyncCmd: SyncCmd{All: true, Parallel: 1}}, "deploy can't do --all"},
+		{DeployCmd{SyncCmd: SyncCmd{All: true, Parallel: 4}}, "deploy can't do --all"},
+		{DeployCmd{SyncCmd: SyncCmd{Parallel: 4}}, "--parallel only applies"},
+	}
+	for _, tt := range tests {
+		var err error
+		captureStdout(t, func() { err = tt.cmd.Run() })
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+		}
+	}
+	if _, err := os.Stat(SyncLockFileName); err == nil {
+		t.Errorf("Expected nothing to have started")
+	}
+}
+
+// TestDeployMissingBuildOutput tests that a build which doesn't produce its
+// output directory stops the deploy
+func TestDeployMissingBuildOutput(t *testing.T) {
+	t.Chdir(t.TempDir())
+	config := "[site]\nsite_id = \"abc\"\n\n[build]\ncommand = \"true\"\noutput_dir = \"dist\"\n"
+	if err := os.WriteFile(ConfigFileName, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	var err error
+	captureStdout(t, func() { err = (&DeployCmd{}).Run() })
+	if err == nil || !strings.Contains(err.Error(), "output directory dist does not exist") {
+		t.Errorf("Expected a missing output directory error, got %v", err)
+	}
+}
+
+// TestDeployRefusesDirtyTree tests that uncommitted changes stop a deploy
+// unless they're allowed
+func TestDeployRefusesDirtyTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Chdir(t.TempDir())
+	if err := exec.Command("git", "init", "-q").Run(); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+	config := "[site]\nsite_id = \"abc\"\n\n[build]\ncommand = \"true\"\noutput_dir = \"dist\"\n"
+	if err := os.WriteFile(ConfigFileName, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	// What a sync of the directory leaves behind doesn't count
+	for _, name := range []string{SyncDirLockFileName, SyncPlanFileName} {
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dirty := gitDirtyFiles(); len(dirty) != 1 || !strings.HasSuffix(dirty[0], ConfigFileName) {
+		t.Errorf("Expected %s to be the only dirty file, got %v", ConfigFileName, dirty)
+	}
+
+	var err error
+	captureStdout(t, func() { err = (&DeployCmd{}).Run() })
+	if err == nil || !strings.Contains(err.Error(), "refusing to deploy uncommitted changes") {
+		t.Errorf("Expected a dirty tree error, got %v", err)
+	}
+
+	// Allowed, the deploy gets as far as the build
+	captureStdout(t, func() { err = (&DeployCmd{AllowDirty: true}).Run() })
+	if err == nil || !strings.Contains(err.Error(), "output directory dist does not exist") {
+		t.Errorf("Expected the deploy to reach the build, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TokenEnvVar names the environment variable holding a deploy key. When it's
+// set, requests use it instead of the credentials saved by 'efmrl3 login'.
+const TokenEnvVar = "EFMRL_TOKEN"
+
+// DeployKeysCmd manages tokens that can only sync one efmrl
+type DeployKeysCmd struct {
+	Create DeployKeysCreateCmd `cmd:"" help:"Create a deploy key for this efmrl"`
+	List   DeployKeysListCmd   `cmd:"" help:"List deploy keys for this efmrl"`
+	Revoke DeployKeysRevokeCmd `cmd:"" help:"Revoke one or more deploy keys"`
+}
+
+// DeployKey is a token scoped to syncing a single efmrl
+type DeployKey struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix,omitempty"`
+	Token      string `json:"token,omitempty"` // only returned when the key is created
+	CreatedAt  string `json:"createdAt,omitempty"`
+	LastUsedAt string `json:"lastUsedAt,omitempty"`
+	ExpiresAt  string `json:"expiresAt,omitempty"`
+}
+
+// DeployKeysCreateCmd creates a deploy key and prints its token once
+type DeployKeysCreateCmd struct {
+	Name    string `help:"Name describing where the key is used, e.g. github-actions" required:""`
+	Expires string `help:"Revoke the key automatically after this period (e.g. 30d, 12w)"`
+}
+
+func (d *DeployKeysCreateCmd) Run() error {
+	body := map[string]string{"name": d.Name}
+	if d.Expires != "" {
+		period, err := parseSince(d.Expires)
+		if err != nil {
+			return err
+		}
+		body["expiresAt"] = serverNow().Add(period).UTC().Format(time.RFC3339)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	resp, err := apiClient.Post(fmt.Sprintf("/admin/efmrls/%s/deploy-keys", config.Site.SiteID), body)
+	if err != nil {
+		return fmt.Errorf("failed to create deploy key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		DeployKey DeployKey `json:"deployKey"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.DeployKey.Token == "" {
+		return fmt.Errorf("server did not return a token")
+	}
+
+	if jsonOutput {
+		return printJSON(result.DeployKey)
+	}
+
+	outf("%s Created deploy key %s (%s)\n", green("✓"), result.DeployKey.Name, result.DeployKey.ID)
+	if result.DeployKey.ExpiresAt != "" {
+		outf("  Expires: %s\n", formatExpiry(result.DeployKey.ExpiresAt))
+	}
+	outf("\n%s\n\n", result.DeployKey.Token)
+	outln("This token is shown only once. It can sync this efmrl and nothing else.")
+	outf("Store it as a CI secret and expose it as %s, along with --site-id %s\n", TokenEnvVar, config.Site.SiteID)
+
+	return nil
+}
+
+// DeployKeysListCmd lists the deploy keys of the configured efmrl
+type DeployKeysListCmd struct {
+	TableFlags `embed:""`
+}
+
+func (d *DeployKeysListCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	keys, err := fetchDeployKeys(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch deploy keys: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(keys)
+	}
+
+	table := &Table{
+		Title:   "Deploy keys",
+		Empty:   "No deploy keys (create one with 'efmrl3 deploy-keys create --name <name>')",
+		Columns: []string{"ID", "NAME", "TOKEN", "LAST USED", "EXPIRES"},
+	}
+	for _, key := range keys {
+		prefix := ""
+		if key.Prefix != "" {
+			prefix = key.Prefix + "…"
+		}
+		table.AddRow(key.ID, key.Name, prefix, key.LastUsedAt, formatExpiry(key.ExpiresAt))
+	}
+	return table.Render(humanOutput(), d.TableFlags)
+}
+
+// fetchDeployKeys retrieves the deploy keys of an efmrl
+func fetchDeployKeys(client *APIClient, siteID string) ([]DeployKey, error) {
+	var result struct {
+		DeployKeys []DeployKey `json:"deployKeys"`
+	}
+	if err := getJSON(client, fmt.Sprintf("/admin/efmrls/%s/deploy-keys", siteID), &result); err != nil {
+		return nil, err
+	}
+	return result.DeployKeys, nil
+}
+
+// DeployKeysRevokeCmd revokes deploy keys by ID or name
+type DeployKeysRevokeCmd struct {
+	Keys []string `arg:"" name:"key" help:"ID or name of the deploy key(s) to revoke" required:""`
+}
+
+func (d *DeployKeysRevokeCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	keys, err := fetchDeployKeys(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch deploy keys: %w", err)
+	}
+
+	revoked := []string{}
+	for _, ref := range d.Keys {
+		outf("Revoking %s... ", ref)
+
+		var keyID string
+		for _, key := range keys {
+			if key.ID == ref || key.Name == ref {
+				keyID = key.ID
+				break
+			}
+		}
+		if keyID == "" {
+			outf("%s\n", yellow("NOT FOUND"))
+			continue
+		}
+
+		resp, err := apiClient.Delete(fmt.Sprintf("/admin/efmrls/%s/deploy-keys/%s", config.Site.SiteID, url.PathEscape(keyID)))
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to revoke deploy key %s: %w", ref, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		outf("%s\n", green("OK"))
+		revoked = append(revoked, keyID)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]string{"revoked": revoked})
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeploysCmd shows the deploy history of an efmrl
+type DeploysCmd struct {
+	List DeploysListCmd `cmd:"" default:"1" help:"List recent deploys"`
+}
+
+// Deploy is a record of one 'efmrl3 deploy', kept by the server
+type Deploy struct {
+	ID         string `json:"id,omitempty"`
+	CreatedAt  string `json:"createdAt,omitempty"`
+	Uploaded   int    `json:"uploaded"`
+	Deleted    int    `json:"deleted"`
+	Unchanged  int    `json:"unchanged"`
+	Commit     string `json:"commit,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	CLIVersion string `json:"cliVersion,omitempty"`
+	Dirty      bool   `json:"dirty,omitempty"` // deployed with uncommitted changes
+	CIProvider string `json:"ciProvider,omitempty"`
+	CIRunURL   string `json:"ciRunUrl,omitempty"`
+
+	// URL serves exactly this deploy's files for as long as the site
+	// exists, e.g. https://deploy-d42.abc.efmrl.net. It's set by servers
+	// that keep every deploy.
+	URL string `json:"url,omitempty"`
+}
+
+// DeploysListCmd lists recent deploys, newest first
+type DeploysListCmd struct {
+	Absolute   bool `help:"Show deploy times as timestamps instead of relative times"`
+	TableFlags `embed:""`
+}
+
+func (d *DeploysListCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	deploys, err := fetchDeploys(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch deploys: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(deploys)
+	}
+
+	now := serverNow()
+	table := &Table{
+		Title:   "Deploys",
+		Empty:   "No deploys recorded (run 'efmrl3 deploy')",
+		Columns: []string{"ID", "WHEN", "UPLOADED", "DELETED", "COMMIT", "CI", "DURATION", "URL"},
+	}
+	for _, deploy := range deploys {
+		when := formatRelativeTime(deploy.CreatedAt, now)
+		if d.Absolute {
+			when = formatTimestamp(deploy.CreatedAt)
+		}
+		duration := (time.Duration(deploy.DurationMs) * time.Millisecond).Round(100 * time.Millisecond)
+		table.AddRow(deploy.ID, when, strconv.Itoa(deploy.Uploaded), strconv.Itoa(deploy.Deleted),
+			deploy.Commit, deploy.CIProvider, duration.String(), deploy.URL)
+	}
+	return table.Render(humanOutput(), d.TableFlags)
+}
+
+// fetchDeploys returns an efmrl's recent deploys, newest first
+func fetchDeploys(client *APIClient, siteID string) ([]Deploy, error) {
+	var result struct {
+		Deploys []Deploy `json:"deploys"`
+	}
+	if err := getJSON(client, fmt.Sprintf("/admin/efmrls/%s/deploys", siteID), &result); err != nil {
+		return nil, err
+	}
+	return result.Deploys, nil
+}
+
+// errDeploysUnsupported is returned by recordDeploy when the server doesn't
+// keep deploy history
+var errDeploysUnsupported = errors.New("server does not record deploys")
+
+// recordDeploy records a completed deploy on the server and returns the
+// record the server kept, with its ID and any permanent URL
+func recordDeploy(client *APIClient, siteID string, deploy Deploy) (Deploy, error) {
+	resp, err := client.Post(fmt.Sprintf("/admin/efmrls/%s/deploys", siteID), deploy)
+	if err != nil {
+		return Deploy{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return Deploy{}, errDeploysUnsupported
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return Deploy{}, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Deploy Deploy `json:"deploy"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Deploy{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result.Deploy, nil
+}
+
+// gitCommit returns the short commit hash of HEAD in the current directory,
+// or "" if it isn't a git checkout
+func gitCommit() string {
+	out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// gitDirtyFiles returns the `git status --porcelain` lines for uncommitted
+// changes in the current directory, or nil if it's clean or not a git
+// checkout. Local-only efmrl files, and the state files a sync keeps in
+// the directory it syncs, don't count.
+func gitDirtyFiles() []string {
+	out, err := exec.Command("git", "status", "--porcelain").Output()
+	if err != nil {
+		return nil
+	}
+
+	var dirty []string
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		switch path.Base(line[3:]) {
+		case LocalConfigFileName, SyncLockFileName, SyncDirLockFileName, SyncPlanFileName:
+			continue
+		}
+		dirty = append(dirty, line)
+	}
+	return dirty
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRecordDeploy tests recording a deploy, and servers without deploy history
+func TestRecordDeploy(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	status := http.StatusCreated
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/admin/efmrls/abc/deploys" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"deploy": {"id": "d42", "url": "https://deploy-d42.abc.efmrl.net"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	deploy, err := recordDeploy(client, "abc", Deploy{Uploaded: 3})
+	if err != nil || deploy.ID != "d42" {
+		t.Errorf("Expected deploy d42, got %q (%v)", deploy.ID, err)
+	}
+	if deploy.URL != "https://deploy-d42.abc.efmrl.net" {
+		t.Errorf("Expected the deploy's permanent URL, got %q", deploy.URL)
+	}
+
+	status = http.StatusNotFound
+	if _, err := recordDeploy(client, "abc", Deploy{}); !errors.Is(err, errDeploysUnsupported) {
+		t.Errorf("Expected errDeploysUnsupported for a 404, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecordedRequests is how many recent API requests are kept for a
+// diagnostics bundle
+const maxRecordedRequests = 25
+
+// RequestRecord is the metadata of one API request. Bodies and headers are
+// never recorded, so tokens can't leak into a bundle.
+type RequestRecord struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Status     int       `json:"status,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+var (
+	recentRequestsMu sync.Mutex
+	recentRequests   []RequestRecord
+)
+
+// recordingTransport records the metadata of every request it sends
+type recordingTransport struct{}
+
+func (recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := http.DefaultTransport.RoundTrip(req)
+
+	record := RequestRecord{
+		Time:       start,
+		Method:     req.Method,
+		URL:        redactURL(req.URL.Scheme + "://" + req.URL.Host + req.URL.Path),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		record.Error = redactText(err.Error())
+	} else {
+		record.Status = resp.StatusCode
+		observeServerDate(resp.Header.Get("Date"), start, time.Now())
+	}
+
+	recentRequestsMu.Lock()
+	recentRequests = append(recentRequests, record)
+	if len(recentRequests) > maxRecordedRequests {
+		recentRequests = recentRequests[len(recentRequests)-maxRecordedRequests:]
+	}
+	recentRequestsMu.Unlock()
+
+	return resp, err
+}
+
+// newHTTPClient returns an http.Client whose requests show up in
+// diagnostics bundles
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: recordingTransport{}}
+}
+
+// lastRequestFailed reports whether the most recent API request got a
+// server error, which points at a bug or outage rather than a problem the
+// user can fix (like a typo or a flaky network)
+func lastRequestFailed() bool {
+	recentRequestsMu.Lock()
+	defer recentRequestsMu.Unlock()
+	if len(recentRequests) == 0 {
+		return false
+	}
+	return recentRequests[len(recentRequests)-1].Status >= 500
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+(@|%40)[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	tokenPattern = regexp.MustCompile(`(?i)(bearer\s+|token=|key=|secret=|password=)[^\s&"]+`)
+)
+
+// redactText removes email addresses and anything that looks like a
+// credential from s
+func redactText(s string) string {
+	s = emailPattern.ReplaceAllString(s, "<email>")
+	return tokenPattern.ReplaceAllString(s, "${1}<redacted>")
+}
+
+// redactURL redacts a request URL. Query strings are dropped entirely.
+func redactURL(u string) string {
+	u, _, _ = strings.Cut(u, "?")
+	return redactText(u)
+}
+
+// DiagnosticsBundle is written to disk to attach to bug reports
+type DiagnosticsBundle struct {
+	Time      time.Time       `json:"time"`
+	Version   string          `json:"version"`
+	Revision  string          `json:"revision,omitempty"`
+	GoVersion string          `json:"goVersion"`
+	OS        string          `json:"os"`
+	Arch      string          `json:"arch"`
+	Args      []string        `json:"args"`
+	Error     string          `json:"error,omitempty"`
+	Panic     string          `json:"panic,omitempty"`
+	Stack     string          `json:"stack,omitempty"`
+	Requests  []RequestRecord `json:"requests"`
+}
+
+// newDiagnosticsBundle collects diagnostics for a failure. Either err or
+// panicValue should be set.
+func newDiagnosticsBundle(err error, panicValue any, stack []byte) *DiagnosticsBundle {
+	bundle := &DiagnosticsBundle{
+		Time:      time.Now().UTC(),
+		Version:   version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				bundle.Revision = s.Value
+			}
+		}
+	}
+
+	// KEY=VALUE arguments (env set) may be secrets
+	for _, arg := range os.Args[1:] {
+		if key, _, ok := strings.Cut(arg, "="); ok && !strings.HasPrefix(arg, "-") {
+			arg = key + "=<redacted>"
+		}
+		bundle.Args = append(bundle.Args, redactText(arg))
+	}
+
+	if err != nil {
+		bundle.Error = redactText(err.Error())
+	}
+	if panicValue != nil {
+		bundle.Panic = redactText(fmt.Sprint(panicValue))
+		bundle.Stack = string(stack)
+	}
+
+	recentRequestsMu.Lock()
+	bundle.Requests = append([]RequestRecord{}, recentRequests...)
+	recentRequestsMu.Unlock()
+
+	return bundle
+}
+
+// write saves the bundle in the temp directory and returns its path
+func (b *DiagnosticsBundle) write() (string, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("efmrl3-diagnostics-%s.json", b.Time.Format("20060102-150405"))
+	path := filepath.Join(os.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// submit sends the bundle to the efmrl server. Only used when the user has
+// opted in with submit_diagnostics in the global config.
+func (b *DiagnosticsBundle) submit() error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return err
+	}
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return err
+	}
+	return postJSON(apiClient, "/admin/diagnostics", b)
+}
+
+// offerDiagnostics offers to save a diagnostics bundle after a crash or an
+// unexpected API failure. In non-interactive mode the bundle is written
+// without asking, since nobody is there to answer and it stays local.
+func offerDiagnostics(err error, panicValue any, stack []byte) {
+	if panicValue == nil && !lastRequestFailed() {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr)
+	if !nonInteractive {
+		ok, _ := askYesNo("Something went wrong. Save a diagnostics bundle for a bug report?")
+		if !ok {
+			return
+		}
+	}
+
+	bundle := newDiagnosticsBundle(err, panicValue, stack)
+	path, writeErr := bundle.write()
+	if writeErr != nil {
+		warnf("%v\n", writeErr)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Diagnostics saved to %s\n", path)
+	fmt.Fprintln(os.Stderr, "Please attach it to a bug report at https://github.com/efmrl/cli3/issues")
+
+	if config, loadErr := LoadGlobalConfig(); loadErr == nil && config.SubmitDiagnostics {
+		if submitErr := bundle.submit(); submitErr != nil {
+			warnf("failed to submit diagnostics: %v\n", submitErr)
+		} else {
+			fmt.Fprintln(os.Stderr, "Diagnostics submitted to the efmrl team")
+		}
+	}
+}
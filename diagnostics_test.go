@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestRedactText tests that emails and credentials are removed
+func TestRedactText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"/admin/efmrls/abc/access/emails/jo@example.com", "/admin/efmrls/abc/access/emails/<email>"},
+		{"/access/emails/jo%40example.com", "/access/emails/<email>"},
+		{"Authorization: Bearer eyJhbGciOi", "Authorization: Bearer <redacted>"},
+		{"https://x/y?token=s3cret&a=1", "https://x/y?token=<redacted>&a=1"},
+		{"nothing to hide", "nothing to hide"},
+	}
+
+	for _, tt := range tests {
+		if got := redactText(tt.input); got != tt.expected {
+			t.Errorf("redactText(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	if got := redactURL("https://x/files?prefix=/private"); got != "https://x/files" {
+		t.Errorf("Expected query string to be dropped, got %q", got)
+	}
+}
+
+// TestDiagnosticsBundleArgs tests that KEY=VALUE arguments are redacted
+func TestDiagnosticsBundleArgs(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = []string{"efmrl3", "env", "set", "API_KEY=hunter2", "--dir=public"}
+
+	bundle := newDiagnosticsBundle(errors.New("boom"), nil, nil)
+	joined := strings.Join(bundle.Args, " ")
+	if strings.Contains(joined, "hunter2") {
+		t.Errorf("Expected secret to be redacted, got %q", joined)
+	}
+	if !strings.Contains(joined, "--dir=public") {
+		t.Errorf("Expected flags to be kept, got %q", joined)
+	}
+}
+
+// TestLastRequestFailed tests that only server errors trigger diagnostics
+func TestLastRequestFailed(t *testing.T) {
+	defer func() { recentRequests = nil }()
+
+	status := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := newHTTPClient()
+	resp, err := client.Get(server.URL + "/admin/efmrls")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if lastRequestFailed() {
+		t.Errorf("Expected a 404 not to count as an unexpected failure")
+	}
+
+	status = http.StatusInternalServerError
+	resp, err = client.Get(server.URL + "/admin/efmrls")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if !lastRequestFailed() {
+		t.Errorf("Expected a 500 to count as an unexpected failure")
+	}
+}
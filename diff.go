@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiffCmd compares a local directory with the files on the site, without
+// changing either
+type DiffCmd struct {
+	Dir      string `arg:"" optional:"" help:"Directory to compare (defaults to the directory sync would publish)" type:"path"`
+	Stat     bool   `help:"Show how much each path changed and a summary" xor:"format"`
+	NameOnly bool   `help:"Print only the paths that differ, one per line" xor:"format"`
+}
+
+// Examples are shown in 'efmrl3 diff --help'
+func (d *DiffCmd) Examples() []Example {
+	return []Example{
+		{"See what differs between the site and the local files", "efmrl3 diff"},
+		{"Summarize the differences for a build directory", "efmrl3 diff --stat dist"},
+		{"List the paths that differ, for scripting", "efmrl3 diff --name-only"},
+	}
+}
+
+// Diff statuses, named after what a sync with --delete would do
+const (
+	diffAdded   = "added"
+	diffChanged = "changed"
+	diffRemoved = "removed"
+)
+
+// DiffEntry is a path that differs between the local files and the site.
+// LocalSize is zero for removed paths, and RemoteSize for added ones.
+type DiffEntry struct {
+	Path       string `json:"path"`
+	Status     string `json:"status"`
+	LocalSize  int64  `json:"localSize"`
+	RemoteSize int64  `json:"remoteSize"`
+}
+
+// DiffResult is the JSON form of a diff
+type DiffResult struct {
+	SiteID    string      `json:"siteId"`
+	Dir       string      `json:"dir"`
+	Entries   []DiffEntry `json:"entries"`
+	Unchanged int         `json:"unchanged"`
+}
+
+func (d *DiffCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	dir := config.SyncDir()
+	if d.Dir != "" {
+		dir = d.Dir
+	} else if config.Site.Dir == "" && config.Build.OutputDir == "" {
+		detected, err := detectSyncDir(config)
+		if err != nil {
+			return err
+		}
+		if detected != "" {
+			dir = detected
+		}
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory does not exist: %s", absDir)
+	}
+
+	warnIfTransformed(config)
+
+	localFiles, err := scanLocalFiles(absDir)
+	if err != nil {
+		return fmt.Errorf("failed to scan local files: %w", err)
+	}
+	if err := normalizeLocalPaths(localFiles, config.UnicodeNormalization()); err != nil {
+		return err
+	}
+
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+	remoteFiles, err := fetchRemoteFiles(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+
+	entries, unchanged := diffFiles(localFiles, remoteFiles)
+	result := DiffResult{
+		SiteID:    config.Site.SiteID,
+		Dir:       absDir,
+		Entries:   entries,
+		Unchanged: unchanged,
+	}
+
+	switch {
+	case jsonOutput:
+		return printJSON(result)
+	case d.NameOnly:
+		for _, e := range entries {
+			outln(e.Path)
+		}
+	case d.Stat:
+		printDiffStat(result)
+	default:
+		printDiff(result)
+	}
+	return nil
+}
+
+// warnIfTransformed warns when the config has sync rewrite files before
+// they're uploaded, since diff compares the files as they are on disk
+func warnIfTransformed(config *Config) {
+	var steps []string
+	if config.Sync.Fingerprint {
+		steps = append(steps, "fingerprint")
+	}
+	if config.Sync.OptimizeImages || len(config.Sync.ImageVariants) > 0 {
+		steps = append(steps, "optimize_images")
+	}
+	if len(config.Sync.Minify) > 0 {
+		steps = append(steps, "minify")
+	}
+	if config.Sync.Integrity {
+		steps = append(steps, "integrity")
+	}
+	if config.Sync.Sitemap {
+		steps = append(steps, "sitemap")
+	}
+	if len(steps) > 0 {
+		warnf("[sync] %s change(s) what sync uploads, but diff compares the files as they are on disk.\n", strings.Join(steps, ", "))
+		fmt.Fprintf(os.Stderr, "         Use 'efmrl3 sync --dry-run --delete' to see exactly what sync would do.\n\n")
+	}
+}
+
+// diffFiles lists the paths that differ between the local and remote
+// files, sorted by path, and counts the ones that don't. It's the plan a
+// sync with --delete would make, without the intent to carry it out.
+func diffFiles(local []LocalFile, remote []RemoteFile) ([]DiffEntry, int) {
+	remoteSizes := make(map[string]int64, len(remote))
+	for _, rf := range remote {
+		remoteSizes[rf.Path] = rf.Size
+	}
+
+	plan := computeSyncPlan(local, remote, false, true)
+	entries := []DiffEntry{}
+	for _, lf := range plan.ToUpload {
+		size, exists := remoteSizes[lf.Path]
+		status := diffAdded
+		if exists {
+			status = diffChanged
+		}
+		entries = append(entries, DiffEntry{Path: lf.Path, Status: status, LocalSize: lf.Size, RemoteSize: size})
+	}
+	for _, rf := range plan.ToDelete {
+		entries = append(entries, DiffEntry{Path: rf.Path, Status: diffRemoved, RemoteSize: rf.Size})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, len(plan.Unchanged)
+}
+
+// diffMarker is the one-letter, colored status printed before a path
+func diffMarker(status string) string {
+	switch status {
+	case diffAdded:
+		return green("A")
+	case diffRemoved:
+		return red("D")
+	default:
+		return yellow("M")
+	}
+}
+
+// printDiff lists each path that differs with its status, like
+// 'git diff --name-status'
+func printDiff(result DiffResult) {
+	if len(result.Entries) == 0 {
+		outf("%s No differences (%d file(s) unchanged)\n", green("✓"), result.Unchanged)
+		return
+	}
+	for _, e := range result.Entries {
+		outf("%s %s\n", diffMarker(e.Status), e.Path)
+	}
+}
+
+// printDiffStat prints how the size of each differing path changes, then
+// the totals
+func printDiffStat(result DiffResult) {
+	if len(result.Entries) == 0 {
+		outf("%s No differences (%d file(s) unchanged)\n", green("✓"), result.Unchanged)
+		return
+	}
+
+	width := 0
+	for _, e := range result.Entries {
+		width = max(width, len(e.Path))
+	}
+	counts := map[string]int{}
+	var delta int64
+	for _, e := range result.Entries {
+		counts[e.Status]++
+		delta += e.LocalSize - e.RemoteSize
+
+		var change string
+		switch e.Status {
+		case diffAdded:
+			change = green("new, " + formatBytes(e.LocalSize))
+		case diffRemoved:
+			change = red("removed, " + formatBytes(e.RemoteSize))
+		default:
+			change = fmt.Sprintf("%s → %s", formatBytes(e.RemoteSize), formatBytes(e.LocalSize))
+		}
+		outf(" %s %-*s | %s\n", diffMarker(e.Status), width, e.Path, change)
+	}
+	outf(" %d added, %d changed, %d removed, %d unchanged; %s\n",
+		counts[diffAdded], counts[diffChanged], counts[diffRemoved], result.Unchanged, describeSizeDelta(delta))
+}
+
+// describeSizeDelta describes how much bigger or smaller the site gets
+func describeSizeDelta(delta int64) string {
+	switch {
+	case delta > 0:
+		return fmt.Sprintf("site grows by %s", formatBytes(delta))
+	case delta < 0:
+		return fmt.Sprintf("site shrinks by %s", formatBytes(-delta))
+	default:
+		return "site size unchanged"
+	}
+}
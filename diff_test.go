@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffFiles(t *testing.T) {
+	local := []LocalFile{
+		{Path: "/index.html", ETag: "new", Size: 120},
+		{Path: "/about.html", ETag: "same", Size: 50},
+		{Path: "/new.css", ETag: "css", Size: 30},
+	}
+	remote := []RemoteFile{
+		{Path: "/index.html", ETag: "old", Size: 100},
+		{Path: "/about.html", ETag: "same", Size: 50},
+		{Path: "/gone.js", ETag: "js", Size: 70},
+	}
+
+	entries, unchanged := diffFiles(local, remote)
+	want := []DiffEntry{
+		{Path: "/gone.js", Status: diffRemoved, RemoteSize: 70},
+		{Path: "/index.html", Status: diffChanged, LocalSize: 120, RemoteSize: 100},
+		{Path: "/new.css", Status: diffAdded, LocalSize: 30},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Expected %+v, got %+v", want, entries)
+	}
+	if unchanged != 1 {
+		t.Errorf("Expected 1 unchanged file, got %d", unchanged)
+	}
+}
+
+func TestPrintDiffStat(t *testing.T) {
+	result := DiffResult{
+		Entries: []DiffEntry{
+			{Path: "/gone.js", Status: diffRemoved, RemoteSize: 2048},
+			{Path: "/index.html", Status: diffChanged, LocalSize: 120, RemoteSize: 100},
+		},
+		Unchanged: 4,
+	}
+	output := captureStdout(t, func() { printDiffStat(result) })
+
+	for _, want := range []string{
+		"/gone.js    | removed, 2.00 KB",
+		"/index.html | 100 bytes → 120 bytes",
+		"0 added, 1 changed, 1 removed, 4 unchanged; site shrinks by 1.98 KB",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestDescribeSizeDelta(t *testing.T) {
+	tests := []struct {
+		delta int64
+		want  string
+	}{
+		{2048, "site grows by 2.00 KB"},
+		{-10, "site shrinks by 10 bytes"},
+		{0, "site size unchanged"},
+	}
+
+	for _, tt := range tests {
+		if got := describeSizeDelta(tt.delta); got != tt.want {
+			t.Errorf("Expected %q for %d, got %q", tt.want, tt.delta, got)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// SyncDirLockFileName is the state file in a sync directory that a sync
+// holds an advisory lock on while it scans and uploads the directory. It
+// starts with a dot so it's never uploaded.
+const SyncDirLockFileName = ".efmrl-sync.lock"
+
+// errDirLocked is returned by lockFile when another process holds the lock
+var errDirLocked = errors.New("locked by another process")
+
+// lockSyncDir takes an advisory lock on dir, so two syncs of the same
+// directory, even from different projects or with --dir, can't interleave.
+// Unlike the project lock, it's released by the OS when the process exits,
+// so it's never stale. The lock file is left in place, since removing it
+// would let another sync lock a new file while this one still holds the old.
+// A directory the lock file can't be created in is synced unlocked, with a
+// warning. The returned function releases the lock.
+func lockSyncDir(dir string) (func(), error) {
+	lockPath := filepath.Join(dir, SyncDirLockFileName)
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		warnf("Couldn't lock %s, so another sync of it could interfere: %v\n", dir, err)
+		return func() {}, nil
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if !errors.Is(err, errDirLocked) {
+			return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+		}
+		var held SyncLock
+		if _, err := toml.DecodeFile(lockPath, &held); err != nil || held.PID == 0 {
+			return nil, fmt.Errorf("another sync is already using %s; wait for it to finish", dir)
+		}
+		return nil, fmt.Errorf("another sync is already using %s (pid %d, started %s); wait for it to finish",
+			dir, held.PID, held.Started.Local().Format(time.DateTime))
+	}
+
+	// Record the holder, for the message another sync shows
+	if err := file.Truncate(0); err == nil {
+		toml.NewEncoder(file).Encode(SyncLock{PID: os.Getpid(), Started: time.Now().UTC()})
+	}
+
+	return func() {
+		file.Truncate(0)
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestLockSyncDir tests that a locked sync directory blocks a second sync
+// until released, and that the lock file isn't synced
+func TestLockSyncDir(t *testing.T) {
+	dir := t.TempDir()
+
+	unlock, err := lockSyncDir(dir)
+	if err != nil {
+		t.Fatalf("lockSyncDir failed: %v", err)
+	}
+
+	_, err = lockSyncDir(dir)
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Errorf("Expected an error naming the holder while the lock is held, got %v", err)
+	}
+
+	unlock()
+	unlock, err = lockSyncDir(dir)
+	if err != nil {
+		t.Fatalf("lockSyncDir after unlock failed: %v", err)
+	}
+	unlock()
+
+	files, _, err := scanLocalTree(dir, nil)
+	if err != nil || len(files) != 0 {
+		t.Errorf("Expected the lock file to be skipped, got %v (%v)", files, err)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"math"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockRange is where in the file the lock is taken. Windows locks keep
+// other processes from reading the locked bytes, so it's past the end of
+// the holder's details, which they read.
+func lockRange() *windows.Overlapped {
+	return &windows.Overlapped{Offset: math.MaxUint32}
+}
+
+// lockFile takes an exclusive lock on f without waiting, returning
+// errDirLocked if another process holds it
+func lockFile(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0, lockRange())
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errDirLocked
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, lockRange())
+}
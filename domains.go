@@ -9,13 +9,24 @@ import (
 
 // DomainsCmd manages domains for an efmrl
 type DomainsCmd struct {
-	List   DomainsListCmd   `cmd:"" help:"List all domains"`
-	Add    DomainsAddCmd    `cmd:"" help:"Add one or more domains"`
-	Remove DomainsRemoveCmd `cmd:"" help:"Remove one or more domains"`
+	List       DomainsListCmd       `cmd:"" help:"List all domains"`
+	Add        DomainsAddCmd        `cmd:"" help:"Add one or more domains"`
+	Remove     DomainsRemoveCmd     `cmd:"" help:"Remove one or more domains"`
+	SetPrimary DomainsSetPrimaryCmd `cmd:"" help:"Make a domain the canonical one for this efmrl"`
+}
+
+// Domain is a domain name attached to an efmrl
+type Domain struct {
+	ID       int    `json:"id"`
+	Domain   string `json:"domain"`
+	Primary  bool   `json:"primary,omitempty"`
+	Redirect bool   `json:"redirect,omitempty"` // redirect requests to the primary domain
 }
 
 // DomainsListCmd lists all domains for the configured efmrl
-type DomainsListCmd struct{}
+type DomainsListCmd struct {
+	TableFlags `embed:""`
+}
 
 func (d *DomainsListCmd) Run() error {
 	config, err := LoadConfig()
@@ -24,50 +35,66 @@ func (d *DomainsListCmd) Run() error {
 	}
 
 	if config.Site.SiteID == "" {
-		return fmt.Errorf("no site_id configured")
+		return errNoSiteID
 	}
 
 	// Create API client
-	baseURL := fmt.Sprintf("https://%s", config.GetBaseHost())
+	baseURL := config.BaseURL()
 	apiClient, err := NewAPIClient(baseURL)
 	if err != nil {
 		return fmt.Errorf("failed to create API client: %w", err)
 	}
 
 	// Fetch domains
-	resp, err := apiClient.Get(fmt.Sprintf("/admin/efmrls/%s/domains", config.Site.SiteID))
+	domains, err := fetchDomains(apiClient, config.Site.SiteID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch domains: %w", err)
 	}
+
+	if jsonOutput {
+		return printJSON(domains)
+	}
+
+	table := &Table{
+		Title:   "Domains",
+		Empty:   "No domains configured",
+		Columns: []string{"DOMAIN", "ROLE"},
+	}
+	for _, domain := range domains {
+		role := ""
+		switch {
+		case domain.Primary:
+			role = "primary"
+		case domain.Redirect:
+			role = "redirects to primary"
+		}
+		table.AddRow(domain.Domain, role)
+	}
+	return table.Render(humanOutput(), d.TableFlags)
+}
+
+// fetchDomains retrieves the domains attached to an efmrl
+func fetchDomains(client *APIClient, siteID string) ([]Domain, error) {
+	resp, err := client.Get(fmt.Sprintf("/admin/efmrls/%s/domains", siteID))
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var result struct {
-		Domains []struct {
-			ID     int    `json:"id"`
-			Domain string `json:"domain"`
-		} `json:"domains"`
+		Domains []Domain `json:"domains"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(result.Domains) == 0 {
-		fmt.Println("No domains configured")
-		return nil
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	fmt.Printf("Domains (%d):\n", len(result.Domains))
-	for _, domain := range result.Domains {
-		fmt.Printf("  %s\n", domain.Domain)
-	}
-
-	return nil
+	return result.Domains, nil
 }
 
 // DomainsAddCmd adds one or more domains
@@ -82,11 +109,11 @@ func (d *DomainsAddCmd) Run() error {
 	}
 
 	if config.Site.SiteID == "" {
-		return fmt.Errorf("no site_id configured")
+		return errNoSiteID
 	}
 
 	// Create API client
-	baseURL := fmt.Sprintf("https://%s", config.GetBaseHost())
+	baseURL := config.BaseURL()
 	apiClient, err := NewAPIClient(baseURL)
 	if err != nil {
 		return fmt.Errorf("failed to create API client: %w", err)
@@ -94,26 +121,30 @@ func (d *DomainsAddCmd) Run() error {
 
 	// Add each domain
 	for _, domain := range d.Domains {
-		fmt.Printf("Adding %s... ", domain)
+		outf("Adding %s... ", domain)
 
 		body := map[string]string{"domain": domain}
 		resp, err := apiClient.Post(fmt.Sprintf("/admin/efmrls/%s/domains", config.Site.SiteID), body)
 		if err != nil {
-			fmt.Printf("FAILED\n")
+			outf("%s\n", red("FAILED"))
 			return fmt.Errorf("failed to add domain %s: %w", domain, err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			respBody, _ := io.ReadAll(resp.Body)
-			fmt.Printf("FAILED\n")
+			outf("%s\n", red("FAILED"))
 			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
 		}
 
-		fmt.Printf("OK\n")
+		outf("%s\n", green("OK"))
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]string{"added": d.Domains})
 	}
 
-	fmt.Printf("\n✓ Added %d domain(s)\n", len(d.Domains))
+	outf("\n%s Added %d domain(s)\n", green("✓"), len(d.Domains))
 	return nil
 }
 
@@ -129,71 +160,154 @@ func (d *DomainsRemoveCmd) Run() error {
 	}
 
 	if config.Site.SiteID == "" {
-		return fmt.Errorf("no site_id configured")
+		return errNoSiteID
 	}
 
 	// Create API client
-	baseURL := fmt.Sprintf("https://%s", config.GetBaseHost())
+	baseURL := config.BaseURL()
 	apiClient, err := NewAPIClient(baseURL)
 	if err != nil {
 		return fmt.Errorf("failed to create API client: %w", err)
 	}
 
 	// First, fetch all domains to find their IDs
-	resp, err := apiClient.Get(fmt.Sprintf("/admin/efmrls/%s/domains", config.Site.SiteID))
+	domains, err := fetchDomains(apiClient, config.Site.SiteID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch domains: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var listResult struct {
-		Domains []struct {
-			ID     int    `json:"id"`
-			Domain string `json:"domain"`
-		} `json:"domains"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&listResult); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
 
 	// Build a map of domain name to ID
 	domainMap := make(map[string]int)
-	for _, d := range listResult.Domains {
+	for _, d := range domains {
 		domainMap[d.Domain] = d.ID
 	}
 
 	// Remove each domain
+	removed := []string{}
 	for _, domain := range d.Domains {
-		fmt.Printf("Removing %s... ", domain)
+		outf("Removing %s... ", domain)
 
 		domainID, ok := domainMap[domain]
 		if !ok {
-			fmt.Printf("NOT FOUND\n")
+			outf("%s\n", yellow("NOT FOUND"))
 			continue
 		}
 
 		resp, err := apiClient.Delete(fmt.Sprintf("/admin/efmrls/%s/domains/%d", config.Site.SiteID, domainID))
 		if err != nil {
-			fmt.Printf("FAILED\n")
+			outf("%s\n", red("FAILED"))
 			return fmt.Errorf("failed to remove domain %s: %w", domain, err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			respBody, _ := io.ReadAll(resp.Body)
-			fmt.Printf("FAILED\n")
+			outf("%s\n", red("FAILED"))
 			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
 		}
 
-		fmt.Printf("OK\n")
+		outf("%s\n", green("OK"))
+		removed = append(removed, domain)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]string{"removed": removed})
+	}
+
+	outf("\n%s Removed %d domain(s)\n", green("✓"), len(removed))
+	return nil
+}
+
+// DomainsSetPrimaryCmd marks one domain as primary, optionally redirecting
+// every other domain to it
+type DomainsSetPrimaryCmd struct {
+	Domain         string `arg:"" help:"Domain to make primary"`
+	RedirectOthers *bool  `help:"Permanently redirect the other domains to the primary one" negatable:""`
+}
+
+func (d *DomainsSetPrimaryCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	baseURL := config.BaseURL()
+	apiClient, err := NewAPIClient(baseURL)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	domains, err := fetchDomains(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch domains: %w", err)
+	}
+
+	var primary *Domain
+	for i := range domains {
+		if domains[i].Domain == d.Domain {
+			primary = &domains[i]
+		}
+	}
+	if primary == nil {
+		return fmt.Errorf("%s is not attached to this efmrl (add it with 'efmrl3 domains add %s')", d.Domain, d.Domain)
+	}
+
+	if !primary.Primary {
+		if err := updateDomain(apiClient, config.Site.SiteID, primary.ID, map[string]bool{"primary": true}); err != nil {
+			return fmt.Errorf("failed to set primary domain: %w", err)
+		}
+	}
+	outf("%s %s is now the primary domain\n", green("✓"), d.Domain)
+
+	for i := range domains {
+		domains[i].Primary = domains[i].ID == primary.ID
+	}
+
+	// Without the flag, leave each domain's redirect setting alone
+	if d.RedirectOthers != nil {
+		for i, domain := range domains {
+			if domain.ID == primary.ID || domain.Redirect == *d.RedirectOthers {
+				continue
+			}
+
+			if *d.RedirectOthers {
+				outf("Redirecting %s... ", domain.Domain)
+			} else {
+				outf("Serving %s directly... ", domain.Domain)
+			}
+			if err := updateDomain(apiClient, config.Site.SiteID, domain.ID, map[string]bool{"redirect": *d.RedirectOthers}); err != nil {
+				outf("%s\n", red("FAILED"))
+				return fmt.Errorf("failed to update domain %s: %w", domain.Domain, err)
+			}
+			domains[i].Redirect = *d.RedirectOthers
+			outf("%s\n", green("OK"))
+		}
+	}
+
+	if jsonOutput {
+		return printJSON(domains)
+	}
+
+	return nil
+}
+
+// updateDomain changes the settings of a single domain
+func updateDomain(client *APIClient, siteID string, domainID int, body map[string]bool) error {
+	resp, err := client.Patch(fmt.Sprintf("/admin/efmrls/%s/domains/%d", siteID, domainID), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
 	}
 
-	fmt.Printf("\n✓ Removed %d domain(s)\n", len(d.Domains))
 	return nil
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// downloadAttempts is how many times a file is downloaded before giving up
+// on getting a copy that matches its checksum
+const downloadAttempts = 3
+
+// etagPattern matches the ETags that are checksums of a file's content: an
+// MD5, or the multipart formula with its part count
+var etagPattern = regexp.MustCompile(`^([0-9a-f]{32})(?:-([0-9]+))?$`)
+
+// DownloadVerification tallies how the files downloaded by an export or
+// clone checked out against the checksums the server reported for them
+type DownloadVerification struct {
+	Verified   int `json:"verified"`
+	Refetched  int `json:"refetched"`
+	Unverified int `json:"unverified"`
+}
+
+// record counts a file that was downloaded after attempts tries. checked is
+// false if there was nothing to check it against.
+func (v *DownloadVerification) record(checked bool, attempts int) {
+	if v == nil {
+		return
+	}
+	if !checked {
+		v.Unverified++
+		return
+	}
+	v.Verified++
+	if attempts > 1 {
+		v.Refetched++
+	}
+}
+
+// describe summarizes the verification for people
+func (v *DownloadVerification) describe() string {
+	s := fmt.Sprintf("Verified %d file(s) against their checksums", v.Verified)
+	if v.Refetched > 0 {
+		s += fmt.Sprintf(", %d after downloading again", v.Refetched)
+	}
+	if v.Unverified > 0 {
+		s += fmt.Sprintf("; %d had no checksum to verify", v.Unverified)
+	}
+	return s
+}
+
+// ChecksumMismatchError means a downloaded file didn't match the checksum
+// the server reported for it
+type ChecksumMismatchError struct {
+	Path     string
+	Kind     string // "sha256" or "etag"
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s doesn't match its %s (expected %s, got %s)", e.Path, e.Kind, e.Expected, e.Actual)
+}
+
+// downloadChecksums hashes a file as it's downloaded
+type downloadChecksums struct {
+	md5    hash.Hash
+	sha256 hash.Hash
+}
+
+func newDownloadChecksums() *downloadChecksums {
+	return &downloadChecksums{md5: md5.New(), sha256: sha256.New()}
+}
+
+func (c *downloadChecksums) writer() io.Writer {
+	return io.MultiWriter(c.md5, c.sha256)
+}
+
+// verify checks a downloaded file against its SHA-256, if the server
+// reported one, and its ETag, if that's a checksum this client can compute.
+// etag is the ETag the download was served with, if any, since the file may
+// have changed since it was listed. checked is false if there was nothing
+// to check against.
+func (c *downloadChecksums) verify(rf RemoteFile, etag, absPath string, size int64) (checked bool, err error) {
+	if rf.SHA256 != "" {
+		actual := hex.EncodeToString(c.sha256.Sum(nil))
+		if !strings.EqualFold(actual, rf.SHA256) {
+			return true, &ChecksumMismatchError{Path: rf.Path, Kind: "sha256", Expected: rf.SHA256, Actual: actual}
+		}
+		checked = true
+	}
+
+	etag = strings.Trim(etag, `"`)
+	if !etagPattern.MatchString(etag) {
+		etag = rf.ETag
+	}
+	m := etagPattern.FindStringSubmatch(etag)
+	if m == nil {
+		return checked, nil
+	}
+
+	var actual string
+	if m[2] == "" {
+		actual = hex.EncodeToString(c.md5.Sum(nil))
+	} else {
+		// A multipart ETag depends on the part size, which can only be
+		// assumed to be this client's if the part count agrees
+		parts, _ := strconv.ParseInt(m[2], 10, 64)
+		if parts != (size+multipartChunkSize-1)/multipartChunkSize {
+			return checked, nil
+		}
+		if actual, err = computeMultipartETag(absPath); err != nil {
+			return checked, err
+		}
+	}
+	if actual != etag {
+		return true, &ChecksumMismatchError{Path: rf.Path, Kind: "etag", Expected: etag, Actual: actual}
+	}
+	return true, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyDownload(t *testing.T) {
+	content := "hello"
+	sha := sha256.Sum256([]byte(content))
+	shaHex := hex.EncodeToString(sha[:])
+
+	tests := []struct {
+		name        string
+		rf          RemoteFile
+		etag        string
+		wantChecked bool
+		wantKind    string
+	}{
+		{"matching etag", RemoteFile{ETag: md5Hex(content)}, "", true, ""},
+		{"wrong etag", RemoteFile{ETag: md5Hex("other")}, "", true, "etag"},
+		{"served etag wins", RemoteFile{ETag: md5Hex("other")}, `"` + md5Hex(content) + `"`, true, ""},
+		{"opaque etag", RemoteFile{ETag: "e1"}, "", false, ""},
+		{"matching sha256", RemoteFile{ETag: "e1", SHA256: shaHex}, "", true, ""},
+		{"wrong sha256", RemoteFile{ETag: md5Hex(content), SHA256: strings.Repeat("0", 64)}, "", true, "sha256"},
+		{"multipart etag", RemoteFile{ETag: md5Hex(content) + "-1"}, "", true, "etag"},
+		{"multipart etag with other parts", RemoteFile{ETag: md5Hex(content) + "-3"}, "", false, ""},
+	}
+
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sums := newDownloadChecksums()
+			sums.writer().Write([]byte(content))
+			tt.rf.Path = "/index.html"
+			checked, err := sums.verify(tt.rf, tt.etag, path, int64(len(content)))
+			if checked != tt.wantChecked {
+				t.Errorf("Expected checked %v, got %v", tt.wantChecked, checked)
+			}
+			var mismatch *ChecksumMismatchError
+			if tt.wantKind == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			} else if tt.wantKind != "" && (!errors.As(err, &mismatch) || mismatch.Kind != tt.wantKind) {
+				t.Errorf("Expected a %s mismatch, got %v", tt.wantKind, err)
+			}
+		})
+	}
+}
+
+// TestDownloadFileRefetches tests that a download that doesn't match its
+// checksum is tried again, and given up on if it never matches
+func TestDownloadFileRefetches(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/admin/efmrls/abc/files")
+		attempts[path]++
+		if path == "/flaky.html" && attempts[path] > 1 {
+			w.Write([]byte("hello"))
+			return
+		}
+		w.Write([]byte("garbled"))
+	}))
+	defer server.Close()
+	client, _ := NewAPIClient(server.URL)
+	dir := t.TempDir()
+
+	var verification DownloadVerification
+	file, err := downloadFile(client, "abc", RemoteFile{Path: "/flaky.html", ETag: md5Hex("hello")}, dir, &verification)
+	if err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(file.AbsPath); string(data) != "hello" {
+		t.Errorf("Expected the good copy, got %q", data)
+	}
+
+	_, err = downloadFile(client, "abc", RemoteFile{Path: "/broken.html", ETag: md5Hex("hello")}, dir, &verification)
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Expected the download to fail after 3 attempts, got %v", err)
+	}
+	if verification != (DownloadVerification{Verified: 1, Refetched: 1}) {
+		t.Errorf("Expected 1 verified and refetched, got %+v", verification)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected failed downloads removed, got %d file(s)", len(entries))
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// minCopySize is the smallest duplicate that's copied on the server rather
+// than uploaded. Smaller ones are as cheap to upload, often in a batch, as
+// to copy one request at a time.
+const minCopySize = 64 * 1024 // 64 KB
+
+// fileID identifies a file on disk by device and inode. It's only set for
+// files with more than one hard link, so that the links can be hashed once;
+// the zero fileID means the file has no other links, or that the platform
+// can't tell.
+type fileID struct {
+	dev, ino uint64
+}
+
+// planCopies splits plan's uploads into those to upload and those whose
+// content will already be on the site, in an unchanged file or one uploaded
+// before them, and so can be copied there instead. from has the path to
+// copy each from. Only uploads that are hashed are considered.
+func planCopies(plan SyncPlan) (uploads, copies []LocalFile, from map[string]string) {
+	from = make(map[string]string)
+	uploaded := make(map[string]string)
+	for _, lf := range plan.ToUpload {
+		if lf.ETag == "" || lf.Size < minCopySize {
+			uploads = append(uploads, lf)
+			continue
+		}
+		if src, ok := plan.Existing[lf.ETag]; ok {
+			copies = append(copies, lf)
+			from[lf.Path] = src
+			continue
+		}
+		if src, ok := uploaded[lf.ETag]; ok {
+			copies = append(copies, lf)
+			from[lf.Path] = src
+			continue
+		}
+		uploaded[lf.ETag] = lf.Path
+		uploads = append(uploads, lf)
+	}
+	return uploads, copies, from
+}
+
+// copyOrUpload puts file on the site by copying from, a file there with the
+// same content, if from isn't "". Otherwise, or if the copy can't be made,
+// it's uploaded.
+func copyOrUpload(client *APIClient, siteID string, file LocalFile, from, baseETag string, delta *DeltaUploadLimits) error {
+	if from != "" {
+		copied, err := copyRemoteFile(client, siteID, file, from)
+		if copied || err != nil {
+			return err
+		}
+	}
+	return uploadChanged(client, siteID, file, baseETag, delta)
+}
+
+// copyRemoteFile has the server copy from to file's path. copied is false,
+// with no error, when from is gone or no longer has file's content, in
+// which case file should be uploaded instead.
+func copyRemoteFile(client *APIClient, siteID string, file LocalFile, from string) (copied bool, err error) {
+	resp, err := client.Post(fmt.Sprintf("/admin/efmrls/%s/copy", siteID), map[string]string{
+		"from":        from,
+		"to":          file.Path,
+		"etag":        file.ETag,
+		"contentType": file.ContentType,
+	})
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+}
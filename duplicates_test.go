@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPlanCopies(t *testing.T) {
+	big := int64(minCopySize)
+	plan := SyncPlan{
+		ToUpload: []LocalFile{
+			{Path: "/a.bin", ETag: "aaa", Size: big},
+			{Path: "/b.bin", ETag: "aaa", Size: big},
+			{Path: "/c.bin", ETag: "ccc", Size: big},
+			{Path: "/small.txt", ETag: "ccc", Size: 10},
+			{Path: "/unhashed.bin", Size: big},
+			{Path: "/d.bin", ETag: "eee", Size: big},
+		},
+		Existing: map[string]string{"ccc": "/old/c.bin"},
+	}
+
+	uploads, copies, from := planCopies(plan)
+
+	var got []string
+	for _, lf := range uploads {
+		got = append(got, lf.Path)
+	}
+	if want := "/a.bin /small.txt /unhashed.bin /d.bin"; strings.Join(got, " ") != want {
+		t.Errorf("Expected uploads %s, got %v", want, got)
+	}
+	got = nil
+	for _, lf := range copies {
+		got = append(got, lf.Path+"<"+from[lf.Path])
+	}
+	if want := "/b.bin</a.bin /c.bin</old/c.bin"; strings.Join(got, " ") != want {
+		t.Errorf("Expected copies %s, got %v", want, got)
+	}
+}
+
+// TestHardLinksHashedOnce tests that a file's hard links are hashed by
+// reading it once
+func TestHardLinksHashedOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard links aren't detected on Windows")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.html"), []byte("same"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(dir, "a.html"), filepath.Join(dir, "b.html")); err != nil {
+		t.Skipf("can't make a hard link: %v", err)
+	}
+
+	files, _, err := scanLocalTree(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].inode == (fileID{}) || files[0].inode != files[1].inode {
+		t.Fatalf("Expected two links to the same file, got %+v", files)
+	}
+
+	// Hashing /b.html would fail if it were read
+	if err := os.Remove(filepath.Join(dir, "b.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := hashLocalFiles(files, func(*LocalFile) bool { return true }); err != nil {
+		t.Fatalf("Expected the link to be hashed once, got %v", err)
+	}
+	if files[1].ETag != md5Hex("same") {
+		t.Errorf("Expected the link's ETag %s, got %s", md5Hex("same"), files[1].ETag)
+	}
+}
+
+func TestCopyDuplicates(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	big := strings.Repeat("x", minCopySize)
+	site := map[string]string{"/old.bin": big}
+	files := fakeSite(t, site)
+	target, _ := url.Parse(files.URL)
+
+	var copied []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ServerCapabilities{CopyFiles: true})
+	})
+	mux.HandleFunc("POST /admin/efmrls/abc/copy", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		content, ok := site[req["from"]]
+		if !ok || md5Hex(content) != req["etag"] {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		site[req["to"]] = content
+		copied = append(copied, req["to"]+"<"+req["from"])
+	})
+	mux.Handle("/", httputil.NewSingleHostReverseProxy(target))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client, _ := NewAPIClient(server.URL)
+
+	_, local := writeSite(t, map[string]string{
+		"old.bin":  big,
+		"copy.bin": big,
+		"new.bin":  big + "y",
+		"dup.bin":  big + "y",
+	})
+	sort.Slice(local, func(i, j int) bool { return local[i].Path < local[j].Path })
+	var plan SyncPlan
+	for _, lf := range local {
+		if lf.Path == "/old.bin" {
+			plan = computeSyncPlan([]LocalFile{lf}, []RemoteFile{{Path: "/old.bin", ETag: md5Hex(big)}}, false, false)
+		}
+	}
+	for _, lf := range local {
+		if lf.Path != "/old.bin" {
+			lf.ETag = ""
+			plan.ToUpload = append(plan.ToUpload, lf)
+		}
+	}
+
+	var err error
+	output := captureStdout(t, func() { err = executeSyncPlan(client, "abc", plan, false, nil) })
+	if err != nil {
+		t.Fatalf("Expected the sync to succeed, got %v\n%s", err, output)
+	}
+	if got, want := strings.Join(copied, " "), "/copy.bin</old.bin /new.bin</dup.bin"; got != want {
+		t.Errorf("Expected copies %s, got %s", want, got)
+	}
+	for _, p := range []string{"/copy.bin", "/new.bin", "/dup.bin"} {
+		if _, ok := site[p]; !ok {
+			t.Errorf("Expected %s on the site", p)
+		}
+	}
+	if !strings.Contains(output, "Copying /copy.bin from /old.bin... ") {
+		t.Errorf("Expected the copy in the output, got:\n%s", output)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// fileIdentity always returns the zero fileID: Windows only reports a
+// file's index to a handle opened on it, which isn't worth it for the rare
+// hard link
+func fileIdentity(info os.FileInfo) fileID {
+	return fileID{}
+}
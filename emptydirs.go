@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// What sync does about directories with nothing to sync inside, which a
+// static host can't represent, as set by [sync] empty_dirs
+const (
+	emptyDirsWarn   = "warn"   // list them (the default)
+	emptyDirsKeep   = "keep"   // upload a placeholder file into each
+	emptyDirsIgnore = "ignore" // say nothing
+)
+
+// keepFileName is the placeholder uploaded into each empty directory with
+// empty_dirs = "keep"
+const keepFileName = ".keep"
+
+// emptyETag is the ETag of an empty file
+const emptyETag = "d41d8cd98f00b204e9800998ecf8427e"
+
+// maxEmptyDirsListed is how many empty directories the warning names
+const maxEmptyDirsListed = 5
+
+// EmptyDirs returns what sync does about empty directories: emptyDirsWarn,
+// emptyDirsKeep, or emptyDirsIgnore
+func (c *Config) EmptyDirs() string {
+	if c.Sync.EmptyDirs == "" {
+		return emptyDirsWarn
+	}
+	return c.Sync.EmptyDirs
+}
+
+// keepFiles returns an empty placeholder file for each directory, so that
+// it exists on the site
+func keepFiles(dirs []string) []LocalFile {
+	files := make([]LocalFile, len(dirs))
+	for i, dir := range dirs {
+		files[i] = LocalFile{
+			Path:        "/" + dir + "/" + keepFileName,
+			AbsPath:     os.DevNull,
+			ETag:        emptyETag,
+			ContentType: "text/plain; charset=utf-8",
+		}
+	}
+	return files
+}
+
+// warnEmptyDirs warns that directories won't exist on the site, naming the
+// first few
+func warnEmptyDirs(dirs []string) {
+	listed := dirs[:min(len(dirs), maxEmptyDirsListed)]
+	names := strings.Join(listed, "/, ") + "/"
+	if len(dirs) > len(listed) {
+		names += ", ..."
+	}
+	warnf("%d empty director(ies) won't exist on the site, which only holds files: %s\n"+
+		"         pass --keep-empty-dirs, or set empty_dirs = \"keep\" in [sync], to upload a %s file into each\n",
+		len(dirs), names, keepFileName)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanLocalTreeEmptyDirs(t *testing.T) {
+	dir, _ := writeSite(t, map[string]string{
+		"index.html":       "<h1>Hi</h1>",
+		"docs/guide.html":  "guide",
+		"media/.gitignore": "*",
+	})
+	for _, empty := range []string{"downloads", "docs/drafts", "a/b/c", ".cache/x"} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(empty)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, empty, err := scanLocalTree(dir, nil)
+	if err != nil {
+		t.Fatalf("scanLocalTree failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected 2 files, got %d", len(files))
+	}
+	// media only holds a hidden file, so it's empty as far as the site goes
+	if got, want := strings.Join(empty, ","), "a/b/c,docs/drafts,downloads,media"; got != want {
+		t.Errorf("Expected empty dirs %s, got %s", want, got)
+	}
+}
+
+// TestUploadKeepFile tests that a placeholder uploads as an empty file
+func TestUploadKeepFile(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var path, body, checksum string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		path, body, checksum = r.URL.Path, string(data), r.Header.Get("Content-MD5")
+	}))
+	defer server.Close()
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	keep := keepFiles([]string{"downloads"})
+	if len(keep) != 1 || keep[0].ETag != md5Hex("") {
+		t.Fatalf("Expected one placeholder with the empty ETag, got %+v", keep)
+	}
+	if err := uploadFile(client, "abc", keep[0]); err != nil {
+		t.Fatalf("uploadFile failed: %v", err)
+	}
+	if path != "/admin/efmrls/abc/files/downloads/.keep" || body != "" || checksum != "1B2M2Y8AsgTpgAmY7PhCfg==" {
+		t.Errorf("Expected an empty /downloads/.keep, got %s with %q (MD5 %s)", path, body, checksum)
+	}
+}
+
+func TestWarnEmptyDirs(t *testing.T) {
+	stderr := captureStderr(t, func() { warnEmptyDirs([]string{"a", "b", "c", "d", "e", "f"}) })
+	if !strings.Contains(stderr, "6 empty director(ies) won't exist on the site, which only holds files: a/, b/, c/, d/, e/, ...") {
+		t.Errorf("Expected the first five directories named, got %q", stderr)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// EnvCmd manages environment variables used by an efmrl's server-side features
+type EnvCmd struct {
+	List  EnvListCmd  `cmd:"" help:"List environment variables (values are masked)"`
+	Set   EnvSetCmd   `cmd:"" help:"Set one or more environment variables"`
+	Unset EnvUnsetCmd `cmd:"" help:"Remove one or more environment variables"`
+}
+
+// EnvVar is an environment variable stored against an efmrl
+type EnvVar struct {
+	Key       string `json:"key"`
+	Value     string `json:"value,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+}
+
+// EnvListCmd lists the environment variables of the configured efmrl
+type EnvListCmd struct {
+	Reveal     bool `help:"Show values instead of masking them"`
+	TableFlags `embed:""`
+}
+
+func (e *EnvListCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	var result struct {
+		Env []EnvVar `json:"env"`
+	}
+	if err := getJSON(apiClient, fmt.Sprintf("/admin/efmrls/%s/env", config.Site.SiteID), &result); err != nil {
+		return fmt.Errorf("failed to fetch environment variables: %w", err)
+	}
+
+	sort.Slice(result.Env, func(i, j int) bool { return result.Env[i].Key < result.Env[j].Key })
+	if !e.Reveal {
+		for i := range result.Env {
+			result.Env[i].Value = redactSecret(result.Env[i].Value)
+		}
+	}
+
+	if jsonOutput {
+		return printJSON(result.Env)
+	}
+
+	table := &Table{
+		Title:   "Environment variables",
+		Empty:   "No environment variables set",
+		Columns: []string{"KEY", "VALUE", "UPDATED"},
+	}
+	for _, v := range result.Env {
+		table.AddRow(v.Key, v.Value, v.UpdatedAt)
+	}
+	return table.Render(humanOutput(), e.TableFlags)
+}
+
+// EnvSetCmd sets environment variables. Values left off the command line are
+// read from stdin so secrets stay out of shell history.
+type EnvSetCmd struct {
+	Vars []string `arg:"" name:"KEY[=VALUE]" help:"Variable(s) to set; a KEY without a value is read from stdin"`
+}
+
+// Examples are shown in 'efmrl3 env set --help'
+func (e *EnvSetCmd) Examples() []Example {
+	return []Example{
+		{"Set a variable", "efmrl3 env set API_URL=https://api.example.com"},
+		{"Read a secret from stdin so it stays out of shell history", "pass show stripe | efmrl3 env set STRIPE_KEY"},
+	}
+}
+
+func (e *EnvSetCmd) Run() error {
+	var vars []EnvVar
+	for _, arg := range e.Vars {
+		key, value, hasValue := strings.Cut(arg, "=")
+		if err := validateEnvKey(key); err != nil {
+			return err
+		}
+		if !hasValue {
+			var err error
+			value, err = askSecret(fmt.Sprintf("Value for %s: ", key))
+			if err != nil {
+				return fmt.Errorf("failed to read value for %s: %w", key, err)
+			}
+		}
+		vars = append(vars, EnvVar{Key: key, Value: value})
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	for _, v := range vars {
+		outf("Setting %s... ", v.Key)
+
+		body := map[string]string{"value": v.Value}
+		resp, err := apiClient.Put(fmt.Sprintf("/admin/efmrls/%s/env/%s", config.Site.SiteID, url.PathEscape(v.Key)), body)
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to set %s: %w", v.Key, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		outf("%s\n", green("OK"))
+	}
+
+	if jsonOutput {
+		keys := []string{}
+		for _, v := range vars {
+			keys = append(keys, v.Key)
+		}
+		return printJSON(map[string][]string{"set": keys})
+	}
+
+	return nil
+}
+
+// validateEnvKey checks that key is a conventional environment variable name
+func validateEnvKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("environment variable name must not be empty")
+	}
+	for i, r := range key {
+		if r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return fmt.Errorf("invalid environment variable name %q (use letters, digits, and _, not starting with a digit)", key)
+	}
+	return nil
+}
+
+// EnvUnsetCmd removes environment variables
+type EnvUnsetCmd struct {
+	Keys []string `arg:"" name:"KEY" help:"Variable(s) to remove" required:""`
+}
+
+func (e *EnvUnsetCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	removed := []string{}
+	for _, key := range e.Keys {
+		outf("Removing %s... ", key)
+
+		resp, err := apiClient.Delete(fmt.Sprintf("/admin/efmrls/%s/env/%s", config.Site.SiteID, url.PathEscape(key)))
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to remove %s: %w", key, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			outf("%s\n", yellow("NOT FOUND"))
+			continue
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		outf("%s\n", green("OK"))
+		removed = append(removed, key)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]string{"removed": removed})
+	}
+
+	return nil
+}
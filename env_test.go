@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestValidateEnvKey(t *testing.T) {
+	for _, key := range []string{"API_KEY", "_private", "a1", "SMTP_PASSWORD_2"} {
+		if err := validateEnvKey(key); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "1ABC", "WITH-DASH", "WITH SPACE", "DOT.KEY"} {
+		if err := validateEnvKey(key); err == nil {
+			t.Errorf("Expected %q to be rejected", key)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// Exit codes, so scripts can tell failures apart. Anything not covered here
+// exits with ExitFailure.
+const (
+	ExitFailure       = 1 // general failure, including bad command-line usage
+	ExitAuthRequired  = 2 // not logged in, session expired, or deploy key rejected
+	ExitQuotaExceeded = 3 // the site would exceed its storage quota
+	ExitConfigMissing = 4 // no config file, or no site ID configured
+	ExitPartialSync   = 5 // a sync failed after changing some remote files
+)
+
+// The error types below carry an exit code; kong exits with it when a
+// command returns one (directly or wrapped).
+
+// AuthError means the command needs valid credentials
+type AuthError struct{ Err error }
+
+func (e *AuthError) Error() string { return e.Err.Error() }
+func (e *AuthError) Unwrap() error { return e.Err }
+func (e *AuthError) ExitCode() int { return ExitAuthRequired }
+
+// QuotaError means the operation would exceed the site's quota
+type QuotaError struct{ Err error }
+
+func (e *QuotaError) Error() string { return e.Err.Error() }
+func (e *QuotaError) Unwrap() error { return e.Err }
+func (e *QuotaError) ExitCode() int { return ExitQuotaExceeded }
+
+// MissingConfigError means required configuration is missing
+type MissingConfigError struct{ Err error }
+
+func (e *MissingConfigError) Error() string { return e.Err.Error() }
+func (e *MissingConfigError) Unwrap() error { return e.Err }
+func (e *MissingConfigError) ExitCode() int { return ExitConfigMissing }
+
+// PartialSyncError means a sync stopped partway, after Completed of Total
+// operations had been applied to the remote site
+type PartialSyncError struct {
+	Completed int
+	Total     int
+	Err       error
+}
+
+func (e *PartialSyncError) Error() string {
+	return fmt.Sprintf("%v (sync stopped after %d of %d changes; run sync again to finish)", e.Err, e.Completed, e.Total)
+}
+func (e *PartialSyncError) Unwrap() error { return e.Err }
+func (e *PartialSyncError) ExitCode() int { return ExitPartialSync }
+
+// SyncFailure is a change a sync with --keep-going couldn't make, even
+// after retrying it
+type SyncFailure struct {
+	Op    string `json:"op"` // "upload" or "delete"
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// SyncFailuresError means a sync with --keep-going made every change it
+// could, but some failed even when retried
+type SyncFailuresError struct {
+	Failures []SyncFailure
+	Total    int
+}
+
+func (e *SyncFailuresError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d change(s) failed:", len(e.Failures), e.Total)
+	for _, f := range e.Failures {
+		fmt.Fprintf(&b, "\n  %s %s: %s", f.Op, f.Path, f.Error)
+	}
+	b.WriteString("\nEverything else was synced; run sync again to retry these")
+	return b.String()
+}
+func (e *SyncFailuresError) ExitCode() int { return ExitPartialSync }
+
+// exitCode returns the exit code for an error returned by a command
+func exitCode(err error) int {
+	var coder kong.ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return ExitFailure
+}
+
+// errNoSiteID is returned by commands that need a site ID when none is set
+var errNoSiteID = &MissingConfigError{Err: fmt.Errorf("no site_id configured (run 'efmrl3 config --id <site-id>')")}
+
+// errSessionExpired is returned when the server rejects our credentials and
+// they can't be refreshed
+var errSessionExpired = &AuthError{Err: fmt.Errorf("session expired — run 'efmrl3 login' to re-authenticate")}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+// TestExitCodes tests that typed errors carry their exit codes through wrapping
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"auth", fmt.Errorf("failed to fetch domains: %w", errSessionExpired), ExitAuthRequired},
+		{"quota", &QuotaError{Err: errors.New("too big")}, ExitQuotaExceeded},
+		{"config", errNoSiteID, ExitConfigMissing},
+		{"partial sync", partialSyncError(2, 5, errors.New("upload failed")), ExitPartialSync},
+	}
+
+	for _, tt := range tests {
+		var coder kong.ExitCoder
+		if !errors.As(tt.err, &coder) {
+			t.Errorf("%s: expected an ExitCoder, got %T", tt.name, tt.err)
+			continue
+		}
+		if coder.ExitCode() != tt.expected {
+			t.Errorf("%s: expected exit code %d, got %d", tt.name, tt.expected, coder.ExitCode())
+		}
+	}
+}
+
+// TestPartialSyncErrorNothingChanged tests that a failure on the first
+// operation isn't reported as a partial sync
+func TestPartialSyncErrorNothingChanged(t *testing.T) {
+	err := partialSyncError(0, 5, errors.New("upload failed"))
+	var partial *PartialSyncError
+	if errors.As(err, &partial) {
+		t.Errorf("Expected a plain error when nothing changed, got %v", err)
+	}
+}
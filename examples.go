@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/alecthomas/kong"
+)
+
+// Example is a realistic invocation of a command, shown in --help and in the
+// generated docs
+type Example struct {
+	Description string
+	Command     string
+}
+
+// exampleProvider is implemented by commands that have examples
+type exampleProvider interface {
+	Examples() []Example
+}
+
+// nodeExamples returns the examples for a command node, if it has any
+func nodeExamples(n *kong.Node) []Example {
+	if n == nil || n.Type != kong.CommandNode || !n.Target.IsValid() || !n.Target.CanAddr() {
+		return nil
+	}
+	if provider, ok := n.Target.Addr().Interface().(exampleProvider); ok {
+		return provider.Examples()
+	}
+	return nil
+}
+
+// printExamples writes an "Examples:" section for --help
+func printExamples(w io.Writer, examples []Example) {
+	if len(examples) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Examples:")
+	for i, example := range examples {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "  # %s\n", example.Description)
+		fmt.Fprintf(w, "  $ %s\n", example.Command)
+	}
+}
+
+// helpWithExamples is kong's help printer with the selected command's
+// examples added at the end
+func helpWithExamples(options kong.HelpOptions, ctx *kong.Context) error {
+	if err := kong.DefaultHelpPrinter(options, ctx); err != nil {
+		return err
+	}
+	if !options.Summary {
+		printExamples(ctx.Stdout, nodeExamples(ctx.Selected()))
+	}
+	return nil
+}
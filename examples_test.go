@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+// splitExample splits the efmrl3 part of an example command line into
+// arguments, dropping env assignments and pipes and honoring simple quotes
+func splitExample(command string) []string {
+	for _, segment := range strings.Split(command, "|") {
+		var args []string
+		var current strings.Builder
+		var quote rune
+		inArg := false
+		for _, r := range strings.TrimSpace(segment) {
+			switch {
+			case quote != 0 && r == quote:
+				quote = 0
+			case quote != 0:
+				current.WriteRune(r)
+			case r == '\'' || r == '"':
+				quote = r
+				inArg = true
+			case r == ' ':
+				if inArg {
+					args = append(args, current.String())
+					current.Reset()
+					inArg = false
+				}
+			default:
+				current.WriteRune(r)
+				inArg = true
+			}
+		}
+		if inArg {
+			args = append(args, current.String())
+		}
+
+		for i, arg := range args {
+			if arg == "efmrl3" {
+				return args[i+1:]
+			}
+		}
+	}
+	return nil
+}
+
+// TestExamplesParse tests that every example is a valid invocation, so they
+// can't drift from the flags they show
+func TestExamplesParse(t *testing.T) {
+	parser, err := kong.New(&CLI, kong.Name("efmrl3"), kong.Exit(func(int) {}))
+	if err != nil {
+		t.Fatalf("Failed to build parser: %v", err)
+	}
+
+	var nodes []*kong.Node
+	collectDocNodes(parser.Model.Node, &nodes)
+
+	found := 0
+	for _, node := range nodes {
+		for _, example := range nodeExamples(node) {
+			found++
+			args := splitExample(example.Command)
+			if args == nil {
+				t.Errorf("%s: example doesn't run efmrl3: %s", node.Path(), example.Command)
+				continue
+			}
+			// A fresh parser, since values from the last parse would
+			// otherwise trip the --json/--format check
+			parser, err := kong.New(&CLI, kong.Name("efmrl3"), kong.Exit(func(int) {}))
+			if err != nil {
+				t.Fatalf("Failed to build parser: %v", err)
+			}
+			ctx, err := parser.Parse(args)
+			if err != nil {
+				t.Errorf("%s: example %q doesn't parse: %v", node.Path(), example.Command, err)
+				continue
+			}
+			if ctx.Selected().Path() != node.Path() {
+				t.Errorf("%s: example %q runs %s", node.Path(), example.Command, ctx.Command())
+			}
+		}
+	}
+	if found == 0 {
+		t.Errorf("Expected some commands to have examples")
+	}
+}
+
+// TestHelpShowsExamples tests that --help ends with the command's examples
+func TestHelpShowsExamples(t *testing.T) {
+	var out bytes.Buffer
+	parser, err := kong.New(&CLI, kong.Name("efmrl3"), kong.Help(helpWithExamples),
+		kong.Writers(&out, &out), kong.Exit(func(int) {}))
+	if err != nil {
+		t.Fatalf("Failed to build parser: %v", err)
+	}
+
+	parser.Parse([]string{"sync", "--help"})
+	if !strings.Contains(out.String(), "Examples:\n  # Preview what would change") {
+		t.Errorf("Expected sync help to show examples, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	parser.Parse([]string{"status", "--help"})
+	if strings.Contains(out.String(), "Examples:") {
+		t.Errorf("Expected no examples for status, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// noExpiryWarning is set by --no-expiry-warning
+var noExpiryWarning bool
+
+// warnIfExpiring fetches the site's expiry and warns if it's close.
+// Failing to fetch it isn't worth more than silence here.
+func warnIfExpiring(config *Config, client *APIClient) {
+	if noExpiryWarning || config.ExpiryWarningDays() < 0 {
+		return
+	}
+	efmrl, err := fetchEfmrl(client, config.Site.SiteID)
+	if err != nil {
+		return
+	}
+	printExpiryWarning(config, efmrl.ExpiresAt)
+}
+
+// printExpiryWarning warns if expiresAt is within the configured number of
+// days, unless warnings are off
+func printExpiryWarning(config *Config, expiresAt string) {
+	if noExpiryWarning || config.ExpiryWarningDays() < 0 {
+		return
+	}
+	if warning := expiryWarning(expiresAt, config.ExpiryWarningDays(), serverNow()); warning != "" {
+		warnf("%s\n", warning)
+		fmt.Fprintf(os.Stderr, "         Keep a copy with 'efmrl3 export', or see plans without expiry with 'efmrl3 plan'.\n\n")
+	}
+}
+
+// expiryWarning describes how soon a site expires, if that's within days of
+// now, and returns "" otherwise
+func expiryWarning(expiresAt string, days int, now time.Time) string {
+	if expiresAt == "" {
+		return ""
+	}
+	t, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return ""
+	}
+	left := t.Sub(now)
+	date := t.Local().Format(time.DateOnly)
+	switch {
+	case left <= 0:
+		return red(fmt.Sprintf("this efmrl expired on %s; its files may be deleted at any moment.", date))
+	case left > time.Duration(days)*24*time.Hour:
+		return ""
+	case left < time.Hour:
+		return red("this efmrl expires in less than an hour; its files will be deleted then.")
+	case left < 24*time.Hour:
+		return red(fmt.Sprintf("this efmrl expires in %d hour(s); its files will be deleted then.", int(left.Hours())))
+	default:
+		return fmt.Sprintf("this efmrl expires in %d day(s), on %s; its files will be deleted then.", int(left.Hours()/24), date)
+	}
+}
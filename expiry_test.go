@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiryWarning(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) string { return now.Add(d).Format(time.RFC3339) }
+
+	tests := []struct {
+		name      string
+		expiresAt string
+		days      int
+		want      string // substring; "" means no warning
+	}{
+		{"never expires", "", 7, ""},
+		{"unparseable", "soon", 7, ""},
+		{"far off", at(30 * 24 * time.Hour), 7, ""},
+		{"just outside the threshold", at(7*24*time.Hour + time.Minute), 7, ""},
+		{"within the threshold", at(3*24*time.Hour + time.Hour), 7, "expires in 3 day(s)"},
+		{"larger threshold", at(10 * 24 * time.Hour), 14, "expires in 10 day(s)"},
+		{"hours left", at(5*time.Hour + time.Minute), 7, "expires in 5 hour(s)"},
+		{"minutes left", at(10 * time.Minute), 7, "less than an hour"},
+		{"already expired", at(-time.Hour), 7, "expired on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expiryWarning(tt.expiresAt, tt.days, now)
+			if tt.want == "" && got != "" {
+				t.Errorf("Expected no warning, got %q", got)
+			}
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("Expected a warning containing %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExpiryWarningDays(t *testing.T) {
+	config := &Config{}
+	if got := config.ExpiryWarningDays(); got != DefaultExpiryWarningDays {
+		t.Errorf("Expected default %d, got %d", DefaultExpiryWarningDays, got)
+	}
+	config.Sync.ExpiryWarningDays = -1
+	if got := config.ExpiryWarningDays(); got != -1 {
+		t.Errorf("Expected -1, got %d", got)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ExportManifestName is the manifest written at the root of an export
+// archive. It starts with a dot so syncing an extracted export skips it.
+const ExportManifestName = ".efmrl-manifest.json"
+
+// ExportCmd downloads every file of an efmrl into a single archive
+type ExportCmd struct {
+	Output string `help:"Archive to write; .zip for a zip archive, otherwise a gzipped tarball (defaults to <site-id>.tar.gz)" short:"o" type:"path"`
+}
+
+// Examples are shown in 'efmrl3 export --help'
+func (e *ExportCmd) Examples() []Example {
+	return []Example{
+		{"Back up the site before it expires", "efmrl3 export -o site.tar.gz"},
+		{"Export as a zip archive", "efmrl3 export -o site.zip"},
+	}
+}
+
+// ExportManifest describes the files in an export archive
+type ExportManifest struct {
+	SiteID       string                `json:"siteId"`
+	ExportedAt   time.Time             `json:"exportedAt"`
+	Files        []ExportManifestFile  `json:"files"`
+	Verification *DownloadVerification `json:"verification,omitempty"`
+}
+
+// ExportManifestFile is one file in an export archive
+type ExportManifestFile struct {
+	Path        string `json:"path"`
+	ETag        string `json:"etag"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+}
+
+// archiveWriter adds files to a tar.gz or zip archive
+type archiveWriter interface {
+	add(name string, size int64, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+func (e *ExportCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	output := e.Output
+	if output == "" {
+		output = config.Site.SiteID + ".tar.gz"
+	}
+
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	manifest, err := exportSite(apiClient, config.Site.SiteID, output)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(manifest)
+	}
+
+	var total int64
+	for _, f := range manifest.Files {
+		total += f.Size
+	}
+	outf("\n%s Exported %d file(s) (%s) to %s\n", green("✓"), len(manifest.Files), formatBytes(total), output)
+	outln(dim(manifest.Verification.describe()))
+	return nil
+}
+
+// exportSite downloads every file of siteID into an archive at output. The
+// archive is written to a temporary file first, so a failed export never
+// leaves a truncated archive behind.
+func exportSite(client *APIClient, siteID, output string) (*ExportManifest, error) {
+	files, err := fetchRemoteFiles(client, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "efmrl-export-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	partial := output + ".partial"
+	out, err := os.Create(partial)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	defer os.Remove(partial)
+	defer out.Close()
+
+	var archive archiveWriter
+	if strings.HasSuffix(strings.ToLower(output), ".zip") {
+		archive = &zipArchive{zip.NewWriter(out)}
+	} else {
+		archive = newTarGzArchive(out)
+	}
+
+	manifest := &ExportManifest{
+		SiteID:       siteID,
+		ExportedAt:   time.Now().UTC().Truncate(time.Second),
+		Files:        []ExportManifestFile{},
+		Verification: &DownloadVerification{},
+	}
+	for i, rf := range files {
+		outf("[%d/%d] Exporting %s... ", i+1, len(files), rf.Path)
+		file, err := downloadFile(client, siteID, rf, tmpDir, manifest.Verification)
+		if err == nil {
+			err = addFileToArchive(archive, *file, manifest.ExportedAt)
+			os.Remove(file.AbsPath)
+		}
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return nil, fmt.Errorf("failed to export %s: %w", rf.Path, err)
+		}
+		outf("%s\n", green("OK"))
+
+		manifest.Files = append(manifest.Files, ExportManifestFile{
+			Path:        file.Path,
+			ETag:        file.ETag,
+			Size:        file.Size,
+			ContentType: file.ContentType,
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := archive.add(ExportManifestName, int64(len(data)), manifest.ExportedAt, strings.NewReader(string(data))); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish %s: %w", output, err)
+	}
+	if err := out.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish %s: %w", output, err)
+	}
+	if err := os.Rename(partial, output); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	return manifest, nil
+}
+
+// addFileToArchive copies a downloaded file into the archive under its
+// site path
+func addFileToArchive(archive archiveWriter, file LocalFile, modTime time.Time) error {
+	f, err := os.Open(file.AbsPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return archive.add(strings.TrimPrefix(file.Path, "/"), file.Size, modTime, f)
+}
+
+// tarGzArchive writes a gzipped tarball
+type tarGzArchive struct {
+	gz  *gzip.Writer
+	tar *tar.Writer
+}
+
+func newTarGzArchive(w io.Writer) *tarGzArchive {
+	gz := gzip.NewWriter(w)
+	return &tarGzArchive{gz: gz, tar: tar.NewWriter(gz)}
+}
+
+func (a *tarGzArchive) add(name string, size int64, modTime time.Time, r io.Reader) error {
+	if err := a.tar.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err := io.Copy(a.tar, r)
+	return err
+}
+
+func (a *tarGzArchive) Close() error {
+	if err := a.tar.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+// zipArchive writes a zip archive
+type zipArchive struct {
+	zip *zip.Writer
+}
+
+func (a *zipArchive) add(name string, size int64, modTime time.Time, r io.Reader) error {
+	w, err := a.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.zip.Close()
+}
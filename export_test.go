@@ -0,0 +1,158 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exportTestServer serves a site with two files
+func exportTestServer(t *testing.T) *APIClient {
+	t.Helper()
+	t.Setenv(TokenEnvVar, "test-token")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin/efmrls/abc/files":
+			w.Write([]byte(`{"files": [{"path": "/index.html", "etag": "e1", "size": 5}, {"path": "/css/app.css", "etag": "e2", "size": 6}]}`))
+		case "/admin/efmrls/abc/files/index.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("hello"))
+		case "/admin/efmrls/abc/files/css/app.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Write([]byte("body{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+// checkExport checks the files and manifest read back from an archive
+func checkExport(t *testing.T, contents map[string]string) {
+	t.Helper()
+	if contents["index.html"] != "hello" || contents["css/app.css"] != "body{}" {
+		t.Errorf("Unexpected archive contents %v", contents)
+	}
+
+	var manifest ExportManifest
+	if err := json.Unmarshal([]byte(contents[ExportManifestName]), &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if manifest.SiteID != "abc" || len(manifest.Files) != 2 {
+		t.Fatalf("Unexpected manifest %+v", manifest)
+	}
+	expected := ExportManifestFile{Path: "/css/app.css", ETag: "e2", Size: 6, ContentType: "text/css"}
+	if manifest.Files[1] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, manifest.Files[1])
+	}
+}
+
+// TestExportTarGz tests exporting into a gzipped tarball
+func TestExportTarGz(t *testing.T) {
+	client := exportTestServer(t)
+	output := filepath.Join(t.TempDir(), "site.tar.gz")
+
+	captureStdout(t, func() {
+		if _, err := exportSite(client, "abc", output); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+	})
+
+	f, err := os.Open(output)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read gzip: %v", err)
+	}
+
+	contents := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		contents[header.Name] = string(data)
+	}
+	checkExport(t, contents)
+}
+
+// TestExportZip tests exporting into a zip archive
+func TestExportZip(t *testing.T) {
+	client := exportTestServer(t)
+	output := filepath.Join(t.TempDir(), "site.zip")
+
+	captureStdout(t, func() {
+		if _, err := exportSite(client, "abc", output); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+	})
+
+	r, err := zip.OpenReader(output)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer r.Close()
+
+	contents := map[string]string{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(data)
+	}
+	checkExport(t, contents)
+}
+
+// TestExportFailureLeavesNoArchive tests that a failed download doesn't
+// leave a partial archive behind
+func TestExportFailureLeavesNoArchive(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin/efmrls/abc/files" {
+			w.Write([]byte(`{"files": [{"path": "/missing.html"}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "site.tar.gz")
+	captureStdout(t, func() { _, err = exportSite(client, "abc", output) })
+	if err == nil || !strings.Contains(err.Error(), "failed to export /missing.html") {
+		t.Errorf("Expected an export error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no files left behind, got %v", entries)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FilesCmd inspects the files on an efmrl
+type FilesCmd struct {
+	Ls FilesLsCmd `cmd:"" aliases:"list" help:"List the files on the efmrl"`
+}
+
+// FilesLsCmd lists the remote files, optionally under a path prefix
+type FilesLsCmd struct {
+	Prefix     string `arg:"" optional:"" help:"Only list files under this path, e.g. /blog/"`
+	Sort       string `help:"Sort by name, size (largest first), or time (newest first)" enum:"name,size,time" default:"name"`
+	Absolute   bool   `help:"Show upload times as timestamps instead of relative times"`
+	TableFlags `embed:""`
+}
+
+// Examples are shown in 'efmrl3 files ls --help'
+func (f *FilesLsCmd) Examples() []Example {
+	return []Example{
+		{"List the largest images", "efmrl3 files ls /images --sort size"},
+		{"List the most recently uploaded files with exact times", "efmrl3 files ls --sort time --absolute"},
+		{"Print just the paths, for scripts", "efmrl3 --format '{{.Path}}' files ls"},
+	}
+}
+
+func (f *FilesLsCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	files, err := fetchRemoteFiles(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+
+	if f.Prefix != "" {
+		prefix := "/" + strings.TrimPrefix(f.Prefix, "/")
+		var matched []RemoteFile
+		for _, file := range files {
+			if strings.HasPrefix(file.Path, prefix) {
+				matched = append(matched, file)
+			}
+		}
+		files = matched
+	}
+	sortRemoteFiles(files, f.Sort)
+
+	if jsonOutput {
+		return printJSON(files)
+	}
+
+	now := serverNow()
+	table := &Table{
+		Title:   "Files",
+		Empty:   "No files found",
+		Columns: []string{"PATH", "SIZE", "UPLOADED"},
+	}
+	for _, file := range files {
+		uploaded := formatRelativeTime(file.Uploaded, now)
+		if f.Absolute {
+			uploaded = formatTimestamp(file.Uploaded)
+		}
+		table.AddRow(file.Path, formatBytes(file.Size), uploaded)
+	}
+	return table.Render(humanOutput(), f.TableFlags)
+}
+
+// sortRemoteFiles sorts files by name, size (largest first), or upload time
+// (newest first). Ties are broken by name so the order is stable.
+func sortRemoteFiles(files []RemoteFile, by string) {
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		switch by {
+		case "size":
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+		case "time":
+			ta, _ := time.Parse(time.RFC3339, a.Uploaded)
+			tb, _ := time.Parse(time.RFC3339, b.Uploaded)
+			if !ta.Equal(tb) {
+				return ta.After(tb)
+			}
+		}
+		return a.Path < b.Path
+	})
+}
+
+// formatRelativeTime formats an RFC 3339 time relative to now, e.g. "3h ago".
+// Anything older than a month is shown as a date, and unparseable values are
+// returned unchanged.
+func formatRelativeTime(value string, now time.Time) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	default:
+		return t.Local().Format(time.DateOnly)
+	}
+}
+
+// formatTimestamp formats an RFC 3339 time in local time, or returns it
+// unchanged if it can't be parsed
+func formatTimestamp(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFormatRelativeTime tests relative upload times
+func TestFormatRelativeTime(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"2025-06-15T11:59:30Z", "just now"},
+		{"2025-06-15T11:15:00Z", "45m ago"},
+		{"2025-06-15T09:00:00Z", "3h ago"},
+		{"2025-06-10T12:00:00Z", "5d ago"},
+		{"not a time", "not a time"},
+	}
+
+	for _, tt := range tests {
+		if got := formatRelativeTime(tt.value, now); got != tt.expected {
+			t.Errorf("formatRelativeTime(%q): expected %q, got %q", tt.value, tt.expected, got)
+		}
+	}
+}
+
+// TestSortRemoteFiles tests each sort order
+func TestSortRemoteFiles(t *testing.T) {
+	files := []RemoteFile{
+		{Path: "/b.html", Size: 10, Uploaded: "2025-06-01T00:00:00Z"},
+		{Path: "/a.html", Size: 30, Uploaded: "2025-05-01T00:00:00Z"},
+		{Path: "/c.html", Size: 20, Uploaded: "2025-07-01T00:00:00Z"},
+	}
+
+	tests := []struct {
+		by       string
+		expected []string
+	}{
+		{"name", []string{"/a.html", "/b.html", "/c.html"}},
+		{"size", []string{"/a.html", "/c.html", "/b.html"}},
+		{"time", []string{"/c.html", "/b.html", "/a.html"}},
+	}
+
+	for _, tt := range tests {
+		sortRemoteFiles(files, tt.by)
+		for i, path := range tt.expected {
+			if files[i].Path != path {
+				t.Errorf("Sort by %s: expected %s at %d, got %s", tt.by, path, i, files[i].Path)
+			}
+		}
+	}
+}
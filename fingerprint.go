@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// fingerprintCacheControl is set on fingerprinted assets: their content
+// never changes under the same name, so browsers may cache them forever
+const fingerprintCacheControl = "public, max-age=31536000, immutable"
+
+// defaultFingerprintExtensions are the assets fingerprinted unless
+// [sync] fingerprint_extensions says otherwise
+var defaultFingerprintExtensions = []string{
+	".css", ".js", ".mjs",
+	".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif",
+	".woff", ".woff2", ".ttf", ".otf",
+}
+
+var (
+	// htmlRefPattern finds the URL attributes of HTML elements
+	htmlRefPattern = regexp.MustCompile(`(?i)\b(?:src|href|poster)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+	// srcsetPattern finds srcset attributes, which hold several URLs
+	srcsetPattern = regexp.MustCompile(`(?i)\bsrcset\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+	// cssRefPattern finds url(...) and @import references in stylesheets
+	// and in HTML style attributes and elements
+	cssRefPattern = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^'")\s]+))\s*\)|@import\s+(?:"([^"]*)"|'([^']*)')`)
+)
+
+// fingerprinter renames the assets referenced by a site's HTML and CSS to
+// names containing a hash of their content (app.js becomes app.1a2b3c4d.js)
+// and rewrites the references to match
+type fingerprinter struct {
+	files   map[string]*LocalFile // by site path
+	exts    []string
+	tmpDir  string
+	renamed map[string]string // original site path → fingerprinted one
+	visited map[string]bool
+}
+
+// fingerprintAssets fingerprints the assets among files that HTML or CSS
+// refers to. Assets nothing refers to keep their names, since there's no
+// reference to update. It returns the new set of files and the paths of
+// the fingerprinted ones; the returned function removes rewritten copies.
+func fingerprintAssets(files []LocalFile, exts []string) ([]LocalFile, []string, func(), error) {
+	if len(exts) == 0 {
+		exts = defaultFingerprintExtensions
+	}
+	tmpDir, err := os.MkdirTemp("", "efmrl-fingerprint-")
+	if err != nil {
+		return nil, nil, func() {}, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	f := &fingerprinter{
+		files:   make(map[string]*LocalFile, len(files)),
+		exts:    exts,
+		tmpDir:  tmpDir,
+		renamed: map[string]string{},
+		visited: map[string]bool{},
+	}
+	for i := range files {
+		f.files[files[i].Path] = &files[i]
+	}
+
+	// Each page and stylesheet fingerprints what it refers to before
+	// rewriting its own references
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if err := f.rewrite(p); err != nil {
+			cleanup()
+			return nil, nil, func() {}, err
+		}
+	}
+
+	result := make([]LocalFile, 0, len(files))
+	var fingerprinted []string
+	for _, file := range files {
+		if newPath, ok := f.renamed[file.Path]; ok {
+			file.Path = newPath
+			fingerprinted = append(fingerprinted, newPath)
+		}
+		result = append(result, file)
+	}
+	sort.Strings(fingerprinted)
+	return result, fingerprinted, cleanup, nil
+}
+
+// rewrite updates the references in an HTML or CSS file, after first
+// fingerprinting whatever it refers to. Other files are left alone.
+func (f *fingerprinter) rewrite(sitePath string) error {
+	if f.visited[sitePath] {
+		return nil
+	}
+	f.visited[sitePath] = true
+
+	file := f.files[sitePath]
+	isHTML := strings.HasPrefix(file.ContentType, "text/html")
+	isCSS := strings.HasPrefix(file.ContentType, "text/css")
+	if !isHTML && !isCSS {
+		return nil
+	}
+
+	data, err := os.ReadFile(file.AbsPath)
+	if err != nil {
+		return err
+	}
+	content := string(data)
+	dir := path.Dir(sitePath)
+
+	// Fingerprint everything this file refers to first, so its references
+	// can point at the new names
+	var refErr error
+	visit := func(ref string) string {
+		target, ok := f.resolve(dir, ref)
+		if !ok {
+			return ref
+		}
+		if err := f.rewrite(target); err != nil && refErr == nil {
+			refErr = err
+		}
+		f.fingerprint(target)
+		return f.renameRef(ref, target)
+	}
+
+	rewritten := replaceRefs(content, isHTML, visit)
+	if refErr != nil {
+		return refErr
+	}
+	if rewritten == content {
+		return nil
+	}
+
+	out, err := os.CreateTemp(f.tmpDir, "file-*"+path.Ext(sitePath))
+	if err != nil {
+		return err
+	}
+	if _, err := out.WriteString(rewritten); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	etag, err := computeFileETag(out.Name())
+	if err != nil {
+		return err
+	}
+	file.AbsPath = out.Name()
+	file.ETag = etag
+	file.Size = int64(len(rewritten))
+	return nil
+}
+
+// fingerprint gives a referenced asset its fingerprinted name, based on
+// its content after any rewriting
+func (f *fingerprinter) fingerprint(sitePath string) {
+	if _, ok := f.renamed[sitePath]; ok {
+		return
+	}
+	ext := path.Ext(sitePath)
+	if !slices.Contains(f.exts, strings.ToLower(ext)) {
+		return
+	}
+	hash := f.files[sitePath].ETag
+	if len(hash) > 8 {
+		hash = hash[:8]
+	}
+	f.renamed[sitePath] = strings.TrimSuffix(sitePath, ext) + "." + hash + ext
+}
+
+// resolve returns the site path a reference in a file in dir points at, if
+// it's a file on this site
+func (f *fingerprinter) resolve(dir, ref string) (string, bool) {
+	target, ok := resolveSiteRef(dir, ref)
+	if !ok {
+		return "", false
+	}
+	_, ok = f.files[target]
+	return target, ok
+}
+
+// resolveSiteRef returns the site path a reference in a file in dir points
+// at, ignoring any query or fragment, unless it's a URL of another site
+func resolveSiteRef(dir, ref string) (string, bool) {
+	ref, _, _ = strings.Cut(ref, "#")
+	ref, _, _ = strings.Cut(ref, "?")
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	if ref == "" || strings.HasPrefix(ref, "//") || strings.Contains(strings.SplitN(ref, "/", 2)[0], ":") {
+		return "", false
+	}
+
+	target := ref
+	if !strings.HasPrefix(ref, "/") {
+		target = path.Join(dir, ref)
+	}
+	return path.Clean(target), true
+}
+
+// renameRef swaps the file name in a reference for the fingerprinted one.
+// The asset stays in the same directory, so however the reference was
+// written, only its last segment changes.
+func (f *fingerprinter) renameRef(ref, target string) string {
+	newPath, ok := f.renamed[target]
+	if !ok {
+		return ref
+	}
+	end := len(ref)
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		end = i
+	}
+	start := strings.LastIndex(ref[:end], "/") + 1
+	return ref[:start] + path.Base(newPath) + ref[end:]
+}
+
+// replaceRefs calls replace for every asset reference in content and
+// substitutes the result
+func replaceRefs(content string, isHTML bool, replace func(string) string) string {
+	// replaceGroups replaces whichever submatch of each match is present
+	replaceGroups := func(s string, re *regexp.Regexp, each func(string) string) string {
+		var b strings.Builder
+		last := 0
+		for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+			for g := 2; g < len(m); g += 2 {
+				if m[g] < 0 {
+					continue
+				}
+				b.WriteString(s[last:m[g]])
+				b.WriteString(each(s[m[g]:m[g+1]]))
+				last = m[g+1]
+				break
+			}
+		}
+		b.WriteString(s[last:])
+		return b.String()
+	}
+
+	content = replaceGroups(content, cssRefPattern, replace)
+	if !isHTML {
+		return content
+	}
+	content = replaceGroups(content, htmlRefPattern, replace)
+	return replaceGroups(content, srcsetPattern, func(srcset string) string {
+		candidates := strings.Split(srcset, ",")
+		for i, candidate := range candidates {
+			fields := strings.Fields(candidate)
+			if len(fields) == 0 {
+				continue
+			}
+			candidates[i] = strings.Replace(candidate, fields[0], replace(fields[0]), 1)
+		}
+		return strings.Join(candidates, ",")
+	})
+}
+
+// applyImmutableHeaders sets an immutable Cache-Control rule on each
+// fingerprinted asset, and removes the rules of fingerprinted assets that
+// are no longer on the site. files is everything the site now has.
+func applyImmutableHeaders(client *APIClient, siteID string, fingerprinted []string, files []LocalFile) error {
+	rules, err := fetchHeaderRules(client, siteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch header rules: %w", err)
+	}
+
+	has := map[string]bool{}
+	for _, rule := range rules {
+		if rule.Name == "Cache-Control" && rule.Value == fingerprintCacheControl {
+			has[rule.Path] = true
+		}
+	}
+	current := map[string]bool{}
+	for _, file := range files {
+		current[file.Path] = true
+	}
+
+	added := 0
+	for _, p := range fingerprinted {
+		if has[p] {
+			continue
+		}
+		if err := putJSON(client, fmt.Sprintf("/admin/efmrls/%s/headers", siteID),
+			HeaderRule{Path: p, Name: "Cache-Control", Value: fingerprintCacheControl}); err != nil {
+			return fmt.Errorf("failed to set Cache-Control on %s: %w", p, err)
+		}
+		added++
+	}
+
+	removed := 0
+	for _, rule := range rules {
+		if !has[rule.Path] || current[rule.Path] || strings.HasSuffix(rule.Path, "*") ||
+			rule.Name != "Cache-Control" || rule.Value != fingerprintCacheControl {
+			continue
+		}
+		resp, err := client.Delete(fmt.Sprintf("/admin/efmrls/%s/headers/%d", siteID, rule.ID))
+		if err != nil {
+			return fmt.Errorf("failed to remove Cache-Control from %s: %w", rule.Path, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+		removed++
+	}
+
+	if added > 0 || removed > 0 {
+		outf("Immutable caching: %d asset(s) added, %d removed\n", added, removed)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFingerprintAssets tests renaming referenced assets and rewriting the
+// references to them in HTML and CSS
+func TestFingerprintAssets(t *testing.T) {
+	dir := t.TempDir()
+	site := map[string]string{
+		"index.html": `<link rel="stylesheet" href="css/app.css">
+<script src="/js/app.js?v=1"></script>
+<img srcset="img/a.png 1x, img/b.png 2x" src='https://cdn.example.com/x.png'>
+<a href="about.html">About</a>`,
+		"css/app.css": `body { background: url("../img/bg.png"); }`,
+		"js/app.js":   `console.log("hi")`,
+		"img/a.png":   "a",
+		"img/b.png":   "b",
+		"img/bg.png":  "bg",
+		"about.html":  `<p>about</p>`,
+		"unused.js":   `unused()`,
+	}
+	for name, content := range site {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	files, err := scanLocalFiles(dir)
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+
+	result, fingerprinted, cleanup, err := fingerprintAssets(files, nil)
+	if err != nil {
+		t.Fatalf("Failed to fingerprint: %v", err)
+	}
+	defer cleanup()
+
+	byName := map[string]LocalFile{}
+	for _, f := range result {
+		byName[f.Path] = f
+	}
+	find := func(prefix string) string {
+		for _, p := range fingerprinted {
+			if strings.HasPrefix(p, prefix) {
+				return p
+			}
+		}
+		t.Fatalf("Expected %s to be fingerprinted, got %v", prefix, fingerprinted)
+		return ""
+	}
+
+	// Everything referenced gets renamed; pages and unreferenced assets don't
+	if len(fingerprinted) != 5 {
+		t.Errorf("Expected 5 fingerprinted assets, got %v", fingerprinted)
+	}
+	for _, kept := range []string{"/index.html", "/about.html", "/unused.js"} {
+		if _, ok := byName[kept]; !ok {
+			t.Errorf("Expected %s to keep its name", kept)
+		}
+	}
+
+	css := find("/css/app.")
+	bg := find("/img/bg.")
+	js := find("/js/app.")
+	cssContent, _ := os.ReadFile(byName[css].AbsPath)
+	if !strings.Contains(string(cssContent), `url("../img/`+filepath.Base(bg)+`")`) {
+		t.Errorf("Expected the stylesheet to refer to %s, got %s", bg, cssContent)
+	}
+
+	page, _ := os.ReadFile(byName["/index.html"].AbsPath)
+	for _, want := range []string{
+		`href="css/` + filepath.Base(css) + `"`,
+		`src="` + js + `?v=1"`,
+		`srcset="img/` + filepath.Base(find("/img/a.")) + ` 1x, img/` + filepath.Base(find("/img/b.")) + ` 2x"`,
+		`src='https://cdn.example.com/x.png'`,
+		`href="about.html"`,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("Expected the page to contain %s, got:\n%s", want, page)
+		}
+	}
+	if byName["/index.html"].AbsPath == filepath.Join(dir, "index.html") {
+		t.Errorf("Expected the page to be rewritten into a copy, not in place")
+	}
+
+	// The stylesheet's name hashes its rewritten content
+	etag, _ := computeFileETag(byName[css].AbsPath)
+	if !strings.Contains(css, "."+etag[:8]+".") {
+		t.Errorf("Expected %s to contain the hash of its rewritten content %s", css, etag[:8])
+	}
+}
+
+// TestRenameRef tests that only the file name in a reference changes
+func TestRenameRef(t *testing.T) {
+	f := &fingerprinter{renamed: map[string]string{"/a/b.js": "/a/b.12345678.js"}}
+	tests := []struct{ ref, expected string }{
+		{"b.js", "b.12345678.js"},
+		{"../a/b.js?x=1#top", "../a/b.12345678.js?x=1#top"},
+		{"/a/b.js", "/a/b.12345678.js"},
+	}
+	for _, tt := range tests {
+		if got := f.renameRef(tt.ref, "/a/b.js"); got != tt.expected {
+			t.Errorf("renameRef(%q): Expected %q, got %q", tt.ref, tt.expected, got)
+		}
+	}
+}
+
+// TestApplyImmutableHeaders tests adding rules for new fingerprinted assets
+// and removing those of assets that are gone
+func TestApplyImmutableHeaders(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var writes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`{"headers": [
+				{"id": 1, "path": "/app.aaaaaaaa.js", "name": "Cache-Control", "value": "` + fingerprintCacheControl + `"},
+				{"id": 2, "path": "/assets/*", "name": "Cache-Control", "value": "` + fingerprintCacheControl + `"},
+				{"id": 3, "path": "/app.cccccccc.js", "name": "Cache-Control", "value": "` + fingerprintCacheControl + `"}
+			]}`))
+			return
+		}
+		writes = append(writes, r.Method+" "+r.URL.Path)
+	}))
+	defer server.Close()
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	fingerprinted := []string{"/app.bbbbbbbb.js", "/app.cccccccc.js"}
+	files := []LocalFile{{Path: "/index.html"}, {Path: "/app.bbbbbbbb.js"}, {Path: "/app.cccccccc.js"}}
+	captureStdout(t, func() { err = applyImmutableHeaders(client, "abc", fingerprinted, files) })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "PUT /admin/efmrls/abc/headers\nDELETE /admin/efmrls/abc/headers/1"
+	if got := strings.Join(writes, "\n"); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
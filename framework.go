@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Framework describes a static site generator we know how to build
+type Framework struct {
+	Name         string
+	BuildCommand string
+	OutputDir    string
+	markers      []string // any of these files in the project root identifies it
+}
+
+// frameworks are checked in order, so more specific generators come first
+// (Astro projects are also Vite projects, for example)
+var frameworks = []Framework{
+	{
+		Name:         "Hugo",
+		BuildCommand: "hugo --minify",
+		OutputDir:    "public",
+		markers:      []string{"hugo.toml", "hugo.yaml", "hugo.json"},
+	},
+	{
+		Name:         "Astro",
+		BuildCommand: "npm run build",
+		OutputDir:    "dist",
+		markers:      []string{"astro.config.mjs", "astro.config.js", "astro.config.ts"},
+	},
+	{
+		Name:         "Vite",
+		BuildCommand: "npm run build",
+		OutputDir:    "dist",
+		markers:      []string{"vite.config.js", "vite.config.mjs", "vite.config.ts"},
+	},
+	{
+		// Only a static export ('output: "export"' in next.config) can be
+		// synced; server-rendered Next apps can't be hosted on efmrl
+		Name:         "Next.js",
+		BuildCommand: "npm run build",
+		OutputDir:    "out",
+		markers:      []string{"next.config.js", "next.config.mjs", "next.config.ts"},
+	},
+	{
+		Name:         "Jekyll",
+		BuildCommand: "bundle exec jekyll build",
+		OutputDir:    "_site",
+		markers:      []string{"_config.yml", "_config.yaml"},
+	},
+}
+
+// detectFramework looks for a known static site generator in dir and returns
+// nil if none is found
+func detectFramework(dir string) *Framework {
+	for i := range frameworks {
+		for _, marker := range frameworks[i].markers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return &frameworks[i]
+			}
+		}
+	}
+	return nil
+}
+
+// resolveBuild fills in any build settings missing from config with those of
+// the detected framework. The returned framework is nil if nothing was
+// detected or nothing needed filling in.
+func resolveBuild(config *Config) (BuildConfig, *Framework) {
+	build := config.Build
+	if build.Command != "" && build.OutputDir != "" {
+		return build, nil
+	}
+
+	framework := detectFramework(".")
+	if framework == nil {
+		return build, nil
+	}
+
+	if build.Command == "" {
+		build.Command = framework.BuildCommand
+	}
+	if build.OutputDir == "" {
+		build.OutputDir = framework.OutputDir
+	}
+	return build, framework
+}
+
+// offerSaveBuild asks whether to write the detected framework's build
+// settings to the config file, so later runs don't depend on detection and
+// the choice is visible in efmrl.toml. Nothing is saved without asking, and
+// nothing is asked in non-interactive mode or without a config file.
+func offerSaveBuild(config *Config, framework *Framework) error {
+	if nonInteractive || config.fileName == "" {
+		return nil
+	}
+
+	ok, err := askConfirm(fmt.Sprintf("Save the %s build settings (%s, output in %s/) to %s?",
+		framework.Name, framework.BuildCommand, framework.OutputDir, config.FileName()), true)
+	if err != nil || !ok {
+		return err
+	}
+
+	editConfig, err := loadConfigForEdit(false)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	if editConfig.Build.Command == "" {
+		editConfig.Build.Command = framework.BuildCommand
+	}
+	if editConfig.Build.OutputDir == "" {
+		editConfig.Build.OutputDir = framework.OutputDir
+	}
+	if err := SaveConfig(editConfig); err != nil {
+		return err
+	}
+	outf("%s Saved [build] settings to %s\n", green("✓"), editConfig.FileName())
+	return nil
+}
+
+// detectSyncDir picks the directory to sync when none is configured: the
+// output directory of a detected framework, rather than the whole project.
+// It returns "" if no framework is detected.
+func detectSyncDir(config *Config) (string, error) {
+	framework := detectFramework(".")
+	if framework == nil {
+		return "", nil
+	}
+
+	outf("Detected %s project; syncing its output directory %s/\n", framework.Name, framework.OutputDir)
+	if info, err := os.Stat(framework.OutputDir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s/ does not exist yet; run 'efmrl3 deploy' to build and sync it, or pass --dir",
+			framework.OutputDir)
+	}
+	if err := offerSaveBuild(config, framework); err != nil {
+		return "", err
+	}
+	outln()
+	return framework.OutputDir, nil
+}
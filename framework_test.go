@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestResolveBuild tests framework detection and config precedence
+func TestResolveBuild(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// Nothing configured or detected
+	build, framework := resolveBuild(&Config{})
+	if framework != nil || build.Command != "" {
+		t.Errorf("Expected no build, got %+v (%v)", build, framework)
+	}
+
+	// Astro wins over Vite when both markers exist
+	os.WriteFile("vite.config.js", []byte(""), 0644)
+	os.WriteFile("astro.config.mjs", []byte(""), 0644)
+	build, framework = resolveBuild(&Config{})
+	if framework == nil || framework.Name != "Astro" {
+		t.Fatalf("Expected Astro to be detected, got %v", framework)
+	}
+	if build.Command != "npm run build" || build.OutputDir != "dist" {
+		t.Errorf("Unexpected detected build: %+v", build)
+	}
+
+	// Configured values take precedence over detected ones
+	config := &Config{Build: BuildConfig{OutputDir: "out"}}
+	build, _ = resolveBuild(config)
+	if build.Command != "npm run build" || build.OutputDir != "out" {
+		t.Errorf("Expected configured output_dir to win, got %+v", build)
+	}
+}
+
+// TestDetectSyncDir tests syncing a detected framework's output directory
+func TestDetectSyncDir(t *testing.T) {
+	t.Chdir(t.TempDir())
+	defer func() { nonInteractive = false }()
+	nonInteractive = true
+
+	if dir, err := detectSyncDir(&Config{}); dir != "" || err != nil {
+		t.Errorf("Expected nothing detected, got %q, %v", dir, err)
+	}
+
+	os.WriteFile("next.config.mjs", []byte(""), 0644)
+	captureStdout(t, func() {
+		if _, err := detectSyncDir(&Config{}); err == nil {
+			t.Errorf("Expected an error before out/ is built")
+		}
+
+		os.Mkdir("out", 0755)
+		if dir, err := detectSyncDir(&Config{}); dir != "out" || err != nil {
+			t.Errorf("Expected out, got %q, %v", dir, err)
+		}
+	})
+}
+
+// TestOfferSaveBuild tests writing detected settings after confirmation
+func TestOfferSaveBuild(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile(ConfigFileName, []byte("[site]\nsite_id = \"abc\"\n"), 0644)
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	withStdin(t, "y\n")
+	captureStdout(t, func() {
+		if err := offerSaveBuild(config, &frameworks[0]); err != nil {
+			t.Errorf("offerSaveBuild failed: %v", err)
+		}
+	})
+
+	saved, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if saved.Build.Command != frameworks[0].BuildCommand || saved.Build.OutputDir != frameworks[0].OutputDir {
+		t.Errorf("Expected detected build settings to be saved, got %+v", saved.Build)
+	}
+	if saved.Site.SiteID != "abc" {
+		t.Errorf("Expected site_id to be kept, got %q", saved.Site.SiteID)
+	}
+}
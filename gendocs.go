@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// GenDocsCmd renders the command tree into man pages and markdown, for
+// packaging. It's hidden from --help.
+type GenDocsCmd struct {
+	Dir  string   `help:"Directory to write docs into (man/ and markdown/ are created inside it)" default:"docs"`
+	Type []string `help:"Kind(s) of docs to generate" default:"man,markdown" enum:"man,markdown"`
+}
+
+func (g *GenDocsCmd) Run(ctx *kong.Context) error {
+	count, err := genDocs(ctx.Model.Node, g.Dir, g.Type)
+	if err != nil {
+		return err
+	}
+	outf("%s Wrote docs for %d command(s) to %s\n", green("✓"), count, g.Dir)
+	return nil
+}
+
+// genDocs writes a page per visible command in the requested formats and
+// returns how many commands were documented
+func genDocs(root *kong.Node, dir string, formats []string) (int, error) {
+	var nodes []*kong.Node
+	collectDocNodes(root, &nodes)
+
+	for _, format := range formats {
+		formatDir := filepath.Join(dir, format)
+		if err := os.MkdirAll(formatDir, 0755); err != nil {
+			return 0, fmt.Errorf("failed to create %s: %w", formatDir, err)
+		}
+
+		for _, n := range nodes {
+			var name, content string
+			switch format {
+			case "man":
+				name, content = docPageName(n)+".1", renderManPage(n)
+			case "markdown":
+				name, content = docPageName(n)+".md", renderMarkdown(n)
+			default:
+				return 0, fmt.Errorf("unknown docs format %q", format)
+			}
+			if err := os.WriteFile(filepath.Join(formatDir, name), []byte(content), 0644); err != nil {
+				return 0, fmt.Errorf("failed to write %s: %w", name, err)
+			}
+		}
+	}
+
+	return len(nodes), nil
+}
+
+// collectDocNodes appends n and its visible descendant commands to nodes
+func collectDocNodes(n *kong.Node, nodes *[]*kong.Node) {
+	*nodes = append(*nodes, n)
+	for _, child := range docChildren(n) {
+		collectDocNodes(child, nodes)
+	}
+}
+
+// docChildren returns n's visible subcommands
+func docChildren(n *kong.Node) []*kong.Node {
+	var children []*kong.Node
+	for _, child := range n.Children {
+		if child.Type == kong.CommandNode && !child.Hidden {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// docCommandPath returns the words used to invoke n, e.g. "efmrl3 sites list"
+func docCommandPath(n *kong.Node) string {
+	var words []string
+	for ; n != nil; n = n.Parent {
+		words = append(words, n.Name)
+	}
+	slices.Reverse(words)
+	return strings.Join(words, " ")
+}
+
+// docPageName returns the page name for n, e.g. "efmrl3-sites-list"
+func docPageName(n *kong.Node) string {
+	return strings.ReplaceAll(docCommandPath(n), " ", "-")
+}
+
+// docUsage returns the usage line for n, e.g. "efmrl3 sites delete <site> [flags]"
+func docUsage(n *kong.Node) string {
+	if n.Parent == nil {
+		return n.Name + " <command> [flags]"
+	}
+	usage := docCommandPath(n)
+	if flags := n.FlagSummary(true); flags != "" {
+		usage += " " + flags
+	}
+	for _, arg := range n.Positional {
+		usage += " " + arg.Summary()
+	}
+	if len(docChildren(n)) > 0 {
+		usage += " <command>"
+	}
+	return usage + " [flags]"
+}
+
+// docFlags returns the visible flags defined directly on n
+func docFlags(n *kong.Node) []*kong.Flag {
+	var flags []*kong.Flag
+	for _, flag := range n.Flags {
+		if !flag.Hidden {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// docFlagHelp returns a flag's help with its default and environment
+// variables appended
+func docFlagHelp(flag *kong.Flag) string {
+	help := flag.Help
+	if flag.HasDefault && flag.Default != "" {
+		help += fmt.Sprintf(" (default: %s)", flag.Default)
+	}
+	if len(flag.Envs) > 0 {
+		help += fmt.Sprintf(" ($%s)", strings.Join(flag.Envs, ", $"))
+	}
+	return help
+}
+
+// renderMarkdown renders the markdown page for n
+func renderMarkdown(n *kong.Node) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", docCommandPath(n))
+	if n.Help != "" {
+		fmt.Fprintf(&b, "%s\n\n", n.Help)
+	}
+	if n.Detail != "" {
+		fmt.Fprintf(&b, "%s\n\n", n.Detail)
+	}
+	fmt.Fprintf(&b, "## Usage\n\n```\n%s\n```\n", docUsage(n))
+
+	if len(n.Positional) > 0 {
+		b.WriteString("\n## Arguments\n\n")
+		for _, arg := range n.Positional {
+			fmt.Fprintf(&b, "- `%s`: %s\n", arg.Summary(), arg.Help)
+		}
+	}
+
+	if flags := docFlags(n); len(flags) > 0 {
+		heading := "Flags"
+		if n.Parent == nil {
+			heading = "Global flags"
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", heading)
+		for _, flag := range flags {
+			fmt.Fprintf(&b, "- `%s`: %s\n", flag.String(), docFlagHelp(flag))
+		}
+	}
+
+	if examples := nodeExamples(n); len(examples) > 0 {
+		b.WriteString("\n## Examples\n\n")
+		for _, example := range examples {
+			fmt.Fprintf(&b, "%s:\n\n```\n%s\n```\n\n", example.Description, example.Command)
+		}
+	}
+
+	if children := docChildren(n); len(children) > 0 {
+		b.WriteString("\n## Commands\n\n")
+		for _, child := range children {
+			fmt.Fprintf(&b, "- [%s](%s.md): %s\n", docCommandPath(child), docPageName(child), child.Help)
+		}
+	}
+
+	if n.Parent != nil {
+		fmt.Fprintf(&b, "\n## See also\n\n- [%s](%s.md)\n", docCommandPath(n.Parent), docPageName(n.Parent))
+	}
+
+	return b.String()
+}
+
+// renderManPage renders the roff man page for n
+func renderManPage(n *kong.Node) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, ".TH %q 1 \"\" %q \"efmrl3 Manual\"\n",
+		strings.ToUpper(docPageName(n)), "efmrl3 "+version)
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", docPageName(n), roffEscape(n.Help))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n", roffEscape(docUsage(n)))
+
+	if n.Detail != "" {
+		fmt.Fprintf(&b, ".SH DESCRIPTION\n%s\n", roffEscape(n.Detail))
+	}
+
+	if len(n.Positional) > 0 {
+		b.WriteString(".SH ARGUMENTS\n")
+		for _, arg := range n.Positional {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape(arg.Summary()), roffEscape(arg.Help))
+		}
+	}
+
+	if flags := docFlags(n); len(flags) > 0 {
+		heading := "OPTIONS"
+		if n.Parent == nil {
+			heading = "GLOBAL OPTIONS"
+		}
+		fmt.Fprintf(&b, ".SH %s\n", heading)
+		for _, flag := range flags {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape(flag.String()), roffEscape(docFlagHelp(flag)))
+		}
+	}
+
+	if examples := nodeExamples(n); len(examples) > 0 {
+		b.WriteString(".SH EXAMPLES\n")
+		for _, example := range examples {
+			fmt.Fprintf(&b, "%s:\n.PP\n.RS\n.nf\n%s\n.fi\n.RE\n.PP\n", roffEscape(example.Description), roffEscape(example.Command))
+		}
+	}
+
+	if children := docChildren(n); len(children) > 0 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, child := range children {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape(child.Name), roffEscape(child.Help))
+		}
+	}
+
+	var seeAlso []string
+	if n.Parent != nil {
+		seeAlso = append(seeAlso, docPageName(n.Parent))
+	}
+	for _, child := range docChildren(n) {
+		seeAlso = append(seeAlso, docPageName(child))
+	}
+	if len(seeAlso) > 0 {
+		b.WriteString(".SH SEE ALSO\n")
+		for i, name := range seeAlso {
+			sep := ","
+			if i == len(seeAlso)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(&b, ".BR %s (1)%s\n", name, sep)
+		}
+	}
+
+	return b.String()
+}
+
+// roffEscape escapes text for use in a man page
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
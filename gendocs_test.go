@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+// TestGenDocs tests that docs are generated for visible commands only
+func TestGenDocs(t *testing.T) {
+	parser, err := kong.New(&CLI, kong.Name("efmrl3"))
+	if err != nil {
+		t.Fatalf("Failed to build parser: %v", err)
+	}
+
+	dir := t.TempDir()
+	if _, err := genDocs(parser.Model.Node, dir, []string{"man", "markdown"}); err != nil {
+		t.Fatalf("genDocs failed: %v", err)
+	}
+
+	for _, name := range []string{"man/efmrl3.1", "man/efmrl3-sites-list.1", "markdown/efmrl3-sync.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to be generated: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "markdown", "efmrl3-gen-docs.md")); err == nil {
+		t.Errorf("Expected hidden gen-docs command to be skipped")
+	}
+
+	page, err := os.ReadFile(filepath.Join(dir, "markdown", "efmrl3-sync.md"))
+	if err != nil {
+		t.Fatalf("Failed to read sync docs: %v", err)
+	}
+	if !strings.Contains(string(page), "--dry-run") {
+		t.Errorf("Expected sync docs to list --dry-run, got:\n%s", page)
+	}
+}
+
+// TestRoffEscape tests escaping of man page text
+func TestRoffEscape(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"--dry-run", `\-\-dry\-run`},
+		{`a\b`, `a\eb`},
+		{".hidden", `\&.hidden`},
+	}
+
+	for _, tt := range tests {
+		if got := roffEscape(tt.input); got != tt.expected {
+			t.Errorf("roffEscape(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
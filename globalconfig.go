@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -13,7 +14,11 @@ const GlobalConfigFileName = "credentials.toml"
 
 // GlobalConfig stores credentials for multiple hosts
 type GlobalConfig struct {
-	Hosts map[string]HostCredentials `toml:"host"`
+	// SubmitDiagnostics opts in to sending diagnostics bundles to the efmrl
+	// server after a crash, in addition to saving them locally
+	SubmitDiagnostics bool                       `toml:"submit_diagnostics,omitempty"`
+	Performance       PerformanceConfig          `toml:"performance,omitempty"`
+	Hosts             map[string]HostCredentials `toml:"host"`
 }
 
 // HostCredentials stores authentication credentials for a specific host
@@ -73,16 +78,12 @@ func SaveGlobalConfig(config *GlobalConfig) error {
 		return fmt.Errorf("error creating config directory: %w", err)
 	}
 
-	// Create or truncate the file
-	file, err := os.OpenFile(configPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	// Write it alongside and rename it into place, so a crash partway
+	// doesn't lose everyone's credentials
+	err = writeFileAtomic(configPath, 0600, func(w io.Writer) error {
+		return toml.NewEncoder(w).Encode(config)
+	})
 	if err != nil {
-		return fmt.Errorf("error creating config file: %w", err)
-	}
-	defer file.Close()
-
-	// Encode to TOML
-	encoder := toml.NewEncoder(file)
-	if err := encoder.Encode(config); err != nil {
 		return fmt.Errorf("error writing config file: %w", err)
 	}
 
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// globalConfigLockTimeout is how long a command waits for another to
+	// finish updating the global config
+	globalConfigLockTimeout = 30 * time.Second
+
+	// globalConfigLockPoll is how often a waiting command tries the lock
+	globalConfigLockPoll = 50 * time.Millisecond
+)
+
+// updateGlobalConfig loads the global config, lets update change it, and
+// saves it, all under a lock, so that commands running at once, or
+// goroutines refreshing the same token, can't overwrite each other's
+// changes with a stale copy. The lock is on a file next to the config
+// rather than the config itself, which is replaced on every save. Nothing
+// is saved if update fails.
+func updateGlobalConfig(update func(*GlobalConfig) error) error {
+	unlock, err := lockGlobalConfig()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := LoadGlobalConfig()
+	if err != nil {
+		return err
+	}
+	if err := update(config); err != nil {
+		return err
+	}
+	return SaveGlobalConfig(config)
+}
+
+// lockGlobalConfig waits up to globalConfigLockTimeout for the global
+// config's lock. The returned function releases it.
+func lockGlobalConfig() (func(), error) {
+	configPath, err := GetGlobalConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return nil, fmt.Errorf("error creating config directory: %w", err)
+	}
+	lockPath := configPath + ".lock"
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", configPath, err)
+	}
+
+	deadline := time.Now().Add(globalConfigLockTimeout)
+	for {
+		err := lockFile(file)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errDirLocked) {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", configPath, err)
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("timed out waiting for another efmrl3 command to finish updating %s", configPath)
+		}
+		time.Sleep(globalConfigLockPoll)
+	}
+
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestUpdateGlobalConfigConcurrently tests that updates made at once are
+// all kept, rather than overwriting each other
+func TestUpdateGlobalConfigConcurrently(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- updateGlobalConfig(func(config *GlobalConfig) error {
+				config.SetHostCredentials(fmt.Sprintf("host%d.example", i), HostCredentials{AccessToken: "token"})
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := LoadGlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Hosts) != n {
+		t.Errorf("Expected %d hosts saved, got %d", n, len(config.Hosts))
+	}
+}
+
+func TestUpdateGlobalConfigFails(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	updateGlobalConfig(func(config *GlobalConfig) error {
+		config.SetHostCredentials("kept.example", HostCredentials{AccessToken: "token"})
+		return nil
+	})
+
+	err := updateGlobalConfig(func(config *GlobalConfig) error {
+		config.DeleteHostCredentials("kept.example")
+		return fmt.Errorf("refresh failed")
+	})
+	if err == nil {
+		t.Fatalf("Expected the update's error")
+	}
+	config, _ := LoadGlobalConfig()
+	if _, ok := config.GetHostCredentials("kept.example"); !ok {
+		t.Errorf("Expected nothing saved after a failed update")
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// HeadersCmd manages per-path response header rules for an efmrl
+type HeadersCmd struct {
+	List   HeadersListCmd   `cmd:"" help:"List all header rules"`
+	Set    HeadersSetCmd    `cmd:"" help:"Set one or more response headers for a path"`
+	Remove HeadersRemoveCmd `cmd:"" help:"Remove header rules for a path"`
+}
+
+// HeaderRule adds a response header to every path matching Path, which may
+// end in "*" to match a prefix
+type HeaderRule struct {
+	ID    int    `json:"id,omitempty"`
+	Path  string `json:"path"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HeadersListCmd lists header rules grouped by path
+type HeadersListCmd struct {
+	TableFlags `embed:""`
+}
+
+func (h *HeadersListCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	rules, err := fetchHeaderRules(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch header rules: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(rules)
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Path < rules[j].Path })
+
+	table := &Table{
+		Title:   "Header rules",
+		Empty:   "No header rules configured",
+		Columns: []string{"PATH", "HEADER", "VALUE"},
+	}
+	for _, rule := range rules {
+		table.AddRow(rule.Path, rule.Name, rule.Value)
+	}
+	return table.Render(humanOutput(), h.TableFlags)
+}
+
+// fetchHeaderRules retrieves the header rules configured for an efmrl
+func fetchHeaderRules(client *APIClient, siteID string) ([]HeaderRule, error) {
+	var result struct {
+		Headers []HeaderRule `json:"headers"`
+	}
+	if err := getJSON(client, fmt.Sprintf("/admin/efmrls/%s/headers", siteID), &result); err != nil {
+		return nil, err
+	}
+	return result.Headers, nil
+}
+
+// HeadersSetCmd adds or replaces header rules for a path
+type HeadersSetCmd struct {
+	Path    string   `arg:"" help:"Path the headers apply to, e.g. / or /assets/*"`
+	Headers []string `arg:"" name:"header" help:"Header(s) to set, as 'Name: value' or Name=value"`
+}
+
+func (h *HeadersSetCmd) Run() error {
+	if !strings.HasPrefix(h.Path, "/") {
+		return fmt.Errorf("path %q must start with /", h.Path)
+	}
+
+	var rules []HeaderRule
+	for _, header := range h.Headers {
+		name, value, err := parseHeaderAssignment(header)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, HeaderRule{Path: h.Path, Name: name, Value: value})
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	for _, rule := range rules {
+		outf("Setting %s on %s... ", rule.Name, rule.Path)
+
+		// PUT replaces any existing rule with the same path and name
+		resp, err := apiClient.Put(fmt.Sprintf("/admin/efmrls/%s/headers", config.Site.SiteID), rule)
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to set header %s: %w", rule.Name, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		outf("%s\n", green("OK"))
+	}
+
+	if jsonOutput {
+		return printJSON(rules)
+	}
+
+	return nil
+}
+
+// parseHeaderAssignment splits "Name: value" or "Name=value" into a
+// canonicalized header name and its value
+func parseHeaderAssignment(s string) (name, value string, err error) {
+	i := strings.IndexAny(s, ":=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("invalid header %q (expected 'Name: value' or Name=value)", s)
+	}
+
+	name = strings.TrimSpace(s[:i])
+	value = strings.TrimSpace(s[i+1:])
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return "", "", fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if value == "" {
+		return "", "", fmt.Errorf("header %s has an empty value (use 'efmrl3 headers remove' to delete it)", name)
+	}
+
+	return textproto.CanonicalMIMEHeaderKey(name), value, nil
+}
+
+// HeadersRemoveCmd removes all header rules for a path, or just the named ones
+type HeadersRemoveCmd struct {
+	Path  string   `arg:"" help:"Path whose header rules should be removed"`
+	Names []string `arg:"" name:"name" optional:"" help:"Header name(s) to remove (default: all headers for the path)"`
+}
+
+func (h *HeadersRemoveCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	// First, fetch all rules to find their IDs
+	rules, err := fetchHeaderRules(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch header rules: %w", err)
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range h.Names {
+		wanted[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+
+	removed := []HeaderRule{}
+	for _, rule := range rules {
+		if rule.Path != h.Path || (len(wanted) > 0 && !wanted[textproto.CanonicalMIMEHeaderKey(rule.Name)]) {
+			continue
+		}
+
+		outf("Removing %s from %s... ", rule.Name, rule.Path)
+		resp, err := apiClient.Delete(fmt.Sprintf("/admin/efmrls/%s/headers/%d", config.Site.SiteID, rule.ID))
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to remove header %s: %w", rule.Name, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		outf("%s\n", green("OK"))
+		removed = append(removed, rule)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]HeaderRule{"removed": removed})
+	}
+
+	if len(removed) == 0 {
+		outf("No matching header rules for %s\n", h.Path)
+		return nil
+	}
+
+	outf("\n%s Removed %d header rule(s)\n", green("✓"), len(removed))
+	return nil
+}
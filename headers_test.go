@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestParseHeaderAssignment(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+		value string
+		valid bool
+	}{
+		{"Cache-Control: max-age=3600", "Cache-Control", "max-age=3600", true},
+		{"x-frame-options=DENY", "X-Frame-Options", "DENY", true},
+		{"Access-Control-Allow-Origin:*", "Access-Control-Allow-Origin", "*", true},
+		{"no-separator", "", "", false},
+		{": value", "", "", false},
+		{"Bad Name: x", "", "", false},
+		{"Empty:", "", "", false},
+	}
+
+	for _, tt := range tests {
+		name, value, err := parseHeaderAssignment(tt.input)
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected valid=%v for %q, got error %v", tt.valid, tt.input, err)
+			continue
+		}
+		if name != tt.name || value != tt.value {
+			t.Errorf("Expected %q=%q for %q, got %q=%q", tt.name, tt.value, tt.input, name, value)
+		}
+	}
+}
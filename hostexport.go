@@ -0,0 +1,533 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// hostedSite is a site exported from another static host, mapped onto
+// efmrl: the files to publish and the rules efmrl can express. Rules it
+// can't express are described in Skipped rather than silently dropped.
+type hostedSite struct {
+	Provider   string
+	ContentDir string
+	Exclude    []string // site paths of the host's own config files
+	Redirects  []Redirect
+	Headers    []HeaderRule
+	Rewrites   []string
+	Skipped    []string
+}
+
+// skip records a rule that can't be imported
+func (s *hostedSite) skip(format string, a ...any) {
+	s.Skipped = append(s.Skipped, fmt.Sprintf(format, a...))
+}
+
+// addHeader adds a header rule, joining repeated headers for the same path
+// the way the hosts do
+func (s *hostedSite) addHeader(rulePath, name, value string) {
+	for i, rule := range s.Headers {
+		if rule.Path == rulePath && rule.Name == name {
+			s.Headers[i].Value += ", " + value
+			return
+		}
+	}
+	s.Headers = append(s.Headers, HeaderRule{Path: rulePath, Name: name, Value: value})
+}
+
+// addRedirect adds a redirect if efmrl can express it
+func (s *hostedSite) addRedirect(source string, redirect Redirect) {
+	if err := redirect.validate(); err != nil {
+		s.skip("%s: %v", source, err)
+		return
+	}
+	s.Redirects = append(s.Redirects, redirect)
+}
+
+// addRewrite maps a rewrite onto efmrl's, which serve one file for every
+// missing path. Only catch-all rewrites to a local file fit.
+func (s *hostedSite) addRewrite(source, from, to string) {
+	if from != "/*" || !strings.HasPrefix(to, "/") || strings.ContainsAny(to, "*:$") {
+		s.skip("%s: only catch-all rewrites to a file (/* → /index.html) are supported", source)
+		return
+	}
+	s.Rewrites = append(s.Rewrites, strings.TrimPrefix(to, "/"))
+}
+
+// detectHostProvider guesses which host exported dir
+func detectHostProvider(dir string) string {
+	switch {
+	case fileExists(filepath.Join(dir, ".vercel", "output", "config.json")),
+		fileExists(filepath.Join(dir, "config.json")) && dirExists(filepath.Join(dir, "static")),
+		fileExists(filepath.Join(dir, "vercel.json")):
+		return "vercel"
+	case fileExists(filepath.Join(dir, "_redirects")),
+		fileExists(filepath.Join(dir, "_headers")),
+		fileExists(filepath.Join(dir, "netlify.toml")):
+		return "netlify"
+	}
+	return ""
+}
+
+func fileExists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
+func dirExists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
+
+// loadNetlifySite reads a Netlify project or publish directory: the files
+// in the publish directory, its _redirects and _headers files, and the
+// [[redirects]] and [[headers]] of netlify.toml
+func loadNetlifySite(dir string) (*hostedSite, error) {
+	site := &hostedSite{Provider: "netlify", ContentDir: dir}
+
+	var netlifyToml struct {
+		Build struct {
+			Publish string `toml:"publish"`
+		} `toml:"build"`
+		Redirects []struct {
+			From       string            `toml:"from"`
+			To         string            `toml:"to"`
+			Status     int               `toml:"status"`
+			Force      bool              `toml:"force"`
+			Query      map[string]string `toml:"query"`
+			Conditions map[string]any    `toml:"conditions"`
+		} `toml:"redirects"`
+		Headers []struct {
+			For    string            `toml:"for"`
+			Values map[string]string `toml:"values"`
+		} `toml:"headers"`
+	}
+	tomlPath := filepath.Join(dir, "netlify.toml")
+	hasToml := fileExists(tomlPath)
+	if hasToml {
+		if _, err := toml.DecodeFile(tomlPath, &netlifyToml); err != nil {
+			return nil, fmt.Errorf("failed to parse netlify.toml: %w", err)
+		}
+		if publish := netlifyToml.Build.Publish; publish != "" && dirExists(filepath.Join(dir, publish)) {
+			site.ContentDir = filepath.Join(dir, publish)
+		} else {
+			site.Exclude = append(site.Exclude, "/netlify.toml")
+		}
+	}
+
+	// Netlify reads _redirects and _headers from the publish directory
+	// and doesn't publish them
+	site.Exclude = append(site.Exclude, "/_redirects", "/_headers")
+	if data, err := os.ReadFile(filepath.Join(site.ContentDir, "_redirects")); err == nil {
+		parseNetlifyRedirects(site, string(data))
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, r := range netlifyToml.Redirects {
+		source := fmt.Sprintf("netlify.toml redirect %s", r.From)
+		if len(r.Query) > 0 || len(r.Conditions) > 0 {
+			site.skip("%s: query and condition matches aren't supported", source)
+			continue
+		}
+		addNetlifyRule(site, source, r.From, r.To, r.Status)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(site.ContentDir, "_headers")); err == nil {
+		parseNetlifyHeaders(site, string(data))
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, h := range netlifyToml.Headers {
+		source := fmt.Sprintf("netlify.toml headers for %s", h.For)
+		for _, name := range slices.Sorted(maps.Keys(h.Values)) {
+			addNetlifyHeader(site, source, h.For, name+": "+h.Values[name])
+		}
+	}
+
+	return site, nil
+}
+
+// parseNetlifyRedirects parses a _redirects file. Each line is
+// "from to [status][!] [conditions]".
+func parseNetlifyRedirects(site *hostedSite, data string) {
+	for n, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		source := fmt.Sprintf("_redirects line %d (%s)", n+1, line)
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			site.skip("%s: expected a source and a destination", source)
+			continue
+		}
+		from, to, rest := fields[0], fields[1], fields[2:]
+		if strings.Contains(to, "=") {
+			site.skip("%s: query parameter matches aren't supported", source)
+			continue
+		}
+
+		status := 301
+		if len(rest) > 0 {
+			code, err := strconv.Atoi(strings.TrimSuffix(rest[0], "!"))
+			if err != nil {
+				site.skip("%s: invalid status %q", source, rest[0])
+				continue
+			}
+			status, rest = code, rest[1:]
+		}
+		if len(rest) > 0 {
+			site.skip("%s: conditions aren't supported", source)
+			continue
+		}
+
+		addNetlifyRule(site, source, from, to, status)
+	}
+}
+
+// addNetlifyRule maps one Netlify redirect rule, which is a redirect, a
+// rewrite (200), or a custom 404 page, onto efmrl
+func addNetlifyRule(site *hostedSite, source, from, to string, status int) {
+	if status == 0 {
+		status = 301
+	}
+	if strings.Contains(from, ":") {
+		site.skip("%s: placeholders aren't supported, only a trailing *", source)
+		return
+	}
+	to = strings.Replace(to, ":splat", "*", 1)
+
+	switch status {
+	case 200:
+		site.addRewrite(source, from, to)
+	case 404:
+		// efmrl already serves /404.html for missing paths
+		if from != "/*" || to != notFoundPage {
+			site.skip("%s: only the site-wide 404 page (/404.html) is supported", source)
+		}
+	default:
+		site.addRedirect(source, Redirect{From: from, To: to, Status: status})
+	}
+}
+
+// parseNetlifyHeaders parses a _headers file: unindented path lines, each
+// followed by indented "Name: value" lines
+func parseNetlifyHeaders(site *hostedSite, data string) {
+	currentPath := ""
+	for n, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			currentPath = trimmed
+			continue
+		}
+		source := fmt.Sprintf("_headers line %d (%s)", n+1, trimmed)
+		if currentPath == "" {
+			site.skip("%s: header without a path", source)
+			continue
+		}
+		addNetlifyHeader(site, source, currentPath, trimmed)
+	}
+}
+
+// addNetlifyHeader adds a "Name: value" header for a Netlify path pattern
+func addNetlifyHeader(site *hostedSite, source, rulePath, header string) {
+	if !strings.HasPrefix(rulePath, "/") || strings.Contains(rulePath, ":") {
+		site.skip("%s: path %q isn't supported, only paths with a trailing *", source, rulePath)
+		return
+	}
+	if i := strings.Index(rulePath, "*"); i >= 0 && i != len(rulePath)-1 {
+		site.skip("%s: path %q may only contain * at the end", source, rulePath)
+		return
+	}
+	name, value, err := parseHeaderAssignment(header)
+	if err != nil {
+		site.skip("%s: %v", source, err)
+		return
+	}
+	site.addHeader(rulePath, name, value)
+}
+
+// vercelRoute is one entry of the routes in a Build Output API config.json
+type vercelRoute struct {
+	Src      string            `json:"src"`
+	Dest     string            `json:"dest"`
+	Headers  map[string]string `json:"headers"`
+	Status   int               `json:"status"`
+	Continue bool              `json:"continue"`
+	Handle   string            `json:"handle"`
+}
+
+// vercelConfig is the subset of vercel.json that maps onto efmrl
+type vercelConfig struct {
+	OutputDirectory string `json:"outputDirectory"`
+	Redirects       []struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+		Permanent   *bool  `json:"permanent"`
+		StatusCode  int    `json:"statusCode"`
+	} `json:"redirects"`
+	Rewrites []struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+	} `json:"rewrites"`
+	Headers []struct {
+		Source  string `json:"source"`
+		Headers []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"headers"`
+	} `json:"headers"`
+}
+
+// loadVercelSite reads a Vercel Build Output API directory (.vercel/output,
+// with static/ and a config.json of routes), or a directory with vercel.json
+func loadVercelSite(dir string) (*hostedSite, error) {
+	output := dir
+	if dirExists(filepath.Join(dir, ".vercel", "output")) {
+		output = filepath.Join(dir, ".vercel", "output")
+	}
+
+	if fileExists(filepath.Join(output, "config.json")) && dirExists(filepath.Join(output, "static")) {
+		site := &hostedSite{Provider: "vercel", ContentDir: filepath.Join(output, "static")}
+
+		var config struct {
+			Routes []vercelRoute `json:"routes"`
+		}
+		data, err := os.ReadFile(filepath.Join(output, "config.json"))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config.json: %w", err)
+		}
+		parseVercelRoutes(site, config.Routes)
+		return site, nil
+	}
+
+	var config vercelConfig
+	data, err := os.ReadFile(filepath.Join(dir, "vercel.json"))
+	if err != nil {
+		return nil, fmt.Errorf("no .vercel/output/config.json or vercel.json found in %s", dir)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse vercel.json: %w", err)
+	}
+
+	site := &hostedSite{Provider: "vercel", ContentDir: dir, Exclude: []string{"/vercel.json"}}
+	if config.OutputDirectory != "" && dirExists(filepath.Join(dir, config.OutputDirectory)) {
+		site.ContentDir = filepath.Join(dir, config.OutputDirectory)
+	}
+	parseVercelConfig(site, config)
+	return site, nil
+}
+
+// parseVercelRoutes maps Build Output API routes. Routes are regular
+// expressions; only literal paths and a trailing (.*) can be mapped.
+func parseVercelRoutes(site *hostedSite, routes []vercelRoute) {
+	for _, route := range routes {
+		if route.Handle != "" {
+			continue
+		}
+		source := fmt.Sprintf("route %s", route.Src)
+
+		from, ok := vercelRoutePattern(route.Src)
+		if !ok {
+			site.skip("%s: only literal paths and a trailing (.*) are supported", source)
+			continue
+		}
+
+		location := route.Headers["Location"]
+		switch {
+		case route.Status >= 300 && route.Status < 400 && location != "":
+			site.addRedirect(source, Redirect{From: from, To: strings.Replace(location, "$1", "*", 1), Status: route.Status})
+		case route.Dest != "" && route.Status == 0:
+			site.addRewrite(source, from, route.Dest)
+		case len(route.Headers) > 0 && route.Status == 0:
+			for _, name := range slices.Sorted(maps.Keys(route.Headers)) {
+				addNetlifyHeader(site, source, from, name+": "+route.Headers[name])
+			}
+		default:
+			site.skip("%s: not a redirect, rewrite, or header rule efmrl supports", source)
+		}
+	}
+}
+
+// vercelRoutePattern turns a route regular expression into an efmrl path
+// pattern, if it's a literal path optionally ending in (.*)
+func vercelRoutePattern(src string) (string, bool) {
+	pattern := strings.TrimSuffix(strings.TrimPrefix(src, "^"), "$")
+	wildcard := false
+	if rest, ok := strings.CutSuffix(pattern, "(.*)"); ok {
+		pattern, wildcard = rest, true
+	}
+	pattern = strings.NewReplacer(`\.`, ".", `\/`, "/", `\-`, "-").Replace(pattern)
+	if pattern == "" || !strings.HasPrefix(pattern, "/") || strings.ContainsAny(pattern, `\()[]{}?+*|^$`) {
+		return "", false
+	}
+	if wildcard {
+		pattern += "*"
+	}
+	return pattern, true
+}
+
+// vercelParam matches a path-to-regexp parameter at the end of a vercel.json
+// source: ":name", ":name*", or ":name(.*)"
+var vercelParam = regexp.MustCompile(`:([A-Za-z0-9_]+)(\*|\(\.\*\))?$`)
+
+// vercelSourcePattern turns a vercel.json source into an efmrl path
+// pattern. It returns the name of the trailing parameter, if any, so the
+// destination can refer to it.
+func vercelSourcePattern(source string) (pattern, param string, ok bool) {
+	if rest, found := strings.CutSuffix(source, "/(.*)"); found {
+		source = rest + "/*"
+	} else if m := vercelParam.FindStringSubmatchIndex(source); m != nil {
+		if m[4] < 0 {
+			// A single-segment parameter can't be expressed as a prefix
+			return "", "", false
+		}
+		param = source[m[2]:m[3]]
+		source = source[:m[0]] + "*"
+	}
+	if !strings.HasPrefix(source, "/") || strings.ContainsAny(source, ":()[]{}?+|") {
+		return "", "", false
+	}
+	if i := strings.Index(source, "*"); i >= 0 && i != len(source)-1 {
+		return "", "", false
+	}
+	return source, param, true
+}
+
+// vercelDestination replaces the reference to the source's trailing
+// parameter (or first group) in a vercel.json destination with efmrl's *
+func vercelDestination(destination, param string) string {
+	refs := []string{"$1"}
+	if param != "" {
+		refs = []string{":" + param + "*", ":" + param, "$1"}
+	}
+	for _, ref := range refs {
+		if strings.Contains(destination, ref) {
+			return strings.Replace(destination, ref, "*", 1)
+		}
+	}
+	return destination
+}
+
+// parseVercelConfig maps the redirects, rewrites, and headers of vercel.json
+func parseVercelConfig(site *hostedSite, config vercelConfig) {
+	for _, r := range config.Redirects {
+		source := fmt.Sprintf("vercel.json redirect %s", r.Source)
+		from, param, ok := vercelSourcePattern(r.Source)
+		if !ok {
+			site.skip("%s: only literal paths and a trailing :param* are supported", source)
+			continue
+		}
+		// Vercel's defaults: permanent redirects unless told otherwise
+		status := r.StatusCode
+		if status == 0 {
+			status = 308
+			if r.Permanent != nil && !*r.Permanent {
+				status = 307
+			}
+		}
+		site.addRedirect(source, Redirect{From: from, To: vercelDestination(r.Destination, param), Status: status})
+	}
+
+	for _, r := range config.Rewrites {
+		source := fmt.Sprintf("vercel.json rewrite %s", r.Source)
+		from, _, ok := vercelSourcePattern(r.Source)
+		if !ok {
+			site.skip("%s: only catch-all rewrites to a file (/* → /index.html) are supported", source)
+			continue
+		}
+		site.addRewrite(source, from, r.Destination)
+	}
+
+	for _, h := range config.Headers {
+		source := fmt.Sprintf("vercel.json headers for %s", h.Source)
+		from, _, ok := vercelSourcePattern(h.Source)
+		if !ok {
+			site.skip("%s: only literal paths and a trailing :param* are supported", source)
+			continue
+		}
+		for _, header := range h.Headers {
+			addNetlifyHeader(site, source, from, header.Key+": "+header.Value)
+		}
+	}
+}
+
+// extractZip extracts a zip archive into dir and returns the directory
+// holding its contents: dir itself, or the archive's single top-level
+// directory if everything is inside one. Entries are kept inside dir, and
+// names with a backslash, which Windows would take as a separator, are
+// refused.
+func extractZip(archive, dir string) (string, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", archive, err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		name := filepath.FromSlash(strings.TrimPrefix(path.Clean("/"+f.Name), "/"))
+		if strings.Contains(f.Name, `\`) || (name != "." && !filepath.IsLocal(name)) {
+			return "", fmt.Errorf("refusing to extract %s: unsafe path in archive", f.Name)
+		}
+		target := filepath.Join(dir, name)
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return "", err
+		}
+		if err := extractZipFile(f, target); err != nil {
+			return "", fmt.Errorf("failed to extract %s: %w", f.Name, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, rc)
+	return err
+}
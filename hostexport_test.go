@@ -0,0 +1,253 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseNetlifyRedirects tests mapping _redirects rules onto redirects
+// and rewrites, and skipping what efmrl can't express
+func TestParseNetlifyRedirects(t *testing.T) {
+	site := &hostedSite{}
+	parseNetlifyRedirects(site, `# comment
+/old /new
+/blog/*  /news/:splat  302
+/docs/*  https://docs.example.com/:splat 301!
+/*    /index.html   200
+/*    /404.html     404
+/posts/:id  /p/:id
+/store id=:id  /products/:id  301
+/us/*  /us.html  302  Country=us
+/loop /loop
+`)
+
+	expectedRedirects := []Redirect{
+		{From: "/old", To: "/new", Status: 301},
+		{From: "/blog/*", To: "/news/*", Status: 302},
+		{From: "/docs/*", To: "https://docs.example.com/*", Status: 301},
+	}
+	if !reflect.DeepEqual(site.Redirects, expectedRedirects) {
+		t.Errorf("Expected redirects %v, got %v", expectedRedirects, site.Redirects)
+	}
+	if !reflect.DeepEqual(site.Rewrites, []string{"index.html"}) {
+		t.Errorf("Expected rewrite index.html, got %v", site.Rewrites)
+	}
+	// Placeholders, query matches, conditions, and the loop
+	if len(site.Skipped) != 4 {
+		t.Errorf("Expected 4 skipped rules, got %d: %v", len(site.Skipped), site.Skipped)
+	}
+}
+
+// TestParseNetlifyHeaders tests parsing _headers blocks
+func TestParseNetlifyHeaders(t *testing.T) {
+	site := &hostedSite{}
+	parseNetlifyHeaders(site, `/*
+  X-Frame-Options: DENY
+  Cache-Control: public
+  Cache-Control: max-age=60
+
+/assets/*
+  cache-control: max-age=31536000
+/*/private
+  X-Robots-Tag: noindex
+`)
+
+	expected := []HeaderRule{
+		{Path: "/*", Name: "X-Frame-Options", Value: "DENY"},
+		{Path: "/*", Name: "Cache-Control", Value: "public, max-age=60"},
+		{Path: "/assets/*", Name: "Cache-Control", Value: "max-age=31536000"},
+	}
+	if !reflect.DeepEqual(site.Headers, expected) {
+		t.Errorf("Expected %v, got %v", expected, site.Headers)
+	}
+	if len(site.Skipped) != 1 {
+		t.Errorf("Expected the mid-path wildcard to be skipped, got %v", site.Skipped)
+	}
+}
+
+// TestVercelRoutePattern tests converting route regular expressions
+func TestVercelRoutePattern(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+		ok       bool
+	}{
+		{"^/old$", "/old", true},
+		{"/about\\.html", "/about.html", true},
+		{"^/blog/(.*)$", "/blog/*", true},
+		{"/(.*)", "/*", true},
+		{"^/posts/([^/]+)$", "", false},
+		{"^/(en|fr)/(.*)$", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := vercelRoutePattern(tt.src)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("vercelRoutePattern(%q): Expected %q, %v, got %q, %v", tt.src, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
+
+// TestVercelSourcePattern tests converting vercel.json sources and their
+// destinations
+func TestVercelSourcePattern(t *testing.T) {
+	tests := []struct {
+		source      string
+		destination string
+		from        string
+		to          string
+		ok          bool
+	}{
+		{"/old", "/new", "/old", "/new", true},
+		{"/blog/:slug*", "/news/:slug*", "/blog/*", "/news/*", true},
+		{"/docs/:path(.*)", "https://docs.example.com/:path", "/docs/*", "https://docs.example.com/*", true},
+		{"/(.*)", "/index.html", "/*", "/index.html", true},
+		{"/posts/:id", "/p/:id", "", "", false},
+		{"/:lang/about", "/about", "", "", false},
+	}
+
+	for _, tt := range tests {
+		from, param, ok := vercelSourcePattern(tt.source)
+		if from != tt.from || ok != tt.ok {
+			t.Errorf("vercelSourcePattern(%q): Expected %q, %v, got %q, %v", tt.source, tt.from, tt.ok, from, ok)
+			continue
+		}
+		if ok {
+			if to := vercelDestination(tt.destination, param); to != tt.to {
+				t.Errorf("vercelDestination(%q, %q): Expected %q, got %q", tt.destination, param, tt.to, to)
+			}
+		}
+	}
+}
+
+// TestLoadVercelBuildOutput tests reading a Build Output API directory
+func TestLoadVercelBuildOutput(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, ".vercel", "output")
+	if err := os.MkdirAll(filepath.Join(output, "static"), 0755); err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	config := `{"version": 3, "routes": [
+		{"src": "^/old$", "headers": {"Location": "/new"}, "status": 308},
+		{"src": "^/assets/(.*)$", "headers": {"Cache-Control": "immutable"}, "continue": true},
+		{"handle": "filesystem"},
+		{"src": "/(.*)", "dest": "/index.html"}
+	]}`
+	if err := os.WriteFile(filepath.Join(output, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config.json: %v", err)
+	}
+
+	if provider := detectHostProvider(dir); provider != "vercel" {
+		t.Errorf("Expected vercel to be detected, got %q", provider)
+	}
+
+	site, err := loadVercelSite(dir)
+	if err != nil {
+		t.Fatalf("Failed to load site: %v", err)
+	}
+	if site.ContentDir != filepath.Join(output, "static") {
+		t.Errorf("Expected content in static/, got %s", site.ContentDir)
+	}
+	if !reflect.DeepEqual(site.Redirects, []Redirect{{From: "/old", To: "/new", Status: 308}}) {
+		t.Errorf("Unexpected redirects %v", site.Redirects)
+	}
+	if !reflect.DeepEqual(site.Headers, []HeaderRule{{Path: "/assets/*", Name: "Cache-Control", Value: "immutable"}}) {
+		t.Errorf("Unexpected headers %v", site.Headers)
+	}
+	if !reflect.DeepEqual(site.Rewrites, []string{"index.html"}) {
+		t.Errorf("Unexpected rewrites %v", site.Rewrites)
+	}
+}
+
+// TestLoadNetlifySite tests that netlify.toml's publish directory is used
+// and the host's config files aren't published
+func TestLoadNetlifySite(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"netlify.toml":    "[build]\npublish = \"dist\"\n\n[[redirects]]\nfrom = \"/a\"\nto = \"/b\"\n",
+		"dist/_headers":   "/*\n  X-Test: 1\n",
+		"dist/index.html": "<h1>hi</h1>",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	site, err := loadNetlifySite(dir)
+	if err != nil {
+		t.Fatalf("Failed to load site: %v", err)
+	}
+	if site.ContentDir != filepath.Join(dir, "dist") {
+		t.Errorf("Expected content in dist, got %s", site.ContentDir)
+	}
+	if !reflect.DeepEqual(site.Redirects, []Redirect{{From: "/a", To: "/b", Status: 301}}) {
+		t.Errorf("Unexpected redirects %v", site.Redirects)
+	}
+	if len(site.Headers) != 1 || site.Headers[0].Name != "X-Test" {
+		t.Errorf("Unexpected headers %v", site.Headers)
+	}
+	if !reflect.DeepEqual(site.Exclude, []string{"/_redirects", "/_headers"}) {
+		t.Errorf("Unexpected excludes %v", site.Exclude)
+	}
+}
+
+// writeZip creates a zip archive holding the named files
+func writeZip(t *testing.T, names ...string) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "site.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for _, name := range names {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		fw.Write([]byte("x"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	return archive
+}
+
+// TestExtractZip tests that a single top-level directory is descended into
+// and that entries can't escape the target directory
+func TestExtractZip(t *testing.T) {
+	dir := t.TempDir()
+	root, err := extractZip(writeZip(t, "site/index.html", "site/about.html"), dir)
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if root != filepath.Join(dir, "site") {
+		t.Errorf("Expected the site directory, got %s", root)
+	}
+
+	dir = t.TempDir()
+	root, err = extractZip(writeZip(t, "index.html", "../../escape.html"), dir)
+	if err != nil {
+		t.Fatalf("Failed to extract: %v", err)
+	}
+	if root != dir {
+		t.Errorf("Expected %s, got %s", dir, root)
+	}
+	if !fileExists(filepath.Join(dir, "escape.html")) || fileExists(filepath.Join(filepath.Dir(dir), "escape.html")) {
+		t.Errorf("Expected the escaping entry to be kept inside the target directory")
+	}
+
+	_, err = extractZip(writeZip(t, "index.html", `..\..\escape.html`), t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "unsafe path") {
+		t.Errorf("Expected a backslashed path to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName lists paths in the sync directory that should never be
+// uploaded. It uses a small subset of .gitignore syntax: blank lines and
+// lines starting with # are skipped, a trailing / matches only directories,
+// a leading / anchors the pattern to the sync directory, and patterns
+// without a / match the name at any depth.
+const IgnoreFileName = ".efmrlignore"
+
+// defaultIgnoreFile is the .efmrlignore written by init
+const defaultIgnoreFile = `# Paths listed here are never uploaded by 'efmrl3 sync'.
+# Files and directories starting with "." are always skipped.
+
+# Editor and OS leftovers
+*~
+*.swp
+Thumbs.db
+
+# Source files that don't belong on the live site
+*.psd
+*.sketch
+*.map
+`
+
+type ignorePattern struct {
+	pattern  string
+	dirOnly  bool
+	anchored bool // pattern contains a / and matches the full relative path
+}
+
+// ignoreRules holds the patterns from a .efmrlignore file
+type ignoreRules struct {
+	patterns []ignorePattern
+}
+
+// loadIgnoreRules reads .efmrlignore from rootDir. A missing file yields an
+// empty rule set that matches nothing.
+func loadIgnoreRules(rootDir string) (*ignoreRules, error) {
+	rules := &ignoreRules{}
+
+	file, err := os.Open(filepath.Join(rootDir, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := ignorePattern{pattern: line}
+		if strings.HasSuffix(p.pattern, "/") {
+			p.dirOnly = true
+			p.pattern = strings.TrimSuffix(p.pattern, "/")
+		}
+		if strings.Contains(p.pattern, "/") {
+			p.anchored = true
+			p.pattern = strings.TrimPrefix(p.pattern, "/")
+		}
+		if _, err := path.Match(p.pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: bad pattern %q: %w", IgnoreFileName, lineNum, line, err)
+		}
+
+		rules.patterns = append(rules.patterns, p)
+	}
+
+	return rules, scanner.Err()
+}
+
+// Match reports whether relPath (slash-separated, relative to the sync
+// directory) is ignored
+func (r *ignoreRules) Match(relPath string, isDir bool) bool {
+	for _, p := range r.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		target := path.Base(relPath)
+		if p.anchored {
+			target = relPath
+		}
+		if ok, _ := path.Match(p.pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestIgnoreRules tests .efmrlignore pattern matching
+func TestIgnoreRules(t *testing.T) {
+	tempDir := t.TempDir()
+	content := "# comment\n\n*.map\ndrafts/\n/notes.txt\nassets/*.psd\n"
+	if err := os.WriteFile(filepath.Join(tempDir, IgnoreFileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", IgnoreFileName, err)
+	}
+
+	rules, err := loadIgnoreRules(tempDir)
+	if err != nil {
+		t.Fatalf("loadIgnoreRules failed: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"app.js.map", false, true},
+		{"js/app.js.map", false, true},
+		{"app.js", false, false},
+		{"drafts", true, true},
+		{"blog/drafts", true, true},
+		{"drafts", false, false}, // dir-only pattern
+		{"notes.txt", false, true},
+		{"sub/notes.txt", false, false}, // anchored to root
+		{"assets/logo.psd", false, true},
+		{"other/logo.psd", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := rules.Match(tt.path, tt.isDir); got != tt.expected {
+			t.Errorf("Match(%s, %v) = %v, expected %v", tt.path, tt.isDir, got, tt.expected)
+		}
+	}
+
+	// Scanning honors the rules
+	os.MkdirAll(filepath.Join(tempDir, "drafts"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "drafts", "wip.html"), []byte("wip"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "index.html"), []byte("hi"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "app.js.map"), []byte("{}"), 0644)
+
+	scanned, err := scanLocalFiles(tempDir)
+	if err != nil {
+		t.Fatalf("scanLocalFiles failed: %v", err)
+	}
+	if len(scanned) != 1 || scanned[0].Path != "/index.html" {
+		t.Errorf("Expected only /index.html, got %+v", scanned)
+	}
+}
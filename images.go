@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/png"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// imageVariantFormats are the formats image_variants may ask for, and the
+// tool that creates each
+var imageVariantFormats = map[string]string{
+	"webp": "cwebp",
+	"avif": "avifenc",
+}
+
+// imageReport summarizes what optimizeImages did
+type imageReport struct {
+	Optimized   int
+	BytesBefore int64
+	BytesAfter  int64
+	Variants    int
+}
+
+// imageOptimizer recompresses images into a staging directory, leaving the
+// local tree untouched
+type imageOptimizer struct {
+	tmpDir  string
+	missing map[string]bool // tools already reported as missing
+}
+
+// optimizeImages losslessly recompresses the PNG and JPEG images among
+// files, and adds a variant in each of the given formats ("webp", "avif")
+// where that's smaller. PNGs are recompressed in-process; JPEGs and the
+// variants need jpegtran, cwebp, and avifenc, and are skipped with a
+// warning when those aren't installed. The returned function removes the
+// staged copies.
+func optimizeImages(files []LocalFile, variants []string) ([]LocalFile, imageReport, func(), error) {
+	var report imageReport
+	tmpDir, err := os.MkdirTemp("", "efmrl-images-")
+	if err != nil {
+		return nil, report, func() {}, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	o := &imageOptimizer{tmpDir: tmpDir, missing: map[string]bool{}}
+	exists := make(map[string]bool, len(files))
+	for _, f := range files {
+		exists[f.Path] = true
+	}
+
+	result := make([]LocalFile, 0, len(files))
+	for _, file := range files {
+		ext := strings.ToLower(path.Ext(file.Path))
+		if ext != ".png" && ext != ".jpg" && ext != ".jpeg" {
+			result = append(result, file)
+			continue
+		}
+
+		before := file.Size
+		var optimized string
+		if ext == ".png" {
+			optimized, err = o.optimizePNG(file.AbsPath)
+		} else {
+			optimized, err = o.optimizeJPEG(file.AbsPath)
+		}
+		if err != nil {
+			cleanup()
+			return nil, report, func() {}, fmt.Errorf("failed to optimize %s: %w", file.Path, err)
+		}
+		if optimized != "" {
+			if file, err = o.staged(file, optimized); err != nil {
+				cleanup()
+				return nil, report, func() {}, err
+			}
+			report.Optimized++
+		}
+		report.BytesBefore += before
+		report.BytesAfter += file.Size
+		result = append(result, file)
+
+		for _, format := range variants {
+			variantPath := strings.TrimSuffix(file.Path, path.Ext(file.Path)) + "." + format
+			if exists[variantPath] {
+				continue
+			}
+			variant, err := o.variant(file, format)
+			if err != nil {
+				cleanup()
+				return nil, report, func() {}, fmt.Errorf("failed to create %s: %w", variantPath, err)
+			}
+			if variant == "" {
+				continue
+			}
+			staged, err := o.staged(LocalFile{Path: variantPath, ContentType: detectContentType(variantPath)}, variant)
+			if err != nil {
+				cleanup()
+				return nil, report, func() {}, err
+			}
+			exists[variantPath] = true
+			result = append(result, staged)
+			report.Variants++
+		}
+	}
+	return result, report, cleanup, nil
+}
+
+// staged points file at a staged copy, updating its size and ETag
+func (o *imageOptimizer) staged(file LocalFile, stagedPath string) (LocalFile, error) {
+	info, err := os.Stat(stagedPath)
+	if err != nil {
+		return file, err
+	}
+	etag, err := computeFileETag(stagedPath)
+	if err != nil {
+		return file, err
+	}
+	file.AbsPath = stagedPath
+	file.Size = info.Size()
+	file.ETag = etag
+	return file, nil
+}
+
+// optimizePNG re-encodes a PNG at the best compression level. It returns
+// the staged copy, or "" if that isn't smaller or the PNG holds something
+// re-encoding would lose.
+func (o *imageOptimizer) optimizePNG(src string) (string, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+
+	// Go's encoder keeps the pixels but not animation or color management
+	// chunks, so leave those images alone
+	for _, chunk := range pngChunkTypes(data) {
+		switch chunk {
+		case "acTL", "iCCP", "gAMA", "cHRM":
+			return "", nil
+		}
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		// Not really a PNG; upload it as it is
+		return "", nil
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	if buf.Len() >= len(data) {
+		return "", nil
+	}
+	return o.write(buf.Bytes(), ".png")
+}
+
+// pngChunkTypes lists the chunk types in a PNG file, in order
+func pngChunkTypes(data []byte) []string {
+	const signatureLen = 8
+	var types []string
+	for i := signatureLen; i+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		types = append(types, string(data[i+4:i+8]))
+		i += 12 + length // length, type, data, CRC
+	}
+	return types
+}
+
+// optimizeJPEG losslessly optimizes a JPEG's encoding with jpegtran,
+// keeping its metadata. It returns the staged copy, or "" if that isn't
+// smaller or jpegtran isn't installed.
+func (o *imageOptimizer) optimizeJPEG(src string) (string, error) {
+	out := o.path(".jpg")
+	ok, err := o.run("jpegtran", "-copy", "all", "-optimize", "-progressive", "-outfile", out, src)
+	if !ok || err != nil {
+		return "", err
+	}
+	return o.keepIfSmaller(out, src)
+}
+
+// variant creates a copy of an image in format, returning "" if it isn't
+// smaller than the image or the tool for it isn't installed
+func (o *imageOptimizer) variant(file LocalFile, format string) (string, error) {
+	out := o.path("." + format)
+	var ok bool
+	var err error
+	switch format {
+	case "webp":
+		ok, err = o.run("cwebp", "-quiet", "-q", "80", file.AbsPath, "-o", out)
+	case "avif":
+		ok, err = o.run("avifenc", file.AbsPath, out)
+	default:
+		return "", fmt.Errorf("unknown image variant format %q", format)
+	}
+	if !ok || err != nil {
+		return "", err
+	}
+	return o.keepIfSmaller(out, file.AbsPath)
+}
+
+// run runs an image tool, returning false if it isn't installed. The first
+// time a tool is missing, that's reported as a warning.
+func (o *imageOptimizer) run(tool string, args ...string) (bool, error) {
+	toolPath, err := exec.LookPath(tool)
+	if err != nil {
+		if !o.missing[tool] {
+			o.missing[tool] = true
+			warnf("%s not found; install it to optimize images with it\n", tool)
+		}
+		return false, nil
+	}
+	if output, err := exec.Command(toolPath, args...).CombinedOutput(); err != nil {
+		return false, fmt.Errorf("%s failed: %w: %s", tool, err, strings.TrimSpace(string(output)))
+	}
+	return true, nil
+}
+
+// keepIfSmaller returns out if it's smaller than src, removing it otherwise
+func (o *imageOptimizer) keepIfSmaller(out, src string) (string, error) {
+	outInfo, err := os.Stat(out)
+	if err != nil {
+		return "", err
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+	if outInfo.Size() >= srcInfo.Size() {
+		os.Remove(out)
+		return "", nil
+	}
+	return out, nil
+}
+
+// path returns a new, unused file name in the staging directory
+func (o *imageOptimizer) path(ext string) string {
+	f, err := os.CreateTemp(o.tmpDir, "image-*"+ext)
+	if err != nil {
+		return path.Join(o.tmpDir, "image"+ext)
+	}
+	f.Close()
+	return f.Name()
+}
+
+// write stages data as a new file
+func (o *imageOptimizer) write(data []byte, ext string) (string, error) {
+	name := o.path(ext)
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// describe summarizes the report for the sync output
+func (r imageReport) describe() string {
+	saved := r.BytesBefore - r.BytesAfter
+	s := fmt.Sprintf("Optimized %d image(s), saving %s", r.Optimized, formatBytes(saved))
+	if r.BytesBefore > 0 {
+		s += fmt.Sprintf(" (%.0f%%)", float64(saved)*100/float64(r.BytesBefore))
+	}
+	if r.Variants > 0 {
+		s += fmt.Sprintf("; created %d variant(s)", r.Variants)
+	}
+	return s
+}
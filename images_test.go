@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeTestPNG writes a PNG with no compression, which re-encoding shrinks
+func writeTestPNG(t *testing.T, name string) image.Image {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x * 4), uint8(y * 4), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.NoCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func localFileFor(t *testing.T, sitePath, absPath string) LocalFile {
+	t.Helper()
+	info, err := os.Stat(absPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag, err := computeFileETag(absPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return LocalFile{Path: sitePath, AbsPath: absPath, Size: info.Size(), ETag: etag, ContentType: detectContentType(absPath)}
+}
+
+func TestOptimizeImagesPNG(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	dir := t.TempDir()
+	src := filepath.Join(dir, "logo.png")
+	original := writeTestPNG(t, src)
+	files := []LocalFile{localFileFor(t, "/logo.png", src)}
+
+	result, report, cleanup, err := optimizeImages(files, nil)
+	if err != nil {
+		t.Fatalf("optimizeImages failed: %v", err)
+	}
+	defer cleanup()
+
+	if report.Optimized != 1 {
+		t.Errorf("Expected 1 optimized image, got %d", report.Optimized)
+	}
+	if result[0].Size >= files[0].Size || report.BytesAfter >= report.BytesBefore {
+		t.Errorf("Expected a smaller image, got %d bytes from %d", result[0].Size, files[0].Size)
+	}
+	if result[0].AbsPath == src {
+		t.Error("Expected the optimized image to be staged, not written over the original")
+	}
+	if result[0].ETag == files[0].ETag {
+		t.Error("Expected the ETag to follow the new content")
+	}
+
+	f, err := os.Open(result[0].AbsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	optimized, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("Expected a valid PNG, got %v", err)
+	}
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			r1, g1, b1, a1 := optimized.At(x, y).RGBA()
+			r2, g2, b2, a2 := original.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				t.Fatalf("Expected identical pixels, got a difference at %d,%d", x, y)
+			}
+		}
+	}
+}
+
+func TestOptimizeImagesSkipsColorManagedPNG(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "photo.png")
+	writeTestPNG(t, src)
+
+	// Insert a gAMA chunk after IHDR (8 byte signature, 25 byte IHDR)
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gama := []byte{0, 0, 0, 4, 'g', 'A', 'M', 'A', 0, 0, 0xb1, 0x8f, 0, 0, 0, 0}
+	data = append(data[:33:33], append(gama, data[33:]...)...)
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []LocalFile{localFileFor(t, "/photo.png", src)}
+	result, report, cleanup, err := optimizeImages(files, nil)
+	if err != nil {
+		t.Fatalf("optimizeImages failed: %v", err)
+	}
+	defer cleanup()
+
+	if report.Optimized != 0 || result[0].AbsPath != src {
+		t.Errorf("Expected a PNG with gAMA to be left alone, got %+v", result[0])
+	}
+}
+
+func TestOptimizeImagesVariants(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake cwebp")
+	}
+	bin := t.TempDir()
+	// The fake cwebp writes a one-byte image to the path after -o
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do if [ \"$1\" = -o ]; then printf x > \"$2\"; fi; shift; done\n"
+	if err := os.WriteFile(filepath.Join(bin, "cwebp"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	dir := t.TempDir()
+	writeTestPNG(t, filepath.Join(dir, "a.png"))
+	writeTestPNG(t, filepath.Join(dir, "b.png"))
+	if err := os.WriteFile(filepath.Join(dir, "b.webp"), []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []LocalFile{
+		localFileFor(t, "/a.png", filepath.Join(dir, "a.png")),
+		localFileFor(t, "/b.png", filepath.Join(dir, "b.png")),
+		localFileFor(t, "/b.webp", filepath.Join(dir, "b.webp")),
+	}
+
+	result, report, cleanup, err := optimizeImages(files, []string{"webp"})
+	if err != nil {
+		t.Fatalf("optimizeImages failed: %v", err)
+	}
+	defer cleanup()
+
+	if report.Variants != 1 {
+		t.Errorf("Expected 1 variant, got %d", report.Variants)
+	}
+	var variant *LocalFile
+	for i := range result {
+		if result[i].Path == "/a.webp" {
+			variant = &result[i]
+		}
+		if result[i].Path == "/b.webp" && result[i].AbsPath != filepath.Join(dir, "b.webp") {
+			t.Error("Expected the existing b.webp to be kept")
+		}
+	}
+	if variant == nil {
+		t.Fatal("Expected /a.webp to be added")
+	}
+	if variant.Size != 1 || variant.ContentType != "image/webp" {
+		t.Errorf("Expected a 1 byte image/webp variant, got %d bytes of %s", variant.Size, variant.ContentType)
+	}
+}
+
+func TestPNGChunkTypes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	got := pngChunkTypes(buf.Bytes())
+	want := []string{"IHDR", "IDAT", "IEND"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ImportCmd copies an existing static site into this efmrl
+type ImportCmd struct {
+	Source   string `arg:"" help:"Where to import from: s3://bucket/prefix, or a Netlify or Vercel export directory or .zip"`
+	From     string `help:"Kind of source (auto guesses from the source)" enum:"auto,s3,netlify,vercel" default:"auto"`
+	Endpoint string `help:"S3-compatible endpoint, e.g. https://<account>.r2.cloudflarestorage.com for R2 (defaults to AWS_ENDPOINT_URL, then AWS)"`
+	Region   string `help:"S3 region (defaults to AWS_REGION, then the AWS config, then us-east-1)"`
+	DryRun   bool   `help:"List what would be imported without copying anything"`
+}
+
+// Examples are shown in 'efmrl3 import --help'
+func (i *ImportCmd) Examples() []Example {
+	return []Example{
+		{"Import everything under a prefix of an S3 bucket", "efmrl3 import s3://my-bucket/site"},
+		{"Import from a Cloudflare R2 bucket", "efmrl3 import s3://my-bucket --endpoint https://<account>.r2.cloudflarestorage.com --region auto"},
+		{"Import a Netlify site with its _redirects and _headers", "efmrl3 import --from netlify ./netlify-site"},
+		{"Import a Vercel build output archive", "efmrl3 import --from vercel output.zip"},
+	}
+}
+
+// ImportedFile is one file copied by import
+type ImportedFile struct {
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+}
+
+// ImportResult is the JSON form of an import
+type ImportResult struct {
+	Source    string         `json:"source"`
+	Provider  string         `json:"provider"`
+	SiteID    string         `json:"siteId"`
+	DryRun    bool           `json:"dryRun"`
+	Files     []ImportedFile `json:"files"`
+	Bytes     int64          `json:"bytes"`
+	Redirects []Redirect     `json:"redirects,omitempty"`
+	Headers   []HeaderRule   `json:"headers,omitempty"`
+	Rewrites  []string       `json:"rewrites,omitempty"`
+	Skipped   []string       `json:"skipped,omitempty"`
+}
+
+func (i *ImportCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	unlock, err := acquireSyncLock(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer watchInterrupts()()
+	setUploadTimeouts(config)
+	setPerformance(config)
+
+	var result *ImportResult
+	if i.From == "s3" || i.From == "auto" && strings.HasPrefix(i.Source, "s3://") {
+		source, err := url.Parse(i.Source)
+		if err != nil || source.Scheme != "s3" || source.Host == "" {
+			return fmt.Errorf("unsupported import source %q (expected s3://bucket/prefix)", i.Source)
+		}
+		s3, err := newS3Client(i.Endpoint, i.Region)
+		if err != nil {
+			return err
+		}
+		result, err = i.importS3(config, s3, source.Host, strings.TrimPrefix(source.Path, "/"))
+		if err != nil {
+			return err
+		}
+	} else {
+		site, cleanup, err := i.loadHostedSite()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		if result, err = i.importHosted(config, site); err != nil {
+			return err
+		}
+	}
+
+	if jsonOutput {
+		return printJSON(result)
+	}
+	if quietOutput {
+		fmt.Printf("Imported %d file(s) (%s) from %s into %s\n",
+			len(result.Files), formatBytes(result.Bytes), result.Source, result.SiteID)
+	}
+	return nil
+}
+
+// importS3 copies every object under prefix into the site, keeping the
+// paths below prefix and the objects' content types
+func (i *ImportCmd) importS3(config *Config, s3 *s3Client, bucket, prefix string) (*ImportResult, error) {
+	// A prefix names a directory, not the start of a key
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	result := &ImportResult{
+		Source:   "s3://" + bucket + "/" + prefix,
+		Provider: "s3",
+		SiteID:   config.Site.SiteID,
+		DryRun:   i.DryRun,
+		Files:    []ImportedFile{},
+	}
+
+	spin := startSpinner("Listing " + result.Source + "...")
+	objects, err := s3.listObjects(bucket, prefix)
+	spin.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", result.Source, err)
+	}
+
+	// Folder placeholder objects have nothing to import
+	var files []LocalFile
+	keys := map[string]string{}
+	for _, object := range objects {
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+		sitePath := path.Clean("/" + strings.TrimPrefix(object.Key, prefix))
+		files = append(files, LocalFile{Path: sitePath, Size: object.Size})
+		keys[sitePath] = object.Key
+	}
+	outf("Found %d object(s) (%s) in %s\n\n", len(files), formatBytes(calculateTotalSize(files)), result.Source)
+
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API client: %w", err)
+	}
+	quota, err := fetchQuota(apiClient, config.Site.SiteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quota: %w", err)
+	}
+	if err := validateQuota(files, quota); err != nil {
+		return nil, err
+	}
+
+	if i.DryRun {
+		for _, file := range files {
+			outf("  + %s\n", file.Path)
+			result.Files = append(result.Files, ImportedFile{Path: file.Path, Size: file.Size})
+			result.Bytes += file.Size
+		}
+		outln("\n--dry-run mode: no changes made")
+		return result, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "efmrl-import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for n, file := range files {
+		outf("[%d/%d] Importing %s... ", n+1, len(files), file.Path)
+
+		imported, err := downloadS3Object(s3, bucket, keys[file.Path], file.Path, tmpDir)
+		if err == nil {
+			err = uploadFile(apiClient, config.Site.SiteID, *imported)
+			os.Remove(imported.AbsPath)
+		}
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return nil, partialSyncError(n, len(files), fmt.Errorf("failed to import %s: %w", file.Path, err))
+		}
+		outf("%s\n", green("OK"))
+
+		result.Files = append(result.Files, ImportedFile{Path: imported.Path, Size: imported.Size, ContentType: imported.ContentType})
+		result.Bytes += imported.Size
+	}
+
+	outf("\n%s Imported %d file(s) (%s) from %s\n", green("✓"), len(result.Files), formatBytes(result.Bytes), result.Source)
+	return result, nil
+}
+
+// downloadS3Object saves an object into dir and describes it as a LocalFile
+// ready to be uploaded to sitePath
+func downloadS3Object(s3 *s3Client, bucket, key, sitePath, dir string) (*LocalFile, error) {
+	resp, err := s3.getObject(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	f, err := os.CreateTemp(dir, "object-")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	size, err := io.Copy(f, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Buckets often hold objects with S3's generic default type; the
+	// extension says more than that does
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" || contentType == "binary/octet-stream" || contentType == "application/octet-stream" {
+		contentType = detectContentType(path.Base(sitePath))
+	}
+
+	return &LocalFile{
+		Path:        sitePath,
+		AbsPath:     f.Name(),
+		Size:        size,
+		ContentType: contentType,
+	}, nil
+}
+
+// loadHostedSite reads a Netlify or Vercel export from a directory or .zip.
+// The returned function removes anything extracted for it.
+func (i *ImportCmd) loadHostedSite() (*hostedSite, func(), error) {
+	cleanup := func() {}
+	info, err := os.Stat(i.Source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("import source %s does not exist", i.Source)
+	}
+
+	dir := i.Source
+	if !info.IsDir() {
+		if !strings.EqualFold(filepath.Ext(i.Source), ".zip") {
+			return nil, nil, fmt.Errorf("import source %s must be a directory, a .zip archive, or s3://bucket/prefix", i.Source)
+		}
+		tmpDir, err := os.MkdirTemp("", "efmrl-import-")
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { os.RemoveAll(tmpDir) }
+		if dir, err = extractZip(i.Source, tmpDir); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	provider := i.From
+	if provider == "auto" {
+		if provider = detectHostProvider(dir); provider == "" {
+			cleanup()
+			return nil, nil, fmt.Errorf("can't tell which host exported %s; use --from netlify or --from vercel", i.Source)
+		}
+	}
+
+	var site *hostedSite
+	switch provider {
+	case "netlify":
+		site, err = loadNetlifySite(dir)
+	case "vercel":
+		site, err = loadVercelSite(dir)
+	default:
+		err = fmt.Errorf("--from %s needs an s3://bucket/prefix source", provider)
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return site, cleanup, nil
+}
+
+// importHosted uploads a hosted site's files, skipping any the efmrl
+// already has, and then adds its redirects, header rules, and rewrites
+func (i *ImportCmd) importHosted(config *Config, site *hostedSite) (*ImportResult, error) {
+	result := &ImportResult{
+		Source:    i.Source,
+		Provider:  site.Provider,
+		SiteID:    config.Site.SiteID,
+		DryRun:    i.DryRun,
+		Files:     []ImportedFile{},
+		Redirects: site.Redirects,
+		Headers:   site.Headers,
+		Rewrites:  site.Rewrites,
+		Skipped:   site.Skipped,
+	}
+
+	scanned, err := scanLocalFiles(site.ContentDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", site.ContentDir, err)
+	}
+	var files []LocalFile
+	for _, file := range scanned {
+		if !slices.Contains(site.Exclude, file.Path) {
+			files = append(files, file)
+		}
+	}
+	outf("Found %d file(s), %d redirect(s), %d header rule(s), and %d rewrite(s) in the %s export\n",
+		len(files), len(site.Redirects), len(site.Headers), len(site.Rewrites), site.Provider)
+	for _, skipped := range site.Skipped {
+		warnf("skipping %s\n", skipped)
+	}
+	outln()
+
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API client: %w", err)
+	}
+	quota, err := fetchQuota(apiClient, config.Site.SiteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quota: %w", err)
+	}
+	if err := validateQuota(files, quota); err != nil {
+		return nil, err
+	}
+	remoteFiles, err := fetchRemoteFiles(apiClient, config.Site.SiteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+
+	// Import adds to the site; it never deletes what's already there
+	plan := computeSyncPlan(files, remoteFiles, false, false)
+	for _, file := range plan.ToUpload {
+		result.Files = append(result.Files, ImportedFile{Path: file.Path, Size: file.Size, ContentType: file.ContentType})
+		result.Bytes += file.Size
+	}
+
+	if i.DryRun {
+		for _, file := range plan.ToUpload {
+			outf("  + %s\n", file.Path)
+		}
+		for _, redirect := range site.Redirects {
+			outf("  redirect %s → %s (%d)\n", redirect.From, redirect.To, redirect.Status)
+		}
+		for _, rule := range site.Headers {
+			outf("  header %s on %s\n", rule.Name, rule.Path)
+		}
+		for _, rewrite := range site.Rewrites {
+			outf("  rewrite %s\n", rewrite)
+		}
+		outln("\n--dry-run mode: no changes made")
+		return result, nil
+	}
+
+	if len(plan.ToUpload) > 0 {
+		if err := executeSyncPlan(apiClient, config.Site.SiteID, plan, false, nil); err != nil {
+			return nil, err
+		}
+		outln()
+	}
+	if err := applyHostedRules(apiClient, config.Site.SiteID, site); err != nil {
+		return nil, err
+	}
+
+	outf("%s Imported %d file(s) (%s, %d unchanged) and %d rule(s) from %s\n", green("✓"),
+		len(result.Files), formatBytes(result.Bytes), len(plan.Unchanged),
+		len(site.Redirects)+len(site.Headers)+len(site.Rewrites), i.Source)
+	return result, nil
+}
+
+// applyHostedRules adds a hosted site's rules to the efmrl, leaving out
+// redirects and rewrites it already has
+func applyHostedRules(client *APIClient, siteID string, site *hostedSite) error {
+	existingRedirects, err := fetchRedirects(client, siteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch redirects: %w", err)
+	}
+	existingRewrites, err := fetchRewrites(client, siteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch rewrites: %w", err)
+	}
+
+	for _, redirect := range site.Redirects {
+		if slices.ContainsFunc(existingRedirects, func(r Redirect) bool { return r.From == redirect.From }) {
+			outf("Redirect %s already exists, %s\n", redirect.From, dim("skipped"))
+			continue
+		}
+		outf("Adding redirect %s → %s (%d)... ", redirect.From, redirect.To, redirect.Status)
+		if err := postJSON(client, fmt.Sprintf("/admin/efmrls/%s/redirects", siteID), redirect); err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to add redirect %s: %w", redirect.From, err)
+		}
+		outf("%s\n", green("OK"))
+	}
+
+	// PUT replaces any existing rule with the same path and name
+	for _, rule := range site.Headers {
+		outf("Setting %s on %s... ", rule.Name, rule.Path)
+		if err := putJSON(client, fmt.Sprintf("/admin/efmrls/%s/headers", siteID), rule); err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to set header %s on %s: %w", rule.Name, rule.Path, err)
+		}
+		outf("%s\n", green("OK"))
+	}
+
+	for _, filename := range site.Rewrites {
+		if slices.ContainsFunc(existingRewrites, func(r Rewrite) bool { return r.Filename == filename }) {
+			outf("Rewrite %s already exists, %s\n", filename, dim("skipped"))
+			continue
+		}
+		outf("Adding rewrite %s... ", filename)
+		if err := postJSON(client, fmt.Sprintf("/admin/efmrls/%s/rewrites", siteID), map[string]string{"filename": filename}); err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to add rewrite %s: %w", filename, err)
+		}
+		outf("%s\n", green("OK"))
+	}
+	return nil
+}
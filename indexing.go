@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+const (
+	// robotsHeader tells search engines whether to index a response
+	robotsHeader = "X-Robots-Tag"
+
+	// noindexRulePath is the header rule path indexing off covers: every
+	// path on the site
+	noindexRulePath = "/*"
+)
+
+// IndexingCmd turns search engine indexing of the site on or off, with an
+// X-Robots-Tag: noindex header on every path
+type IndexingCmd struct {
+	State string `arg:"" optional:"" help:"on to let search engines index the site, off to ask them not to (omit to show the current state)" placeholder:"on|off"`
+}
+
+// Examples are shown in 'efmrl3 indexing --help'
+func (i *IndexingCmd) Examples() []Example {
+	return []Example{
+		{"Keep a preview site out of search results", "efmrl3 indexing off"},
+		{"Let search engines index the site again", "efmrl3 indexing on"},
+		{"Show whether the site can be indexed", "efmrl3 indexing"},
+	}
+}
+
+func (i *IndexingCmd) Run() error {
+	if i.State != "" && i.State != "on" && i.State != "off" {
+		return fmt.Errorf("invalid state %q (expected on or off)", i.State)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	rules, err := fetchHeaderRules(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch header rules: %w", err)
+	}
+
+	if i.State != "" {
+		if rules, err = setIndexing(apiClient, config.Site.SiteID, rules, i.State == "on"); err != nil {
+			return err
+		}
+	}
+
+	state := indexingState(rules)
+	if jsonOutput {
+		return printJSON(map[string]string{"indexing": state})
+	}
+	if i.State != "" {
+		outln()
+	}
+	outf("Indexing: %s\n", describeIndexing(state))
+	for _, rule := range rules {
+		if rule.Path != noindexRulePath && textproto.CanonicalMIMEHeaderKey(rule.Name) == robotsHeader {
+			outf("  %s has its own rule: %s: %s\n", rule.Path, rule.Name, rule.Value)
+		}
+	}
+	return nil
+}
+
+// setIndexing adds or removes the noindex rule among a site's header rules,
+// returning the rules the site has afterwards
+func setIndexing(client *APIClient, siteID string, rules []HeaderRule, on bool) ([]HeaderRule, error) {
+	if !on {
+		if indexingState(rules) == "off" {
+			return rules, nil
+		}
+		outf("Setting %s: noindex on %s... ", robotsHeader, noindexRulePath)
+		rule := HeaderRule{Path: noindexRulePath, Name: robotsHeader, Value: "noindex"}
+		if err := putJSON(client, fmt.Sprintf("/admin/efmrls/%s/headers", siteID), rule); err != nil {
+			outf("%s\n", red("FAILED"))
+			return nil, fmt.Errorf("failed to set %s: %w", robotsHeader, err)
+		}
+		outf("%s\n", green("OK"))
+		return append(rules, rule), nil
+	}
+
+	var kept []HeaderRule
+	for _, rule := range rules {
+		if !isNoindexRule(rule) {
+			kept = append(kept, rule)
+			continue
+		}
+		outf("Removing %s from %s... ", rule.Name, rule.Path)
+		if err := deleteHeaderRule(client, siteID, rule.ID); err != nil {
+			outf("%s\n", red("FAILED"))
+			return nil, fmt.Errorf("failed to remove %s: %w", robotsHeader, err)
+		}
+		outf("%s\n", green("OK"))
+	}
+	return kept, nil
+}
+
+// isNoindexRule reports whether a header rule is the one indexing off sets
+func isNoindexRule(rule HeaderRule) bool {
+	return rule.Path == noindexRulePath &&
+		textproto.CanonicalMIMEHeaderKey(rule.Name) == robotsHeader &&
+		strings.Contains(strings.ToLower(rule.Value), "noindex")
+}
+
+// indexingState returns "off" if the site's header rules ask search engines
+// not to index any of it, and "on" otherwise
+func indexingState(rules []HeaderRule) string {
+	for _, rule := range rules {
+		if isNoindexRule(rule) {
+			return "off"
+		}
+	}
+	return "on"
+}
+
+// describeIndexing explains an indexing state for status and indexing
+func describeIndexing(state string) string {
+	if state == "off" {
+		return fmt.Sprintf("off (%s: noindex on every path)", robotsHeader)
+	}
+	return "on (search engines may list the site; 'efmrl3 indexing off' asks them not to)"
+}
+
+// deleteHeaderRule removes a header rule by ID
+func deleteHeaderRule(client *APIClient, siteID string, id int) error {
+	resp, err := client.Delete(fmt.Sprintf("/admin/efmrls/%s/headers/%d", siteID, id))
+	if err != nil {
+		return err
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
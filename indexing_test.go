@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIndexingState(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []HeaderRule
+		want  string
+	}{
+		{"no rules", nil, "on"},
+		{"noindex everywhere", []HeaderRule{{Path: "/*", Name: "X-Robots-Tag", Value: "noindex"}}, "off"},
+		{"lowercase name and combined value", []HeaderRule{{Path: "/*", Name: "x-robots-tag", Value: "noindex, nofollow"}}, "off"},
+		{"noindex on one section", []HeaderRule{{Path: "/drafts/*", Name: "X-Robots-Tag", Value: "noindex"}}, "on"},
+		{"other header", []HeaderRule{{Path: "/*", Name: "Cache-Control", Value: "noindex"}}, "on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := indexingState(tt.rules); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestSetIndexing tests that turning indexing off adds the rule once, and
+// turning it on removes only that rule
+func TestSetIndexing(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var writes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writes = append(writes, r.Method+" "+r.URL.Path)
+	}))
+	defer server.Close()
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	rules := []HeaderRule{
+		{ID: 1, Path: "/drafts/*", Name: "X-Robots-Tag", Value: "noindex"},
+		{ID: 2, Path: "/*", Name: "X-Robots-Tag", Value: "noindex"},
+	}
+	captureStdout(t, func() {
+		if _, err = setIndexing(client, "abc", rules, false); err != nil {
+			return
+		}
+		rules, err = setIndexing(client, "abc", rules, true)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got := strings.Join(writes, "\n"); got != "DELETE /admin/efmrls/abc/headers/2" {
+		t.Errorf("Expected only the site-wide rule to be deleted, got:\n%s", got)
+	}
+	if len(rules) != 1 || rules[0].ID != 1 {
+		t.Errorf("Expected the /drafts/* rule to remain, got %+v", rules)
+	}
+
+	writes = nil
+	captureStdout(t, func() { rules, err = setIndexing(client, "abc", rules, false) })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := strings.Join(writes, "\n"); got != "PUT /admin/efmrls/abc/headers" {
+		t.Errorf("Expected the noindex rule to be added, got:\n%s", got)
+	}
+	if indexingState(rules) != "off" {
+		t.Errorf("Expected indexing to be off, got %+v", rules)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// starterTemplates are the templates under templates/, in the order they're
+// offered
+var starterTemplates = []string{"blank", "landing", "docs"}
+
+// InitCmd scaffolds a new efmrl project in the current directory
+type InitCmd struct {
+	Template string `help:"Starter template to scaffold (blank, landing, docs)" enum:"blank,landing,docs" default:"blank"`
+	ID       string `help:"Site ID to write to efmrl.toml"`
+	Dir      string `help:"Directory for the site's files" default:"public"`
+	Title    string `help:"Title used in the scaffolded pages" default:"Hello, efmrl"`
+	Force    bool   `help:"Overwrite existing files"`
+	FromURL  string `name:"from-url" help:"Seed the site by downloading an existing public website instead of a template"`
+	Depth    int    `help:"How many links away from --from-url to follow pages" default:"3"`
+}
+
+// Examples are shown in 'efmrl3 init --help'
+func (i *InitCmd) Examples() []Example {
+	return []Example{
+		{"Scaffold a blank site into public/", "efmrl3 init"},
+		{"Start from the docs template with a site ID", "efmrl3 init --template docs --id <site-id> --title \"Project Docs\""},
+		{"Mirror an existing static site into an ephemeral copy", "efmrl3 init --from-url https://example.com"},
+	}
+}
+
+func (i *InitCmd) Run() error {
+	// Refuse to clobber an existing project unless asked to
+	fileName, err := findConfigFile()
+	if err != nil {
+		return err
+	}
+	if fileName != "" && !i.Force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", fileName)
+	}
+
+	if i.Depth < 0 {
+		return fmt.Errorf("--depth can't be negative")
+	}
+	var written []string
+	if i.FromURL != "" {
+		written, err = seedFromURL(i.FromURL, i.Dir, i.Depth, i.Force)
+	} else {
+		written, err = scaffoldSite(i.Template, i.Dir, i.Title, i.Force)
+	}
+	if err != nil {
+		return err
+	}
+
+	config := &Config{fileName: fileName}
+	if fileName != "" {
+		if config, err = loadConfigFile(fileName); err != nil {
+			return err
+		}
+	}
+	config.Site.Dir = i.Dir
+	if i.ID != "" {
+		config.Site.SiteID = i.ID
+	}
+	if err := SaveConfig(config); err != nil {
+		return err
+	}
+	outf("  + %s\n", config.FileName())
+
+	if jsonOutput {
+		written = append(written, config.FileName())
+		if i.FromURL != "" {
+			return printJSON(map[string]any{"fromUrl": i.FromURL, "dir": i.Dir, "files": written})
+		}
+		return printJSON(map[string]any{"template": i.Template, "dir": i.Dir, "files": written})
+	}
+
+	outln()
+	if config.Site.SiteID == "" {
+		outln("Next, set your site ID and publish:")
+		outln("  efmrl3 config --id <site-id>")
+	} else {
+		outln("Next, publish your site:")
+	}
+	outln("  efmrl3 sync")
+	return nil
+}
+
+// scaffoldSite writes the named template and a default ignore file into
+// dir, listing each file as it goes, and returns the paths it wrote
+func scaffoldSite(name, dir, title string, force bool) ([]string, error) {
+	outf("Scaffolding %q template into %s/\n", name, dir)
+
+	written, err := scaffoldTemplate(name, dir, title, force)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range written {
+		outf("  + %s\n", file)
+	}
+	return writeIgnoreFile(dir, written, force)
+}
+
+// seedFromURL mirrors the website at rawURL into dir, along with a default
+// ignore file, and returns the paths it wrote
+func seedFromURL(rawURL, dir string, depth int, force bool) ([]string, error) {
+	written, err := mirrorSite(rawURL, dir, depth, force)
+	if err != nil {
+		return nil, err
+	}
+	return writeIgnoreFile(dir, written, force)
+}
+
+// writeIgnoreFile writes the default ignore file into dir, adding it to
+// written if it did
+func writeIgnoreFile(dir string, written []string, force bool) ([]string, error) {
+	ignorePath := filepath.Join(dir, IgnoreFileName)
+	if wrote, err := writeScaffoldFile(ignorePath, []byte(defaultIgnoreFile), force); err != nil {
+		return nil, err
+	} else if wrote {
+		outf("  + %s\n", ignorePath)
+		written = append(written, ignorePath)
+	}
+	return written, nil
+}
+
+// scaffoldTemplate renders the named embedded template into dir and returns
+// the paths it wrote. Existing files are skipped unless force is set.
+func scaffoldTemplate(name, dir, title string, force bool) ([]string, error) {
+	root := path.Join("templates", name)
+	if _, err := fs.Stat(templatesFS, root); err != nil {
+		return nil, fmt.Errorf("unknown template %q", name)
+	}
+
+	data := struct{ Title string }{Title: title}
+	var written []string
+
+	err := fs.WalkDir(templatesFS, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		raw, err := templatesFS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(p).Parse(string(raw))
+		if err != nil {
+			return fmt.Errorf("bad template %s: %w", p, err)
+		}
+		var rendered strings.Builder
+		if err := tmpl.Execute(&rendered, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", p, err)
+		}
+
+		dest := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(p, root+"/")))
+		wrote, err := writeScaffoldFile(dest, []byte(rendered.String()), force)
+		if err != nil {
+			return err
+		}
+		if wrote {
+			written = append(written, dest)
+		}
+		return nil
+	})
+
+	return written, err
+}
+
+// writeScaffoldFile writes content to dest, creating parent directories. It
+// leaves existing files alone unless force is set, and reports whether it
+// wrote anything.
+func writeScaffoldFile(dest string, content []byte, force bool) (bool, error) {
+	if _, err := os.Stat(dest); err == nil && !force {
+		outf("  %s\n", dim("= "+dest+" (exists, skipped)"))
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", filepath.Dir(dest), err)
+	}
+	if err := os.WriteFile(dest, content, 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	return true, nil
+}
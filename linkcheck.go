@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CheckLinksCmd checks that a site's internal links resolve before it's
+// published
+type CheckLinksCmd struct {
+	Dir string `arg:"" optional:"" help:"Directory to check (defaults to the directory sync would publish)" type:"path"`
+}
+
+// Examples are shown in 'efmrl3 check-links --help'
+func (c *CheckLinksCmd) Examples() []Example {
+	return []Example{
+		{"Check the site's links before syncing it", "efmrl3 check-links"},
+		{"Check a build directory, reporting as JSON", "efmrl3 --json check-links dist"},
+	}
+}
+
+// BrokenLink is a link to a file or anchor that isn't on the site
+type BrokenLink struct {
+	Page   string `json:"page"`
+	Link   string `json:"link"`
+	Reason string `json:"reason"`
+
+	file string // the page's local path, for annotations
+}
+
+// LinkReport is the result of checking a site's links
+type LinkReport struct {
+	Pages  int          `json:"pages"`
+	Links  int          `json:"links"`
+	Broken []BrokenLink `json:"broken"`
+}
+
+var (
+	// scriptContentPattern matches script elements, whose contents aren't
+	// markup and may look like links that aren't
+	scriptContentPattern = regexp.MustCompile(`(?is)(<script\b[^>]*>).*?</script\s*>`)
+
+	// htmlCommentPattern matches HTML comments
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+	// anchorPattern finds the id and name attributes a fragment can target
+	anchorPattern = regexp.MustCompile(`(?i)\s(?:id|name)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+func (c *CheckLinksCmd) Run() error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	dir := c.Dir
+	if dir == "" {
+		dir = config.SyncDir()
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory does not exist: %s", absDir)
+	}
+
+	files, err := scanLocalFiles(absDir)
+	if err != nil {
+		return fmt.Errorf("failed to scan local files: %w", err)
+	}
+	if err := normalizeLocalPaths(files, config.UnicodeNormalization()); err != nil {
+		return err
+	}
+	report, err := checkLinks(files, config.Sync.CheckLinksIgnore)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		printLinkReport(report)
+	}
+	if len(report.Broken) > 0 {
+		return fmt.Errorf("found %d broken link(s)", len(report.Broken))
+	}
+	return nil
+}
+
+// printLinkReport lists the broken links in a report, or says there are
+// none. Under GitHub Actions each is also annotated on its page.
+func printLinkReport(report *LinkReport) {
+	if len(report.Broken) == 0 {
+		outf("%s Checked %d link(s) on %d page(s); none are broken\n", green("✓"), report.Links, report.Pages)
+		return
+	}
+
+	outf("Checked %d link(s) on %d page(s); %s:\n\n", report.Links, report.Pages,
+		red(fmt.Sprintf("%d broken", len(report.Broken))))
+	for _, link := range report.Broken {
+		outf("  %s → %s %s\n", link.Page, link.Link, dim("("+link.Reason+")"))
+		annotateError("Broken link", link.file, fmt.Errorf("%s: %s", link.Link, link.Reason))
+	}
+	outln()
+}
+
+// linkChecker resolves links against the files about to be published
+type linkChecker struct {
+	files   map[string]*LocalFile // by site path
+	ignore  []string
+	anchors map[string]map[string]bool // by site path, loaded on demand
+}
+
+// checkLinks checks the links, images, scripts, stylesheets, and anchors
+// that the HTML pages and stylesheets among files refer to, and reports
+// those that don't resolve to one of the files. Links to other sites
+// aren't checked, nor are targets matching an ignore pattern, which may
+// end in "*" (for paths the server handles with rewrites, say).
+func checkLinks(files []LocalFile, ignore []string) (*LinkReport, error) {
+	c := &linkChecker{
+		files:   make(map[string]*LocalFile, len(files)),
+		ignore:  ignore,
+		anchors: map[string]map[string]bool{},
+	}
+	for i := range files {
+		c.files[files[i].Path] = &files[i]
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	sort.Strings(paths)
+
+	report := &LinkReport{Broken: []BrokenLink{}}
+	for _, p := range paths {
+		file := c.files[p]
+		isHTML := strings.HasPrefix(file.ContentType, "text/html")
+		if !isHTML && !strings.HasPrefix(file.ContentType, "text/css") {
+			continue
+		}
+		if isHTML {
+			report.Pages++
+		}
+
+		content, err := c.read(file)
+		if err != nil {
+			return nil, err
+		}
+		replaceRefs(content, isHTML, func(ref string) string {
+			checked, reason := c.check(p, ref)
+			if checked {
+				report.Links++
+			}
+			if reason != "" {
+				report.Broken = append(report.Broken, BrokenLink{Page: p, Link: ref, Reason: reason, file: file.AbsPath})
+			}
+			return ref
+		})
+	}
+	return report, nil
+}
+
+// read returns a file's content, without the comments and scripts of an
+// HTML page
+func (c *linkChecker) read(file *LocalFile) (string, error) {
+	data, err := os.ReadFile(file.AbsPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+	content := string(data)
+	if strings.HasPrefix(file.ContentType, "text/html") {
+		content = htmlCommentPattern.ReplaceAllString(content, "")
+		content = scriptContentPattern.ReplaceAllString(content, "$1</script>")
+	}
+	return content, nil
+}
+
+// check resolves a reference on page. It reports whether the reference was
+// checked at all, and if so why it's broken, or "" if it isn't.
+func (c *linkChecker) check(page, ref string) (bool, string) {
+	ref = strings.TrimSpace(ref)
+	p, fragment, _ := strings.Cut(ref, "#")
+	p, _, _ = strings.Cut(p, "?")
+	if strings.HasPrefix(p, "//") || strings.Contains(strings.SplitN(p, "/", 2)[0], ":") {
+		return false, ""
+	}
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+
+	target := page
+	if p != "" {
+		target = p
+		if !strings.HasPrefix(p, "/") {
+			target = path.Join(path.Dir(page), p)
+		}
+		target = path.Clean(target)
+		if strings.HasSuffix(p, "/") && target != "/" {
+			target += "/"
+		}
+	}
+	for _, pattern := range c.ignore {
+		if _, ok := matchPathPattern(pattern, target); ok {
+			return false, ""
+		}
+	}
+
+	file, ok := c.resolve(target)
+	if !ok {
+		return true, "not found"
+	}
+	if fragment == "" || !strings.HasPrefix(file.ContentType, "text/html") {
+		return true, ""
+	}
+
+	// "top" always scrolls to the top, and fragments starting with / or !
+	// are routes for scripts rather than anchors
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	if fragment == "top" || strings.HasPrefix(fragment, "/") || strings.HasPrefix(fragment, "!") {
+		return true, ""
+	}
+	anchors, err := c.anchorsOf(file)
+	if err != nil {
+		return true, err.Error()
+	}
+	if !anchors[fragment] {
+		return true, fmt.Sprintf("no element with id %q", fragment)
+	}
+	return true, ""
+}
+
+// resolve returns the file a site path is served from, the same way serve
+// does: a directory path is served from its index.html
+func (c *linkChecker) resolve(target string) (*LocalFile, bool) {
+	if strings.HasSuffix(target, "/") {
+		target += "index.html"
+	}
+	if file, ok := c.files[target]; ok {
+		return file, true
+	}
+	file, ok := c.files[strings.TrimSuffix(target, "/")+"/index.html"]
+	return file, ok
+}
+
+// anchorsOf returns the ids and names in a page that fragments can target
+func (c *linkChecker) anchorsOf(file *LocalFile) (map[string]bool, error) {
+	if anchors, ok := c.anchors[file.Path]; ok {
+		return anchors, nil
+	}
+	content, err := c.read(file)
+	if err != nil {
+		return nil, err
+	}
+	anchors := map[string]bool{}
+	for _, m := range anchorPattern.FindAllStringSubmatch(content, -1) {
+		anchors[m[1]+m[2]+m[3]] = true
+	}
+	c.anchors[file.Path] = anchors
+	return anchors, nil
+}
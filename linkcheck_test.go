@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeSite writes files into a temporary directory and returns them as
+// sync would scan them
+func writeSite(t *testing.T, files map[string]string) (string, []LocalFile) {
+	t.Helper()
+	dir := t.TempDir()
+	var local []LocalFile
+	for name, content := range files {
+		abs := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		local = append(local, localFileFor(t, "/"+name, abs))
+	}
+	return dir, local
+}
+
+func TestCheckLinks(t *testing.T) {
+	_, files := writeSite(t, map[string]string{
+		"index.html": `<a href="/about/">About</a> <a href="about">About</a>
+<a href="docs/guide.html#install">Install</a> <a href="docs/guide.html#nope">Nope</a>
+<a href="#top">Top</a> <a href="#main">Main</a> <main id="main"></main>
+<a href="https://example.com/missing">External</a> <a href="mailto:a@example.com">Mail</a>
+<img src="logo.png" srcset="logo.png 1x, logo@2x.png 2x"> <a href="/api/users">API</a>
+<!-- <a href="/commented-out">old</a> -->
+<script>el.href = "/from-script";</script>
+<link rel="stylesheet" href="/style.css?v=2">`,
+		"about/index.html": `<a href="../index.html">Home</a> <a href="../missing.html">Missing</a>`,
+		"docs/guide.html":  `<h2 id="install">Install</h2> <a href="#/route">Route</a>`,
+		"logo.png":         "png",
+		"style.css":        `body { background: url(img/bg.png); } a { background: url("logo.png") }`,
+	})
+
+	report, err := checkLinks(files, []string{"/api/*"})
+	if err != nil {
+		t.Fatalf("checkLinks failed: %v", err)
+	}
+
+	if report.Pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", report.Pages)
+	}
+	var broken []string
+	for _, link := range report.Broken {
+		broken = append(broken, link.Page+" "+link.Link+" ("+link.Reason+")")
+	}
+	sort.Strings(broken)
+	want := []string{
+		`/about/index.html ../missing.html (not found)`,
+		`/index.html docs/guide.html#nope (no element with id "nope")`,
+		`/index.html logo@2x.png (not found)`,
+		`/style.css img/bg.png (not found)`,
+	}
+	if strings.Join(broken, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected broken links:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(broken, "\n"))
+	}
+}
+
+func TestCheckLinksCommand(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir, _ := writeSite(t, map[string]string{
+		"index.html": `<a href="/gone.html">Gone</a>`,
+	})
+
+	var err error
+	output := captureStdout(t, func() {
+		err = (&CheckLinksCmd{Dir: dir}).Run()
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 broken link") {
+		t.Errorf("Expected an error about 1 broken link, got %v", err)
+	}
+	if !strings.Contains(output, "/index.html → /gone.html") {
+		t.Errorf("Expected the broken link to be listed, got %q", output)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// liveReloadPath is the server-sent events endpoint pages listen on
+	liveReloadPath = "/__efmrl/livereload"
+
+	// liveReloadInterval is how often the directory is checked for changes
+	liveReloadInterval = 500 * time.Millisecond
+)
+
+// liveReloadScript is injected into HTML pages to reload them on change
+var liveReloadScript = []byte(`<script>new EventSource("` + liveReloadPath + `").onmessage = () => location.reload();</script>`)
+
+// liveReloader watches a directory and tells connected pages to reload when
+// anything in it changes. It polls rather than relying on OS file events,
+// which behave differently on every platform.
+type liveReloader struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+func newLiveReloader() *liveReloader {
+	return &liveReloader{clients: map[chan struct{}]struct{}{}}
+}
+
+// watch polls root until stop is closed, notifying clients after a change
+func (l *liveReloader) watch(root string, interval time.Duration, stop <-chan struct{}) {
+	last := snapshotDir(root)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		if current := snapshotDir(root); current != last {
+			last = current
+			outln(dim("Change detected, reloading pages"))
+			l.notify()
+		}
+	}
+}
+
+// notify tells every connected page to reload
+func (l *liveReloader) notify() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for client := range l.clients {
+		select {
+		case client <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ServeHTTP holds an event stream open until the next change
+func (l *liveReloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	client := make(chan struct{}, 1)
+	l.mu.Lock()
+	l.clients[client] = struct{}{}
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.clients, client)
+		l.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	select {
+	case <-client:
+		fmt.Fprint(w, "data: reload\n\n")
+		flusher.Flush()
+	case <-r.Context().Done():
+	}
+}
+
+// snapshotDir returns a hash of the names, sizes, and modification times of
+// everything under root, which changes whenever a file does
+func snapshotDir(root string) uint64 {
+	h := fnv.New64a()
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return h.Sum64()
+}
+
+// injectLiveReload adds the reload script to an HTML page, just before
+// </body> if there is one
+func injectLiveReload(page []byte) []byte {
+	i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if i < 0 {
+		return append(page, liveReloadScript...)
+	}
+	out := make([]byte, 0, len(page)+len(liveReloadScript))
+	out = append(out, page[:i]...)
+	out = append(out, liveReloadScript...)
+	return append(out, page[i:]...)
+}
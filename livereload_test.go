@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestInjectLiveReload tests where the reload script goes
+func TestInjectLiveReload(t *testing.T) {
+	tests := []struct {
+		page     string
+		expected string
+	}{
+		{"<html><body>hi</body></html>", "<html><body>hi" + string(liveReloadScript) + "</body></html>"},
+		{"<P>no body tag", "<P>no body tag" + string(liveReloadScript)},
+		{"<BODY>x</BODY>", "<BODY>x" + string(liveReloadScript) + "</BODY>"},
+	}
+
+	for _, tt := range tests {
+		if got := string(injectLiveReload([]byte(tt.page))); got != tt.expected {
+			t.Errorf("injectLiveReload(%q): expected %q, got %q", tt.page, tt.expected, got)
+		}
+	}
+}
+
+// TestLiveReload tests that a change in the directory reaches a connected page
+func TestLiveReload(t *testing.T) {
+	root := t.TempDir()
+	index := filepath.Join(root, "index.html")
+	if err := os.WriteFile(index, []byte("<body>v1</body>"), 0644); err != nil {
+		t.Fatalf("Failed to write index.html: %v", err)
+	}
+
+	ignore, _ := loadIgnoreRules(root)
+	handler := &previewHandler{root: root, ignore: ignore, reload: newLiveReloader()}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// The watcher prints, so it runs, and is stopped, within the capture
+	captureStdout(t, func() {
+		stop, done := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(done)
+			handler.reload.watch(root, 10*time.Millisecond, stop)
+		}()
+		defer func() {
+			close(stop)
+			<-done
+		}()
+
+		resp, err := http.Get(server.URL + "/")
+		if err != nil {
+			t.Fatalf("Failed to fetch page: %v", err)
+		}
+		body := new(strings.Builder)
+		bufio.NewReader(resp.Body).WriteTo(body)
+		resp.Body.Close()
+		if page := body.String(); !strings.Contains(page, liveReloadPath) {
+			t.Errorf("Expected the reload script in the page, got %q", page)
+		}
+
+		resp, err = http.Get(server.URL + liveReloadPath)
+		if err != nil {
+			t.Fatalf("Failed to connect to the event stream: %v", err)
+		}
+		defer resp.Body.Close()
+
+		// Wait for the stream to register before changing anything
+		time.Sleep(50 * time.Millisecond)
+		if err := os.WriteFile(index, []byte("<body>version 2</body>"), 0644); err != nil {
+			t.Fatalf("Failed to update index.html: %v", err)
+		}
+
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		if err != nil || line != "data: reload\n" {
+			t.Errorf("Expected a reload event, got %q, %v", line, err)
+		}
+	})
+}
@@ -2,65 +2,61 @@ package main
 
 import (
 	"fmt"
+	"sort"
 )
 
 // LogoutCmd handles clearing authentication credentials
 type LogoutCmd struct {
-	Host string `help:"Server host (defaults to base_host from efmrl.toml or efmrl.work)" default:""`
-	All  bool   `help:"Remove credentials for all hosts" default:"false"`
+	All bool `help:"Remove credentials for all hosts" default:"false"`
 }
 
 // Run executes the logout command
 func (l *LogoutCmd) Run() error {
-	// Determine which host to use
-	host := l.Host
-	if host == "" && !l.All {
-		// Try to load efmrl.toml from current directory
-		config, err := LoadConfig()
-		if err == nil && config.BaseHost != "" {
-			host = config.BaseHost
-		} else {
-			host = DefaultBaseHost
-		}
-	}
-
-	// Load global config
-	config, err := LoadGlobalConfig()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	// Determine which host to use (--host, then config, then the default)
+	var host string
+	if !l.All {
+		host = resolveHost()
 	}
 
-	if l.All {
-		// Remove all credentials
-		if len(config.Hosts) == 0 {
-			fmt.Println("No credentials to remove")
+	// Remove them under the global config's lock, so that credentials
+	// another command saves meanwhile aren't lost
+	var removed []string
+	err := updateGlobalConfig(func(config *GlobalConfig) error {
+		if l.All {
+			for h := range config.Hosts {
+				removed = append(removed, h)
+			}
+			config.Hosts = make(map[string]HostCredentials)
 			return nil
 		}
-
-		count := len(config.Hosts)
-		config.Hosts = make(map[string]HostCredentials)
-
-		if err := SaveGlobalConfig(config); err != nil {
-			return fmt.Errorf("failed to save config: %w", err)
+		if _, ok := config.GetHostCredentials(host); ok {
+			removed = []string{host}
+			config.DeleteHostCredentials(host)
 		}
-
-		fmt.Printf("✓ Removed credentials for %d host(s)\n", count)
 		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
 	}
 
-	// Remove credentials for specific host
-	_, ok := config.GetHostCredentials(host)
-	if !ok {
-		fmt.Printf("No credentials found for %s\n", host)
-		return nil
+	switch {
+	case len(removed) == 0 && l.All:
+		outln("No credentials to remove")
+	case len(removed) == 0:
+		outf("No credentials found for %s\n", host)
+	case l.All:
+		outf("%s Removed credentials for %d host(s)\n", green("✓"), len(removed))
+	default:
+		outf("%s Logged out from %s\n", green("✓"), host)
 	}
+	return printLogoutJSON(removed)
+}
 
-	config.DeleteHostCredentials(host)
-
-	if err := SaveGlobalConfig(config); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+// printLogoutJSON prints the hosts whose credentials were removed, in JSON mode
+func printLogoutJSON(hosts []string) error {
+	if !jsonOutput {
+		return nil
 	}
-
-	fmt.Printf("✓ Logged out from %s\n", host)
-	return nil
+	sort.Strings(hosts)
+	return printJSON(map[string][]string{"loggedOut": hosts})
 }
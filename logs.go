@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogsCmd shows recent HTTP access logs for the site
+type LogsCmd struct {
+	Follow   bool          `help:"Keep polling for new requests" short:"f"`
+	Status   []string      `help:"Only show these status codes; classes like 4xx are allowed"`
+	Path     string        `help:"Only show paths with this prefix, or matching this glob (e.g. /blog/*)"`
+	Lines    int           `help:"Number of recent entries to show first" default:"50"`
+	Interval time.Duration `help:"How often to poll with --follow" default:"2s"`
+}
+
+// LogEntry is one HTTP request served by the site
+type LogEntry struct {
+	Timestamp string `json:"timestamp"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Status    int    `json:"status"`
+	Bytes     int64  `json:"bytes"`
+	Referer   string `json:"referer,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// Examples are shown in 'efmrl3 logs --help'
+func (l *LogsCmd) Examples() []Example {
+	return []Example{
+		{"Watch requests as they come in", "efmrl3 logs --follow"},
+		{"Find broken links under /blog", "efmrl3 logs --status 404 --path '/blog/*'"},
+		{"Show the last 200 server errors", "efmrl3 logs --status 5xx --lines 200"},
+	}
+}
+
+// minLogsInterval is the shortest --interval allowed, so --follow can't
+// poll the server in a tight loop
+const minLogsInterval = time.Second
+
+func (l *LogsCmd) Run() error {
+	if l.Follow && l.Interval < minLogsInterval {
+		return fmt.Errorf("--interval must be at least %s", minLogsInterval)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	for _, status := range l.Status {
+		if _, err := parseStatusFilter(status); err != nil {
+			return err
+		}
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	cursor := ""
+	limit := l.Lines
+	for {
+		entries, next, err := fetchLogs(apiClient, config.Site.SiteID, cursor, limit, l.Status, l.Path)
+		if err != nil {
+			return fmt.Errorf("failed to fetch logs: %w", err)
+		}
+
+		for _, entry := range entries {
+			if l.matches(entry) {
+				printLogEntry(entry)
+			}
+		}
+
+		if !l.Follow {
+			return nil
+		}
+
+		cursor = next
+		limit = 0
+		time.Sleep(l.Interval)
+	}
+}
+
+// matches applies the filters locally too, in case the server ignores them
+func (l *LogsCmd) matches(entry LogEntry) bool {
+	if len(l.Status) > 0 {
+		ok := false
+		for _, status := range l.Status {
+			match, _ := parseStatusFilter(status)
+			if match(entry.Status) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	if l.Path != "" {
+		if strings.ContainsAny(l.Path, "*?[") {
+			if ok, _ := path.Match(l.Path, entry.Path); !ok {
+				return false
+			}
+		} else if !strings.HasPrefix(entry.Path, l.Path) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseStatusFilter parses "404" or "4xx" into a status matcher
+func parseStatusFilter(filter string) (func(int) bool, error) {
+	lower := strings.ToLower(filter)
+	if len(lower) == 3 && strings.HasSuffix(lower, "xx") && lower[0] >= '1' && lower[0] <= '5' {
+		class := int(lower[0] - '0')
+		return func(status int) bool { return status/100 == class }, nil
+	}
+
+	code, err := strconv.Atoi(filter)
+	if err != nil || code < 100 || code > 599 {
+		return nil, fmt.Errorf("invalid status filter %q (use e.g. 404 or 4xx)", filter)
+	}
+	return func(status int) bool { return status == code }, nil
+}
+
+// fetchLogs retrieves log entries after cursor (or the most recent limit
+// entries if cursor is empty) and returns the cursor for the next poll
+func fetchLogs(client *APIClient, siteID, cursor string, limit int, statuses []string, pathFilter string) ([]LogEntry, string, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("after", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	for _, status := range statuses {
+		query.Add("status", status)
+	}
+	if pathFilter != "" {
+		query.Set("path", pathFilter)
+	}
+
+	reqPath := fmt.Sprintf("/admin/efmrls/%s/logs", siteID)
+	if encoded := query.Encode(); encoded != "" {
+		reqPath += "?" + encoded
+	}
+
+	resp, err := client.Get(reqPath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Logs   []LogEntry `json:"logs"`
+		Cursor string     `json:"cursor"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// Keep the old cursor if there was nothing new
+	if result.Cursor == "" {
+		result.Cursor = cursor
+	}
+	return result.Logs, result.Cursor, nil
+}
+
+func printLogEntry(entry LogEntry) {
+	// One object per line, so --follow can be streamed into other tools
+	if jsonOutput {
+		json.NewEncoder(os.Stdout).Encode(entry)
+		return
+	}
+
+	timestamp := entry.Timestamp
+	if t, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+		timestamp = t.Local().Format(time.DateTime)
+	}
+	outf("%s  %d  %-6s %s  %s\n", timestamp, entry.Status, entry.Method, entry.Path, formatBytes(entry.Bytes))
+}
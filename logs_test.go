@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLogFilters tests status and path filtering of log entries
+func TestLogFilters(t *testing.T) {
+	tests := []struct {
+		cmd      LogsCmd
+		entry    LogEntry
+		expected bool
+	}{
+		{LogsCmd{}, LogEntry{Path: "/", Status: 200}, true},
+		{LogsCmd{Status: []string{"404"}}, LogEntry{Path: "/x", Status: 404}, true},
+		{LogsCmd{Status: []string{"404"}}, LogEntry{Path: "/x", Status: 200}, false},
+		{LogsCmd{Status: []string{"4xx"}}, LogEntry{Path: "/x", Status: 410}, true},
+		{LogsCmd{Status: []string{"5xx", "404"}}, LogEntry{Path: "/x", Status: 404}, true},
+		{LogsCmd{Path: "/blog"}, LogEntry{Path: "/blog/post.html", Status: 200}, true},
+		{LogsCmd{Path: "/blog"}, LogEntry{Path: "/docs/", Status: 200}, false},
+		{LogsCmd{Path: "/*.html"}, LogEntry{Path: "/index.html", Status: 200}, true},
+		{LogsCmd{Path: "/*.html"}, LogEntry{Path: "/a/index.html", Status: 200}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cmd.matches(tt.entry); got != tt.expected {
+			t.Errorf("matches(%+v) with status=%v path=%q = %v, expected %v",
+				tt.entry, tt.cmd.Status, tt.cmd.Path, got, tt.expected)
+		}
+	}
+
+	for _, bad := range []string{"abc", "6xx", "99", "4x"} {
+		if _, err := parseStatusFilter(bad); err == nil {
+			t.Errorf("Expected error for status filter %q, got nil", bad)
+		}
+	}
+}
+
+func TestLogsIntervalTooShort(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second, 500 * time.Millisecond} {
+		err := (&LogsCmd{Follow: true, Interval: interval}).Run()
+		if err == nil || !strings.Contains(err.Error(), "--interval must be at least 1s") {
+			t.Errorf("Interval %s: Expected an interval error, got %v", interval, err)
+		}
+	}
+}
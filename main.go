@@ -1,6 +1,11 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+
 	"github.com/alecthomas/kong"
 )
 
@@ -8,22 +13,124 @@ import (
 var version = "dev"
 
 var CLI struct {
-	Status   StatusCmd   `cmd:"" help:"Show site status and configuration"`
-	Config   ConfigCmd   `cmd:"" help:"View or modify configuration"`
-	Login    LoginCmd    `cmd:"" help:"Authenticate with efmrl server"`
-	Logout   LogoutCmd   `cmd:"" help:"Clear authentication credentials"`
-	Sync     SyncCmd     `cmd:"" help:"Synchronize local files with remote site"`
-	Domains  DomainsCmd  `cmd:"" help:"Manage domains for this efmrl"`
-	Rewrites RewritesCmd `cmd:"" help:"Manage rewrites for this efmrl"`
-	Version  VersionCmd  `cmd:"" help:"Print version information"`
+	Host            string `help:"efmrl server host (overrides base_host from the config file)" env:"EFMRL_HOST"`
+	Site            string `help:"Named site profile from [sites.<name>] in the config file" env:"EFMRL_SITE"`
+	SiteID          string `help:"Site ID to operate on (overrides the config file, which becomes optional)" env:"EFMRL_SITE_ID"`
+	Package         string `help:"Work in this package of a monorepo, e.g. sites/blog (see 'efmrl3 workspace discover')" short:"C" env:"EFMRL_PACKAGE" placeholder:"PATH"`
+	JSON            bool   `help:"Print machine-readable JSON on stdout; other messages go to stderr" xor:"output"`
+	Format          string `help:"Print results with a Go template instead, e.g. '{{.ID}} {{.Name}}' (one line per item)" placeholder:"TEMPLATE" xor:"output"`
+	Quiet           bool   `help:"Only print errors (and a one-line summary for sync)" short:"q"`
+	NoColor         bool   `help:"Disable colored output (NO_COLOR is also honored)"`
+	NonInteractive  bool   `help:"Never prompt; fail instead of waiting for input (the default when stdin isn't a terminal or CI=true)"`
+	NoExpiryWarning bool   `help:"Don't warn when the site is about to expire (or set expiry_warning_days = -1 in [sync])" env:"EFMRL_NO_EXPIRY_WARNING"`
+	CPUProfile      string `help:"Write a CPU profile of the command to this file, for 'go tool pprof'" name:"cpuprofile" type:"path" hidden:""`
+	Trace           string `help:"Write an execution trace of the command to this file, for 'go tool trace'" type:"path" hidden:""`
+
+	Quickstart   QuickstartCmd   `cmd:"" help:"Log in, create a site, scaffold it, and publish it in one go"`
+	Init         InitCmd         `cmd:"" help:"Create a new efmrl project from a starter template"`
+	Status       StatusCmd       `cmd:"" help:"Show site status and configuration"`
+	Config       ConfigCmd       `cmd:"" help:"View or modify configuration"`
+	Login        LoginCmd        `cmd:"" help:"Authenticate with efmrl server"`
+	Logout       LogoutCmd       `cmd:"" help:"Clear authentication credentials"`
+	Sync         SyncCmd         `cmd:"" help:"Synchronize local files with remote site"`
+	Diff         DiffCmd         `cmd:"" help:"Show how the local files differ from the live site, without syncing"`
+	Deploy       DeployCmd       `cmd:"" help:"Build the site, sync the build output, and record the deploy"`
+	Deploys      DeploysCmd      `cmd:"" help:"Show the deploy history of this efmrl"`
+	VerifyDeploy VerifyDeployCmd `cmd:"" help:"Check that the live site serves the files that were synced"`
+	Import       ImportCmd       `cmd:"" help:"Copy an existing static site into this efmrl"`
+	Export       ExportCmd       `cmd:"" help:"Download every file of this efmrl into a tar.gz or zip archive"`
+	Serve        ServeCmd        `cmd:"" help:"Preview the site locally with its redirects, headers, and rewrites applied"`
+	CheckLinks   CheckLinksCmd   `cmd:"" help:"Check that the site's internal links, assets, and anchors resolve before publishing it"`
+	Workspace    WorkspaceCmd    `cmd:"" help:"Find the sites of a monorepo"`
+	Files        FilesCmd        `cmd:"" help:"Browse the files on this efmrl"`
+	Open         OpenCmd         `cmd:"" help:"Open the live site in a browser"`
+	Logs         LogsCmd         `cmd:"" help:"Show recent HTTP requests served by the site"`
+	Analytics    AnalyticsCmd    `cmd:"" help:"Summarize page views, visitors, top paths, and referrers"`
+	Usage        UsageCmd        `cmd:"" help:"Report storage, bandwidth, and request usage"`
+	Plan         PlanCmd         `cmd:"" help:"Show or change your plan and quota limits"`
+	Sites        SitesCmd        `cmd:"" aliases:"site" help:"Create and manage your efmrls"`
+	Domains      DomainsCmd      `cmd:"" help:"Manage domains for this efmrl"`
+	Access       AccessCmd       `cmd:"" help:"Restrict who can view this efmrl"`
+	Rewrites     RewritesCmd     `cmd:"" help:"Manage rewrites for this efmrl"`
+	Redirects    RedirectsCmd    `cmd:"" help:"Manage HTTP redirects for this efmrl"`
+	Headers      HeadersCmd      `cmd:"" help:"Manage response header rules for this efmrl"`
+	Indexing     IndexingCmd     `cmd:"" help:"Show or change whether search engines may index this efmrl"`
+	Webhooks     WebhooksCmd     `cmd:"" help:"Manage webhooks notified when this efmrl changes"`
+	DeployKeys   DeployKeysCmd   `cmd:"" help:"Manage deploy keys that can only sync this efmrl"`
+	Env          EnvCmd          `cmd:"" help:"Manage environment variables for this efmrl's server-side features"`
+	Version      VersionCmd      `cmd:"" help:"Print version information"`
+	Update       UpdateCmd       `cmd:"" help:"Update efmrl3 to the latest release"`
+	GenDocs      GenDocsCmd      `cmd:"" hidden:"" help:"Generate man pages and markdown docs"`
 }
 
 func main() {
-	ctx := kong.Parse(&CLI,
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "efmrl3: internal error: %v\n", r)
+			offerDiagnostics(nil, r, debug.Stack())
+			os.Exit(ExitFailure)
+		}
+	}()
+
+	options := []kong.Option{
 		kong.Name("efmrl3"),
-		kong.Description("CLI for efmrl ephemeral web site hosting"),
-		kong.UsageOnError(),
-	)
-	err := ctx.Run()
-	ctx.FatalIfErrorf(err)
+		kong.Help(helpWithExamples),
+		kong.Description("CLI for efmrl ephemeral web site hosting\n\n" +
+			"Exit codes: 0 success, 1 failure, 2 authentication required, 3 quota exceeded, " +
+			"4 configuration missing, 5 sync partially applied"),
+	}
+	// Plugins are added as commands alongside the built-in ones, which they
+	// can't shadow
+	builtins := kong.Must(&CLI, options...)
+	parser := kong.Must(&CLI, append(options, pluginOptions(builtins.Model.Node)...)...)
+	ctx, err := parser.Parse(os.Args[1:])
+	if err != nil {
+		reportParseError(parser, err)
+		parser.Exit(ExitFailure)
+	}
+	// Everything else, config files included, is relative to the package
+	if CLI.Package != "" {
+		if err := enterPackage(CLI.Package); err != nil {
+			parser.Errorf("%s", err)
+			parser.Exit(ExitFailure)
+		}
+	}
+	hostOverride = CLI.Host
+	siteOverride = CLI.Site
+	siteIDOverride = CLI.SiteID
+	jsonOutput = CLI.JSON
+	quietOutput = CLI.Quiet
+	if err := setupFormat(CLI.Format); err != nil {
+		parser.Errorf("%s", err)
+		parser.Exit(ExitFailure)
+	}
+	ciEnvironment = detectCI()
+	setupColor(CLI.NoColor || ciEnvironment != nil)
+	nonInteractive = CLI.NonInteractive || detectNonInteractive()
+	noExpiryWarning = CLI.NoExpiryWarning
+	startUpdateCheck()
+	stopProfiling, err := startProfiling(CLI.CPUProfile, CLI.Trace)
+	if err != nil {
+		parser.Errorf("%s", err)
+		parser.Exit(ExitFailure)
+	}
+	err = ctx.Run()
+	stopProfiling()
+	var pluginErr *PluginExitError
+	if errors.As(err, &pluginErr) {
+		// The plugin has already reported its own error
+		ctx.Exit(pluginErr.Code)
+	}
+	endGroup()
+	if err != nil && jsonOutput && outputTemplate == nil {
+		printJSON(map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		ctx.Errorf("%s", err)
+		offerDiagnostics(err, nil, nil)
+		ctx.Exit(exitCode(err))
+	}
+	if ctx.Command() != "update" {
+		printUpdateNotice()
+	}
 }
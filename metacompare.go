@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How sync tells whether a file on the site is up to date, as set by [sync]
+// compare
+const (
+	compareETag     = "etag"     // by checksum (the default)
+	compareAuto     = "auto"     // by metadata where the ETag isn't a checksum this client computes
+	compareMetadata = "metadata" // by metadata for every file
+)
+
+// CompareMode returns how sync compares local files with remote ones:
+// compareETag, compareAuto, or compareMetadata
+func (c *Config) CompareMode() string {
+	if c.Sync.Compare == "" {
+		return compareETag
+	}
+	return c.Sync.Compare
+}
+
+// etagComparable reports whether etag is what this client computes for a
+// file of size: an MD5 for a small file, or the multipart formula with the
+// same part count for a large one
+func etagComparable(etag string, size int64) bool {
+	m := etagPattern.FindStringSubmatch(strings.Trim(etag, `"`))
+	if m == nil {
+		return false
+	}
+	if m[2] == "" {
+		return size <= multipartThreshold
+	}
+	parts, _ := strconv.ParseInt(m[2], 10, 64)
+	return size > multipartThreshold && parts == (size+multipartChunkSize-1)/multipartChunkSize
+}
+
+// comparesByMetadata reports whether mode compares lf with rf by metadata
+// rather than by ETag
+func comparesByMetadata(lf LocalFile, rf RemoteFile, mode string) bool {
+	switch mode {
+	case compareMetadata:
+		return true
+	case compareAuto:
+		return !etagComparable(rf.ETag, lf.Size)
+	default:
+		return false
+	}
+}
+
+// metadataUnchanged reports whether lf looks the same as rf by metadata: the
+// same size, and not modified since rf was uploaded. Files restored with old
+// modification times look unchanged, so it's only as good as they are.
+func metadataUnchanged(lf LocalFile, rf RemoteFile) bool {
+	if lf.Size != rf.Size || lf.modTime.IsZero() {
+		return false
+	}
+	uploaded, err := time.Parse(time.RFC3339, rf.Uploaded)
+	if err != nil {
+		return false
+	}
+	return !toServerTime(lf.modTime).After(uploaded)
+}
+
+// matchByMetadata gives each local file that mode compares by metadata, and
+// that looks unchanged that way, the ETag of the remote file at its path, so
+// that it isn't hashed and the plan leaves it be. It returns how many files
+// were compared by metadata.
+func matchByMetadata(local []LocalFile, remote []RemoteFile, mode string) int {
+	if mode == compareETag {
+		return 0
+	}
+	remoteMap := make(map[string]RemoteFile, len(remote))
+	for _, rf := range remote {
+		remoteMap[rf.Path] = rf
+	}
+
+	compared := 0
+	for i := range local {
+		lf := &local[i]
+		rf, ok := remoteMap[lf.Path]
+		if !ok || !comparesByMetadata(*lf, rf, mode) {
+			continue
+		}
+		compared++
+		if metadataUnchanged(*lf, rf) {
+			lf.ETag = rf.ETag
+		}
+	}
+	return compared
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestETagComparable(t *testing.T) {
+	small := int64(1024)
+	large := int64(multipartThreshold + 1)
+	tests := []struct {
+		etag string
+		size int64
+		want bool
+	}{
+		{md5Hex("x"), small, true},
+		{`"` + md5Hex("x") + `"`, small, true},
+		{md5Hex("x"), large, false},
+		{md5Hex("x") + "-2", large, true},
+		{md5Hex("x") + "-3", large, false},
+		{md5Hex("x") + "-2", small, false},
+		{"W/abc123", small, false},
+		{"", small, false},
+	}
+	for _, tt := range tests {
+		if got := etagComparable(tt.etag, tt.size); got != tt.want {
+			t.Errorf("Expected etagComparable(%q, %d) = %v, got %v", tt.etag, tt.size, tt.want, got)
+		}
+	}
+}
+
+func TestMetadataUnchanged(t *testing.T) {
+	resetClockSkew(t)
+	uploaded := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rf := RemoteFile{Path: "/big.bin", ETag: "opaque", Size: 100, Uploaded: uploaded.Format(time.RFC3339)}
+
+	tests := []struct {
+		name string
+		lf   LocalFile
+		want bool
+	}{
+		{"older", LocalFile{Size: 100, modTime: uploaded.Add(-time.Hour)}, true},
+		{"same time", LocalFile{Size: 100, modTime: uploaded}, true},
+		{"newer", LocalFile{Size: 100, modTime: uploaded.Add(time.Second)}, false},
+		{"other size", LocalFile{Size: 99, modTime: uploaded.Add(-time.Hour)}, false},
+		{"not scanned", LocalFile{Size: 100}, false},
+	}
+	for _, tt := range tests {
+		if got := metadataUnchanged(tt.lf, rf); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	rf.Uploaded = ""
+	if metadataUnchanged(LocalFile{Size: 100, modTime: uploaded.Add(-time.Hour)}, rf) {
+		t.Errorf("Expected a file without an upload time not to be unchanged")
+	}
+}
+
+func TestMatchByMetadata(t *testing.T) {
+	resetClockSkew(t)
+	uploaded := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	remote := []RemoteFile{
+		{Path: "/a.html", ETag: md5Hex("a"), Size: 1, Uploaded: uploaded},
+		{Path: "/b.bin", ETag: "opaque", Size: 1, Uploaded: uploaded},
+	}
+
+	tests := []struct {
+		mode     string
+		compared int
+		matched  []string
+	}{
+		{compareETag, 0, nil},
+		{compareAuto, 1, []string{"/b.bin"}},
+		{compareMetadata, 2, []string{"/a.html", "/b.bin"}},
+	}
+	for _, tt := range tests {
+		local := []LocalFile{
+			{Path: "/a.html", Size: 1, modTime: time.Now()},
+			{Path: "/b.bin", Size: 1, modTime: time.Now()},
+			{Path: "/new.html", Size: 1, modTime: time.Now()},
+		}
+		if got := matchByMetadata(local, remote, tt.mode); got != tt.compared {
+			t.Errorf("%s: expected %d file(s) compared, got %d", tt.mode, tt.compared, got)
+		}
+		var matched []string
+		for _, lf := range local {
+			if lf.ETag != "" {
+				matched = append(matched, lf.Path)
+			}
+		}
+		if fmt.Sprint(matched) != fmt.Sprint(tt.matched) {
+			t.Errorf("%s: expected %v matched, got %v", tt.mode, tt.matched, matched)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// scriptTypePattern finds the type attribute of a script tag
+var scriptTypePattern = regexp.MustCompile(`(?i)\stype\s*=\s*["']?([^"'\s>]*)`)
+
+// minifyKinds are the kinds of file minify may list, by extension
+var minifyKinds = map[string]string{
+	".html": "html",
+	".htm":  "html",
+	".css":  "css",
+	".js":   "js",
+	".mjs":  "js",
+}
+
+// minifyFiles minifies the HTML, CSS, and JavaScript files among files, for
+// each kind listed in kinds ("html", "css", "js"). The minifiers are
+// deliberately conservative: they drop comments and collapse whitespace,
+// and never rename or reorder anything. Minified copies are staged in a
+// temporary directory and their ETags computed from the minified content;
+// the returned function removes them.
+func minifyFiles(files []LocalFile, kinds []string) ([]LocalFile, int, int64, func(), error) {
+	enabled := map[string]bool{}
+	for _, kind := range kinds {
+		enabled[kind] = true
+	}
+
+	tmpDir, err := os.MkdirTemp("", "efmrl-minify-")
+	if err != nil {
+		return nil, 0, 0, func() {}, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	result := make([]LocalFile, 0, len(files))
+	minified := 0
+	var saved int64
+	for _, file := range files {
+		kind := minifyKinds[strings.ToLower(path.Ext(file.Path))]
+		if !enabled[kind] {
+			result = append(result, file)
+			continue
+		}
+
+		data, err := os.ReadFile(file.AbsPath)
+		if err != nil {
+			cleanup()
+			return nil, 0, 0, func() {}, err
+		}
+		var out string
+		switch kind {
+		case "html":
+			out = minifyHTML(string(data), enabled["css"], enabled["js"])
+		case "css":
+			out = minifyCSS(string(data))
+		case "js":
+			out = minifyJS(string(data))
+		}
+		if len(out) >= len(data) {
+			result = append(result, file)
+			continue
+		}
+
+		f, err := os.CreateTemp(tmpDir, "file-*"+path.Ext(file.Path))
+		if err != nil {
+			cleanup()
+			return nil, 0, 0, func() {}, err
+		}
+		if _, err := f.WriteString(out); err != nil {
+			f.Close()
+			cleanup()
+			return nil, 0, 0, func() {}, err
+		}
+		if err := f.Close(); err != nil {
+			cleanup()
+			return nil, 0, 0, func() {}, err
+		}
+		etag, err := computeFileETag(f.Name())
+		if err != nil {
+			cleanup()
+			return nil, 0, 0, func() {}, fmt.Errorf("failed to hash minified %s: %w", file.Path, err)
+		}
+
+		saved += file.Size - int64(len(out))
+		file.AbsPath = f.Name()
+		file.ETag = etag
+		file.Size = int64(len(out))
+		result = append(result, file)
+		minified++
+	}
+	return result, minified, saved, cleanup, nil
+}
+
+// minifyHTML removes comments from a page and collapses the whitespace in
+// its text. Whitespace is never removed outright, since between inline
+// elements it's visible. Tags are copied as they are, and so are the
+// contents of pre and textarea; those of style and script are minified as
+// CSS and JavaScript if css and js are set.
+func minifyHTML(src string, css, js bool) string {
+	var b strings.Builder
+	b.Grow(len(src))
+	for i := 0; i < len(src); {
+		switch {
+		case strings.HasPrefix(src[i:], "<!--"):
+			end := strings.Index(src[i+4:], "-->")
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += i + 4 + 3
+			}
+			// Conditional comments mean something to old browsers
+			if strings.HasPrefix(src[i+4:], "[") || strings.HasPrefix(src[i+4:], "<!") {
+				b.WriteString(src[i:end])
+			}
+			i = end
+
+		case src[i] == '<' && i+1 < len(src) && (isASCIILetter(src[i+1]) || src[i+1] == '/' || src[i+1] == '!'):
+			end := htmlTagEnd(src, i)
+			tag := src[i:end]
+			b.WriteString(tag)
+			i = end
+
+			name := htmlTagName(tag)
+			if name != "pre" && name != "textarea" && name != "script" && name != "style" {
+				continue
+			}
+			closing := indexFold(src[i:], "</"+name)
+			if closing < 0 {
+				closing = len(src) - i
+			}
+			content := src[i : i+closing]
+			switch {
+			case name == "style" && css:
+				content = minifyCSS(content)
+			case name == "script" && js && isJavaScriptTag(tag):
+				content = minifyJS(content)
+			}
+			b.WriteString(content)
+			i += closing
+
+		case isSpace(src[i]):
+			end := i
+			newline := false
+			for end < len(src) && isSpace(src[end]) {
+				newline = newline || src[end] == '\n'
+				end++
+			}
+			if newline {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(' ')
+			}
+			i = end
+
+		default:
+			b.WriteByte(src[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// htmlTagEnd returns the index just past the tag starting at i, skipping
+// over quoted attribute values
+func htmlTagEnd(src string, i int) int {
+	var quote byte
+	for j := i + 1; j < len(src); j++ {
+		switch {
+		case quote != 0:
+			if src[j] == quote {
+				quote = 0
+			}
+		case src[j] == '"' || src[j] == '\'':
+			quote = src[j]
+		case src[j] == '>':
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+// htmlTagName returns the lowercased name of an opening tag, or "" for a
+// closing tag or declaration
+func htmlTagName(tag string) string {
+	end := 1
+	for end < len(tag) && (isASCIILetter(tag[end]) || tag[end] >= '0' && tag[end] <= '9') {
+		end++
+	}
+	return strings.ToLower(tag[1:end])
+}
+
+// isJavaScriptTag reports whether a script tag holds JavaScript rather than
+// data such as JSON or a template
+func isJavaScriptTag(tag string) bool {
+	m := scriptTypePattern.FindStringSubmatch(tag)
+	if m == nil {
+		return true
+	}
+	value := strings.ToLower(m[1])
+	return value == "" || value == "module" || strings.Contains(value, "javascript")
+}
+
+// minifyCSS removes comments from a stylesheet, except /*! ones, which
+// usually hold licenses, and the whitespace that doesn't separate anything.
+// Spaces before a colon are kept, since in a selector they matter.
+func minifyCSS(src string) string {
+	out := make([]byte, 0, len(src))
+	space := false
+	last := byte(0)
+	write := func(s string) {
+		if space && last != 0 && !strings.ContainsRune("{};,:>(", rune(last)) && !strings.ContainsRune("{};,>)", rune(s[0])) {
+			out = append(out, ' ')
+		}
+		space = false
+		out = append(out, s...)
+		last = s[len(s)-1]
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += i + 2 + 2
+			}
+			if strings.HasPrefix(src[i:], "/*!") {
+				write(src[i:end])
+			} else {
+				space = true
+			}
+			i = end
+
+		case c == '"' || c == '\'':
+			end := quotedEnd(src, i)
+			write(src[i:end])
+			i = end
+
+		case len(src)-i >= 4 && strings.EqualFold(src[i:i+4], "url(") && (i == 0 || !isIdentByte(src[i-1])):
+			end := strings.IndexByte(src[i:], ')')
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += i + 1
+			}
+			write(src[i:end])
+			i = end
+
+		case isSpace(c):
+			space = true
+			i++
+
+		case c == '}' && last == ';':
+			// The last declaration in a block doesn't need its semicolon
+			out = out[:len(out)-1]
+			space = false
+			write("}")
+			i++
+
+		default:
+			write(src[i : i+1])
+			i++
+		}
+	}
+	return string(out)
+}
+
+// jsRegexPrefixes are the characters after which a slash starts a regular
+// expression rather than being division
+const jsRegexPrefixes = "(,=:[!&|?{};+-*%<>~^"
+
+// jsRegexKeywords are the keywords after which a slash starts a regular
+// expression
+var jsRegexKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "do": true, "else": true,
+	"in": true, "instanceof": true, "new": true, "delete": true, "void": true,
+	"throw": true, "yield": true, "await": true,
+}
+
+// jsMinifier removes comments and redundant whitespace from JavaScript
+type jsMinifier struct {
+	src     string
+	i       int
+	b       strings.Builder
+	space   bool   // whitespace is pending
+	newline bool   // a line break is pending
+	last    byte   // the last character written
+	word    string // the last identifier or keyword written
+}
+
+// minifyJS removes comments from a script, except /*! ones, and its
+// indentation, blank lines, and repeated spaces. Line breaks are kept,
+// since automatic semicolon insertion depends on them, and so is a single
+// space wherever there was whitespace, so tokens like "a + +b" stay apart.
+func minifyJS(src string) string {
+	m := &jsMinifier{src: src}
+	m.b.Grow(len(src))
+	m.code(false)
+	return m.b.String()
+}
+
+// write outputs s, preceded by any whitespace that's pending
+func (m *jsMinifier) write(s string) {
+	if m.last != 0 {
+		if m.newline {
+			m.b.WriteByte('\n')
+		} else if m.space {
+			m.b.WriteByte(' ')
+		}
+	}
+	m.space, m.newline = false, false
+	m.b.WriteString(s)
+	m.last = s[len(s)-1]
+	m.word = ""
+}
+
+// code minifies code up to the end of the source or, if inTemplate is set,
+// up to the } closing a template literal's ${ substitution
+func (m *jsMinifier) code(inTemplate bool) {
+	depth := 0
+	for m.i < len(m.src) {
+		src, i := m.src, m.i
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			m.i += end
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += i + 2 + 2
+			}
+			if strings.HasPrefix(src[i:], "/*!") {
+				m.write(src[i:end])
+			} else if strings.Contains(src[i:end], "\n") {
+				// A comment spanning lines counts as a line break
+				m.newline = true
+			} else {
+				m.space = true
+			}
+			m.i = end
+
+		case c == '/' && m.regexAllowed():
+			m.regex()
+
+		case c == '"' || c == '\'':
+			end := quotedEnd(src, i)
+			m.write(src[i:end])
+			m.i = end
+
+		case c == '`':
+			m.template()
+
+		case c == '\n':
+			m.newline = true
+			m.i++
+
+		case isSpace(c):
+			m.space = true
+			m.i++
+
+		case isIdentByte(c):
+			end := i
+			for end < len(src) && isIdentByte(src[end]) {
+				end++
+			}
+			m.write(src[i:end])
+			m.word = src[i:end]
+			m.i = end
+
+		default:
+			if c == '{' {
+				depth++
+			} else if c == '}' {
+				if inTemplate && depth == 0 {
+					return
+				}
+				depth--
+			}
+			m.write(src[i : i+1])
+			m.i++
+		}
+	}
+}
+
+// regexAllowed reports whether a slash here starts a regular expression
+func (m *jsMinifier) regexAllowed() bool {
+	if m.last == 0 || jsRegexKeywords[m.word] {
+		return true
+	}
+	if m.word != "" {
+		return false
+	}
+	return strings.IndexByte(jsRegexPrefixes, m.last) >= 0
+}
+
+// regex copies a regular expression literal, including its flags
+func (m *jsMinifier) regex() {
+	src := m.src
+	j := m.i + 1
+	inClass := false
+	for j < len(src) && src[j] != '\n' {
+		c := src[j]
+		j++
+		if c == '\\' {
+			j++
+		} else if c == '[' {
+			inClass = true
+		} else if c == ']' {
+			inClass = false
+		} else if c == '/' && !inClass {
+			break
+		}
+	}
+	for j < len(src) && isIdentByte(src[j]) {
+		j++
+	}
+	j = min(j, len(src))
+	m.write(src[m.i:j])
+	m.i = j
+}
+
+// template copies a template literal, minifying the code in its ${}
+// substitutions but nothing else
+func (m *jsMinifier) template() {
+	src := m.src
+	start := m.i
+	m.i++
+	for m.i < len(src) {
+		c := src[m.i]
+		switch {
+		case c == '\\':
+			m.i += 2
+		case c == '`':
+			m.i++
+			m.write(src[start:m.i])
+			return
+		case strings.HasPrefix(src[m.i:], "${"):
+			m.i += 2
+			m.write(src[start:m.i])
+			m.code(true)
+			start = m.i
+			if m.i < len(src) {
+				m.i++ // the closing }
+			}
+		default:
+			m.i++
+		}
+	}
+	m.write(src[start:min(m.i, len(src))])
+}
+
+// quotedEnd returns the index just past the string literal starting at i,
+// which ends at its closing quote or an unescaped line break
+func quotedEnd(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		case '\n':
+			return j
+		}
+	}
+	return len(src)
+}
+
+// indexFold is strings.Index, ignoring ASCII case
+func indexFold(s, substr string) int {
+	return strings.Index(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isIdentByte reports whether c may be part of an identifier or number.
+// Bytes of multibyte characters count, so those pass through whole.
+func isIdentByte(c byte) bool {
+	return isASCIILetter(c) || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMinifyCSS(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"whitespace and comments", "/* header */\nbody {\n  color: red;\n  margin: 0 auto;\n}\n", "body{color:red;margin:0 auto}"},
+		{"descendant pseudo-class keeps its space", "a :hover { x: y }", "a :hover{x:y}"},
+		{"selector lists", "h1 , h2 > p {a:b}", "h1,h2>p{a:b}"},
+		{"license comments stay", "/*! MIT */ a{b:c}", "/*! MIT */ a{b:c}"},
+		{"strings untouched", `a::after { content: "  /* not a comment */  "; }`, `a::after{content:"  /* not a comment */  "}`},
+		{"unquoted url untouched", "a { background: url(//cdn.example.com/a.png) }", "a{background:url(//cdn.example.com/a.png)}"},
+		{"calc keeps its operators apart", "a { width: calc(100% - 2px) }", "a{width:calc(100% - 2px)}"},
+		{"media queries", "@media screen and (max-width: 600px) {\n  a { b: c; }\n}", "@media screen and (max-width:600px){a{b:c}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := minifyCSS(tt.in); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMinifyJS(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"comments and indentation", "// setup\nfunction f() {\n    /* body */\n    return 1;\n}\n", "function f() {\nreturn 1;\n}"},
+		{"line breaks kept for semicolon insertion", "let a = 1\nlet b = 2\n", "let a = 1\nlet b = 2"},
+		{"multiline comment is a line break", "a /* x\n */ b", "a\nb"},
+		{"unary operators stay apart", "x = a +   +b", "x = a + +b"},
+		{"strings untouched", `s = "a // b /* c */"`, `s = "a // b /* c */"`},
+		{"regex after assignment", `re = /\/\/.*$/g // comment`, `re = /\/\/.*$/g`},
+		{"regex after return", "return /[/]*/.test(s)", "return /[/]*/.test(s)"},
+		{"division", "x = a / b / c // half", "x = a / b / c"},
+		{"template literal", "t = `a  // ${ b /* c */ }  d`", "t = `a  // ${ b }  d`"},
+		{"nested template", "t = `${`x  y`}`", "t = `${`x  y`}`"},
+		{"license comments stay", "/*! MIT */\nvar a", "/*! MIT */\nvar a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := minifyJS(tt.in); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"comments and whitespace", "<p>\n    Hello   <b>world</b>  <!-- note -->\n</p>", "<p>\nHello <b>world</b> \n</p>"},
+		{"conditional comments stay", "<!--[if IE]><p>old</p><![endif]-->", "<!--[if IE]><p>old</p><![endif]-->"},
+		{"attributes untouched", `<a title="a   b" href="x">`, `<a title="a   b" href="x">`},
+		{"pre untouched", "<pre>  a\n\n  b  </pre>", "<pre>  a\n\n  b  </pre>"},
+		{"inline style and script", "<style>\n  a { b: c; }\n</style><script>\n  // hi\n  go()\n</script>", "<style>a{b:c}</style><script>go()</script>"},
+		{"data scripts untouched", "<script type=\"application/ld+json\">\n  {\"a\":  1}\n</script>", "<script type=\"application/ld+json\">\n  {\"a\":  1}\n</script>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := minifyHTML(tt.in, true, true); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMinifyFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) LocalFile {
+		abs := filepath.Join(dir, name)
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return localFileFor(t, "/"+name, abs)
+	}
+	files := []LocalFile{
+		write("style.css", "a {\n  b: c;\n}\n"),
+		write("app.js", "// app\ngo()\n"),
+	}
+
+	result, minified, saved, cleanup, err := minifyFiles(files, []string{"css"})
+	if err != nil {
+		t.Fatalf("minifyFiles failed: %v", err)
+	}
+	defer cleanup()
+
+	if minified != 1 || saved != files[0].Size-int64(len("a{b:c}")) {
+		t.Errorf("Expected 1 file minified, got %d saving %d bytes", minified, saved)
+	}
+	css := result[0]
+	data, err := os.ReadFile(css.AbsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a{b:c}" || css.Size != int64(len(data)) {
+		t.Errorf("Expected the staged stylesheet to be minified, got %q (%d bytes)", data, css.Size)
+	}
+	want, err := computeFileETag(css.AbsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if css.ETag != want {
+		t.Errorf("Expected the ETag of the minified content %s, got %s", want, css.ETag)
+	}
+	if result[1] != files[1] {
+		t.Errorf("Expected app.js to be left alone, got %+v", result[1])
+	}
+}
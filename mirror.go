@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mirrorMaxFiles caps how many files 'init --from-url' downloads, so a site
+// that generates endless pages can't fill the disk
+const mirrorMaxFiles = 5000
+
+// mirror downloads a public website into a directory, following links that
+// stay on the same origin
+type mirror struct {
+	origin *url.URL
+	dir    string
+	depth  int
+	force  bool
+	client *http.Client
+
+	queue   []mirrorItem
+	seen    map[string]bool // URL paths queued
+	saved   map[string]bool // local paths written or skipped
+	written []string
+}
+
+// mirrorItem is a URL waiting to be downloaded, and how many links away
+// from the start page it was found
+type mirrorItem struct {
+	url   *url.URL
+	depth int
+}
+
+// mirrorSite crawls the site at rawURL into dir, following links up to depth
+// pages away from it. Assets are fetched whatever their depth, so the
+// deepest pages still have their stylesheets and images. Absolute links to
+// the same origin are rewritten as site paths, so the copy links to itself
+// rather than the original. It lists each file as it goes and returns the
+// paths it wrote.
+func mirrorSite(rawURL, dir string, depth int, force bool) ([]string, error) {
+	start, err := url.Parse(rawURL)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: expected an http or https URL", rawURL)
+	}
+	start.Fragment = ""
+
+	m := &mirror{
+		origin: &url.URL{Scheme: start.Scheme, Host: start.Host},
+		dir:    dir,
+		depth:  depth,
+		force:  force,
+		client: &http.Client{Timeout: 30 * time.Second},
+		seen:   map[string]bool{},
+		saved:  map[string]bool{},
+	}
+	outf("Mirroring %s into %s/\n", start, dir)
+
+	m.enqueue(start, 0)
+	for i := 0; i < len(m.queue); i++ {
+		if len(m.saved) >= mirrorMaxFiles {
+			warnf("stopped after %d files; the rest of the site wasn't downloaded\n", mirrorMaxFiles)
+			break
+		}
+		item := m.queue[i]
+		if err := m.fetch(item); err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("failed to download %s: %w", item.url, err)
+			}
+			warnf("skipped %s: %v\n", item.url, err)
+		}
+	}
+	return m.written, nil
+}
+
+// enqueue adds a URL to the crawl, unless it's already been seen
+func (m *mirror) enqueue(u *url.URL, depth int) {
+	key := u.Path
+	if key == "" {
+		key = "/"
+	}
+	if m.seen[key] {
+		return
+	}
+	m.seen[key] = true
+	m.queue = append(m.queue, mirrorItem{url: u, depth: depth})
+}
+
+// fetch downloads one URL, saves it, and queues what it links to
+func (m *mirror) fetch(item mirrorItem) error {
+	resp, err := m.client.Get(item.url.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	// A redirect may have taken it somewhere else
+	final := resp.Request.URL
+	if !m.sameOrigin(final) {
+		return fmt.Errorf("redirected off the site to %s", final)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	ext := strings.ToLower(path.Ext(final.Path))
+	isHTML := mediaType == "text/html" || ext == ".html" || ext == ".htm"
+	isCSS := mediaType == "text/css" || ext == ".css"
+
+	if isHTML || isCSS {
+		content := replaceRefs(string(body), isHTML, func(ref string) string {
+			return m.follow(final, ref, item.depth)
+		})
+		body = []byte(content)
+	}
+
+	local := mirrorPath(final, isHTML)
+	if m.saved[local] {
+		return nil
+	}
+	m.saved[local] = true
+	dest := filepath.Join(m.dir, filepath.FromSlash(local))
+	wrote, err := writeScaffoldFile(dest, body, m.force)
+	if err != nil {
+		return err
+	}
+	if wrote {
+		outf("  + %s\n", dest)
+		m.written = append(m.written, dest)
+	}
+	return nil
+}
+
+// follow queues the target of a reference in the page at base, if it's on
+// the same origin and within the depth limit, and returns the reference as
+// the copy should have it
+func (m *mirror) follow(base *url.URL, ref string, depth int) string {
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	target := base.ResolveReference(parsed)
+	if (target.Scheme != "http" && target.Scheme != "https") || !m.sameOrigin(target) {
+		return ref
+	}
+
+	next := *target
+	next.Fragment = ""
+	if depth < m.depth || !isPagePath(next.Path) {
+		m.enqueue(&next, depth+1)
+	}
+
+	if parsed.Scheme == "" && parsed.Host == "" {
+		return ref
+	}
+	// An absolute link to the original site becomes a link to the copy
+	rewritten := target.EscapedPath()
+	if rewritten == "" {
+		rewritten = "/"
+	}
+	if target.RawQuery != "" {
+		rewritten += "?" + target.RawQuery
+	}
+	if target.Fragment != "" {
+		rewritten += "#" + target.EscapedFragment()
+	}
+	return rewritten
+}
+
+func (m *mirror) sameOrigin(u *url.URL) bool {
+	return strings.EqualFold(u.Scheme, m.origin.Scheme) && strings.EqualFold(u.Host, m.origin.Host)
+}
+
+// isPagePath reports whether a URL path looks like a page rather than an
+// asset, going by its extension
+func isPagePath(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	return ext == "" || ext == ".html" || ext == ".htm"
+}
+
+// mirrorPath is where a downloaded URL goes in the site directory, as a
+// slash-separated relative path. Directory URLs and extensionless pages get
+// an index.html, so the copy serves them at the same paths.
+func mirrorPath(u *url.URL, isHTML bool) string {
+	p := u.Path
+	switch {
+	case p == "" || strings.HasSuffix(p, "/"):
+		p += "index.html"
+	case isHTML && path.Ext(p) == "":
+		p += "/index.html"
+	}
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
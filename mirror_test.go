@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMirrorSite(t *testing.T) {
+	var server *httptest.Server
+	pages := map[string]string{
+		"/":                `<a href="/about">About</a> <a href="https://elsewhere.example/">Away</a> <link href="style.css" rel="stylesheet"> <a href="ORIGIN/docs/#intro">Docs</a>`,
+		"/about":           `<img src="/img/logo.png" srcset="/img/logo.png 1x, /img/logo@2x.png 2x"> <a href="/deep/">Deep</a>`,
+		"/docs/":           `<p>Docs</p>`,
+		"/deep/":           `<a href="/deeper/">Deeper</a> <script src="/deep.js"></script>`,
+		"/style.css":       `body { background: url("img/bg.png") }`,
+		"/img/logo.png":    "logo",
+		"/img/logo@2x.png": "logo2x",
+		"/img/bg.png":      "bg",
+		"/deep.js":         "js",
+	}
+	requested := map[string]bool{}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested[r.URL.Path] = true
+		content, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".css"):
+			w.Header().Set("Content-Type", "text/css")
+		case strings.HasSuffix(r.URL.Path, ".png"), strings.HasSuffix(r.URL.Path, ".js"):
+			w.Header().Set("Content-Type", "application/octet-stream")
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		w.Write([]byte(strings.ReplaceAll(content, "ORIGIN", server.URL)))
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "public")
+	var written []string
+	var err error
+	captureStdout(t, func() { written, err = mirrorSite(server.URL, dir, 2, false) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"index.html":       "",
+		"about/index.html": "",
+		"docs/index.html":  "<p>Docs</p>",
+		"deep/index.html":  "",
+		"style.css":        `url("img/bg.png")`,
+		"img/logo.png":     "logo",
+		"img/logo@2x.png":  "logo2x",
+		"img/bg.png":       "bg",
+		"deep.js":          "js",
+	}
+	for local, contains := range want {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(local)))
+		if err != nil {
+			t.Errorf("Expected %s downloaded, got %v", local, err)
+			continue
+		}
+		if !strings.Contains(string(data), contains) {
+			t.Errorf("Expected %s to contain %q, got %q", local, contains, data)
+		}
+	}
+	if len(written) != len(want) {
+		t.Errorf("Expected %d files written, got %d: %v", len(want), len(written), written)
+	}
+	if requested["/deeper/"] {
+		t.Errorf("Expected pages beyond the depth limit not to be fetched")
+	}
+
+	index, _ := os.ReadFile(filepath.Join(dir, "index.html"))
+	if !strings.Contains(string(index), `href="/docs/#intro"`) {
+		t.Errorf("Expected the absolute link rewritten to a site path, got %s", index)
+	}
+	if !strings.Contains(string(index), `href="https://elsewhere.example/"`) {
+		t.Errorf("Expected links to other sites left alone, got %s", index)
+	}
+}
+
+func TestMirrorSiteErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"ftp://example.com/", "expected an http or https URL"},
+		{"example.com", "expected an http or https URL"},
+		{server.URL, "status 404"},
+	}
+	for _, tt := range tests {
+		var err error
+		captureStdout(t, func() { _, err = mirrorSite(tt.url, t.TempDir(), 1, false) })
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("mirrorSite(%q): Expected an error containing %q, got %v", tt.url, tt.want, err)
+		}
+	}
+}
+
+func TestMirrorPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		isHTML bool
+		want   string
+	}{
+		{"", true, "index.html"},
+		{"/", true, "index.html"},
+		{"/docs/", true, "docs/index.html"},
+		{"/about", true, "about/index.html"},
+		{"/about.html", true, "about.html"},
+		{"/img/logo.png", false, "img/logo.png"},
+		{"/LICENSE", false, "LICENSE"},
+		{"/../../etc/passwd", false, "etc/passwd"},
+	}
+	for _, tt := range tests {
+		if got := mirrorPath(&url.URL{Path: tt.path}, tt.isHTML); got != tt.want {
+			t.Errorf("mirrorPath(%q, %v): Expected %q, got %q", tt.path, tt.isHTML, tt.want, got)
+		}
+	}
+}
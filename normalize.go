@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// The Unicode forms [sync] unicode_normalization can upload file names in.
+// macOS stores names decomposed (NFD: "e" then a combining accent), while
+// links, URLs, and other systems spell them composed (NFC: "é"), so without
+// normalizing, a page's link to é.html wouldn't find the file uploaded from
+// a Mac.
+const (
+	normalizeNFC  = "nfc"
+	normalizeNone = "none"
+)
+
+// normalizePath returns a site path in the Unicode form
+func normalizePath(p, form string) string {
+	if form == normalizeNone {
+		return p
+	}
+	return norm.NFC.String(p)
+}
+
+// normalizeLocalPaths rewrites the site paths of files in the Unicode form.
+// Two files whose names only differ in form would be uploaded to the same
+// path, so that's an error.
+func normalizeLocalPaths(files []LocalFile, form string) error {
+	if form == normalizeNone {
+		return nil
+	}
+	seen := make(map[string]string, len(files))
+	for i := range files {
+		original := files[i].Path
+		files[i].Path = normalizePath(original, form)
+		if other, ok := seen[files[i].Path]; ok {
+			return fmt.Errorf("%s and %s are the same name in different Unicode forms (%s and %s); rename or remove one of them",
+				other, original, strconv.QuoteToASCII(other), strconv.QuoteToASCII(original))
+		}
+		seen[files[i].Path] = original
+	}
+	return nil
+}
+
+// warnUnnormalizedRemote warns about remote files that are local files
+// under another Unicode form, typically uploaded from a Mac before names
+// were normalized. The local file is uploaded under the normalized name,
+// and the old copy lingers until a sync with --delete.
+func warnUnnormalizedRemote(local []LocalFile, remote []RemoteFile, form string) {
+	if form == normalizeNone {
+		return
+	}
+	localPaths := make(map[string]bool, len(local))
+	for _, lf := range local {
+		localPaths[lf.Path] = true
+	}
+	var stale []string
+	for _, rf := range remote {
+		if p := normalizePath(rf.Path, form); p != rf.Path && localPaths[p] {
+			stale = append(stale, rf.Path)
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+	warnf("%d remote file(s) are local files under a different Unicode form, e.g. %s;\n"+
+		"         they're uploaded again under the normalized name, and 'efmrl3 sync --delete' removes the old copies\n",
+		len(stale), strconv.QuoteToASCII(stale[0]))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	cafeNFC = "/caf\u00e9.html"
+	cafeNFD = "/cafe\u0301.html"
+)
+
+func TestNormalizeLocalPaths(t *testing.T) {
+	files := []LocalFile{{Path: cafeNFD}, {Path: "/index.html"}}
+	if err := normalizeLocalPaths(files, normalizeNFC); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if files[0].Path != cafeNFC || files[1].Path != "/index.html" {
+		t.Errorf("Expected %q and /index.html, got %q and %q", cafeNFC, files[0].Path, files[1].Path)
+	}
+
+	files = []LocalFile{{Path: cafeNFD}}
+	if err := normalizeLocalPaths(files, normalizeNone); err != nil || files[0].Path != cafeNFD {
+		t.Errorf("Expected the name left alone, got %q (%v)", files[0].Path, err)
+	}
+}
+
+// TestNormalizeLocalPathsConflict tests that two files uploading to the
+// same normalized path are refused
+func TestNormalizeLocalPathsConflict(t *testing.T) {
+	files := []LocalFile{{Path: cafeNFC}, {Path: cafeNFD}}
+	err := normalizeLocalPaths(files, normalizeNFC)
+	if err == nil || !strings.Contains(err.Error(), `("/caf\u00e9.html" and "/cafe\u0301.html")`) {
+		t.Errorf("Expected both spellings in the error, got %v", err)
+	}
+}
+
+// TestScanNormalizesForPlan tests that a file stored decomposed on disk is
+// compared with the site under its composed name
+func TestScanNormalizesForPlan(t *testing.T) {
+	_, files := writeSite(t, map[string]string{strings.TrimPrefix(cafeNFD, "/"): "<h1>Café</h1>"})
+	if err := normalizeLocalPaths(files, normalizeNFC); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := computeSyncPlan(files, []RemoteFile{{Path: cafeNFC, ETag: md5Hex("<h1>Café</h1>")}}, false, true)
+	if len(plan.Unchanged) != 1 || len(plan.ToUpload) != 0 || len(plan.ToDelete) != 0 {
+		t.Errorf("Expected the file unchanged, got %+v", plan)
+	}
+}
+
+func TestWarnUnnormalizedRemote(t *testing.T) {
+	local := []LocalFile{{Path: cafeNFC}}
+	remote := []RemoteFile{{Path: cafeNFD}, {Path: "/index.html"}}
+
+	stderr := captureStderr(t, func() { warnUnnormalizedRemote(local, remote, normalizeNFC) })
+	if !strings.Contains(stderr, `1 remote file(s) are local files under a different Unicode form, e.g. "/cafe\u0301.html"`) {
+		t.Errorf("Expected a warning about the decomposed copy, got %q", stderr)
+	}
+
+	stderr = captureStderr(t, func() { warnUnnormalizedRemote(local, remote, normalizeNone) })
+	if stderr != "" {
+		t.Errorf("Expected no warning without normalization, got %q", stderr)
+	}
+}
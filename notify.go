@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// notificationEvents are the outcomes [notifications] events may list
+var notificationEvents = []string{"success", "failure"}
+
+// notifySync posts the outcome of a sync or deploy to the webhook under
+// [notifications], if there is one and the outcome is among its events.
+// action is "Sync" or "Deploy". Dry runs and syncs that changed nothing
+// aren't announced. Notifying is best effort, so failures are warnings.
+func notifySync(config *Config, action string, result *SyncResult, syncErr error) {
+	webhookURL := os.ExpandEnv(config.Notifications.WebhookURL)
+	if webhookURL == "" {
+		return
+	}
+	event := "success"
+	if syncErr != nil {
+		event = "failure"
+	}
+	events := config.Notifications.Events
+	if len(events) == 0 {
+		events = notificationEvents
+	}
+	if !slices.Contains(events, event) {
+		return
+	}
+	if syncErr == nil && (result == nil || result.DryRun || len(result.Uploaded)+len(result.Deleted) == 0) {
+		return
+	}
+
+	siteURL := ""
+	if client, err := NewAPIClient(config.BaseURL()); err == nil {
+		siteURL, _ = primarySiteURL(client, config.Site.SiteID)
+	}
+	text := notificationText(action, config.Site.SiteID, siteURL, gitCommit(), result, syncErr)
+	if err := postNotification(webhookURL, text); err != nil {
+		warnf("failed to send notification: %v\n", err)
+	}
+}
+
+// notificationText is the message posted for a sync or deploy
+func notificationText(action, siteID, siteURL, commit string, result *SyncResult, syncErr error) string {
+	site := siteID
+	if siteURL != "" {
+		site = fmt.Sprintf("%s (%s)", siteID, siteURL)
+	}
+
+	var b strings.Builder
+	if syncErr != nil {
+		fmt.Fprintf(&b, "❌ %s of %s failed: %v", action, site, syncErr)
+	} else {
+		fmt.Fprintf(&b, "✅ %s of %s succeeded: %d uploaded, %d deleted, %d unchanged",
+			action, site, len(result.Uploaded), len(result.Deleted), result.Unchanged)
+		if result.DeployID != "" {
+			fmt.Fprintf(&b, " (deploy %s)", result.DeployID)
+		}
+		if result.DeployURL != "" {
+			fmt.Fprintf(&b, "\nThis version: %s", result.DeployURL)
+		}
+	}
+	if commit != "" {
+		fmt.Fprintf(&b, "\nCommit: %s", commit)
+	}
+	if ciEnvironment != nil && ciEnvironment.RunURL != "" {
+		fmt.Fprintf(&b, "\nCI run: %s", ciEnvironment.RunURL)
+	}
+	return b.String()
+}
+
+// notificationPayload wraps a message the way the webhook expects: Discord
+// wants it in "content", while Slack, Mattermost, and most others take
+// Slack's "text"
+func notificationPayload(webhookURL, text string) map[string]string {
+	if u, err := url.Parse(webhookURL); err == nil {
+		host := strings.ToLower(u.Hostname())
+		if host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com") {
+			return map[string]string{"content": text}
+		}
+	}
+	return map[string]string{"text": text}
+}
+
+// postNotification posts a message to a chat webhook
+func postNotification(webhookURL, text string) error {
+	body, err := json.Marshal(notificationPayload(webhookURL, text))
+	if err != nil {
+		return err
+	}
+
+	// Not newHTTPClient: diagnostics bundles would record the secret URL
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL is a secret, so leave it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
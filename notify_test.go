@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNotificationPayload(t *testing.T) {
+	tests := []struct {
+		url string
+		key string
+	}{
+		{"https://hooks.slack.com/services/T0/B0/x", "text"},
+		{"https://discord.com/api/webhooks/1/x", "content"},
+		{"https://ptb.discord.com/api/webhooks/1/x", "content"},
+		{"https://discordapp.com/api/webhooks/1/x", "content"},
+		{"https://chat.example.com/hooks/x", "text"},
+	}
+
+	for _, tt := range tests {
+		payload := notificationPayload(tt.url, "hi")
+		if payload[tt.key] != "hi" || len(payload) != 1 {
+			t.Errorf("Expected {%q: \"hi\"} for %s, got %v", tt.key, tt.url, payload)
+		}
+	}
+}
+
+func TestNotificationText(t *testing.T) {
+	result := &SyncResult{Uploaded: []string{"/a", "/b"}, Deleted: []string{"/c"}, Unchanged: 7, DeployID: "d1", DeployURL: "https://deploy-d1.abc.example.com"}
+	got := notificationText("Deploy", "abc", "https://abc.example.com", "1a2b3c4", result, nil)
+	want := "✅ Deploy of abc (https://abc.example.com) succeeded: 2 uploaded, 1 deleted, 7 unchanged (deploy d1)\nThis version: https://deploy-d1.abc.example.com\nCommit: 1a2b3c4"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	got = notificationText("Sync", "abc", "", "", nil, errors.New("upload failed"))
+	want = "❌ Sync of abc failed: upload failed"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestNotifySync(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+	t.Setenv("TEST_HOOK_PATH", "/hook/secret")
+
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hook/secret" {
+			http.NotFound(w, r)
+			return
+		}
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		posted = append(posted, payload["text"])
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	config := &Config{}
+	config.Site.SiteID = "abc"
+	config.Site.BaseHost = "localhost:" + u.Port()
+	config.Notifications.WebhookURL = server.URL + "${TEST_HOOK_PATH}"
+	config.Notifications.Events = []string{"failure"}
+
+	changed := &SyncResult{Uploaded: []string{"/index.html"}}
+	notifySync(config, "Sync", changed, nil)
+	if len(posted) != 0 {
+		t.Errorf("Expected no post for a success with events = [failure], got %v", posted)
+	}
+
+	notifySync(config, "Sync", nil, errors.New("boom"))
+	if len(posted) != 1 || !strings.Contains(posted[0], "Sync of abc failed: boom") {
+		t.Errorf("Expected the failure to be posted, got %v", posted)
+	}
+
+	config.Notifications.Events = nil
+	notifySync(config, "Sync", &SyncResult{Unchanged: 3}, nil)
+	if len(posted) != 1 {
+		t.Errorf("Expected no post for a sync that changed nothing, got %v", posted)
+	}
+	notifySync(config, "Sync", changed, nil)
+	if len(posted) != 2 || !strings.Contains(posted[1], "1 uploaded") {
+		t.Errorf("Expected the success to be posted, got %v", posted)
+	}
+}
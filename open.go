@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/browser"
+)
+
+// OpenCmd opens the live site in a browser
+type OpenCmd struct {
+	Path    string `arg:"" optional:"" help:"Page to open, e.g. /about.html"`
+	Preview bool   `help:"Open the latest preview deploy instead of the live site"`
+}
+
+func (o *OpenCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	var baseURL string
+	if o.Preview {
+		baseURL, err = fetchPreviewURL(apiClient, config.Site.SiteID)
+		if err != nil {
+			return fmt.Errorf("failed to find preview deploy: %w", err)
+		}
+	} else {
+		baseURL, err = primarySiteURL(apiClient, config.Site.SiteID)
+		if err != nil {
+			return err
+		}
+	}
+
+	target := strings.TrimSuffix(baseURL, "/")
+	if o.Path != "" {
+		target += "/" + strings.TrimPrefix(o.Path, "/")
+	}
+
+	// Scripts asking for JSON want the URL, not a browser window
+	if jsonOutput {
+		return printJSON(map[string]string{"url": target})
+	}
+
+	if ciEnvironment != nil {
+		outln(target)
+		return nil
+	}
+	outf("Opening %s\n", target)
+	openBrowser(target)
+	return nil
+}
+
+// openBrowser opens url in the user's browser, if there is one to open it
+// in, telling them to visit it manually if that fails
+func openBrowser(url string) {
+	if ciEnvironment != nil {
+		return
+	}
+	if err := browser.OpenURL(url); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not open browser automatically: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Please visit the URL above manually.\n")
+	}
+}
+
+// primarySiteURL returns the URL of an efmrl's primary domain, falling back
+// to its first domain
+func primarySiteURL(client *APIClient, siteID string) (string, error) {
+	efmrl, err := fetchEfmrl(client, siteID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch efmrl: %w", err)
+	}
+	if efmrl.PrimaryDomain != "" {
+		return "https://" + efmrl.PrimaryDomain, nil
+	}
+
+	if url := siteURL(client, siteID); url != "" {
+		return url, nil
+	}
+	return "", fmt.Errorf("efmrl has no domains (add one with 'efmrl3 domains add')")
+}
+
+// fetchPreviewURL returns the URL of the most recent preview deploy
+func fetchPreviewURL(client *APIClient, siteID string) (string, error) {
+	resp, err := client.Get(fmt.Sprintf("/admin/efmrls/%s/deploys/latest?preview=true", siteID))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("no preview deploys found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Deploy struct {
+			URL string `json:"url"`
+		} `json:"deploy"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Deploy.URL == "" {
+		return "", fmt.Errorf("server did not return a preview URL")
+	}
+
+	return result.Deploy.URL, nil
+}
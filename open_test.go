@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestOpenURL tests which URL open picks, printed as JSON rather than
+// opened in a browser
+func TestOpenURL(t *testing.T) {
+	var noPreview atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/admin/efmrls/abc":
+			w.Write([]byte(`{"efmrl": {"id": "abc", "primaryDomain": "www.example.test"}}`))
+		case r.URL.Path == "/admin/efmrls/abc/deploys/latest" && !noPreview.Load():
+			w.Write([]byte(`{"deploy": {"url": "https://preview-1.example.test/"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	siteProject(t, server)
+
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+
+	tests := []struct {
+		cmd      OpenCmd
+		expected string
+	}{
+		{OpenCmd{}, `"url": "https://www.example.test"`},
+		{OpenCmd{Path: "/about.html"}, `"url": "https://www.example.test/about.html"`},
+		{OpenCmd{Path: "docs/"}, `"url": "https://www.example.test/docs/"`},
+		{OpenCmd{Preview: true, Path: "/about.html"}, `"url": "https://preview-1.example.test/about.html"`},
+	}
+	for _, tt := range tests {
+		var err error
+		output := captureStdout(t, func() { err = tt.cmd.Run() })
+		if err != nil || !strings.Contains(output, tt.expected) {
+			t.Errorf("%+v: Expected %s, got %q (%v)", tt.cmd, tt.expected, output, err)
+		}
+	}
+
+	noPreview.Store(true)
+	var err error
+	captureStdout(t, func() { err = (&OpenCmd{Preview: true}).Run() })
+	if err == nil || !strings.Contains(err.Error(), "no preview deploys found") {
+		t.Errorf("Expected no preview deploys, got %v", err)
+	}
+}
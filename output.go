@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// jsonOutput is set by the global --json flag. Commands then print a single
+// JSON document on stdout, and anything meant for humans goes to stderr.
+var jsonOutput bool
+
+// quietOutput is set by the global --quiet flag. Progress and informational
+// messages are dropped; errors, warnings, and prompts still get through.
+var quietOutput bool
+
+// humanOutput returns where human-readable output should be written
+func humanOutput() io.Writer {
+	if quietOutput {
+		return io.Discard
+	}
+	if jsonOutput || progressEvents {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// promptOutput returns where interactive prompts should be written. Unlike
+// humanOutput, it's never silenced, since the user has to see the question.
+func promptOutput() io.Writer {
+	if jsonOutput || quietOutput || progressEvents {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// outf prints human-readable output, like fmt.Printf
+func outf(format string, a ...any) {
+	fmt.Fprintf(humanOutput(), format, a...)
+}
+
+// outln prints human-readable output, like fmt.Println
+func outln(a ...any) {
+	fmt.Fprintln(humanOutput(), a...)
+}
+
+// outputTemplate is set by the global --format flag. Commands take the same
+// path as for --json, but printJSON renders the result through the template.
+var outputTemplate *template.Template
+
+// setupFormat parses the --format template. Each item is printed on its own
+// line, so the template doesn't need a trailing newline.
+func setupFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	tmpl, err := template.New("format").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"join": strings.Join,
+	}).Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid --format template: %w", err)
+	}
+	outputTemplate = tmpl
+	jsonOutput = true
+	return nil
+}
+
+// printTemplate renders v through outputTemplate, once per item if v is a
+// slice
+func printTemplate(v any) error {
+	items := []any{v}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		items = items[:0]
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i).Interface())
+		}
+	}
+	for _, item := range items {
+		if err := outputTemplate.Execute(os.Stdout, item); err != nil {
+			return fmt.Errorf("failed to apply --format template: %w", err)
+		}
+	}
+	return nil
+}
+
+// printJSON writes v to stdout as indented JSON. Nil slices are written as
+// [] so consumers don't have to special-case null. With --format, v is
+// rendered through the template instead.
+func printJSON(v any) error {
+	if outputTemplate != nil {
+		return printTemplate(v)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+		v = []any{}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// colorOutput is set when human output goes to a terminal and color hasn't
+// been turned off with --no-color or NO_COLOR
+var colorOutput bool
+
+// setupColor decides whether to colorize output. It must run after
+// jsonOutput and quietOutput are set, since they change where output goes.
+func setupColor(disabled bool) {
+	if disabled || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		colorOutput = false
+		return
+	}
+	out := os.Stdout
+	if jsonOutput || quietOutput {
+		out = os.Stderr
+	}
+	colorOutput = isTerminal(out)
+}
+
+// paint wraps s in the given ANSI SGR code when color is enabled
+func paint(code, s string) string {
+	if !colorOutput {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// green is used for success marks
+func green(s string) string { return paint("32", s) }
+
+// red is used for failures
+func red(s string) string { return paint("31", s) }
+
+// yellow is used for warnings and missing items
+func yellow(s string) string { return paint("33", s) }
+
+// dim is used for things that didn't change
+func dim(s string) string { return paint("2", s) }
+
+// warnf prints a warning to stderr, like fmt.Fprintf with a "Warning: " prefix
+func warnf(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, yellow("Warning:")+" "+format, a...)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns whatever f writes to os.Stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+// captureStderr returns whatever f writes to os.Stderr
+func captureStderr(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	f()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+// TestPrintJSONNilSlice tests that empty lists are printed as [] rather than null
+func TestPrintJSONNilSlice(t *testing.T) {
+	var domains []Domain
+	out := captureStdout(t, func() { printJSON(domains) })
+	if strings.TrimSpace(out) != "[]" {
+		t.Errorf("Expected [], got %q", out)
+	}
+}
+
+// TestHumanOutputInJSONMode tests that human-readable output moves to stderr
+func TestHumanOutputInJSONMode(t *testing.T) {
+	defer func() { jsonOutput = false }()
+
+	if humanOutput() != os.Stdout {
+		t.Errorf("Expected human output on stdout by default")
+	}
+	jsonOutput = true
+	if humanOutput() != os.Stderr {
+		t.Errorf("Expected human output on stderr in JSON mode")
+	}
+}
+
+// TestQuietOutput tests that --quiet silences human output but not prompts
+func TestQuietOutput(t *testing.T) {
+	defer func() { quietOutput = false }()
+
+	quietOutput = true
+	if humanOutput() != io.Discard {
+		t.Errorf("Expected human output to be discarded in quiet mode")
+	}
+	if promptOutput() != os.Stderr {
+		t.Errorf("Expected prompts on stderr in quiet mode")
+	}
+}
+
+// TestPaint tests that colors are only applied when enabled
+func TestPaint(t *testing.T) {
+	defer func() { colorOutput = false }()
+
+	colorOutput = false
+	if got := green("✓"); got != "✓" {
+		t.Errorf("Expected plain text without color, got %q", got)
+	}
+
+	colorOutput = true
+	if got := red("FAILED"); got != "\x1b[31mFAILED\x1b[0m" {
+		t.Errorf("Expected red FAILED, got %q", got)
+	}
+}
+
+// TestSetupColorNoColor tests that NO_COLOR and --no-color disable color
+func TestSetupColorNoColor(t *testing.T) {
+	defer func() { colorOutput = false }()
+
+	colorOutput = true
+	setupColor(true)
+	if colorOutput {
+		t.Errorf("Expected --no-color to disable color")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	colorOutput = true
+	setupColor(false)
+	if colorOutput {
+		t.Errorf("Expected NO_COLOR to disable color")
+	}
+}
+
+// TestFormatTemplate tests that --format renders each item of a list
+func TestFormatTemplate(t *testing.T) {
+	defer func() {
+		jsonOutput = false
+		outputTemplate = nil
+	}()
+
+	if err := setupFormat("{{.ID}} {{.Name}}"); err != nil {
+		t.Fatalf("Failed to set up format: %v", err)
+	}
+	if !jsonOutput {
+		t.Errorf("Expected --format to take the JSON output path")
+	}
+
+	sites := []Efmrl{{ID: "abc", Name: "blog"}, {ID: "def", Name: "docs"}}
+	output := captureStdout(t, func() {
+		if err := printJSON(sites); err != nil {
+			t.Errorf("printJSON failed: %v", err)
+		}
+	})
+	if output != "abc blog\ndef docs\n" {
+		t.Errorf("Expected one line per site, got %q", output)
+	}
+
+	output = captureStdout(t, func() {
+		if err := printJSON(sites[0]); err != nil {
+			t.Errorf("printJSON failed: %v", err)
+		}
+	})
+	if output != "abc blog\n" {
+		t.Errorf("Expected a single line, got %q", output)
+	}
+}
+
+// TestFormatTemplateInvalid tests that a bad template is rejected up front
+func TestFormatTemplateInvalid(t *testing.T) {
+	defer func() {
+		jsonOutput = false
+		outputTemplate = nil
+	}()
+
+	if err := setupFormat("{{.ID"); err == nil {
+		t.Errorf("Expected an error for an unclosed action")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alecthomas/kong"
+)
+
+// reportParseError prints a command-line parse error with a short hint for
+// the command that was being parsed, rather than the full usage. If a
+// subcommand is missing, it lists the subcommands to choose from. (kong's
+// errors already include "did you mean" suggestions for typos.)
+func reportParseError(parser *kong.Kong, err error) {
+	var perr *kong.ParseError
+	if !errors.As(err, &perr) || perr.Context == nil {
+		parser.Errorf("%s", err)
+		return
+	}
+	node := perr.Context.Selected()
+	if node == nil {
+		node = perr.Context.Model.Node
+	}
+	path := docCommandPath(node)
+
+	children := docChildren(node)
+	if len(children) == 0 || !strings.HasPrefix(err.Error(), "expected ") {
+		parser.Errorf("%s", err)
+		fmt.Fprintf(parser.Stderr, "Run \"%s --help\" for usage.\n", path)
+		return
+	}
+
+	parser.Errorf("missing command")
+	fmt.Fprintf(parser.Stderr, "\n%s needs one of these commands:\n", path)
+	tw := tabwriter.NewWriter(parser.Stderr, 0, 0, 2, ' ', 0)
+	for _, child := range children {
+		fmt.Fprintf(tw, "  %s\t%s\n", child.Name, child.Help)
+	}
+	tw.Flush()
+	fmt.Fprintf(parser.Stderr, "\nRun \"%s <command> --help\" for more information on a command.\n", path)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+// TestReportParseError tests the hints printed for bad command lines
+func TestReportParseError(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"domians", "list"}, []string{`did you mean "domains"?`, `Run "efmrl3 --help"`}},
+		{[]string{"domains"}, []string{"missing command", "set-primary", `Run "efmrl3 domains <command> --help"`}},
+		{[]string{"sync", "--dry-rn"}, []string{`did you mean "--dry-run"?`, `Run "efmrl3 sync --help"`}},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		parser, err := kong.New(&CLI, kong.Name("efmrl3"), kong.Writers(&out, &out))
+		if err != nil {
+			t.Fatalf("Failed to build parser: %v", err)
+		}
+
+		_, err = parser.Parse(tt.args)
+		if err == nil {
+			t.Errorf("%v: expected a parse error", tt.args)
+			continue
+		}
+		reportParseError(parser, err)
+
+		for _, want := range tt.expected {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%v: expected output to contain %q, got:\n%s", tt.args, want, out.String())
+			}
+		}
+		if strings.Contains(out.String(), "Flags:") {
+			t.Errorf("%v: expected no full usage, got:\n%s", tt.args, out.String())
+		}
+	}
+}
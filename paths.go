@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// Path handling that differs between operating systems. The per-OS parts
+// are in paths_windows.go and paths_other.go.
+//
+// Long Windows paths need no special care here: the os package adds the
+// \\?\ prefix itself to absolute paths past MAX_PATH, which is why the
+// directories that are walked are made absolute first.
+
+// isHidden reports whether a file or directory is hidden and so left out of
+// syncs: its name starts with a dot, or on Windows, it has the hidden
+// attribute
+func isHidden(info fs.FileInfo) bool {
+	return strings.HasPrefix(info.Name(), ".") || hasHiddenAttribute(info)
+}
+
+// pathKey is the form of a local path to compare for equality: on
+// case-insensitive filesystems, paths that differ only in case are the same
+// file
+func pathKey(p string) string {
+	if caseInsensitiveFS {
+		return strings.ToLower(p)
+	}
+	return p
+}
+
+// isDriveRelative reports whether p is a Windows path like "C:public",
+// relative to the current directory of a drive rather than to the project.
+// It's checked on every OS, since config files are shared.
+func isDriveRelative(p string) bool {
+	if len(p) < 2 || p[1] != ':' {
+		return false
+	}
+	if c := p[0] | 0x20; c < 'a' || c > 'z' {
+		return false
+	}
+	return len(p) == 2 || (p[2] != '\\' && p[2] != '/')
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import (
+	"io/fs"
+	"runtime"
+)
+
+// caseInsensitiveFS is whether local paths that differ only in case name
+// the same file, as they do on macOS's default filesystem
+const caseInsensitiveFS = runtime.GOOS == "darwin"
+
+// hasHiddenAttribute is always false: outside Windows, only a leading dot
+// hides a file
+func hasHiddenAttribute(info fs.FileInfo) bool {
+	return false
+}
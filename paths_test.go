@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsDriveRelative(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"C:public", true},
+		{"d:", true},
+		{"C:\\site\\public", false},
+		{"C:/site/public", false},
+		{"public", false},
+		{"./C:public", false},
+		{"1:public", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isDriveRelative(tt.path); got != tt.want {
+			t.Errorf("isDriveRelative(%q): Expected %v, got %v", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestPathKey(t *testing.T) {
+	same := pathKey("Sites/Blog") == pathKey("sites/blog")
+	if same != caseInsensitiveFS {
+		t.Errorf("Expected paths differing in case to match only on case-insensitive filesystems, got %v", same)
+	}
+}
+
+// TestValidateDriveRelativeDir tests the warning for dirs that depend on a
+// drive's current directory
+func TestValidateDriveRelativeDir(t *testing.T) {
+	config := &Config{
+		Site:  SiteConfig{SiteID: "abc", Dir: "C:public"},
+		Build: BuildConfig{Command: "make", OutputDir: "D:\\out"},
+	}
+
+	warnings := strings.Join(config.validate(), "\n")
+	if !strings.Contains(warnings, `[site] dir "C:public" is relative to the current directory of drive C:`) {
+		t.Errorf("Expected a warning about [site] dir, got %q", warnings)
+	}
+	if strings.Contains(warnings, "output_dir") {
+		t.Errorf("Expected no warning about an absolute output_dir, got %q", warnings)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// caseInsensitiveFS is whether local paths that differ only in case name
+// the same file, as they do on NTFS
+const caseInsensitiveFS = true
+
+// hasHiddenAttribute reports whether a file has the hidden attribute, which
+// Windows uses where other systems use a leading dot
+func hasHiddenAttribute(info fs.FileInfo) bool {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	return ok && data.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+}
@@ -0,0 +1,45 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// setHidden gives a file or directory the hidden attribute
+func setHidden(t *testing.T, path string) {
+	t.Helper()
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.SetFileAttributes(p, syscall.FILE_ATTRIBUTE_HIDDEN); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestScanSkipsHiddenAttribute tests that files and directories hidden by
+// attribute are left out, like dotfiles
+func TestScanSkipsHiddenAttribute(t *testing.T) {
+	dir, _ := writeSite(t, map[string]string{
+		"index.html":         "<h1>Hi</h1>",
+		"desktop.ini":        "[.ShellClassInfo]",
+		"private/notes.html": "secret",
+	})
+	setHidden(t, filepath.Join(dir, "desktop.ini"))
+	setHidden(t, filepath.Join(dir, "private"))
+
+	files, err := scanLocalFiles(dir)
+	if err != nil {
+		t.Fatalf("scanLocalFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "/index.html" {
+		t.Errorf("Expected only /index.html, got %+v", files)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "desktop.ini")); err != nil {
+		t.Errorf("Expected the hidden file to still exist, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import "runtime"
+
+// Defaults for the [performance] settings left unset
+const (
+	maxDefaultHashWorkers    = 4
+	defaultUploadConcurrency = 1
+	defaultDeleteConcurrency = 8
+	defaultStreamBuffer      = 256
+)
+
+// PerformanceConfig tunes how much sync does at once, for slow machines or
+// fast connections. It can go in the global config, for every project on
+// the machine, and in a project's config, which takes precedence. Zero
+// means the default.
+type PerformanceConfig struct {
+	// HashWorkers is how many files are hashed at once. It defaults to the
+	// number of CPUs, up to 4.
+	HashWorkers int `toml:"hash_workers,omitempty" json:"hash_workers,omitempty" yaml:"hash_workers,omitempty"`
+
+	// UploadConcurrency is how many files are uploaded at once, outside
+	// batches. It defaults to 1, which reports each upload as it starts.
+	UploadConcurrency int `toml:"upload_concurrency,omitempty" json:"upload_concurrency,omitempty" yaml:"upload_concurrency,omitempty"`
+
+	// DeleteConcurrency is how many files are deleted at once when the
+	// server has no bulk delete. It defaults to 8.
+	DeleteConcurrency int `toml:"delete_concurrency,omitempty" json:"delete_concurrency,omitempty" yaml:"delete_concurrency,omitempty"`
+
+	// StreamBuffer is how many files may wait between the stages of a
+	// streaming sync, which bounds how far the scan runs ahead of the
+	// uploads. It defaults to 256.
+	StreamBuffer int `toml:"stream_buffer,omitempty" json:"stream_buffer,omitempty" yaml:"stream_buffer,omitempty"`
+}
+
+// performance is the settings in effect, as set by setPerformance
+var performance = defaultPerformance()
+
+func defaultPerformance() PerformanceConfig {
+	return PerformanceConfig{
+		HashWorkers:       min(runtime.NumCPU(), maxDefaultHashWorkers),
+		UploadConcurrency: defaultUploadConcurrency,
+		DeleteConcurrency: defaultDeleteConcurrency,
+		StreamBuffer:      defaultStreamBuffer,
+	}
+}
+
+// overlay returns p with the settings o sets in place of its own
+func (p PerformanceConfig) overlay(o PerformanceConfig) PerformanceConfig {
+	if o.HashWorkers > 0 {
+		p.HashWorkers = o.HashWorkers
+	}
+	if o.UploadConcurrency > 0 {
+		p.UploadConcurrency = o.UploadConcurrency
+	}
+	if o.DeleteConcurrency > 0 {
+		p.DeleteConcurrency = o.DeleteConcurrency
+	}
+	if o.StreamBuffer > 0 {
+		p.StreamBuffer = o.StreamBuffer
+	}
+	return p
+}
+
+// setPerformance applies the [performance] settings of the global config,
+// then of a project's, to the syncs that follow. A global config that can't
+// be read is left out.
+func setPerformance(config *Config) {
+	p := defaultPerformance()
+	if global, err := LoadGlobalConfig(); err == nil {
+		p = p.overlay(global.Performance)
+	}
+	performance = p.overlay(config.Performance)
+}
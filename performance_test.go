@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withPerformance sets the performance settings for a test
+func withPerformance(t *testing.T, p PerformanceConfig) {
+	saved := performance
+	performance = defaultPerformance().overlay(p)
+	t.Cleanup(func() { performance = saved })
+}
+
+func TestSetPerformance(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	saved := performance
+	t.Cleanup(func() { performance = saved })
+
+	global := filepath.Join(home, GlobalConfigDir, GlobalConfigFileName)
+	os.MkdirAll(filepath.Dir(global), 0700)
+	os.WriteFile(global, []byte("[performance]\nhash_workers = 2\nupload_concurrency = 3\n"), 0600)
+
+	setPerformance(&Config{Performance: PerformanceConfig{UploadConcurrency: 6}})
+	want := PerformanceConfig{
+		HashWorkers:       2,
+		UploadConcurrency: 6,
+		DeleteConcurrency: defaultDeleteConcurrency,
+		StreamBuffer:      defaultStreamBuffer,
+	}
+	if performance != want {
+		t.Errorf("Expected %+v, got %+v", want, performance)
+	}
+}
+
+// TestGlobalConfigWithoutPerformance tests that a global config without
+// performance settings is saved without an empty table
+func TestGlobalConfigWithoutPerformance(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config := &GlobalConfig{}
+	config.SetHostCredentials("example.com", HostCredentials{AccessToken: "token"})
+	if err := SaveGlobalConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	path, _ := GetGlobalConfigPath()
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "performance") {
+		t.Errorf("Expected no [performance] table, got:\n%s", data)
+	}
+}
+
+func TestHashWorkers(t *testing.T) {
+	withPerformance(t, PerformanceConfig{HashWorkers: 4})
+
+	content := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		content[name+".txt"] = strings.Repeat(name, 100)
+	}
+	_, files := writeSite(t, content)
+	for i := range files {
+		files[i].ETag = ""
+	}
+	progress := &scanProgress{}
+	if err := hashLocalFilesProgress(files, func(*LocalFile) bool { return true }, progress); err != nil {
+		t.Fatal(err)
+	}
+	for _, lf := range files {
+		if want := md5Hex(content[lf.Path[1:]]); lf.ETag != want {
+			t.Errorf("Expected ETag %s for %s, got %s", want, lf.Path, lf.ETag)
+		}
+	}
+	if got := progress.files.Load(); got != 8 {
+		t.Errorf("Expected 8 files hashed, got %d", got)
+	}
+}
+
+func TestUploadConcurrency(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+	withPerformance(t, PerformanceConfig{UploadConcurrency: 3})
+
+	site := map[string]string{}
+	server := fakeSite(t, site, "/broken.html")
+	client, _ := NewAPIClient(server.URL)
+
+	content := map[string]string{"broken.html": "x"}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		content[name+".html"] = name
+	}
+	_, files := writeSite(t, content)
+
+	var err error
+	output := captureStdout(t, func() { err = executeSyncPlan(client, "abc", SyncPlan{ToUpload: files}, true, nil) })
+	var failures *SyncFailuresError
+	if !errors.As(err, &failures) || len(failures.Failures) != 1 {
+		t.Fatalf("Expected /broken.html to fail, got %v", err)
+	}
+	if len(site) != 5 {
+		t.Errorf("Expected 5 files uploaded, got %d", len(site))
+	}
+	if !strings.Contains(output, "[6/6] ") {
+		t.Errorf("Expected every upload reported, got:\n%s", output)
+	}
+}
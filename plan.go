@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// PlanCmd shows and changes the account's plan
+type PlanCmd struct {
+	Show    PlanShowCmd    `cmd:"" default:"1" help:"Show the account's plan and quota limits"`
+	Upgrade PlanUpgradeCmd `cmd:"" help:"Request a change to a different plan"`
+}
+
+// Plan describes a plan tier and the limits that come with it
+type Plan struct {
+	Tier        string `json:"tier"`
+	Name        string `json:"name,omitempty"`
+	MaxSpace    int64  `json:"maxSpace"`
+	MaxSites    int    `json:"maxSites,omitempty"`
+	MaxFileSize int64  `json:"maxFileSize,omitempty"`
+	ExpiryDays  int    `json:"expiryDays,omitempty"`
+	Price       string `json:"price,omitempty"`
+}
+
+// AccountPlan is the current plan plus the plans the account can move to
+type AccountPlan struct {
+	Plan      Plan   `json:"plan"`
+	Available []Plan `json:"available,omitempty"`
+	Pending   string `json:"pending,omitempty"`
+}
+
+// PlanShowCmd prints the account's tier and limits
+type PlanShowCmd struct{}
+
+func (p *PlanShowCmd) Run() error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	var account AccountPlan
+	if err := getJSON(apiClient, "/admin/account/plan", &account); err != nil {
+		return fmt.Errorf("failed to fetch plan: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(account)
+	}
+
+	outf("Plan: %s\n", planName(account.Plan))
+	printPlanLimits(account.Plan, "  ")
+	if account.Pending != "" {
+		outf("  Pending change: %s\n", account.Pending)
+	}
+
+	// Usage is per site, so only show it when a site is configured
+	if config.Site.SiteID != "" {
+		if quota, err := fetchQuota(apiClient, config.Site.SiteID); err == nil {
+			outf("\nThis efmrl uses %s of %s\n", formatBytes(quota.CurrentSpace), formatBytes(quota.MaxSpace))
+		}
+	}
+
+	var upgrades []Plan
+	for _, plan := range account.Available {
+		if plan.Tier != account.Plan.Tier {
+			upgrades = append(upgrades, plan)
+		}
+	}
+	if len(upgrades) > 0 {
+		outln("\nOther plans:")
+		for _, plan := range upgrades {
+			outf("  %s", planName(plan))
+			if plan.Price != "" {
+				outf(" (%s)", plan.Price)
+			}
+			outf(": %s per site\n", formatBytes(plan.MaxSpace))
+		}
+		outln("\nTo change plans, run: efmrl3 plan upgrade <tier>")
+	}
+
+	return nil
+}
+
+// planName returns a plan's display name, falling back to its tier
+func planName(plan Plan) string {
+	if plan.Name != "" {
+		return plan.Name
+	}
+	return plan.Tier
+}
+
+// printPlanLimits prints the non-zero limits of a plan
+func printPlanLimits(plan Plan, indent string) {
+	outf("%sSpace per site: %s\n", indent, formatBytes(plan.MaxSpace))
+	if plan.MaxFileSize > 0 {
+		outf("%sMax file size:  %s\n", indent, formatBytes(plan.MaxFileSize))
+	}
+	if plan.MaxSites > 0 {
+		outf("%sSites:          %d\n", indent, plan.MaxSites)
+	}
+	if plan.ExpiryDays > 0 {
+		outf("%sSites expire:   after %d days\n", indent, plan.ExpiryDays)
+	}
+}
+
+// PlanUpgradeCmd requests a change to another plan tier
+type PlanUpgradeCmd struct {
+	Tier string `arg:"" help:"Plan tier to change to (see 'efmrl3 plan show')"`
+	Yes  bool   `help:"Skip the confirmation prompt" short:"y"`
+}
+
+func (p *PlanUpgradeCmd) Run() error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	var account AccountPlan
+	if err := getJSON(apiClient, "/admin/account/plan", &account); err != nil {
+		return fmt.Errorf("failed to fetch plan: %w", err)
+	}
+
+	if strings.EqualFold(account.Plan.Tier, p.Tier) {
+		outf("Already on the %s plan\n", planName(account.Plan))
+		if jsonOutput {
+			return printJSON(map[string]Plan{"plan": account.Plan})
+		}
+		return nil
+	}
+
+	var target *Plan
+	var tiers []string
+	for i := range account.Available {
+		tiers = append(tiers, account.Available[i].Tier)
+		if strings.EqualFold(account.Available[i].Tier, p.Tier) {
+			target = &account.Available[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("unknown plan %q (available: %s)", p.Tier, strings.Join(tiers, ", "))
+	}
+
+	if !p.Yes {
+		outf("Change from %s to %s", planName(account.Plan), planName(*target))
+		if target.Price != "" {
+			outf(" (%s)", target.Price)
+		}
+		outln(":")
+		printPlanLimits(*target, "  ")
+		ok, err := askYesNo("Continue?")
+		if err != nil {
+			return fmt.Errorf("%w (use --yes to skip the confirmation)", err)
+		}
+		if !ok {
+			return fmt.Errorf("plan change cancelled")
+		}
+	}
+
+	resp, err := apiClient.Post("/admin/account/plan", map[string]string{"tier": target.Tier})
+	if err != nil {
+		return fmt.Errorf("failed to change plan: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// The change either applies immediately, or needs to be completed (for
+	// example, by entering payment details) at a URL the server returns
+	var result struct {
+		Plan Plan   `json:"plan"`
+		URL  string `json:"url,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if result.URL != "" {
+		outf("Complete the change at: %s\n", result.URL)
+		openBrowser(result.URL)
+	} else {
+		outf("%s Changed to the %s plan\n", green("✓"), planName(result.Plan))
+		printPlanLimits(result.Plan, "  ")
+	}
+
+	if jsonOutput {
+		return printJSON(result)
+	}
+	return nil
+}
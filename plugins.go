@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// PluginPrefix is the prefix of external commands on PATH: an executable
+// named efmrl3-approve becomes 'efmrl3 approve', like git's external commands
+const PluginPrefix = "efmrl3-"
+
+// PluginCmd runs an external efmrl3-<name> command. Everything after the
+// command name is passed through untouched, and the global flags are passed
+// in the environment:
+//
+//	EFMRL_HOST, EFMRL_SITE, EFMRL_SITE_ID  --host, --site, --site-id
+//	EFMRL_JSON, EFMRL_QUIET                "1" with --json, --quiet
+//	EFMRL_FORMAT                           --format
+//	EFMRL_NON_INTERACTIVE                  "1" when prompts aren't allowed
+//	NO_COLOR                               "1" when color is off
+//	EFMRL_BIN                              this efmrl3 binary, to call back into
+type PluginCmd struct {
+	Args []string `arg:"" optional:"" help:"Arguments for the plugin"`
+
+	name string
+	path string
+}
+
+// PluginExitError is returned when a plugin exits with a non-zero status,
+// which efmrl3 then exits with too
+type PluginExitError struct {
+	Name string
+	Code int
+}
+
+func (e *PluginExitError) Error() string {
+	return fmt.Sprintf("plugin %s%s exited with status %d", PluginPrefix, e.Name, e.Code)
+}
+func (e *PluginExitError) ExitCode() int { return e.Code }
+
+func (p *PluginCmd) Run() error {
+	cmd := exec.Command(p.path, p.Args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), pluginEnv()...)
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &PluginExitError{Name: p.name, Code: exitErr.ExitCode()}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run plugin %s: %w", p.path, err)
+	}
+	return nil
+}
+
+// pluginEnv returns the environment that passes global flags to plugins
+func pluginEnv() []string {
+	var env []string
+	set := func(key, value string) {
+		if value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	flag := func(key string, on bool) {
+		if on {
+			env = append(env, key+"=1")
+		}
+	}
+
+	set("EFMRL_HOST", hostOverride)
+	set("EFMRL_SITE", siteOverride)
+	set("EFMRL_SITE_ID", siteIDOverride)
+	flag("EFMRL_JSON", jsonOutput && outputTemplate == nil)
+	set("EFMRL_FORMAT", CLI.Format)
+	flag("EFMRL_QUIET", quietOutput)
+	flag("EFMRL_NON_INTERACTIVE", nonInteractive)
+	flag("NO_COLOR", !colorOutput)
+	if exe, err := os.Executable(); err == nil {
+		set("EFMRL_BIN", exe)
+	}
+	return env
+}
+
+// findPlugins returns the efmrl3-<name> executables on PATH, keyed by name.
+// Earlier PATH entries win, as they would in a shell.
+func findPlugins(pathList string) map[string]string {
+	plugins := map[string]string{}
+	for _, dir := range filepath.SplitList(pathList) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+			if !ok || name == "" || entry.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				if name, ok = strings.CutSuffix(name, ".exe"); !ok {
+					continue
+				}
+			} else if info, err := entry.Info(); err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+			if _, seen := plugins[name]; !seen {
+				plugins[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+	return plugins
+}
+
+// pluginOptions registers each plugin on PATH as a command. Plugins can't
+// replace built-in commands.
+func pluginOptions(builtins *kong.Node) []kong.Option {
+	taken := map[string]bool{}
+	for _, child := range builtins.Children {
+		taken[child.Name] = true
+		for _, alias := range child.Aliases {
+			taken[alias] = true
+		}
+	}
+
+	plugins := findPlugins(os.Getenv("PATH"))
+	var options []kong.Option
+	for _, name := range slices.Sorted(maps.Keys(plugins)) {
+		if taken[name] {
+			continue
+		}
+		path := plugins[name]
+		cmd := &PluginCmd{name: name, path: path}
+		options = append(options, kong.DynamicCommand(name, "Plugin: "+path, "", cmd, "cmd", "passthrough"))
+	}
+	return options
+}
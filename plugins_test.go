@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+// writePlugin creates an efmrl3-<name> script in dir
+func writePlugin(t *testing.T, dir, name string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, PluginPrefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), mode); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	return path
+}
+
+// TestFindPlugins tests plugin discovery on PATH
+func TestFindPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by .exe suffix on windows")
+	}
+
+	first := t.TempDir()
+	second := t.TempDir()
+	approve := writePlugin(t, first, "approve", 0755)
+	writePlugin(t, second, "approve", 0755)
+	notify := writePlugin(t, second, "notify", 0755)
+	writePlugin(t, second, "readme", 0644)
+	if err := os.Mkdir(filepath.Join(second, PluginPrefix+"dir"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	plugins := findPlugins(first + string(os.PathListSeparator) + second)
+
+	expected := map[string]string{"approve": approve, "notify": notify}
+	if len(plugins) != len(expected) {
+		t.Errorf("Expected plugins %v, got %v", expected, plugins)
+	}
+	for name, path := range expected {
+		if plugins[name] != path {
+			t.Errorf("Expected %s at %s, got %q", name, path, plugins[name])
+		}
+	}
+}
+
+// TestPluginsCannotShadowBuiltins tests that built-in commands win over
+// plugins of the same name
+func TestPluginsCannotShadowBuiltins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by .exe suffix on windows")
+	}
+
+	dir := t.TempDir()
+	writePlugin(t, dir, "sync", 0755)
+	approve := writePlugin(t, dir, "approve", 0755)
+	t.Setenv("PATH", dir)
+
+	builtins, err := kong.New(&CLI, kong.Name("efmrl3"))
+	if err != nil {
+		t.Fatalf("Failed to build parser: %v", err)
+	}
+	parser, err := kong.New(&CLI, append([]kong.Option{kong.Name("efmrl3")}, pluginOptions(builtins.Model.Node)...)...)
+	if err != nil {
+		t.Fatalf("Failed to build parser with plugins: %v", err)
+	}
+
+	ctx, err := parser.Parse([]string{"approve", "--env", "prod", "release"})
+	if err != nil {
+		t.Fatalf("Failed to parse plugin command: %v", err)
+	}
+	plugin, ok := ctx.Selected().Target.Addr().Interface().(*PluginCmd)
+	if !ok {
+		t.Fatalf("Expected a plugin command, got %s", ctx.Command())
+	}
+	if plugin.path != approve {
+		t.Errorf("Expected plugin path %s, got %s", approve, plugin.path)
+	}
+	if len(plugin.Args) != 3 || plugin.Args[0] != "--env" {
+		t.Errorf("Expected args to be passed through, got %v", plugin.Args)
+	}
+
+	if ctx, err := parser.Parse([]string{"sync"}); err != nil || ctx.Command() != "sync" {
+		t.Errorf("Expected the built-in sync command, got %v", err)
+	}
+}
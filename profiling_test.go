@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartProfiling(t *testing.T) {
+	dir := t.TempDir()
+	cpu := filepath.Join(dir, "cpu.pprof")
+	trace := filepath.Join(dir, "trace.out")
+
+	stop, err := startProfiling(cpu, trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+
+	for _, path := range []string{cpu, trace} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("Expected %s written, got %v", filepath.Base(path), err)
+		}
+	}
+
+	if _, err := startProfiling(filepath.Join(dir, "missing", "cpu.pprof"), ""); err == nil {
+		t.Errorf("Expected an error for a profile that can't be created")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressEvents is set by sync --progress-json. Sync then streams one JSON
+// object per line on stdout as it goes, for wrappers that draw their own
+// progress UI, and human-readable output moves to stderr.
+//
+// Every event has "event" and "time" keys. The events are:
+//
+//	scan_started   dir
+//	scan_complete  files, bytes
+//	plan_ready     upload, delete, unchanged
+//	delete_done    path, current, total
+//	file_uploaded  path, bytes, current, total
+//	op_failed      path, current, total, error
+//	sync_complete  siteId, dryRun, uploaded, deleted, unchanged, deployId
+var progressEvents bool
+
+var progressMu sync.Mutex
+
+// enableProgressEvents turns on the --progress-json event stream
+func enableProgressEvents() error {
+	if jsonOutput {
+		return errors.New("--progress-json can't be combined with --json or --format")
+	}
+	progressEvents = true
+	return nil
+}
+
+// emitEvent writes a progress event to stdout when --progress-json is set
+func emitEvent(event string, fields map[string]any) {
+	if !progressEvents {
+		return
+	}
+
+	line := map[string]any{
+		"event": event,
+		"time":  time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	for key, value := range fields {
+		line[key] = value
+	}
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	os.Stdout.Write(append(data, '\n'))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestEmitEvent tests that progress events are written as one JSON object
+// per line, and only with --progress-json
+func TestEmitEvent(t *testing.T) {
+	defer func() { progressEvents = false }()
+
+	output := captureStdout(t, func() {
+		emitEvent("scan_started", map[string]any{"dir": "/site"})
+	})
+	if output != "" {
+		t.Errorf("Expected no events without --progress-json, got %q", output)
+	}
+
+	if err := enableProgressEvents(); err != nil {
+		t.Fatalf("Failed to enable progress events: %v", err)
+	}
+	if humanOutput() != os.Stderr {
+		t.Errorf("Expected human output on stderr with --progress-json")
+	}
+
+	output = captureStdout(t, func() {
+		emitEvent("scan_started", map[string]any{"dir": "/site"})
+		emitEvent("file_uploaded", map[string]any{"path": "/index.html", "current": 1, "total": 2})
+	})
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", output)
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", lines[1], err)
+	}
+	if event["event"] != "file_uploaded" || event["path"] != "/index.html" || event["time"] == nil {
+		t.Errorf("Unexpected event: %v", event)
+	}
+}
+
+// TestProgressEventsWithJSON tests that --progress-json and --json conflict,
+// since both want stdout
+func TestProgressEventsWithJSON(t *testing.T) {
+	defer func() {
+		jsonOutput = false
+		progressEvents = false
+	}()
+	jsonOutput = true
+
+	if err := enableProgressEvents(); err == nil {
+		t.Errorf("Expected an error combining --progress-json with --json")
+	}
+}
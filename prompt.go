@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdinReader is shared by all prompts so buffered input isn't lost between
+// consecutive questions
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// nonInteractive is set by --non-interactive, or automatically when stdin
+// isn't a terminal or in CI. Prompts then fail instead of waiting for input.
+var nonInteractive bool
+
+// detectNonInteractive reports whether nobody is around to answer prompts
+func detectNonInteractive() bool {
+	if detectCI() != nil {
+		return true
+	}
+	return !term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// errPromptNotAllowed returns the error for a prompt that can't be shown in
+// non-interactive mode
+func errPromptNotAllowed(prompt string) error {
+	return fmt.Errorf("cannot prompt for %q in non-interactive mode",
+		strings.TrimRight(strings.TrimSpace(prompt), ":?"))
+}
+
+// askLine prints a prompt and returns the trimmed line typed in response
+func askLine(prompt string) (string, error) {
+	if nonInteractive {
+		return "", errPromptNotAllowed(prompt)
+	}
+	fmt.Fprint(promptOutput(), prompt)
+	answer, err := stdinReader.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// askYesNo asks a yes/no question on stdin, defaulting to yes. It fails in
+// non-interactive mode rather than assuming an answer.
+func askYesNo(question string) (bool, error) {
+	return askConfirm(question, true)
+}
+
+// askConfirm asks a yes/no question, returning def if the user just presses
+// enter. Destructive questions should default to no. It fails in
+// non-interactive mode rather than assuming an answer.
+func askConfirm(question string, def bool) (bool, error) {
+	hint := " [y/N] "
+	if def {
+		hint = " [Y/n] "
+	}
+	for {
+		answer, err := askLine(question + hint)
+		if err != nil {
+			if nonInteractive {
+				return false, err
+			}
+			return false, nil
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(promptOutput(), "Please answer y or n.")
+	}
+}
+
+// askInput asks for a line of text, offering def when it isn't empty, and
+// asks again until validate (if given) accepts the answer. In non-interactive
+// mode it falls back to def, or fails if there's no valid default.
+func askInput(prompt, def string, validate func(string) error) (string, error) {
+	if validate == nil {
+		validate = func(string) error { return nil }
+	}
+	if nonInteractive {
+		if err := validate(def); err != nil {
+			return "", errPromptNotAllowed(prompt)
+		}
+		return def, nil
+	}
+
+	label := prompt + ": "
+	if def != "" {
+		label = fmt.Sprintf("%s [%s]: ", prompt, def)
+	}
+	for {
+		answer, err := askLine(label)
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = def
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(promptOutput(), "%s\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// askSelect asks the user to pick one of options, by number or by name, and
+// returns its index. def is the index chosen by pressing enter, or -1 for no
+// default; in non-interactive mode def is chosen without asking, and it's an
+// error if there isn't one.
+func askSelect(prompt string, options []string, def int) (int, error) {
+	if nonInteractive {
+		if def < 0 || def >= len(options) {
+			return -1, errPromptNotAllowed(prompt)
+		}
+		return def, nil
+	}
+
+	fmt.Fprintf(promptOutput(), "%s\n", prompt)
+	for i, option := range options {
+		marker := " "
+		if i == def {
+			marker = "*"
+		}
+		fmt.Fprintf(promptOutput(), " %s %d) %s\n", marker, i+1, option)
+	}
+
+	label := "Choice: "
+	if def >= 0 && def < len(options) {
+		label = fmt.Sprintf("Choice [%d]: ", def+1)
+	}
+	for {
+		answer, err := askLine(label)
+		if err != nil {
+			return -1, err
+		}
+		if answer == "" && def >= 0 && def < len(options) {
+			return def, nil
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1, nil
+		}
+		for i, option := range options {
+			if strings.EqualFold(answer, option) {
+				return i, nil
+			}
+		}
+		fmt.Fprintf(promptOutput(), "Please enter a number from 1 to %d.\n", len(options))
+	}
+}
+
+// askSecret prompts for a value without echoing it when stdin is a terminal;
+// piped input is read as a plain line so secrets can be scripted, even in
+// non-interactive mode
+func askSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		answer, err := stdinReader.ReadString('\n')
+		if err != nil && answer == "" {
+			return "", err
+		}
+		return strings.TrimRight(answer, "\r\n"), nil
+	}
+	if nonInteractive {
+		return "", errPromptNotAllowed(prompt)
+	}
+
+	fmt.Fprint(promptOutput(), prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(promptOutput())
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
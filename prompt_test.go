@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestNonInteractivePrompts tests that prompts fail instead of blocking
+func TestNonInteractivePrompts(t *testing.T) {
+	defer func() { nonInteractive = false }()
+	nonInteractive = true
+
+	if _, err := askLine("Type \"site\" to confirm: "); err == nil {
+		t.Errorf("Expected askLine to fail in non-interactive mode")
+	}
+	if ok, err := askYesNo("Continue?"); err == nil || ok {
+		t.Errorf("Expected askYesNo to fail in non-interactive mode, got %v, %v", ok, err)
+	}
+}
+
+// TestDetectNonInteractiveCI tests that CI=true turns off prompts
+func TestDetectNonInteractiveCI(t *testing.T) {
+	t.Setenv("CI", "true")
+	if !detectNonInteractive() {
+		t.Errorf("Expected CI=true to enable non-interactive mode")
+	}
+}
+
+// withStdin answers prompts with input for the rest of the test
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	reader := stdinReader
+	stdinReader = bufio.NewReader(strings.NewReader(input))
+	t.Cleanup(func() { stdinReader = reader })
+}
+
+// TestAskConfirm tests defaults and re-asking on unclear answers
+func TestAskConfirm(t *testing.T) {
+	withStdin(t, "\nmaybe\nyes\n")
+	captureStdout(t, func() {
+		if ok, err := askConfirm("Delete?", false); err != nil || ok {
+			t.Errorf("Expected enter to pick the default no, got %v, %v", ok, err)
+		}
+		if ok, err := askConfirm("Delete?", false); err != nil || !ok {
+			t.Errorf("Expected yes after re-asking, got %v, %v", ok, err)
+		}
+	})
+}
+
+// TestAskInput tests defaults, validation, and the non-interactive fallback
+func TestAskInput(t *testing.T) {
+	defer func() { nonInteractive = false }()
+	notEmpty := func(s string) error {
+		if s == "" {
+			return errors.New("required")
+		}
+		return nil
+	}
+
+	withStdin(t, "\n\nblog\n")
+	captureStdout(t, func() {
+		if answer, err := askInput("Dir", "public", notEmpty); err != nil || answer != "public" {
+			t.Errorf("Expected the default, got %q, %v", answer, err)
+		}
+		if answer, err := askInput("Name", "", notEmpty); err != nil || answer != "blog" {
+			t.Errorf("Expected blog after re-asking, got %q, %v", answer, err)
+		}
+	})
+
+	nonInteractive = true
+	if answer, err := askInput("Dir", "public", notEmpty); err != nil || answer != "public" {
+		t.Errorf("Expected the default in non-interactive mode, got %q, %v", answer, err)
+	}
+	if _, err := askInput("Name", "", notEmpty); err == nil {
+		t.Errorf("Expected an error without a valid default in non-interactive mode")
+	}
+}
+
+// TestAskSelect tests choosing by number, by name, and by default
+func TestAskSelect(t *testing.T) {
+	defer func() { nonInteractive = false }()
+	options := []string{"blank", "landing", "docs"}
+
+	withStdin(t, "2\nDOCS\n7\n\n")
+	captureStdout(t, func() {
+		for _, want := range []int{1, 2, 0} {
+			if got, err := askSelect("Template?", options, 0); err != nil || got != want {
+				t.Errorf("Expected choice %d, got %d, %v", want, got, err)
+			}
+		}
+	})
+
+	nonInteractive = true
+	if got, err := askSelect("Template?", options, 2); err != nil || got != 2 {
+		t.Errorf("Expected the default in non-interactive mode, got %d, %v", got, err)
+	}
+	if _, err := askSelect("Template?", options, -1); err == nil {
+		t.Errorf("Expected an error without a default in non-interactive mode")
+	}
+}
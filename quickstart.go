@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// QuickstartCmd walks through the whole first-run journey: log in, create a
+// site, scaffold it, publish it, and print its URL
+type QuickstartCmd struct {
+	Name     string `help:"Name for the new efmrl (prompted for if not given)"`
+	Template string `help:"Starter template to scaffold (blank, landing, docs; asked for if not given)"`
+	Dir      string `help:"Directory for the site's files" default:"public"`
+	Title    string `help:"Title used in the scaffolded pages" default:"Hello, efmrl"`
+}
+
+// Examples are shown in 'efmrl3 quickstart --help'
+func (q *QuickstartCmd) Examples() []Example {
+	return []Example{
+		{"Go from nothing to a live site", "efmrl3 quickstart"},
+		{"Name the site and start from the landing page template", "efmrl3 quickstart --name launch --template landing"},
+	}
+}
+
+func (q *QuickstartCmd) Run() error {
+	// Refuse to clobber an existing project; everything after this point
+	// would create a second site for it
+	fileName, err := findConfigFile()
+	if err != nil {
+		return err
+	}
+	if fileName != "" {
+		return fmt.Errorf("%s already exists; use 'efmrl3 deploy' to publish this project", fileName)
+	}
+
+	if q.Template != "" && !slices.Contains(starterTemplates, q.Template) {
+		return fmt.Errorf("unknown template %q (available: %s)", q.Template, strings.Join(starterTemplates, ", "))
+	}
+
+	host := resolveHost()
+
+	// 1. Log in, unless there's already a login or a deploy key
+	outln("Step 1/4: Log in")
+	if q.loggedIn(host) {
+		outf("%s Already logged in to %s\n\n", green("✓"), host)
+	} else {
+		if err := (&LoginCmd{}).loginWithGoogle(host); err != nil {
+			return err
+		}
+		outln()
+	}
+
+	// 2. Create the site
+	outln("Step 2/4: Create a site")
+	name := q.Name
+	if name == "" {
+		if name, err = askInput("Site name (leave empty for a generated one)", "", nil); err != nil {
+			return err
+		}
+	}
+
+	if q.Template == "" {
+		choice, err := askSelect("Which starter template?", starterTemplates, 0)
+		if err != nil {
+			return err
+		}
+		q.Template = starterTemplates[choice]
+	}
+
+	apiClient, err := NewAPIClient(hostToBaseURL(host))
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+	body := map[string]string{}
+	if name != "" {
+		body["name"] = name
+	}
+	efmrl, err := createEfmrl(apiClient, body)
+	if err != nil {
+		return fmt.Errorf("failed to create efmrl: %w", err)
+	}
+	outf("%s Created efmrl %s\n\n", green("✓"), efmrl.ID)
+
+	// 3. Scaffold the site and write efmrl.toml
+	outln("Step 3/4: Scaffold the site")
+	files, err := scaffoldSite(q.Template, q.Dir, q.Title, false)
+	if err != nil {
+		return err
+	}
+	config := &Config{}
+	config.Site.SiteID = efmrl.ID
+	config.Site.Dir = q.Dir
+	if hostOverride != "" {
+		config.Site.BaseHost = hostOverride
+	}
+	if err := SaveConfig(config); err != nil {
+		return err
+	}
+	outf("  + %s\n\n", config.FileName())
+
+	// 4. Publish it
+	outln("Step 4/4: Publish")
+	if config, err = LoadConfig(); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	unlock, err := acquireSyncLock(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err := (&SyncCmd{Delete: true, Yes: true}).syncDir(config, q.Dir); err != nil {
+		return err
+	}
+
+	url := siteURL(apiClient, efmrl.ID)
+	if jsonOutput {
+		return printJSON(map[string]any{"siteId": efmrl.ID, "name": efmrl.Name, "url": url, "dir": q.Dir, "files": files})
+	}
+
+	outln()
+	if url != "" {
+		outf("%s Your site is live at %s\n", green("✓"), url)
+	} else {
+		outf("%s Your site is published\n", green("✓"))
+	}
+	outf("Edit the files in %s/ and run 'efmrl3 deploy' to publish changes.\n", q.Dir)
+	return nil
+}
+
+// loggedIn reports whether there are credentials for host, either saved by
+// login or a deploy key in the environment
+func (q *QuickstartCmd) loggedIn(host string) bool {
+	if os.Getenv(TokenEnvVar) != "" {
+		return true
+	}
+	globalConfig, err := LoadGlobalConfig()
+	if err != nil {
+		return false
+	}
+	_, ok := globalConfig.GetHostCredentials(host)
+	return ok
+}
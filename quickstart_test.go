@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestQuickstartExistingProject tests that quickstart won't create a second
+// site for a directory that already has a config
+func TestQuickstartExistingProject(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(ConfigFileName, []byte("[site]\nsite_id = \"abc\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	err := (&QuickstartCmd{}).Run()
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected an already exists error, got %v", err)
+	}
+}
+
+// TestQuickstartLoggedIn tests that a deploy key counts as being logged in
+func TestQuickstartLoggedIn(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(TokenEnvVar, "")
+
+	if (&QuickstartCmd{}).loggedIn("efmrl.work") {
+		t.Errorf("Expected no login without credentials")
+	}
+
+	t.Setenv(TokenEnvVar, "efk_test")
+	if !(&QuickstartCmd{}).loggedIn("efmrl.work") {
+		t.Errorf("Expected a deploy key to count as logged in")
+	}
+}
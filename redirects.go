@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RedirectsCmd manages HTTP redirects for an efmrl
+type RedirectsCmd struct {
+	List   RedirectsListCmd   `cmd:"" help:"List all redirects"`
+	Add    RedirectsAddCmd    `cmd:"" help:"Add a redirect"`
+	Remove RedirectsRemoveCmd `cmd:"" help:"Remove one or more redirects"`
+}
+
+// Redirect sends requests for one path to another location. A From ending
+// in "*" matches every path with that prefix; a "*" in To is replaced by the
+// matched remainder.
+type Redirect struct {
+	ID     int    `json:"id,omitempty"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Status int    `json:"status"`
+}
+
+// RedirectsListCmd lists all redirects for the configured efmrl
+type RedirectsListCmd struct {
+	TableFlags `embed:""`
+}
+
+func (r *RedirectsListCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	redirects, err := fetchRedirects(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch redirects: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(redirects)
+	}
+
+	table := &Table{
+		Title:   "Redirects",
+		Empty:   "No redirects configured",
+		Columns: []string{"FROM", "TO", "STATUS"},
+	}
+	for _, redirect := range redirects {
+		table.AddRow(redirect.From, redirect.To, strconv.Itoa(redirect.Status))
+	}
+	return table.Render(humanOutput(), r.TableFlags)
+}
+
+// fetchRedirects retrieves the redirects configured for an efmrl
+func fetchRedirects(client *APIClient, siteID string) ([]Redirect, error) {
+	var result struct {
+		Redirects []Redirect `json:"redirects"`
+	}
+	if err := getJSON(client, fmt.Sprintf("/admin/efmrls/%s/redirects", siteID), &result); err != nil {
+		return nil, err
+	}
+	return result.Redirects, nil
+}
+
+// RedirectsAddCmd adds a redirect
+type RedirectsAddCmd struct {
+	From   string `arg:"" help:"Path to redirect from, e.g. /old or /blog/*"`
+	To     string `arg:"" help:"Path or URL to redirect to, e.g. /new or /posts/*"`
+	Status int    `help:"HTTP status code to redirect with" default:"301" enum:"301,302,307,308"`
+}
+
+func (r *RedirectsAddCmd) Run() error {
+	redirect := Redirect{From: r.From, To: r.To, Status: r.Status}
+	if err := redirect.validate(); err != nil {
+		return err
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	resp, err := apiClient.Post(fmt.Sprintf("/admin/efmrls/%s/redirects", config.Site.SiteID), redirect)
+	if err != nil {
+		return fmt.Errorf("failed to add redirect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if jsonOutput {
+		return printJSON(redirect)
+	}
+
+	outf("%s Added redirect %s → %s (%d)\n", green("✓"), redirect.From, redirect.To, redirect.Status)
+	return nil
+}
+
+// validate checks that a redirect is well formed before it's sent
+func (r Redirect) validate() error {
+	if !strings.HasPrefix(r.From, "/") {
+		return fmt.Errorf("redirect source %q must start with /", r.From)
+	}
+	if i := strings.Index(r.From, "*"); i >= 0 && i != len(r.From)-1 {
+		return fmt.Errorf("redirect source %q may only contain * at the end", r.From)
+	}
+	if !strings.HasPrefix(r.To, "/") && !strings.HasPrefix(r.To, "http://") && !strings.HasPrefix(r.To, "https://") {
+		return fmt.Errorf("redirect target %q must be a path starting with / or an http(s) URL", r.To)
+	}
+	if strings.Count(r.To, "*") > 1 {
+		return fmt.Errorf("redirect target %q may contain at most one *", r.To)
+	}
+	if strings.Contains(r.To, "*") && !strings.HasSuffix(r.From, "*") {
+		return fmt.Errorf("redirect target %q uses * but source %q has no wildcard", r.To, r.From)
+	}
+	if r.From == r.To {
+		return fmt.Errorf("redirect from %s to itself would loop", r.From)
+	}
+	return nil
+}
+
+// RedirectsRemoveCmd removes one or more redirects by source path
+type RedirectsRemoveCmd struct {
+	From []string `arg:"" name:"from" help:"Source path(s) of the redirects to remove" required:""`
+}
+
+func (r *RedirectsRemoveCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	// First, fetch all redirects to find their IDs
+	redirects, err := fetchRedirects(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch redirects: %w", err)
+	}
+
+	redirectMap := make(map[string]int)
+	for _, redirect := range redirects {
+		redirectMap[redirect.From] = redirect.ID
+	}
+
+	removed := []string{}
+	for _, from := range r.From {
+		outf("Removing %s... ", from)
+
+		redirectID, ok := redirectMap[from]
+		if !ok {
+			outf("%s\n", yellow("NOT FOUND"))
+			continue
+		}
+
+		resp, err := apiClient.Delete(fmt.Sprintf("/admin/efmrls/%s/redirects/%d", config.Site.SiteID, redirectID))
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to remove redirect %s: %w", from, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		outf("%s\n", green("OK"))
+		removed = append(removed, from)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]string{"removed": removed})
+	}
+
+	outf("\n%s Removed %d redirect(s)\n", green("✓"), len(removed))
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestRedirectValidate(t *testing.T) {
+	tests := []struct {
+		from, to string
+		valid    bool
+	}{
+		{"/old", "/new", true},
+		{"/old", "https://example.com/new", true},
+		{"/blog/*", "/posts/*", true},
+		{"/blog/*", "/posts", true},
+		{"old", "/new", false},
+		{"/old", "new", false},
+		{"/a/*/b", "/c", false},
+		{"/old", "/new/*", false},
+		{"/a/*", "/b/*/*", false},
+		{"/same", "/same", false},
+	}
+
+	for _, tt := range tests {
+		err := Redirect{From: tt.from, To: tt.to, Status: 301}.validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected valid=%v for %s → %s, got error %v", tt.valid, tt.from, tt.to, err)
+		}
+	}
+}
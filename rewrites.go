@@ -14,8 +14,16 @@ type RewritesCmd struct {
 	Remove RewritesRemoveCmd `cmd:"" help:"Remove one or more rewrites"`
 }
 
+// Rewrite is a filename served in place of missing paths on an efmrl
+type Rewrite struct {
+	ID       int    `json:"id"`
+	Filename string `json:"filename"`
+}
+
 // RewritesListCmd lists all rewrites for the configured efmrl
-type RewritesListCmd struct{}
+type RewritesListCmd struct {
+	TableFlags `embed:""`
+}
 
 func (r *RewritesListCmd) Run() error {
 	config, err := LoadConfig()
@@ -24,50 +32,59 @@ func (r *RewritesListCmd) Run() error {
 	}
 
 	if config.Site.SiteID == "" {
-		return fmt.Errorf("no site_id configured")
+		return errNoSiteID
 	}
 
 	// Create API client
-	baseURL := fmt.Sprintf("https://%s", config.GetBaseHost())
+	baseURL := config.BaseURL()
 	apiClient, err := NewAPIClient(baseURL)
 	if err != nil {
 		return fmt.Errorf("failed to create API client: %w", err)
 	}
 
 	// Fetch rewrites
-	resp, err := apiClient.Get(fmt.Sprintf("/admin/efmrls/%s/rewrites", config.Site.SiteID))
+	rewrites, err := fetchRewrites(apiClient, config.Site.SiteID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch rewrites: %w", err)
 	}
+
+	if jsonOutput {
+		return printJSON(rewrites)
+	}
+
+	table := &Table{
+		Title:   "Rewrites",
+		Empty:   "No rewrites configured",
+		Columns: []string{"FILENAME"},
+	}
+	for _, rewrite := range rewrites {
+		table.AddRow(rewrite.Filename)
+	}
+	return table.Render(humanOutput(), r.TableFlags)
+}
+
+// fetchRewrites retrieves the rewrites configured for an efmrl
+func fetchRewrites(client *APIClient, siteID string) ([]Rewrite, error) {
+	resp, err := client.Get(fmt.Sprintf("/admin/efmrls/%s/rewrites", siteID))
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var result struct {
-		Rewrites []struct {
-			ID       int    `json:"id"`
-			Filename string `json:"filename"`
-		} `json:"rewrites"`
+		Rewrites []Rewrite `json:"rewrites"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if len(result.Rewrites) == 0 {
-		fmt.Println("No rewrites configured")
-		return nil
-	}
-
-	fmt.Printf("Rewrites (%d):\n", len(result.Rewrites))
-	for _, rewrite := range result.Rewrites {
-		fmt.Printf("  %s\n", rewrite.Filename)
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return nil
+	return result.Rewrites, nil
 }
 
 // RewritesAddCmd adds one or more rewrites
@@ -82,11 +99,11 @@ func (r *RewritesAddCmd) Run() error {
 	}
 
 	if config.Site.SiteID == "" {
-		return fmt.Errorf("no site_id configured")
+		return errNoSiteID
 	}
 
 	// Create API client
-	baseURL := fmt.Sprintf("https://%s", config.GetBaseHost())
+	baseURL := config.BaseURL()
 	apiClient, err := NewAPIClient(baseURL)
 	if err != nil {
 		return fmt.Errorf("failed to create API client: %w", err)
@@ -94,26 +111,30 @@ func (r *RewritesAddCmd) Run() error {
 
 	// Add each rewrite
 	for _, filename := range r.Filenames {
-		fmt.Printf("Adding %s... ", filename)
+		outf("Adding %s... ", filename)
 
 		body := map[string]string{"filename": filename}
 		resp, err := apiClient.Post(fmt.Sprintf("/admin/efmrls/%s/rewrites", config.Site.SiteID), body)
 		if err != nil {
-			fmt.Printf("FAILED\n")
+			outf("%s\n", red("FAILED"))
 			return fmt.Errorf("failed to add rewrite %s: %w", filename, err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			respBody, _ := io.ReadAll(resp.Body)
-			fmt.Printf("FAILED\n")
+			outf("%s\n", red("FAILED"))
 			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
 		}
 
-		fmt.Printf("OK\n")
+		outf("%s\n", green("OK"))
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]string{"added": r.Filenames})
 	}
 
-	fmt.Printf("\n✓ Added %d rewrite(s)\n", len(r.Filenames))
+	outf("\n%s Added %d rewrite(s)\n", green("✓"), len(r.Filenames))
 	return nil
 }
 
@@ -129,71 +150,60 @@ func (r *RewritesRemoveCmd) Run() error {
 	}
 
 	if config.Site.SiteID == "" {
-		return fmt.Errorf("no site_id configured")
+		return errNoSiteID
 	}
 
 	// Create API client
-	baseURL := fmt.Sprintf("https://%s", config.GetBaseHost())
+	baseURL := config.BaseURL()
 	apiClient, err := NewAPIClient(baseURL)
 	if err != nil {
 		return fmt.Errorf("failed to create API client: %w", err)
 	}
 
 	// First, fetch all rewrites to find their IDs
-	resp, err := apiClient.Get(fmt.Sprintf("/admin/efmrls/%s/rewrites", config.Site.SiteID))
+	rewrites, err := fetchRewrites(apiClient, config.Site.SiteID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch rewrites: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var listResult struct {
-		Rewrites []struct {
-			ID       int    `json:"id"`
-			Filename string `json:"filename"`
-		} `json:"rewrites"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&listResult); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
 
 	// Build a map of filename to ID
 	rewriteMap := make(map[string]int)
-	for _, r := range listResult.Rewrites {
+	for _, r := range rewrites {
 		rewriteMap[r.Filename] = r.ID
 	}
 
 	// Remove each rewrite
+	removed := []string{}
 	for _, filename := range r.Filenames {
-		fmt.Printf("Removing %s... ", filename)
+		outf("Removing %s... ", filename)
 
 		rewriteID, ok := rewriteMap[filename]
 		if !ok {
-			fmt.Printf("NOT FOUND\n")
+			outf("%s\n", yellow("NOT FOUND"))
 			continue
 		}
 
 		resp, err := apiClient.Delete(fmt.Sprintf("/admin/efmrls/%s/rewrites/%d", config.Site.SiteID, rewriteID))
 		if err != nil {
-			fmt.Printf("FAILED\n")
+			outf("%s\n", red("FAILED"))
 			return fmt.Errorf("failed to remove rewrite %s: %w", filename, err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			respBody, _ := io.ReadAll(resp.Body)
-			fmt.Printf("FAILED\n")
+			outf("%s\n", red("FAILED"))
 			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
 		}
 
-		fmt.Printf("OK\n")
+		outf("%s\n", green("OK"))
+		removed = append(removed, filename)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]string{"removed": removed})
 	}
 
-	fmt.Printf("\n✓ Removed %d rewrite(s)\n", len(r.Filenames))
+	outf("\n%s Removed %d rewrite(s)\n", green("✓"), len(removed))
 	return nil
 }
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Credentials are AWS-style access keys, which R2 and other S3-compatible
+// stores also use
+type s3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// s3Client is a minimal S3 client: just enough to list and download objects
+type s3Client struct {
+	endpoint  string // custom endpoint for S3-compatible stores; "" for AWS
+	region    string
+	creds     s3Credentials
+	client    *http.Client
+	now       func() time.Time
+	pathStyle bool
+}
+
+// s3Object is one entry of a bucket listing
+type s3Object struct {
+	Key  string `xml:"Key"`
+	Size int64  `xml:"Size"`
+	ETag string `xml:"ETag"`
+}
+
+// newS3Client creates a client using the usual AWS environment variables and
+// shared config files. endpoint overrides AWS_ENDPOINT_URL for stores like R2.
+func newS3Client(endpoint, region string) (*s3Client, error) {
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	creds := s3Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		section := readAWSConfigSection(awsConfigPath("AWS_SHARED_CREDENTIALS_FILE", "credentials"), profile)
+		creds = s3Credentials{
+			AccessKeyID:     section["aws_access_key_id"],
+			SecretAccessKey: section["aws_secret_access_key"],
+			SessionToken:    section["aws_session_token"],
+		}
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("no S3 credentials found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or add profile %q to ~/.aws/credentials", profile)
+	}
+
+	// The config file names profiles other than default "profile <name>"
+	configSection := profile
+	if profile != "default" {
+		configSection = "profile " + profile
+	}
+	config := readAWSConfigSection(awsConfigPath("AWS_CONFIG_FILE", "config"), configSection)
+
+	if region == "" {
+		region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), config["region"], "us-east-1")
+	}
+	if endpoint == "" {
+		endpoint = firstNonEmpty(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL"), config["endpoint_url"])
+	}
+
+	return &s3Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		region:   region,
+		creds:    creds,
+		client:   newHTTPClient(),
+		now:      time.Now,
+		// Custom endpoints are addressed by path, since their hostnames
+		// rarely have a wildcard certificate for bucket subdomains
+		pathStyle: endpoint != "",
+	}, nil
+}
+
+// awsConfigPath returns the shared AWS file named by envVar, or ~/.aws/name
+func awsConfigPath(envVar, name string) string {
+	if path := os.Getenv(envVar); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// readAWSConfigSection returns the keys of one [section] of an AWS INI file.
+// A missing or unreadable file has no keys.
+func readAWSConfigSection(path, section string) map[string]string {
+	values := map[string]string{}
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			inSection = strings.TrimSpace(line[1:len(line)-1]) == section
+		case inSection:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return values
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// objectURL returns the URL of key in bucket; key "" is the bucket itself
+func (c *s3Client) objectURL(bucket, key string) string {
+	escaped := s3EscapePath("/" + key)
+	switch {
+	case c.pathStyle:
+		return c.endpoint + "/" + bucket + escaped
+	case c.endpoint != "":
+		u, _ := url.Parse(c.endpoint)
+		return u.Scheme + "://" + bucket + "." + u.Host + escaped
+	default:
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", bucket, c.region, escaped)
+	}
+}
+
+// listObjects lists every object in bucket whose key starts with prefix,
+// following continuation tokens across pages
+func (c *s3Client) listObjects(bucket, prefix string) ([]s3Object, error) {
+	var objects []s3Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do("GET", c.objectURL(bucket, "")+"?"+s3EncodeQuery(query))
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Contents              []s3Object `xml:"Contents"`
+			IsTruncated           bool       `xml:"IsTruncated"`
+			NextContinuationToken string     `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bucket listing: %w", err)
+		}
+
+		objects = append(objects, page.Contents...)
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// getObject starts downloading an object. The caller must close the body.
+func (c *s3Client) getObject(bucket, key string) (*http.Response, error) {
+	return c.do("GET", c.objectURL(bucket, key))
+}
+
+// do sends a signed request with an empty body, turning error responses
+// into errors
+func (c *s3Client) do(method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.sign(req, emptyPayloadHash)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		if xml.NewDecoder(resp.Body).Decode(&s3Err) == nil && s3Err.Code != "" {
+			return nil, fmt.Errorf("S3 returned status %d: %s: %s", resp.StatusCode, s3Err.Code, s3Err.Message)
+		}
+		return nil, fmt.Errorf("S3 returned status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req. Every
+// header already on the request is signed, along with host and the x-amz
+// headers added here.
+func (c *s3Client) sign(req *http.Request, payloadHash string) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3EncodeQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+c.creds.SecretAccessKey), day)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// s3Escape percent-encodes everything but unreserved characters, as
+// signing requires
+func s3Escape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3EscapePath escapes each segment of a path, keeping the slashes
+func s3EscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3EncodeQuery encodes a query string with sorted keys, as signing requires
+func s3EncodeQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// scanProgress counts the files a scan or hash has got through, for a
+// spinner to show on big trees. It's safe to read while it's counted.
+type scanProgress struct {
+	verb  string // what's being done to the files, e.g. "hashed"
+	total atomic.Int64
+	files atomic.Int64
+	bytes atomic.Int64
+}
+
+func (p *scanProgress) add(size int64) {
+	if p == nil {
+		return
+	}
+	p.files.Add(1)
+	p.bytes.Add(size)
+}
+
+// String describes the progress, e.g. "hashed 4,200/18,000 files, 1.2 GB"
+func (p *scanProgress) String() string {
+	files := formatCount(p.files.Load())
+	if total := p.total.Load(); total > 0 {
+		files += "/" + formatCount(total)
+	}
+	return fmt.Sprintf("%s %s files, %s", p.verb, files, formatBytes(p.bytes.Load()))
+}
+
+// formatCount formats n with thousands separators, e.g. "18,000"
+func formatCount(n int64) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	s := strconv.FormatInt(n, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{18000, "18,000"},
+		{1234567, "1,234,567"},
+		{-4200, "-4,200"},
+	}
+	for _, tt := range tests {
+		if got := formatCount(tt.n); got != tt.want {
+			t.Errorf("Expected formatCount(%d) = %q, got %q", tt.n, tt.want, got)
+		}
+	}
+}
+
+func TestScanProgressString(t *testing.T) {
+	p := &scanProgress{verb: "hashed"}
+	p.total.Store(18000)
+	for range 4200 {
+		p.add(1024)
+	}
+	if got, want := p.String(), "hashed 4,200/18,000 files, 4.10 MB"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	found := &scanProgress{verb: "found"}
+	found.add(10)
+	if got, want := found.String(), "found 1 files, 10 bytes"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+// TestHashProgress tests that only the files that need hashing are counted
+func TestHashProgress(t *testing.T) {
+	_, files := writeSite(t, map[string]string{
+		"a.html": "aaa",
+		"b.html": "bbbb",
+		"c.html": "c",
+	})
+	for i := range files {
+		files[i].ETag = ""
+	}
+	progress := &scanProgress{verb: "hashed"}
+	need := func(lf *LocalFile) bool { return lf.Path != "/c.html" }
+	if err := hashLocalFilesProgress(files, need, progress); err != nil {
+		t.Fatal(err)
+	}
+	if total, done := progress.total.Load(), progress.files.Load(); total != 2 || done != 2 {
+		t.Errorf("Expected 2/2 files hashed, got %d/%d", done, total)
+	}
+	if got := progress.bytes.Load(); got != 7 {
+		t.Errorf("Expected 7 bytes hashed, got %d", got)
+	}
+}
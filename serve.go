@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ServeCmd serves a local directory the way the efmrl server would serve it
+// once synced, so the site can be previewed before publishing
+type ServeCmd struct {
+	Dir        string `arg:"" optional:"" help:"Directory to serve (defaults to the directory sync would publish)" type:"path"`
+	Listen     string `help:"Address to listen on" default:"localhost:8080"`
+	Offline    bool   `help:"Don't fetch the site's redirects, headers, and rewrites from the server"`
+	LiveReload bool   `help:"Reload open pages whenever a file in the directory changes"`
+}
+
+// siteRules are the server-side rules applied when serving a site
+type siteRules struct {
+	Redirects []Redirect
+	Headers   []HeaderRule
+	Rewrites  []Rewrite
+}
+
+func (s *ServeCmd) Run() error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	dir := s.Dir
+	if dir == "" {
+		dir = config.SyncDir()
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory does not exist: %s", dir)
+	}
+
+	ignore, err := loadIgnoreRules(dir)
+	if err != nil {
+		return err
+	}
+
+	var rules siteRules
+	if !s.Offline && config.Site.SiteID != "" {
+		rules = fetchSiteRules(config)
+	}
+
+	handler := &previewHandler{root: dir, ignore: ignore, rules: rules}
+	if s.LiveReload {
+		handler.reload = newLiveReloader()
+		go handler.reload.watch(dir, liveReloadInterval, nil)
+	}
+
+	outf("Serving %s at http://%s/\n", dir, s.Listen)
+	if config.Site.SiteID != "" && !s.Offline {
+		outf("Applying %d redirect(s), %d header rule(s), and %d rewrite(s) from %s\n",
+			len(rules.Redirects), len(rules.Headers), len(rules.Rewrites), config.Site.SiteID)
+	}
+	if s.LiveReload {
+		outln("Live reload is on; pages refresh when files change")
+	}
+	outln("Press Ctrl+C to stop")
+	outln()
+
+	return http.ListenAndServe(s.Listen, handler)
+}
+
+// fetchSiteRules fetches the site's rules from the server. Serving still
+// works without them, so failures are only warnings.
+func fetchSiteRules(config *Config) siteRules {
+	var rules siteRules
+
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		warnf("failed to create API client: %v\n", err)
+		return rules
+	}
+
+	siteID := config.Site.SiteID
+	if rules.Redirects, err = fetchRedirects(apiClient, siteID); err != nil {
+		warnf("failed to fetch redirects: %v\n", err)
+	}
+	if rules.Headers, err = fetchHeaderRules(apiClient, siteID); err != nil {
+		warnf("failed to fetch header rules: %v\n", err)
+	}
+	if rules.Rewrites, err = fetchRewrites(apiClient, siteID); err != nil {
+		warnf("failed to fetch rewrites: %v\n", err)
+	}
+	return rules
+}
+
+// notFoundPage is served, with a 404 status, for paths that don't exist
+const notFoundPage = "/404.html"
+
+// previewHandler serves files from root, applying the site's rules in the
+// same order as the server: redirects first, then the file itself, then
+// rewrites for missing paths, then the 404 page
+type previewHandler struct {
+	root   string
+	ignore *ignoreRules
+	rules  siteRules
+	reload *liveReloader // set with --live-reload
+}
+
+func (h *previewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.reload != nil && r.URL.Path == liveReloadPath {
+		h.reload.ServeHTTP(w, r)
+		return
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.serve(rec, r)
+	outf("%s %s %s\n", r.Method, r.URL.Path, statusColor(rec.status))
+}
+
+func (h *previewHandler) serve(w http.ResponseWriter, r *http.Request) {
+	urlPath := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") && urlPath != "/" {
+		urlPath += "/"
+	}
+
+	for _, redirect := range h.rules.Redirects {
+		if rest, ok := matchPathPattern(redirect.From, urlPath); ok {
+			http.Redirect(w, r, strings.Replace(redirect.To, "*", rest, 1), redirect.Status)
+			return
+		}
+	}
+
+	h.applyHeaders(w, urlPath)
+
+	if file, ok := h.resolve(urlPath); ok {
+		h.serveFile(w, r, file, http.StatusOK)
+		return
+	}
+	for _, rewrite := range h.rules.Rewrites {
+		if file, ok := h.resolve("/" + strings.TrimPrefix(rewrite.Filename, "/")); ok {
+			h.serveFile(w, r, file, http.StatusOK)
+			return
+		}
+	}
+	if file, ok := h.resolve(notFoundPage); ok {
+		h.serveFile(w, r, file, http.StatusNotFound)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// resolve returns the file that urlPath maps to, if sync would publish it.
+// Directory paths map to their index.html.
+func (h *previewHandler) resolve(urlPath string) (string, bool) {
+	if strings.HasSuffix(urlPath, "/") {
+		urlPath += "index.html"
+	}
+	rel := strings.TrimPrefix(urlPath, "/")
+	if !h.published(rel) {
+		return "", false
+	}
+
+	file := filepath.Join(h.root, filepath.FromSlash(rel))
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", false
+	}
+	if info.IsDir() {
+		return h.resolve(urlPath + "/")
+	}
+	return file, true
+}
+
+// published reports whether sync would upload the file at rel, matching the
+// hidden-file and .efmrlignore rules of scanLocalFiles
+func (h *previewHandler) published(rel string) bool {
+	parts := strings.Split(rel, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+		isDir := i < len(parts)-1
+		if h.ignore.Match(strings.Join(parts[:i+1], "/"), isDir) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyHeaders sets the headers of every rule matching urlPath
+func (h *previewHandler) applyHeaders(w http.ResponseWriter, urlPath string) {
+	for _, rule := range h.rules.Headers {
+		if _, ok := matchPathPattern(rule.Path, urlPath); ok {
+			w.Header().Set(rule.Name, rule.Value)
+		}
+	}
+}
+
+// serveFile writes file with the content type sync would upload it with.
+// With live reload on, HTML pages get the reload script.
+func (h *previewHandler) serveFile(w http.ResponseWriter, r *http.Request, file string, status int) {
+	contentType := detectContentType(file)
+	w.Header().Set("Content-Type", contentType)
+
+	if h.reload != nil && strings.HasPrefix(contentType, "text/html") {
+		page, err := os.ReadFile(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		if r.Method != http.MethodHead {
+			w.Write(injectLiveReload(page))
+		}
+		return
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	if status == http.StatusOK {
+		info, err := f.Stat()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.ServeContent(w, r, file, info.ModTime(), f)
+		return
+	}
+
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		io.Copy(w, f)
+	}
+}
+
+// matchPathPattern matches urlPath against a rule path, which is either
+// exact or ends in "*" to match a prefix. It returns the part matched by *.
+func matchPathPattern(pattern, urlPath string) (string, bool) {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		if rest, ok := strings.CutPrefix(urlPath, prefix); ok {
+			return rest, true
+		}
+		return "", false
+	}
+	return "", pattern == urlPath
+}
+
+// statusRecorder remembers the status code written through it, for logging
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// statusColor colors a status code for the request log
+func statusColor(status int) string {
+	code := fmt.Sprint(status)
+	switch {
+	case status >= 400:
+		return red(code)
+	case status >= 300:
+		return yellow(code)
+	default:
+		return green(code)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPreviewHandler tests that serve applies the site's rules like the server
+func TestPreviewHandler(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"index.html":      "home",
+		"docs/index.html": "docs",
+		"app.html":        "app",
+		"404.html":        "missing",
+		"secret.txt":      "ignored",
+		".env":            "hidden",
+		IgnoreFileName:    "secret.txt\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	ignore, err := loadIgnoreRules(root)
+	if err != nil {
+		t.Fatalf("Failed to load ignore rules: %v", err)
+	}
+	handler := &previewHandler{root: root, ignore: ignore, rules: siteRules{
+		Redirects: []Redirect{{From: "/old/*", To: "/new/*", Status: 301}},
+		Headers:   []HeaderRule{{Path: "/docs/*", Name: "Cache-Control", Value: "no-store"}},
+		Rewrites:  []Rewrite{{Filename: "/app.html"}},
+	}}
+
+	tests := []struct {
+		path     string
+		status   int
+		body     string
+		location string
+	}{
+		{"/", 200, "home", ""},
+		{"/docs/", 200, "docs", ""},
+		{"/old/page", 301, "", "/new/page"},
+		{"/dashboard/settings", 200, "app", ""},
+		{"/secret.txt", 200, "app", ""},
+		{"/.env", 200, "app", ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		captureStdout(t, func() {
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		})
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, rec.Code)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.path, tt.body, rec.Body.String())
+		}
+		if got := rec.Header().Get("Location"); got != tt.location {
+			t.Errorf("%s: expected Location %q, got %q", tt.path, tt.location, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	captureStdout(t, func() {
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+	})
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Expected the header rule to apply, got %q", got)
+	}
+
+	// Without a rewrite, missing paths get the 404 page
+	handler.rules.Rewrites = nil
+	rec = httptest.NewRecorder()
+	captureStdout(t, func() {
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/secret.txt", nil))
+	})
+	if rec.Code != 404 || rec.Body.String() != "missing" {
+		t.Errorf("Expected the 404 page, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+// TestMatchPathPattern tests exact and prefix rule paths
+func TestMatchPathPattern(t *testing.T) {
+	tests := []struct {
+		pattern, path, rest string
+		ok                  bool
+	}{
+		{"/about", "/about", "", true},
+		{"/about", "/about/team", "", false},
+		{"/blog/*", "/blog/2024/post", "2024/post", true},
+		{"/blog/*", "/blogs", "", false},
+		{"/*", "/anything", "anything", true},
+	}
+
+	for _, tt := range tests {
+		rest, ok := matchPathPattern(tt.pattern, tt.path)
+		if ok != tt.ok || rest != tt.rest {
+			t.Errorf("matchPathPattern(%q, %q): expected %q, %v, got %q, %v", tt.pattern, tt.path, tt.rest, tt.ok, rest, ok)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// SitesCloneCmd copies an existing efmrl's content into a new efmrl
+type SitesCloneCmd struct {
+	Source   string `arg:"" help:"Site ID or name of the efmrl to copy"`
+	Name     string `help:"Name for the new efmrl" required:""`
+	Rewrites bool   `help:"Also copy the source's rewrites"`
+	Domains  bool   `help:"Also copy the source's custom domains (each domain can only be attached to one efmrl)"`
+	Save     bool   `help:"Save the new site ID to the config file in the current directory"`
+}
+
+func (s *SitesCloneCmd) Run() error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	efmrls, err := fetchEfmrls(apiClient)
+	if err != nil {
+		return fmt.Errorf("failed to fetch efmrls: %w", err)
+	}
+	source, err := findEfmrl(efmrls, s.Source)
+	if err != nil {
+		return err
+	}
+
+	// Prefer a server-side copy; older servers don't support it, so fall
+	// back to downloading and re-uploading every file
+	outf("Cloning %s (%s)... ", orDash(source.Name), source.ID)
+	clone, supported, err := cloneEfmrlOnServer(apiClient, source.ID, s.Name)
+	if err != nil {
+		outf("%s\n", red("FAILED"))
+		return fmt.Errorf("failed to clone efmrl: %w", err)
+	}
+	if supported {
+		outf("%s\n", green("OK"))
+	} else {
+		outf("copying files locally\n")
+		clone, err = createEfmrl(apiClient, map[string]string{"name": s.Name})
+		if err != nil {
+			return fmt.Errorf("failed to create efmrl: %w", err)
+		}
+		if err := copyEfmrlFiles(apiClient, source.ID, clone.ID); err != nil {
+			return fmt.Errorf("failed to copy files (the partial clone %s was kept): %w", clone.ID, err)
+		}
+	}
+
+	if s.Rewrites {
+		rewrites, err := fetchRewrites(apiClient, source.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch rewrites: %w", err)
+		}
+		for _, rewrite := range rewrites {
+			outf("Adding rewrite %s... ", rewrite.Filename)
+			path := fmt.Sprintf("/admin/efmrls/%s/rewrites", clone.ID)
+			if err := postJSON(apiClient, path, map[string]string{"filename": rewrite.Filename}); err != nil {
+				outf("%s\n", red("FAILED"))
+				return fmt.Errorf("failed to add rewrite %s: %w", rewrite.Filename, err)
+			}
+			outf("%s\n", green("OK"))
+		}
+	}
+
+	if s.Domains {
+		domains, err := fetchDomains(apiClient, source.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch domains: %w", err)
+		}
+		for _, domain := range domains {
+			// The source's own hostname is assigned by the server, not copied
+			if domain.Domain == source.PrimaryDomain {
+				continue
+			}
+			outf("Adding domain %s... ", domain.Domain)
+			path := fmt.Sprintf("/admin/efmrls/%s/domains", clone.ID)
+			if err := postJSON(apiClient, path, map[string]string{"domain": domain.Domain}); err != nil {
+				outf("%s\n", red("FAILED"))
+				warnf("could not add domain %s: %v\n", domain.Domain, err)
+				continue
+			}
+			outf("%s\n", green("OK"))
+		}
+	}
+
+	outf("\n%s Cloned %s into %s\n", green("✓"), orDash(source.Name), s.Name)
+	outf("  Site ID: %s\n", clone.ID)
+	if url := siteURL(apiClient, clone.ID); url != "" {
+		outf("  URL:     %s\n", url)
+	}
+
+	if s.Save {
+		editConfig, err := loadConfigForEdit(false)
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+		editConfig.Site.SiteID = clone.ID
+		if err := SaveConfig(editConfig); err != nil {
+			return err
+		}
+		outf("\nSite ID saved to %s\n", editConfig.FileName())
+	}
+
+	if jsonOutput {
+		return printJSON(clone)
+	}
+
+	return nil
+}
+
+// cloneEfmrlOnServer asks the server to copy an efmrl's content. supported is
+// false when the server has no clone endpoint.
+func cloneEfmrlOnServer(client *APIClient, sourceID, name string) (clone *Efmrl, supported bool, err error) {
+	resp, err := client.Post(fmt.Sprintf("/admin/efmrls/%s/clone", sourceID), map[string]string{"name": name})
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented ||
+		resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, true, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Efmrl Efmrl `json:"efmrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, true, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Efmrl.ID == "" {
+		return nil, true, fmt.Errorf("server did not return a site ID")
+	}
+
+	return &result.Efmrl, true, nil
+}
+
+// copyEfmrlFiles downloads every file from one efmrl and uploads it to another
+func copyEfmrlFiles(client *APIClient, sourceID, destID string) error {
+	files, err := fetchRemoteFiles(client, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "efmrl-clone-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var verification DownloadVerification
+	for i, rf := range files {
+		outf("[%d/%d] Copying %s... ", i+1, len(files), rf.Path)
+
+		file, err := downloadFile(client, sourceID, rf, tmpDir, &verification)
+		if err == nil {
+			err = uploadFile(client, destID, *file)
+			os.Remove(file.AbsPath)
+		}
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to copy %s: %w", rf.Path, err)
+		}
+
+		outf("%s\n", green("OK"))
+	}
+
+	outln(dim(verification.describe()))
+	return nil
+}
+
+// downloadFile saves a remote file into dir and describes it as a LocalFile
+// ready to be uploaded elsewhere. The download is checked against the
+// file's checksums, and tried again if it doesn't match; how it went is
+// added to verification, if that isn't nil.
+func downloadFile(client *APIClient, siteID string, rf RemoteFile, dir string, verification *DownloadVerification) (*LocalFile, error) {
+	for attempt := 1; ; attempt++ {
+		file, checked, err := fetchFile(client, siteID, rf, dir)
+		var mismatch *ChecksumMismatchError
+		if errors.As(err, &mismatch) && attempt < downloadAttempts {
+			continue
+		}
+		if err != nil {
+			if mismatch != nil {
+				err = fmt.Errorf("%w, after %d attempts", err, attempt)
+			}
+			return nil, err
+		}
+		verification.record(checked, attempt)
+		return file, nil
+	}
+}
+
+// fetchFile downloads a remote file once, and verifies it. checked is false
+// if there was nothing to verify it against. A download that fails
+// verification is removed.
+func fetchFile(client *APIClient, siteID string, rf RemoteFile, dir string) (file *LocalFile, checked bool, err error) {
+	resp, err := client.Get(fmt.Sprintf("/admin/efmrls/%s/files%s", siteID, fileURLPath(rf.Path)))
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, false, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	f, err := os.CreateTemp(dir, "file-")
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	sums := newDownloadChecksums()
+	size, err := io.Copy(io.MultiWriter(f, sums.writer()), resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	checked, err = sums.verify(rf, resp.Header.Get("ETag"), f.Name(), size)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, checked, err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = detectContentType(filepath.Base(rf.Path))
+	}
+
+	return &LocalFile{
+		Path:        rf.Path,
+		AbsPath:     f.Name(),
+		ETag:        rf.ETag,
+		Size:        size,
+		ContentType: contentType,
+	}, checked, nil
+}
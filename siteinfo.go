@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+)
+
+// SiteInfo aggregates everything known about one efmrl
+type SiteInfo struct {
+	Efmrl
+	Quota     *QuotaInfo `json:"quota,omitempty"`
+	FileCount int        `json:"fileCount"`
+	FileBytes int64      `json:"fileBytes"`
+	Domains   []string   `json:"domains"`
+	Rewrites  []string   `json:"rewrites"`
+}
+
+// SitesInfoCmd prints a detailed report for the configured efmrl
+type SitesInfoCmd struct{}
+
+func (s *SitesInfoCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	info, err := fetchSiteInfo(apiClient, config.Site.SiteID)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(info)
+	}
+
+	outln("Site Info")
+	outln("=========")
+	outf("Name:      %s\n", orDash(info.Name))
+	outf("Site ID:   %s\n", info.ID)
+	outf("Expires:   %s\n", formatExpiry(info.ExpiresAt))
+	outf("Files:     %d (%s)\n", info.FileCount, formatBytes(info.FileBytes))
+	if info.Quota != nil {
+		outf("Quota:     %s of %s used; %s available\n",
+			formatBytes(info.Quota.CurrentSpace),
+			formatBytes(info.Quota.MaxSpace),
+			formatBytes(info.Quota.AvailableSpace))
+	}
+
+	outf("\nDomains (%d):\n", len(info.Domains))
+	for _, domain := range info.Domains {
+		outf("  %s\n", domain)
+	}
+
+	outf("\nRewrites (%d):\n", len(info.Rewrites))
+	for _, rewrite := range info.Rewrites {
+		outf("  %s\n", rewrite)
+	}
+
+	return nil
+}
+
+// fetchSiteInfo gathers an efmrl's details, quota, files, domains, and
+// rewrites into one report
+func fetchSiteInfo(client *APIClient, siteID string) (*SiteInfo, error) {
+	efmrl, err := fetchEfmrl(client, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch efmrl: %w", err)
+	}
+	if efmrl.ID == "" {
+		efmrl.ID = siteID
+	}
+
+	info := &SiteInfo{Efmrl: *efmrl, Domains: []string{}, Rewrites: []string{}}
+
+	if info.Quota, err = fetchQuota(client, siteID); err != nil {
+		return nil, fmt.Errorf("failed to fetch quota: %w", err)
+	}
+
+	files, err := fetchRemoteFiles(client, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch files: %w", err)
+	}
+	info.FileCount = len(files)
+	for _, f := range files {
+		info.FileBytes += f.Size
+	}
+
+	domains, err := fetchDomains(client, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch domains: %w", err)
+	}
+	for _, d := range domains {
+		info.Domains = append(info.Domains, d.Domain)
+	}
+
+	rewrites, err := fetchRewrites(client, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rewrites: %w", err)
+	}
+	for _, r := range rewrites {
+		info.Rewrites = append(info.Rewrites, r.Filename)
+	}
+
+	return info, nil
+}
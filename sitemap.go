@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SitemapPath is where sync publishes the sitemap it generates
+const SitemapPath = "/sitemap.xml"
+
+// addSitemap generates a sitemap of the HTML pages among files and adds it
+// to them, so it's uploaded whenever the set of pages changes. A site that
+// ships its own sitemap.xml keeps it. The returned function removes the
+// generated file once the sync is done.
+func addSitemap(config *Config, client *APIClient, files []LocalFile) ([]LocalFile, func(), error) {
+	noop := func() {}
+	for _, f := range files {
+		if f.Path == SitemapPath {
+			warnf("the site already has %s; not generating one\n", SitemapPath)
+			return files, noop, nil
+		}
+	}
+
+	baseURL, err := primarySiteURL(client, config.Site.SiteID)
+	if err != nil {
+		return nil, noop, fmt.Errorf("failed to generate sitemap: %w", err)
+	}
+	data, pages := generateSitemap(baseURL, files, config.Sync.SitemapExclude)
+
+	f, err := os.CreateTemp("", "efmrl-sitemap-*.xml")
+	if err != nil {
+		return nil, noop, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, noop, fmt.Errorf("failed to write sitemap: %w", err)
+	}
+
+	etag, err := computeFileETag(f.Name())
+	if err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+
+	outf("Generated %s with %d page(s)\n\n", SitemapPath, pages)
+	return append(files, LocalFile{
+		Path:        SitemapPath,
+		AbsPath:     f.Name(),
+		ETag:        etag,
+		Size:        int64(len(data)),
+		ContentType: "application/xml",
+	}), cleanup, nil
+}
+
+// generateSitemap renders a sitemap of the HTML pages among files, leaving
+// out the 404 page and any path matching exclude. It returns the sitemap
+// and the number of pages in it. There are no lastmod dates, so the
+// sitemap only changes when pages are added or removed.
+func generateSitemap(baseURL string, files []LocalFile, exclude []string) ([]byte, int) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	var urls []string
+	for _, f := range files {
+		if !strings.HasPrefix(f.ContentType, "text/html") || f.Path == notFoundPage || sitemapExcluded(f.Path, exclude) {
+			continue
+		}
+		urls = append(urls, baseURL+(&url.URL{Path: sitemapPagePath(f.Path)}).EscapedPath())
+	}
+	sort.Strings(urls)
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n")
+	for _, u := range urls {
+		b.WriteString("  <url><loc>")
+		xml.EscapeText(&b, []byte(u))
+		b.WriteString("</loc></url>\n")
+	}
+	b.WriteString("</urlset>\n")
+	return []byte(b.String()), len(urls)
+}
+
+// sitemapPagePath returns the path a page is served at: index.html files
+// are served at their directory
+func sitemapPagePath(filePath string) string {
+	if path.Base(filePath) == "index.html" {
+		return strings.TrimSuffix(filePath, "index.html")
+	}
+	return filePath
+}
+
+// sitemapExcluded reports whether filePath matches one of the exclude
+// patterns, which are paths optionally ending in "*"
+func sitemapExcluded(filePath string, exclude []string) bool {
+	for _, pattern := range exclude {
+		if _, ok := matchPathPattern(pattern, filePath); ok {
+			return true
+		}
+	}
+	return false
+}
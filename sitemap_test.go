@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerateSitemap tests which pages are listed and how they're addressed
+func TestGenerateSitemap(t *testing.T) {
+	files := []LocalFile{
+		{Path: "/index.html", ContentType: "text/html; charset=utf-8"},
+		{Path: "/docs/index.html", ContentType: "text/html; charset=utf-8"},
+		{Path: "/about us.html", ContentType: "text/html; charset=utf-8"},
+		{Path: "/drafts/wip.html", ContentType: "text/html; charset=utf-8"},
+		{Path: "/404.html", ContentType: "text/html; charset=utf-8"},
+		{Path: "/style.css", ContentType: "text/css; charset=utf-8"},
+	}
+
+	data, pages := generateSitemap("https://example.com/", files, []string{"/drafts/*"})
+	if pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages)
+	}
+
+	sitemap := string(data)
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc></url>
+  <url><loc>https://example.com/about%20us.html</loc></url>
+  <url><loc>https://example.com/docs/</loc></url>
+</urlset>
+`
+	if sitemap != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, sitemap)
+	}
+}
+
+// TestAddSitemapKeepsExisting tests that a site's own sitemap.xml wins
+func TestAddSitemapKeepsExisting(t *testing.T) {
+	files := []LocalFile{{Path: "/index.html"}, {Path: SitemapPath}}
+
+	var got []LocalFile
+	var err error
+	captureStdout(t, func() { got, _, err = addSitemap(&Config{}, nil, files) })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(got) != 2 || !strings.HasSuffix(got[1].Path, "sitemap.xml") || got[1].AbsPath != "" {
+		t.Errorf("Expected the files to be unchanged, got %+v", got)
+	}
+}
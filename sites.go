@@ -0,0 +1,373 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SitesCmd manages the efmrls owned by the logged-in user
+type SitesCmd struct {
+	List   SitesListCmd   `cmd:"" help:"List all efmrls you own or administer"`
+	Info   SitesInfoCmd   `cmd:"" help:"Show a detailed report for the configured efmrl"`
+	Create SitesCreateCmd `cmd:"" help:"Create a new efmrl"`
+	Delete SitesDeleteCmd `cmd:"" help:"Delete an efmrl and all of its content"`
+	Rename SitesRenameCmd `cmd:"" help:"Rename the configured efmrl"`
+	Clone  SitesCloneCmd  `cmd:"" help:"Copy an efmrl's content into a new efmrl"`
+}
+
+// Efmrl is a site as returned by the admin API
+type Efmrl struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	PrimaryDomain string `json:"primaryDomain,omitempty"`
+	CurrentSpace  int64  `json:"currentSpace,omitempty"`
+	ExpiresAt     string `json:"expiresAt,omitempty"`
+}
+
+// SitesListCmd lists every efmrl the user can administer
+type SitesListCmd struct {
+	TableFlags `embed:""`
+}
+
+func (s *SitesListCmd) Run() error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	efmrls, err := fetchEfmrls(apiClient)
+	if err != nil {
+		return fmt.Errorf("failed to fetch efmrls: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(efmrls)
+	}
+
+	table := &Table{
+		Title:   "Efmrls",
+		Empty:   "No efmrls found (create one with 'efmrl3 sites create')",
+		Columns: []string{"NAME", "SITE ID", "DOMAIN", "SIZE", "EXPIRES"},
+	}
+	for _, e := range efmrls {
+		table.AddRow(e.Name, e.ID, e.PrimaryDomain, formatBytes(e.CurrentSpace), formatExpiry(e.ExpiresAt))
+	}
+	return table.Render(humanOutput(), s.TableFlags)
+}
+
+// fetchEfmrls retrieves every efmrl the user can administer
+func fetchEfmrls(client *APIClient) ([]Efmrl, error) {
+	resp, err := client.Get("/admin/efmrls")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Efmrls []Efmrl `json:"efmrls"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return result.Efmrls, nil
+}
+
+// formatExpiry formats an RFC 3339 expiry time as a date, or "never"
+func formatExpiry(expiresAt string) string {
+	if expiresAt == "" {
+		return "never"
+	}
+	t, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return expiresAt
+	}
+	if t.Before(serverNow()) {
+		return t.Local().Format(time.DateOnly) + " (expired)"
+	}
+	return t.Local().Format(time.DateOnly)
+}
+
+// orDash returns s, or "-" if it's empty, for table cells
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// SitesCreateCmd creates a new efmrl
+type SitesCreateCmd struct {
+	Name string `help:"Name for the new efmrl"`
+	Save bool   `help:"Save the new site ID to the config file in the current directory"`
+}
+
+func (s *SitesCreateCmd) Run() error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	body := map[string]string{}
+	if s.Name != "" {
+		body["name"] = s.Name
+	}
+	efmrl, err := createEfmrl(apiClient, body)
+	if err != nil {
+		return fmt.Errorf("failed to create efmrl: %w", err)
+	}
+
+	outf("%s Created efmrl\n", green("✓"))
+	if efmrl.Name != "" {
+		outf("  Name:    %s\n", efmrl.Name)
+	}
+	outf("  Site ID: %s\n", efmrl.ID)
+	if url := siteURL(apiClient, efmrl.ID); url != "" {
+		outf("  URL:     %s\n", url)
+	}
+
+	if s.Save {
+		editConfig, err := loadConfigForEdit(false)
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+		editConfig.Site.SiteID = efmrl.ID
+		if err := SaveConfig(editConfig); err != nil {
+			return err
+		}
+		outf("\nSite ID saved to %s\n", editConfig.FileName())
+	} else {
+		outf("\nTo use it here, run: efmrl3 config --id %s\n", efmrl.ID)
+	}
+
+	if jsonOutput {
+		return printJSON(efmrl)
+	}
+
+	return nil
+}
+
+// createEfmrl creates a new efmrl with the given settings
+func createEfmrl(client *APIClient, body map[string]string) (*Efmrl, error) {
+	resp, err := client.Post("/admin/efmrls", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Efmrl Efmrl `json:"efmrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Efmrl.ID == "" {
+		return nil, fmt.Errorf("server did not return a site ID")
+	}
+
+	return &result.Efmrl, nil
+}
+
+// siteURL returns the live URL of an efmrl based on its first domain, or ""
+// if it has none (or they can't be fetched)
+func siteURL(client *APIClient, siteID string) string {
+	domains, err := fetchDomains(client, siteID)
+	if err != nil || len(domains) == 0 {
+		return ""
+	}
+	return "https://" + domains[0].Domain
+}
+
+// SitesDeleteCmd deletes an efmrl after the user confirms by typing its name
+type SitesDeleteCmd struct {
+	Site string `arg:"" help:"Site ID or name of the efmrl to delete"`
+	Yes  bool   `help:"Skip the confirmation prompt" short:"y"`
+}
+
+func (s *SitesDeleteCmd) Run() error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	efmrls, err := fetchEfmrls(apiClient)
+	if err != nil {
+		return fmt.Errorf("failed to fetch efmrls: %w", err)
+	}
+	efmrl, err := findEfmrl(efmrls, s.Site)
+	if err != nil {
+		return err
+	}
+
+	// Confirm by typing the name (or the ID, for unnamed sites)
+	confirmation := efmrl.Name
+	if confirmation == "" {
+		confirmation = efmrl.ID
+	}
+
+	if !s.Yes {
+		outf("This will permanently delete %s (%s), including all of its files, domains, and settings.\n",
+			orDash(efmrl.Name), efmrl.ID)
+		answer, err := askLine(fmt.Sprintf("Type %q to confirm: ", confirmation))
+		if err != nil && nonInteractive {
+			return fmt.Errorf("%w (use --yes to skip the confirmation)", err)
+		}
+		if err != nil || answer != confirmation {
+			return fmt.Errorf("confirmation did not match; nothing deleted")
+		}
+	}
+
+	outf("Deleting %s... ", efmrl.ID)
+	resp, err := apiClient.Delete(fmt.Sprintf("/admin/efmrls/%s", efmrl.ID))
+	if err != nil {
+		outf("%s\n", red("FAILED"))
+		return fmt.Errorf("failed to delete efmrl: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		outf("%s\n", red("FAILED"))
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+	outf("%s\n", green("OK"))
+
+	if config.Site.SiteID == efmrl.ID {
+		outf("\nNote: %s still refers to the deleted site ID\n", config.FileName())
+	}
+
+	if jsonOutput {
+		return printJSON(map[string]string{"deleted": efmrl.ID})
+	}
+
+	return nil
+}
+
+// findEfmrl finds an efmrl by site ID, or by name if no ID matches. Names
+// aren't unique, so an ambiguous name is an error.
+func findEfmrl(efmrls []Efmrl, idOrName string) (*Efmrl, error) {
+	for i := range efmrls {
+		if efmrls[i].ID == idOrName {
+			return &efmrls[i], nil
+		}
+	}
+
+	var matches []*Efmrl
+	for i := range efmrls {
+		if efmrls[i].Name == idOrName {
+			matches = append(matches, &efmrls[i])
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no efmrl with ID or name %q", idOrName)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d efmrls are named %q; use the site ID instead", len(matches), idOrName)
+	}
+}
+
+// SitesRenameCmd renames the efmrl configured in efmrl.toml
+type SitesRenameCmd struct {
+	Name string `arg:"" name:"new-name" help:"New name for the efmrl"`
+}
+
+func (s *SitesRenameCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	oldName := ""
+	if efmrl, err := fetchEfmrl(apiClient, config.Site.SiteID); err == nil {
+		oldName = efmrl.Name
+	}
+
+	resp, err := apiClient.Patch(fmt.Sprintf("/admin/efmrls/%s", config.Site.SiteID), map[string]string{"name": s.Name})
+	if err != nil {
+		return fmt.Errorf("failed to rename efmrl: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if oldName != "" {
+		outf("%s Renamed %s to %s\n", green("✓"), oldName, s.Name)
+	} else {
+		outf("%s Renamed %s to %s\n", green("✓"), config.Site.SiteID, s.Name)
+	}
+
+	if jsonOutput {
+		return printJSON(Efmrl{ID: config.Site.SiteID, Name: s.Name})
+	}
+	return nil
+}
+
+// fetchEfmrl retrieves a single efmrl's details
+func fetchEfmrl(client *APIClient, siteID string) (*Efmrl, error) {
+	resp, err := client.Get(fmt.Sprintf("/admin/efmrls/%s", siteID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Efmrl Efmrl `json:"efmrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &result.Efmrl, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestFindEfmrl tests looking up an efmrl by ID or name
+func TestFindEfmrl(t *testing.T) {
+	efmrls := []Efmrl{
+		{ID: "aaa", Name: "blog"},
+		{ID: "bbb", Name: "docs"},
+		{ID: "ccc", Name: "docs"},
+		{ID: "blog", Name: "confusing"},
+	}
+
+	// IDs take precedence over names
+	if e, err := findEfmrl(efmrls, "blog"); err != nil || e.ID != "blog" {
+		t.Errorf("Expected ID match 'blog', got %v (%v)", e, err)
+	}
+	if e, err := findEfmrl(efmrls, "confusing"); err != nil || e.ID != "blog" {
+		t.Errorf("Expected name match for 'confusing', got %v (%v)", e, err)
+	}
+	if _, err := findEfmrl(efmrls, "docs"); err == nil {
+		t.Error("Expected error for ambiguous name, got nil")
+	}
+	if _, err := findEfmrl(efmrls, "missing"); err == nil {
+		t.Error("Expected error for unknown site, got nil")
+	}
+}
+
+// siteProject makes the current directory a project for site abc, served by
+// server
+func siteProject(t *testing.T, server *httptest.Server) {
+	t.Helper()
+	t.Setenv(TokenEnvVar, "test-token")
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	serverURL, _ := url.Parse(server.URL)
+	config := fmt.Sprintf("base_host = \"localhost:%s\"\n\n[site]\nsite_id = \"abc\"\n", serverURL.Port())
+	if err := os.WriteFile(ConfigFileName, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+// TestSitesList tests listing efmrls as a table and as JSON
+func TestSitesList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/efmrls" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"efmrls": [
+			{"id": "aaa", "name": "blog", "primaryDomain": "blog.example", "currentSpace": 2048},
+			{"id": "bbb", "name": "docs", "expiresAt": "2000-01-02T00:00:00Z"}
+		]}`))
+	}))
+	defer server.Close()
+	siteProject(t, server)
+
+	var err error
+	output := captureStdout(t, func() { err = (&SitesListCmd{}).Run() })
+	if err != nil {
+		t.Fatalf("sites list failed: %v", err)
+	}
+	for _, want := range []string{"NAME", "blog", "aaa", "blog.example", "2.00 KB", "never", "docs", "(expired)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the table, got:\n%s", want, output)
+		}
+	}
+
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+	output = captureStdout(t, func() { err = (&SitesListCmd{}).Run() })
+	var efmrls []Efmrl
+	if err != nil || json.Unmarshal([]byte(output), &efmrls) != nil {
+		t.Fatalf("Expected a JSON list, got %q (%v)", output, err)
+	}
+	if len(efmrls) != 2 || efmrls[0].ID != "aaa" || efmrls[1].Name != "docs" {
+		t.Errorf("Unexpected efmrls %+v", efmrls)
+	}
+}
+
+// TestSitesRename tests renaming the configured efmrl, naming it by its old
+// name in the output
+func TestSitesRename(t *testing.T) {
+	var renamed map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path != "/admin/efmrls/abc":
+			http.NotFound(w, r)
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"efmrl": {"id": "abc", "name": "old-name"}}`))
+		case r.Method == http.MethodPatch:
+			json.NewDecoder(r.Body).Decode(&renamed)
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	siteProject(t, server)
+
+	var err error
+	output := captureStdout(t, func() { err = (&SitesRenameCmd{Name: "new-name"}).Run() })
+	if err != nil {
+		t.Fatalf("sites rename failed: %v", err)
+	}
+	if renamed["name"] != "new-name" {
+		t.Errorf("Expected the efmrl renamed to new-name, got %v", renamed)
+	}
+	if !strings.Contains(output, "Renamed old-name to new-name") {
+		t.Errorf("Expected the old and new names in the output, got:\n%s", output)
+	}
+}
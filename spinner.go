@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// spinnerFrames are drawn in turn while a phase is running on a terminal
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const (
+	// spinnerInterval is how often the spinner is redrawn on a terminal
+	spinnerInterval = 100 * time.Millisecond
+
+	// spinnerLogInterval is how often a "still working" line is logged when
+	// output isn't a terminal, e.g. in CI logs
+	spinnerLogInterval = 10 * time.Second
+)
+
+// spinner shows that a slow phase (a scan or a network call) is still
+// making progress. On a terminal it animates with the elapsed time; otherwise
+// it degrades to an occasional log line.
+type spinner struct {
+	message string
+	start   time.Time
+	w       io.Writer
+	tty     bool
+	stop    chan struct{}
+	done    chan struct{}
+
+	mu       sync.Mutex
+	progress fmt.Stringer // how far the phase has got, if it's known
+}
+
+// startSpinner prints message and keeps an indicator running until Stop
+func startSpinner(message string) *spinner {
+	s := &spinner{
+		message: message,
+		start:   time.Now(),
+		w:       humanOutput(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	s.tty = isTerminal(s.w)
+	if !s.tty {
+		fmt.Fprintln(s.w, message)
+	}
+
+	go s.run()
+	return s
+}
+
+// Track shows progress alongside the spinner from then on. progress is read
+// from the spinner's goroutine, so it must be safe to read while it changes.
+func (s *spinner) Track(progress fmt.Stringer) {
+	s.mu.Lock()
+	s.progress = progress
+	s.mu.Unlock()
+}
+
+// detail returns the tracked progress, or "" if there's none
+func (s *spinner) detail() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.progress == nil {
+		return ""
+	}
+	return s.progress.String()
+}
+
+func (s *spinner) run() {
+	defer close(s.done)
+
+	interval := spinnerLogInterval
+	if s.tty {
+		interval = spinnerInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		if s.tty {
+			message := s.message
+			if detail := s.detail(); detail != "" {
+				message += " " + detail
+			}
+			fmt.Fprintf(s.w, "\r\x1b[K%s %s %s", spinnerFrames[frame%len(spinnerFrames)], message, dim(s.elapsed()))
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if !s.tty {
+				detail := s.detail()
+				if detail == "" {
+					detail = "still working"
+				}
+				fmt.Fprintf(s.w, "  %s (%s)\n", detail, s.elapsed())
+			}
+		}
+	}
+}
+
+// elapsed returns the time since the spinner started, e.g. "3.2s"
+func (s *spinner) elapsed() string {
+	return time.Since(s.start).Round(100 * time.Millisecond).String()
+}
+
+// Stop ends the spinner. On a terminal, the spinner line is replaced by the
+// message and how long the phase took.
+func (s *spinner) Stop() {
+	close(s.stop)
+	<-s.done
+	if s.tty {
+		fmt.Fprintf(s.w, "\r\x1b[K%s %s\n", s.message, dim("("+s.elapsed()+")"))
+	}
+}
+
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
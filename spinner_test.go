@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSpinnerWithoutTerminal tests that the spinner degrades to a plain log
+// line when output isn't a terminal
+func TestSpinnerWithoutTerminal(t *testing.T) {
+	out := captureStdout(t, func() {
+		spin := startSpinner("Fetching remote file list...")
+		spin.Stop()
+	})
+
+	if out != "Fetching remote file list...\n" {
+		t.Errorf("Expected a single log line, got %q", out)
+	}
+	if strings.Contains(out, "\r") {
+		t.Errorf("Expected no terminal control characters, got %q", out)
+	}
+}
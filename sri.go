@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// integrityTagPattern finds script and link start tags, allowing ">"
+	// inside quoted attribute values
+	integrityTagPattern = regexp.MustCompile(`(?i)<(script|link)\b(?:[^>"']|"[^"]*"|'[^']*')*>`)
+
+	// integrityAttrPatterns find the attributes of those tags that matter
+	// here, by name
+	integrityAttrPatterns = map[string]*regexp.Regexp{}
+)
+
+func init() {
+	for _, name := range []string{"src", "href", "rel", "integrity"} {
+		integrityAttrPatterns[name] = regexp.MustCompile(`(?i)\s` + name + `\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	}
+}
+
+// addIntegrity sets the integrity attribute of each script and stylesheet
+// a page loads from this site to a hash of the file's content, adding the
+// attribute where it's missing and correcting it where it's stale. Scripts
+// and stylesheets on other sites are left alone. It returns the new set of
+// files and how many pages changed; the returned function removes
+// rewritten copies.
+func addIntegrity(files []LocalFile) ([]LocalFile, int, func(), error) {
+	tmpDir, err := os.MkdirTemp("", "efmrl-integrity-")
+	if err != nil {
+		return nil, 0, func() {}, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	byPath := make(map[string]*LocalFile, len(files))
+	for i := range files {
+		byPath[files[i].Path] = &files[i]
+	}
+	hashes := map[string]string{}
+	hashOf := func(sitePath string) (string, error) {
+		if hash, ok := hashes[sitePath]; ok {
+			return hash, nil
+		}
+		hash, err := integrityHash(byPath[sitePath].AbsPath)
+		hashes[sitePath] = hash
+		return hash, err
+	}
+
+	result := make([]LocalFile, 0, len(files))
+	pages := 0
+	for _, file := range files {
+		if !strings.HasPrefix(file.ContentType, "text/html") {
+			result = append(result, file)
+			continue
+		}
+		data, err := os.ReadFile(file.AbsPath)
+		if err != nil {
+			cleanup()
+			return nil, 0, func() {}, err
+		}
+		content := string(data)
+
+		var hashErr error
+		dir := path.Dir(file.Path)
+		rewritten := integrityTagPattern.ReplaceAllStringFunc(content, func(tag string) string {
+			target, ok := integrityTarget(tag, dir)
+			if _, exists := byPath[target]; !ok || !exists {
+				return tag
+			}
+			hash, err := hashOf(target)
+			if err != nil {
+				if hashErr == nil {
+					hashErr = err
+				}
+				return tag
+			}
+			return setIntegrity(tag, hash)
+		})
+		if hashErr != nil {
+			cleanup()
+			return nil, 0, func() {}, hashErr
+		}
+		if rewritten != content {
+			if err := stageIntegrity(tmpDir, &file, rewritten); err != nil {
+				cleanup()
+				return nil, 0, func() {}, err
+			}
+			pages++
+		}
+		result = append(result, file)
+	}
+	return result, pages, cleanup, nil
+}
+
+// integrityTarget returns the site path a script or stylesheet tag in a
+// page in dir loads, if it loads one. Only <link>s that are stylesheets or
+// module preloads load something an integrity attribute covers.
+func integrityTarget(tag, dir string) (string, bool) {
+	attr := "src"
+	if strings.EqualFold(tag[1:5], "link") {
+		rel, _ := tagAttr(tag, "rel")
+		rels := strings.Fields(strings.ToLower(rel))
+		if !slices.Contains(rels, "stylesheet") && !slices.Contains(rels, "modulepreload") {
+			return "", false
+		}
+		attr = "href"
+	}
+	ref, ok := tagAttr(tag, attr)
+	if !ok {
+		return "", false
+	}
+	return resolveSiteRef(dir, ref)
+}
+
+// tagAttr returns the value of the named attribute of a start tag
+func tagAttr(tag, name string) (string, bool) {
+	m := integrityAttrPatterns[name].FindStringSubmatch(tag)
+	if m == nil {
+		return "", false
+	}
+	return m[1] + m[2] + m[3], true
+}
+
+// setIntegrity sets a tag's integrity attribute, replacing any it has
+func setIntegrity(tag, hash string) string {
+	attr := ` integrity="` + hash + `"`
+	if loc := integrityAttrPatterns["integrity"].FindStringIndex(tag); loc != nil {
+		return tag[:loc[0]] + attr + tag[loc[1]:]
+	}
+	// Right after the element name, where it can't land inside another
+	// attribute
+	end := len("<") + strings.IndexAny(tag[1:], " \t\r\n/>")
+	return tag[:end] + attr + tag[end:]
+}
+
+// integrityHash returns the Subresource Integrity hash of a file, e.g.
+// "sha384-oqVuAfXRKap7fdgcCY5uykM6+R9GqQ8K/uxy9rx7HNQlGYl1kPzQho1wx4JwY8wC"
+func integrityHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha512.New384()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha384-" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// stageIntegrity writes a page's rewritten content to the staging
+// directory and points file at it
+func stageIntegrity(tmpDir string, file *LocalFile, content string) error {
+	out, err := os.CreateTemp(tmpDir, "page-*"+path.Ext(file.Path))
+	if err != nil {
+		return err
+	}
+	if _, err := out.WriteString(content); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	etag, err := computeFileETag(out.Name())
+	if err != nil {
+		return err
+	}
+	file.AbsPath = out.Name()
+	file.ETag = etag
+	file.Size = int64(len(content))
+	return nil
+}
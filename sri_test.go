@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+const (
+	appJSIntegrity = "sha384-HT2E9NfWiuQ/w1PRai+hTyqW16NIoCGA/m8VQDUopfAtcz6YQjtsMmQd5uRbVDpW"
+	styleIntegrity = "sha384-myyg/hQ74aSgjBBvVME/QXAXEkT4Y9dHbVQ5C0lIyGpldvNLJV2IWc5ElXbqLi06"
+)
+
+func TestAddIntegrity(t *testing.T) {
+	_, files := writeSite(t, map[string]string{
+		"index.html": `<script src="/js/app.js"></script>
+<script integrity="sha384-stale" src="js/app.js?v=2" defer></script>
+<link rel="stylesheet" href="style.css"/>
+<link rel="icon" href="style.css">
+<script src="https://cdn.example.com/lib.js"></script>
+<script data-src="/js/app.js">inline()</script>
+<script src="/missing.js"></script>`,
+		"about.html": `<p>No scripts here</p>`,
+		"js/app.js":  "alert(1)",
+		"style.css":  "body{}",
+	})
+
+	result, pages, cleanup, err := addIntegrity(files)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer cleanup()
+	if pages != 1 {
+		t.Errorf("Expected 1 page to change, got %d", pages)
+	}
+
+	var index LocalFile
+	for _, f := range result {
+		if f.Path == "/index.html" {
+			index = f
+		}
+	}
+	data, err := os.ReadFile(index.AbsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	want := `<script integrity="` + appJSIntegrity + `" src="/js/app.js"></script>
+<script integrity="` + appJSIntegrity + `" src="js/app.js?v=2" defer></script>
+<link integrity="` + styleIntegrity + `" rel="stylesheet" href="style.css"/>
+<link rel="icon" href="style.css">
+<script src="https://cdn.example.com/lib.js"></script>
+<script data-src="/js/app.js">inline()</script>
+<script src="/missing.js"></script>`
+	if got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+	if index.Size != int64(len(got)) || index.ETag != md5Hex(got) {
+		t.Errorf("Expected the size and ETag of the rewritten page, got %d and %s", index.Size, index.ETag)
+	}
+
+	// Running again over the result changes nothing
+	_, pages, cleanup2, err := addIntegrity(result)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer cleanup2()
+	if pages != 0 {
+		t.Errorf("Expected no pages to change the second time, got %d", pages)
+	}
+}
+
+func TestSetIntegrity(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{`<script src="a.js">`, `<script integrity="h" src="a.js">`},
+		{`<SCRIPT src=a.js>`, `<SCRIPT integrity="h" src=a.js>`},
+		{`<link href="a.css" rel=stylesheet integrity='old'>`, `<link href="a.css" rel=stylesheet integrity="h">`},
+		{"<link\nrel=\"stylesheet\" href=\"a.css\">", "<link integrity=\"h\"\nrel=\"stylesheet\" href=\"a.css\">"},
+	}
+
+	for _, tt := range tests {
+		if got := setIntegrity(tt.tag, "h"); got != tt.want {
+			t.Errorf("Expected %s, got %s", tt.want, got)
+		}
+	}
+}
+
+func TestIntegrityTarget(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{`<script src="app.js">`, "/docs/app.js"},
+		{`<script type="module" src='/js/app.mjs#x'>`, "/js/app.mjs"},
+		{`<link rel="modulepreload" href="../lib.js">`, "/lib.js"},
+		{`<link rel="preload stylesheet" href="a.css">`, "/docs/a.css"},
+		{`<link rel="icon" href="favicon.ico">`, ""},
+		{`<script>inline()</script>`, ""},
+		{`<script src="//cdn.example.com/x.js">`, ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := integrityTarget(tt.tag, "/docs")
+		if !ok {
+			got = ""
+		}
+		if got != tt.want {
+			t.Errorf("Expected %q for %s, got %q", tt.want, tt.tag, got)
+		}
+	}
+}
@@ -1,19 +1,53 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"time"
 )
 
 type StatusCmd struct{}
 
+// StatusReport is the JSON form of 'efmrl3 status'
+type StatusReport struct {
+	SiteID         string        `json:"siteId"`
+	Name           string        `json:"name,omitempty"`
+	Domains        []string      `json:"domains"`
+	Quota          *QuotaInfo    `json:"quota,omitempty"`
+	ExpiresAt      string        `json:"expiresAt,omitempty"`
+	FileCount      *int          `json:"fileCount,omitempty"`
+	FileBytes      int64         `json:"fileBytes,omitempty"`
+	LastDeploy     *Deploy       `json:"lastDeploy,omitempty"`
+	Indexing       string        `json:"indexing,omitempty"` // "on" or "off"; empty if unknown
+	Dir            string        `json:"dir"`
+	BaseHost       string        `json:"baseHost"`
+	LoggedIn       bool          `json:"loggedIn"`
+	UsingDeployKey bool          `json:"usingDeployKey,omitempty"`
+	NotFound       bool          `json:"notFound,omitempty"`
+	Logins         []LoginStatus `json:"logins"`
+}
+
+// LoginStatus describes the saved login for one host
+type LoginStatus struct {
+	Host        string `json:"host"`
+	Provider    string `json:"provider,omitempty"`
+	ExpiresAt   string `json:"expiresAt,omitempty"` // when the access token expires, if known
+	Expired     bool   `json:"expired"`
+	Refreshable bool   `json:"refreshable"` // an expired token is renewed automatically
+}
+
 func (s *StatusCmd) Run() error {
 	config, err := LoadConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
 		fmt.Fprintf(os.Stderr, "Please navigate to a directory containing an %s file.\n", ConfigFileName)
-		fmt.Fprintf(os.Stderr, "If this is your first time, run 'efmrl3 config' to set up initial configuration.\n")
+		fmt.Fprintf(os.Stderr, "If this is your first time, run 'efmrl3 init' or 'efmrl3 config' to set up initial configuration.\n")
+		fmt.Fprintf(os.Stderr, "To run without a config file, pass --site-id.\n")
 		return fmt.Errorf("config file not found")
 	}
 
@@ -21,23 +55,30 @@ func (s *StatusCmd) Run() error {
 	baseHost := config.GetBaseHost()
 	globalConfig, err := LoadGlobalConfig()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Could not load credentials: %v\n", err)
+		warnf("Could not load credentials: %v\n", err)
 	}
 
 	var loggedIn bool
 	if globalConfig != nil {
 		_, loggedIn = globalConfig.GetHostCredentials(baseHost)
 	}
+	usingDeployKey := os.Getenv(TokenEnvVar) != ""
+	if usingDeployKey {
+		loggedIn = true
+	}
 
 	// Fetch efmrl details from server if logged in and we have a site ID
-	var efmrlName string
+	var efmrl Efmrl
 	var efmrlDomains []string
 	var efmrlQuota *QuotaInfo
 	var efmrlNotFound bool
+	var fileCount *int
+	var fileBytes int64
+	var lastDeploy *Deploy
+	var indexing string
 	var apiClient *APIClient
 	if loggedIn && config.Site.SiteID != "" {
-		baseURL := fmt.Sprintf("https://%s", baseHost)
-		apiClient, err = NewAPIClient(baseURL)
+		apiClient, err = NewAPIClient(config.BaseURL())
 		if err == nil {
 			// Fetch efmrl details (name, etc.)
 			resp, err := apiClient.Get(fmt.Sprintf("/admin/efmrls/%s", config.Site.SiteID))
@@ -45,12 +86,10 @@ func (s *StatusCmd) Run() error {
 				defer resp.Body.Close()
 				if resp.StatusCode == 200 {
 					var efmrlResp struct {
-						Efmrl struct {
-							Name string `json:"name"`
-						} `json:"efmrl"`
+						Efmrl Efmrl `json:"efmrl"`
 					}
 					if err := json.NewDecoder(resp.Body).Decode(&efmrlResp); err == nil {
-						efmrlName = efmrlResp.Efmrl.Name
+						efmrl = efmrlResp.Efmrl
 					}
 				} else if resp.StatusCode == 404 {
 					efmrlNotFound = true
@@ -81,42 +120,175 @@ func (s *StatusCmd) Run() error {
 				if err == nil {
 					efmrlQuota = quota
 				}
+
+				// Fetch the remote file list for a count
+				files, err := fetchRemoteFiles(apiClient, config.Site.SiteID)
+				if err == nil {
+					count := len(files)
+					fileCount = &count
+					for _, f := range files {
+						fileBytes += f.Size
+					}
+				}
+
+				// Fetch the latest deploy; older servers don't keep history
+				deploys, err := fetchDeploys(apiClient, config.Site.SiteID)
+				if err == nil && len(deploys) > 0 {
+					lastDeploy = &deploys[0]
+				}
+
+				if rules, err := fetchHeaderRules(apiClient, config.Site.SiteID); err == nil {
+					indexing = indexingState(rules)
+				}
 			}
 		}
 	}
 
-	fmt.Println("Site Status")
-	fmt.Println("===========")
+	logins := loginStatuses(globalConfig, serverNow())
+
+	if jsonOutput {
+		report := StatusReport{
+			SiteID:         config.Site.SiteID,
+			Name:           efmrl.Name,
+			Domains:        efmrlDomains,
+			Quota:          efmrlQuota,
+			ExpiresAt:      efmrl.ExpiresAt,
+			FileCount:      fileCount,
+			FileBytes:      fileBytes,
+			LastDeploy:     lastDeploy,
+			Indexing:       indexing,
+			Dir:            config.Site.Dir,
+			BaseHost:       baseHost,
+			LoggedIn:       loggedIn && (apiClient == nil || !apiClient.AuthFailed()),
+			UsingDeployKey: usingDeployKey,
+			NotFound:       efmrlNotFound,
+			Logins:         logins,
+		}
+		if report.Domains == nil {
+			report.Domains = []string{}
+		}
+		return printJSON(report)
+	}
+
+	if efmrl.ID != "" {
+		printExpiryWarning(config, efmrl.ExpiresAt)
+	}
+	outln("Site Status")
+	outln("===========")
 	if efmrlNotFound {
 		fmt.Fprintf(os.Stderr, "\nWARNING: Efmrl with this ID was not found or you no longer have access.\n")
 		fmt.Fprintf(os.Stderr, "         It may have been deleted or you may have been removed from the pod.\n\n")
 	}
-	if efmrlName != "" {
-		fmt.Printf("Name:      %s\n", efmrlName)
+	if efmrl.Name != "" {
+		outf("Name:      %s\n", efmrl.Name)
 	}
-	fmt.Printf("Site ID:   %s\n", config.Site.SiteID)
+	outf("Site ID:   %s\n", config.Site.SiteID)
 	if len(efmrlDomains) > 0 {
 		if len(efmrlDomains) == 1 {
-			fmt.Printf("Domain:    %s\n", efmrlDomains[0])
+			outf("Domain:    %s\n", efmrlDomains[0])
 		} else {
-			fmt.Printf("Domains:   %s\n", efmrlDomains[0])
+			outf("Domains:   %s\n", efmrlDomains[0])
 			for _, domain := range efmrlDomains[1:] {
-				fmt.Printf("           %s\n", domain)
+				outf("           %s\n", domain)
 			}
 		}
 	}
 	if efmrlQuota != nil {
-		fmt.Printf("Quota:     currently using %s; %s available\n",
+		outf("Quota:     currently using %s; %s available\n",
 			formatBytes(efmrlQuota.CurrentSpace),
 			formatBytes(efmrlQuota.AvailableSpace))
 	}
-	fmt.Printf("Dir:       %s\n", config.Site.Dir)
-	fmt.Printf("Base Host: %s\n", baseHost)
+	if efmrl.ID != "" {
+		outf("Expires:   %s\n", formatExpiry(efmrl.ExpiresAt))
+	}
+	if fileCount != nil {
+		outf("Files:     %d (%s)\n", *fileCount, formatBytes(fileBytes))
+	}
+	if lastDeploy != nil {
+		outf("Deployed:  %s (%s)\n", formatRelativeTime(lastDeploy.CreatedAt, serverNow()), lastDeploy.ID)
+	}
+	if indexing != "" {
+		outf("Indexing:  %s\n", describeIndexing(indexing))
+	}
+	outf("Dir:       %s\n", config.Site.Dir)
+	outf("Base Host: %s\n", baseHost)
 	if apiClient != nil && apiClient.AuthFailed() {
-		fmt.Println("Logged in: no (session expired — run 'efmrl3 login')")
+		outln("Logged in: no (session expired — run 'efmrl3 login')")
+	} else if usingDeployKey {
+		outf("Logged in: using deploy key from $%s\n", TokenEnvVar)
 	} else {
-		fmt.Printf("Logged in: %v\n", loggedIn)
+		outf("Logged in: %v\n", loggedIn)
+	}
+
+	if len(logins) > 0 {
+		outln("\nLogins:")
+		for _, login := range logins {
+			outf("  %s: %s\n", login.Host, login.describe())
+		}
 	}
 
 	return nil
 }
+
+// loginStatuses returns the saved login for every host, sorted by host
+func loginStatuses(globalConfig *GlobalConfig, now time.Time) []LoginStatus {
+	logins := []LoginStatus{}
+	if globalConfig == nil {
+		return logins
+	}
+
+	for _, host := range slices.Sorted(maps.Keys(globalConfig.Hosts)) {
+		creds := globalConfig.Hosts[host]
+		login := LoginStatus{
+			Host:        host,
+			Provider:    creds.Provider,
+			Refreshable: creds.RefreshToken != "",
+		}
+		if exp, ok := tokenExpiry(creds.AccessToken); ok {
+			login.ExpiresAt = exp.UTC().Format(time.RFC3339)
+			login.Expired = !now.Before(exp)
+		}
+		logins = append(logins, login)
+	}
+	return logins
+}
+
+// describe returns a short description of a login, e.g. "google, token
+// expired (renewed automatically)"
+func (l LoginStatus) describe() string {
+	var parts []string
+	if l.Provider != "" {
+		parts = append(parts, l.Provider)
+	}
+	switch {
+	case l.ExpiresAt == "":
+		parts = append(parts, "token expiry unknown")
+	case l.Expired && l.Refreshable:
+		parts = append(parts, "token expired (renewed automatically)")
+	case l.Expired:
+		parts = append(parts, "token expired (run 'efmrl3 login')")
+	default:
+		parts = append(parts, "token expires "+formatTimestamp(l.ExpiresAt))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// tokenExpiry reads the exp claim of a JWT access token. The signature isn't
+// checked; this is only for display and for deciding when to refresh.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testJWT returns an unsigned JWT with the given exp claim
+func testJWT(exp int64) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp)))
+	return "eyJhbGciOiJub25lIn0." + payload + ".sig"
+}
+
+// TestTokenExpiry tests reading the exp claim from access tokens
+func TestTokenExpiry(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		wantOK bool
+	}{
+		{"jwt", testJWT(1700000000), true},
+		{"opaque token", "efk_abc123", false},
+		{"bad payload", "a.!!!.c", false},
+		{"no exp", "a." + base64.RawURLEncoding.EncodeToString([]byte(`{}`)) + ".c", false},
+	}
+
+	for _, tt := range tests {
+		exp, ok := tokenExpiry(tt.token)
+		if ok != tt.wantOK {
+			t.Errorf("%s: expected ok=%v, got %v", tt.name, tt.wantOK, ok)
+		}
+		if ok && exp.Unix() != 1700000000 {
+			t.Errorf("%s: expected exp 1700000000, got %d", tt.name, exp.Unix())
+		}
+	}
+}
+
+// TestLoginStatuses tests the per-host login report
+func TestLoginStatuses(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	config := &GlobalConfig{Hosts: map[string]HostCredentials{
+		"efmrl.work":     {AccessToken: testJWT(now.Add(time.Hour).Unix()), RefreshToken: "r", Provider: "google"},
+		"localhost:8787": {AccessToken: testJWT(now.Add(-time.Hour).Unix())},
+	}}
+
+	logins := loginStatuses(config, now)
+	if len(logins) != 2 {
+		t.Fatalf("Expected 2 logins, got %d", len(logins))
+	}
+	if logins[0].Host != "efmrl.work" || logins[0].Expired || !logins[0].Refreshable {
+		t.Errorf("Unexpected login for efmrl.work: %+v", logins[0])
+	}
+	if logins[1].Host != "localhost:8787" || !logins[1].Expired || logins[1].Refreshable {
+		t.Errorf("Unexpected login for localhost:8787: %+v", logins[1])
+	}
+	if got := logins[1].describe(); got != "token expired (run 'efmrl3 login')" {
+		t.Errorf("Expected an expired description, got %q", got)
+	}
+
+	if logins := loginStatuses(nil, now); logins == nil || len(logins) != 0 {
+		t.Errorf("Expected an empty list without credentials, got %v", logins)
+	}
+}
@@ -2,40 +2,68 @@ package main
 
 import (
 	"crypto/md5"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
-	"strings"
+	"time"
 )
 
 // SyncCmd synchronizes local files with the remote efmrl site
 type SyncCmd struct {
-	DryRun bool `help:"Show what would be synced without making changes" short:"n"`
-	Force  bool `help:"Force upload all files, ignoring ETags" short:"f"`
-	Delete bool `help:"Delete remote files not present locally" default:"true" negatable:""`
+	DryRun            bool     `help:"Show what would be synced without making changes" short:"n"`
+	Force             bool     `help:"Force upload all files, ignoring ETags" short:"f"`
+	Delete            bool     `help:"Delete remote files not present locally" default:"true" negatable:""`
+	ForceUnlock       bool     `help:"Remove a stale sync lock left behind by an interrupted sync"`
+	Dir               string   `help:"Directory to sync (overrides dir from the config file)" type:"path" xor:"all"`
+	All               bool     `help:"Sync every site configured in the config file, each from its own dir" xor:"all"`
+	Parallel          int      `help:"With --all, how many sites to sync at once" default:"1"`
+	ProgressJSON      bool     `help:"Stream newline-delimited JSON progress events on stdout, for wrappers that draw their own progress"`
+	Yes               bool     `help:"Don't ask before deleting more remote files than confirm_deletes (in efmrl.toml) allows" short:"y"`
+	Sitemap           bool     `help:"Generate and upload a sitemap.xml of the HTML pages (or set sitemap in [sync])"`
+	Fingerprint       bool     `help:"Give referenced assets content-hashed names, rewrite references to them, and cache them as immutable (or set fingerprint in [sync])"`
+	OptimizeImages    bool     `help:"Losslessly recompress PNG and JPEG images before uploading them (or set optimize_images in [sync])"`
+	ImageVariants     []string `help:"Also upload smaller variants of each image in these formats: webp, avif (or set image_variants in [sync])" placeholder:"FORMAT"`
+	Minify            []string `help:"Minify these kinds of file before uploading them: html, css, js (or set minify in [sync])" placeholder:"KIND"`
+	Stream            bool     `help:"For very large sites: scan, compare, and upload in one pass, so uploads start before the scan finishes (or set stream in [sync])"`
+	Integrity         bool     `help:"Add or update the integrity attributes of the site's scripts and stylesheets in each page (or set integrity in [sync])"`
+	CheckLinks        bool     `help:"Check internal links before uploading anything, and stop if any are broken (or set check_links in [sync])"`
+	KeepGoing         bool     `help:"Don't stop at a failed upload or delete: carry on, retry the failures at the end, and report any that still fail" xor:"failure"`
+	RollbackOnFailure bool     `help:"Back up the remote files a sync deletes or overwrites, and if the sync fails partway, put the site back as it was" xor:"failure"`
+	Resume            bool     `help:"Finish an interrupted sync from the plan it saved, instead of scanning and comparing everything again"`
+	KeepEmptyDirs     bool     `help:"Upload a .keep file into each empty directory, so it exists on the site (or set empty_dirs = \"keep\" in [sync])"`
+	Verify            bool     `help:"After syncing, fetch / and the uploaded files from the live site and fail unless it serves them (or set after_sync in [verify])"`
+	Timings           bool     `help:"After syncing, print how long each phase took: scan, hash, remote list, plan, upload, and verify"`
 }
 
 // RemoteFile represents a file on the server
 type RemoteFile struct {
 	Path     string `json:"path"`
 	ETag     string `json:"etag"`
+	SHA256   string `json:"sha256,omitempty"`
 	Size     int64  `json:"size"`
 	Uploaded string `json:"uploaded"`
 }
 
 // LocalFile represents a file on the local filesystem
 type LocalFile struct {
-	Path        string // Relative path with leading slash (e.g., "/index.html")
-	AbsPath     string // Absolute filesystem path
-	ETag        string // MD5 hex hash
-	Size        int64
-	ContentType string
+	Path        string `json:"path"`    // Relative path with leading slash (e.g., "/index.html")
+	AbsPath     string `json:"absPath"` // Absolute filesystem path
+	ETag        string `json:"etag"`    // MD5 hex hash
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+
+	inode   fileID    // set if the file has other hard links
+	modTime time.Time // when the file was last modified, if it was scanned
 }
 
 // SyncPlan describes what operations will be performed
@@ -43,6 +71,29 @@ type SyncPlan struct {
 	ToUpload  []LocalFile
 	ToDelete  []RemoteFile
 	Unchanged []string
+
+	// Replacing has the remote ETag of each upload that replaces a file
+	// already on the site
+	Replacing map[string]string
+
+	// Existing has the path of an unchanged file for each ETag they have,
+	// for uploads with the same content to be copied from
+	Existing map[string]string
+}
+
+// SyncResult is the JSON form of a completed sync. With DryRun set, it lists
+// what would have changed.
+type SyncResult struct {
+	SiteID     string           `json:"siteId"`
+	Dir        string           `json:"dir"`
+	DryRun     bool             `json:"dryRun"`
+	Uploaded   []string         `json:"uploaded"`
+	Deleted    []string         `json:"deleted"`
+	Unchanged  int              `json:"unchanged"`
+	ScanTimeMs int64            `json:"scanTimeMs,omitempty"` // how long scanning and hashing the local files took
+	TimingsMs  map[string]int64 `json:"timingsMs,omitempty"`  // how long each phase took, with --timings
+	DeployID   string           `json:"deployId,omitempty"`   // set by deploy once the deploy is recorded
+	DeployURL  string           `json:"deployUrl,omitempty"`  // the recorded deploy's permanent URL, if it has one
 }
 
 // QuotaInfo represents quota information for an efmrl
@@ -52,7 +103,38 @@ type QuotaInfo struct {
 	AvailableSpace int64 `json:"availableSpace"`
 }
 
+// Examples are shown in 'efmrl3 sync --help'
+func (s *SyncCmd) Examples() []Example {
+	return []Example{
+		{"Preview what would change, without touching the site", "efmrl3 sync --dry-run"},
+		{"Sync a build directory, keeping remote files that aren't there", "efmrl3 sync --dir dist --no-delete"},
+		{"Sync from CI with a deploy key, never prompting", "EFMRL_TOKEN=$DEPLOY_KEY efmrl3 --non-interactive sync --yes"},
+		{"Stream progress events for a wrapper script", "efmrl3 sync --progress-json | my-progress-ui"},
+		{"Sync every site in a monorepo, three at a time", "efmrl3 sync --all --parallel 3"},
+		{"Keep a sitemap.xml of the HTML pages up to date", "efmrl3 sync --sitemap"},
+		{"Shrink images, adding WebP copies of them", "efmrl3 sync --optimize-images --image-variants webp"},
+		{"Minify pages, stylesheets, and scripts as they're uploaded", "efmrl3 sync --minify html,css,js"},
+		{"Protect scripts and stylesheets with Subresource Integrity hashes", "efmrl3 sync --integrity"},
+		{"Refuse to sync a site with broken links", "efmrl3 sync --check-links"},
+		{"Fail unless the live site serves what was synced", "efmrl3 sync --verify"},
+		{"Start uploading a huge site while it's still being scanned", "efmrl3 sync --stream"},
+		{"Sync everything that can be synced, reporting what couldn't", "efmrl3 sync --keep-going"},
+		{"Never leave the site half-updated by a failed sync", "efmrl3 sync --rollback-on-failure"},
+		{"Finish a sync that was interrupted, without starting over", "efmrl3 sync --resume"},
+	}
+}
+
 func (s *SyncCmd) Run() error {
+	if s.ProgressJSON {
+		if err := enableProgressEvents(); err != nil {
+			return err
+		}
+	}
+
+	if s.All {
+		return s.syncAll()
+	}
+
 	// 1. Load configuration
 	config, err := LoadConfig()
 	if err != nil {
@@ -60,150 +142,582 @@ func (s *SyncCmd) Run() error {
 	}
 
 	if config.Site.SiteID == "" {
-		return fmt.Errorf("no site_id configured (run 'efmrl3 config --id <site-id>')")
+		return errNoSiteID
 	}
 
-	// Determine the directory to sync
-	syncDir := config.Site.Dir
-	if syncDir == "" {
-		syncDir = "." // Default to current directory
+	// Make sure no other sync is running in this project
+	unlock, err := acquireSyncLock(s.ForceUnlock)
+	if err != nil {
+		return err
 	}
+	defer unlock()
+	defer watchInterrupts()()
+	setUploadTimeouts(config)
+	setPerformance(config)
 
+	syncDir := config.SyncDir()
+	if s.Dir != "" {
+		syncDir = s.Dir
+	} else if config.Site.Dir == "" && config.Build.OutputDir == "" {
+		detected, err := detectSyncDir(config)
+		if err != nil {
+			return err
+		}
+		if detected != "" {
+			syncDir = detected
+		}
+	}
+
+	result, err := s.syncDir(config, syncDir)
+	if !s.DryRun {
+		notifySync(config, "Sync", result, err)
+	}
+	if err != nil {
+		return err
+	}
+	return printSyncResult(result)
+}
+
+// syncDir runs the scan/plan/execute steps of a sync for the given
+// directory. The caller is responsible for loading config and holding the
+// project sync lock, and for printing the result.
+func (s *SyncCmd) syncDir(config *Config, syncDir string) (*SyncResult, error) {
 	// Convert to absolute path
 	absDir, err := filepath.Abs(syncDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve directory path: %w", err)
+		return nil, fmt.Errorf("failed to resolve directory path: %w", err)
 	}
 
 	// Verify directory exists
 	if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
-		return fmt.Errorf("sync directory does not exist: %s", syncDir)
+		return nil, fmt.Errorf("sync directory does not exist: %s", syncDir)
 	}
 
-	fmt.Printf("Syncing directory: %s\n", absDir)
-	fmt.Printf("Site ID: %s\n", config.Site.SiteID)
-	fmt.Println()
+	// Keep other syncs of the same directory out until this one is done
+	unlock, err := lockSyncDir(absDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	outf("Syncing directory: %s\n", absDir)
+	outf("Site ID: %s\n", config.Site.SiteID)
+	outln()
+
+	if s.Resume {
+		return s.resumeSync(config, absDir)
+	}
+	if !s.DryRun {
+		warnSavedPlan(absDir)
+	}
+
+	if s.Stream || config.Sync.Stream {
+		return s.syncStreaming(config, absDir)
+	}
 
 	// 2. Scan local files
-	fmt.Println("Scanning local files...")
-	localFiles, err := scanLocalFiles(absDir)
+	timings := newSyncTimings()
+	startGroup("Scan")
+	emitEvent("scan_started", map[string]any{"dir": absDir})
+	scanStart := time.Now()
+	spin := startSpinner("Scanning local files...")
+	found := &scanProgress{verb: "found"}
+	spin.Track(found)
+	localFiles, emptyDirs, err := scanLocalTree(absDir, found)
+	spin.Stop()
+	scanTime := time.Since(scanStart)
+	timings.since("scan", scanStart)
 	if err != nil {
-		return fmt.Errorf("failed to scan local files: %w", err)
+		return nil, fmt.Errorf("failed to scan local files: %w", err)
+	}
+	switch {
+	case len(emptyDirs) == 0:
+	case s.KeepEmptyDirs || config.EmptyDirs() == emptyDirsKeep:
+		localFiles = append(localFiles, keepFiles(emptyDirs)...)
+	case config.EmptyDirs() == emptyDirsWarn:
+		warnEmptyDirs(emptyDirs)
+	}
+	if err := normalizeLocalPaths(localFiles, config.UnicodeNormalization()); err != nil {
+		return nil, err
+	}
+	emitEvent("scan_complete", map[string]any{"files": len(localFiles), "bytes": calculateTotalSize(localFiles), "durationMs": scanTime.Milliseconds()})
+	outf("Found %s local file(s), %s, in %s\n\n", formatCount(int64(len(localFiles))), formatBytes(calculateTotalSize(localFiles)), scanTime.Round(time.Millisecond))
+
+	checkMode := config.Sync.CheckLinks
+	if s.CheckLinks {
+		checkMode = "fail"
+	}
+	if checkMode != "" {
+		report, err := checkLinks(localFiles, config.Sync.CheckLinksIgnore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check links: %w", err)
+		}
+		printLinkReport(report)
+		if len(report.Broken) == 0 {
+			outln()
+		} else if checkMode != "warn" {
+			return nil, fmt.Errorf("found %d broken link(s); nothing was uploaded (fix them, or set check_links = \"warn\" in [sync])", len(report.Broken))
+		}
 	}
-	fmt.Printf("Found %d local file(s)\n\n", len(localFiles))
 
 	// 3. Check quota before syncing
-	fmt.Println("Checking quota...")
-	baseURL := fmt.Sprintf("https://%s", config.GetBaseHost())
+	baseURL := config.BaseURL()
 	apiClient, err := NewAPIClient(baseURL)
 	if err != nil {
-		return fmt.Errorf("failed to create API client: %w", err)
+		return nil, fmt.Errorf("failed to create API client: %w", err)
+	}
+	warnIfExpiring(config, apiClient)
+
+	if s.Sitemap || config.Sync.Sitemap {
+		var cleanup func()
+		if localFiles, cleanup, err = addSitemap(config, apiClient, localFiles); err != nil {
+			return nil, err
+		}
+		defer cleanup()
+	}
+
+	// Images are optimized before fingerprinting, so the fingerprints are
+	// of what's uploaded
+	variants := s.ImageVariants
+	if len(variants) == 0 {
+		variants = config.Sync.ImageVariants
+	}
+	for _, format := range variants {
+		if _, ok := imageVariantFormats[format]; !ok {
+			return nil, fmt.Errorf("unknown image variant format %q (expected webp or avif)", format)
+		}
+	}
+	if s.OptimizeImages || config.Sync.OptimizeImages || len(variants) > 0 {
+		var cleanup func()
+		var report imageReport
+		spin = startSpinner("Optimizing images...")
+		localFiles, report, cleanup, err = optimizeImages(localFiles, variants)
+		spin.Stop()
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		outf("%s\n\n", report.describe())
+	}
+
+	minify := s.Minify
+	if len(minify) == 0 {
+		minify = config.Sync.Minify
+	}
+	for _, kind := range minify {
+		if kind != "html" && kind != "css" && kind != "js" {
+			return nil, fmt.Errorf("unknown minify kind %q (expected html, css, or js)", kind)
+		}
+	}
+	if len(minify) > 0 {
+		var cleanup func()
+		var minified int
+		var saved int64
+		if localFiles, minified, saved, cleanup, err = minifyFiles(localFiles, minify); err != nil {
+			return nil, fmt.Errorf("failed to minify files: %w", err)
+		}
+		defer cleanup()
+		outf("Minified %d file(s), saving %s\n\n", minified, formatBytes(saved))
+	}
+
+	var fingerprinted []string
+	if s.Fingerprint || config.Sync.Fingerprint {
+		// Fingerprints are made from ETags, so every file needs one
+		if err := hashLocalFiles(localFiles, func(*LocalFile) bool { return true }); err != nil {
+			return nil, err
+		}
+		var cleanup func()
+		if localFiles, fingerprinted, cleanup, err = fingerprintAssets(localFiles, config.Sync.FingerprintExtensions); err != nil {
+			return nil, fmt.Errorf("failed to fingerprint assets: %w", err)
+		}
+		defer cleanup()
+		outf("Fingerprinted %d asset(s)\n\n", len(fingerprinted))
+	}
+
+	// Integrity hashes come last, so they're of exactly what's uploaded
+	if s.Integrity || config.Sync.Integrity {
+		var cleanup func()
+		var pages int
+		if localFiles, pages, cleanup, err = addIntegrity(localFiles); err != nil {
+			return nil, fmt.Errorf("failed to add integrity attributes: %w", err)
+		}
+		defer cleanup()
+		outf("Updated integrity attributes in %d page(s)\n\n", pages)
 	}
 
+	spin = startSpinner("Checking quota...")
 	quota, err := fetchQuota(apiClient, config.Site.SiteID)
+	spin.Stop()
 	if err != nil {
-		return fmt.Errorf("failed to fetch quota: %w", err)
+		return nil, fmt.Errorf("failed to fetch quota: %w", err)
 	}
 
 	if err := validateQuota(localFiles, quota); err != nil {
-		return err
+		return nil, err
 	}
-	fmt.Printf("Quota check passed (local: %s, quota: %s)\n\n",
+	outf("Quota check passed (local: %s, quota: %s)\n\n",
 		formatBytes(calculateTotalSize(localFiles)),
 		formatBytes(quota.MaxSpace))
 
 	// 4. Fetch remote file list
-	fmt.Println("Fetching remote file list...")
+	listStart := time.Now()
+	spin = startSpinner("Fetching remote file list...")
 	remoteFiles, err := fetchRemoteFiles(apiClient, config.Site.SiteID)
+	spin.Stop()
+	timings.since("remote list", listStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+	outf("Found %d remote file(s)\n\n", len(remoteFiles))
+	if !s.Delete {
+		warnUnnormalizedRemote(localFiles, remoteFiles, config.UnicodeNormalization())
+	}
+
+	// Only files also on the site are compared, so only they need hashing
+	// now, unless they're compared by metadata instead. New files, and
+	// every file with --force, are hashed as they're uploaded instead of
+	// being read twice.
+	if !s.Force {
+		if n := matchByMetadata(localFiles, remoteFiles, config.CompareMode()); n > 0 {
+			outln(dim(fmt.Sprintf("Compared %d file(s) by size and modification time", n)))
+		}
+	}
+	remotePaths := make(map[string]bool, len(remoteFiles))
+	for _, rf := range remoteFiles {
+		remotePaths[rf.Path] = true
+	}
+	hashStart := time.Now()
+	spin = startSpinner("Hashing local files...")
+	hashed := &scanProgress{verb: "hashed"}
+	spin.Track(hashed)
+	err = hashLocalFilesProgress(localFiles, func(lf *LocalFile) bool { return !s.Force && remotePaths[lf.Path] }, hashed)
+	spin.Stop()
 	if err != nil {
-		return fmt.Errorf("failed to fetch remote files: %w", err)
+		return nil, err
 	}
-	fmt.Printf("Found %d remote file(s)\n\n", len(remoteFiles))
+	scanTime += time.Since(hashStart)
+	timings.since("hash", hashStart)
 
 	// 5. Compute sync plan
+	planStart := time.Now()
+	startGroup("Plan")
+	if err := checkCaseConflicts(localFiles, remoteFiles, s.Delete); err != nil {
+		return nil, err
+	}
 	plan := computeSyncPlan(localFiles, remoteFiles, s.Force, s.Delete)
+	impact := computeSyncImpact(plan, remoteFiles, quota.CurrentSpace)
+	emitEvent("plan_ready", map[string]any{
+		"upload":         len(plan.ToUpload),
+		"delete":         len(plan.ToDelete),
+		"unchanged":      len(plan.Unchanged),
+		"uploadBytes":    impact.UploadBytes,
+		"deleteBytes":    impact.DeleteBytes,
+		"projectedSpace": impact.ProjectedSpace,
+	})
 
 	// 6. Display plan
-	fmt.Println("Sync Plan")
-	fmt.Println("=========")
+	outln("Sync Plan")
+	outln("=========")
 	if len(plan.ToUpload) > 0 {
-		fmt.Printf("Files to upload: %d\n", len(plan.ToUpload))
+		outf("Files to upload: %d\n", len(plan.ToUpload))
 		for _, f := range plan.ToUpload {
-			fmt.Printf("  + %s\n", f.Path)
+			outf("  + %s\n", f.Path)
 		}
-		fmt.Println()
+		outln()
 	}
 
 	if len(plan.ToDelete) > 0 {
-		fmt.Printf("Files to delete: %d\n", len(plan.ToDelete))
+		outf("Files to delete: %d\n", len(plan.ToDelete))
 		for _, f := range plan.ToDelete {
-			fmt.Printf("  - %s\n", f.Path)
+			outf("  - %s\n", f.Path)
 		}
-		fmt.Println()
+		outln()
 	}
 
 	if len(plan.Unchanged) > 0 {
-		fmt.Printf("Files unchanged: %d\n", len(plan.Unchanged))
+		outln(dim(fmt.Sprintf("Files unchanged: %d", len(plan.Unchanged))))
+	}
+	if len(plan.ToUpload) > 0 || len(plan.ToDelete) > 0 {
+		outf("Impact: %s\n", impact.describe(plan, quota))
 	}
 
-	if len(plan.ToUpload) == 0 && len(plan.ToDelete) == 0 {
-		fmt.Println("✓ Everything is up to date")
-		return nil
+	result := SyncResult{
+		SiteID:     config.Site.SiteID,
+		Dir:        absDir,
+		DryRun:     s.DryRun,
+		Uploaded:   []string{},
+		Deleted:    []string{},
+		Unchanged:  len(plan.Unchanged),
+		ScanTimeMs: scanTime.Milliseconds(),
+	}
+	for _, f := range plan.ToUpload {
+		result.Uploaded = append(result.Uploaded, f.Path)
 	}
+	for _, f := range plan.ToDelete {
+		result.Deleted = append(result.Deleted, f.Path)
+	}
+	timings.since("plan", planStart)
 
 	// 7. Execute plan (or exit if dry-run)
-	if s.DryRun {
-		fmt.Println("\n--dry-run mode: no changes made")
+	switch {
+	case len(plan.ToUpload) == 0 && len(plan.ToDelete) == 0:
+		outf("%s Everything is up to date\n", green("✓"))
+	case s.DryRun:
+		outln("\n--dry-run mode: no changes made")
+	default:
+		outln()
+		if err := s.confirmDeletes(config, plan); err != nil {
+			return nil, err
+		}
+		startGroup("Sync")
+		uploadStart := time.Now()
+		journal := s.startJournal(config, absDir, plan)
+		err := s.executeSyncPlan(apiClient, config.Site.SiteID, plan, journal)
+		journal.finish(err == nil)
+		timings.since("upload", uploadStart)
+		if err != nil {
+			if journal != nil {
+				outf("\nThe plan was saved: 'efmrl3 sync --resume' finishes this sync without starting over\n")
+			}
+			return nil, err
+		}
+	}
+	if len(fingerprinted) > 0 && !s.DryRun {
+		if err := applyImmutableHeaders(apiClient, config.Site.SiteID, fingerprinted, localFiles); err != nil {
+			return nil, err
+		}
+	}
+
+	verifyStart := time.Now()
+	if err := s.verifyAfterSync(config, apiClient, &result); err != nil {
+		return nil, err
+	}
+	if (s.Verify || config.Verify.AfterSync) && !s.DryRun {
+		timings.since("verify", verifyStart)
+	}
+	endGroup()
+
+	s.reportTimings(timings, &result)
+	return &result, nil
+}
+
+// verifyAfterSync checks the live site serves what was synced, if --verify
+// or [verify] after_sync asks for it
+func (s *SyncCmd) verifyAfterSync(config *Config, client *APIClient, result *SyncResult) error {
+	if (!s.Verify && !config.Verify.AfterSync) || s.DryRun {
 		return nil
 	}
+	startGroup("Verify")
+	outln()
+	verified, err := verifyDeploy(client, config.Site.SiteID, verifyPaths(config, result.Uploaded), defaultVerifyTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to verify the live site: %w", err)
+	}
+	if verified.Failed > 0 {
+		return fmt.Errorf("files were synced, but the live site doesn't serve %d of %d path(s) as synced", verified.Failed, len(verified.Checks))
+	}
+	return nil
+}
+
+// confirmDeletes asks before a sync deletes more remote files than the
+// project allows without confirmation, so a wrong --dir or an empty build
+// can't silently wipe a site
+func (s *SyncCmd) confirmDeletes(config *Config, plan SyncPlan) error {
+	limit := config.ConfirmDeletes()
+	if s.Yes || limit < 0 || len(plan.ToDelete) <= limit {
+		return nil
+	}
+
+	ok, err := askConfirm(fmt.Sprintf("This sync deletes %d remote file(s). Continue?", len(plan.ToDelete)), false)
+	if err != nil {
+		return fmt.Errorf("%w (use --yes to skip the confirmation)", err)
+	}
+	if !ok {
+		return fmt.Errorf("sync cancelled")
+	}
+	outln()
+	return nil
+}
+
+// SyncImpact is the effect a sync plan has on the site's storage
+type SyncImpact struct {
+	UploadBytes    int64
+	DeleteBytes    int64
+	ProjectedSpace int64 // storage used once the plan is applied
+}
+
+// computeSyncImpact works out how much a plan uploads and frees, and the
+// storage the site will use afterwards. Uploads that replace a remote file
+// free that file's old size.
+func computeSyncImpact(plan SyncPlan, remote []RemoteFile, currentSpace int64) SyncImpact {
+	remoteSizes := make(map[string]int64, len(remote))
+	for _, rf := range remote {
+		remoteSizes[rf.Path] = rf.Size
+	}
 
-	fmt.Println()
-	return executeSyncPlan(apiClient, config.Site.SiteID, plan)
+	var impact SyncImpact
+	replaced := int64(0)
+	for _, lf := range plan.ToUpload {
+		impact.UploadBytes += lf.Size
+		replaced += remoteSizes[lf.Path]
+	}
+	for _, rf := range plan.ToDelete {
+		impact.DeleteBytes += rf.Size
+	}
+
+	impact.ProjectedSpace = max(currentSpace+impact.UploadBytes-replaced-impact.DeleteBytes, 0)
+	return impact
+}
+
+// describe returns a one-line summary of the impact, e.g. "3 upload(s)
+// (1.20 MB), 1 deletion(s) (4.00 KB freed), projected storage 12% of quota"
+func (i SyncImpact) describe(plan SyncPlan, quota *QuotaInfo) string {
+	summary := fmt.Sprintf("%d upload(s) (%s), %d deletion(s) (%s freed)",
+		len(plan.ToUpload), formatBytes(i.UploadBytes),
+		len(plan.ToDelete), formatBytes(i.DeleteBytes))
+	if quota != nil && quota.MaxSpace > 0 {
+		summary += fmt.Sprintf(", projected storage %d%% of quota (%s of %s)",
+			i.ProjectedSpace*100/quota.MaxSpace, formatBytes(i.ProjectedSpace), formatBytes(quota.MaxSpace))
+	}
+	return summary
+}
+
+// printSyncResult prints the JSON form of a sync with --json, the final
+// event with --progress-json, or the one-line summary with --quiet. Under
+// GitHub Actions it also writes the job summary.
+func printSyncResult(result *SyncResult) error {
+	if err := writeStepSummary(result); err != nil {
+		warnf("%v\n", err)
+	}
+	if progressEvents {
+		emitEvent("sync_complete", map[string]any{
+			"siteId":    result.SiteID,
+			"dryRun":    result.DryRun,
+			"uploaded":  len(result.Uploaded),
+			"deleted":   len(result.Deleted),
+			"unchanged": result.Unchanged,
+			"deployId":  result.DeployID,
+			"deployUrl": result.DeployURL,
+		})
+		return nil
+	}
+	if jsonOutput {
+		return printJSON(result)
+	}
+	if quietOutput {
+		fmt.Println(result.summary())
+	}
+	return nil
+}
+
+// summary returns a one-line description of the sync, for --quiet
+func (r SyncResult) summary() string {
+	if r.DryRun {
+		return fmt.Sprintf("Dry run for %s: would upload %d, delete %d (%d unchanged)",
+			r.SiteID, len(r.Uploaded), len(r.Deleted), r.Unchanged)
+	}
+	return fmt.Sprintf("Synced %s: %d uploaded, %d deleted, %d unchanged",
+		r.SiteID, len(r.Uploaded), len(r.Deleted), r.Unchanged)
 }
 
 // scanLocalFiles walks the directory tree and computes ETags for all files
 func scanLocalFiles(rootDir string) ([]LocalFile, error) {
+	files, err := scanLocalFilesUnhashed(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	return files, hashLocalFiles(files, func(*LocalFile) bool { return true })
+}
+
+// scanLocalFilesUnhashed walks the directory tree like scanLocalFiles, but
+// leaves every ETag empty, so sync can hash only the files it compares
+func scanLocalFilesUnhashed(rootDir string) ([]LocalFile, error) {
+	files, _, err := scanLocalTree(rootDir, nil)
+	return files, err
+}
+
+// scanLocalTree is scanLocalFilesUnhashed, also returning the directories
+// that would be synced empty: those with nothing to sync inside, not even
+// in a subdirectory. Only the deepest of a chain of empty directories is
+// listed, as a file there would bring back the rest. Each file found is
+// counted in progress, if it isn't nil.
+func scanLocalTree(rootDir string, progress *scanProgress) ([]LocalFile, []string, error) {
 	var files []LocalFile
+	var dirs []string
+	err := walkLocalTree(rootDir, func(dir string) {
+		dirs = append(dirs, dir)
+	}, func(file LocalFile) error {
+		files = append(files, file)
+		progress.add(file.Size)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	notEmpty := make(map[string]bool)
+	for _, lf := range files {
+		for dir := path.Dir(lf.Path[1:]); dir != "."; dir = path.Dir(dir) {
+			notEmpty[dir] = true
+		}
+	}
+	hasSubdir := make(map[string]bool)
+	for _, dir := range dirs {
+		hasSubdir[path.Dir(dir)] = true
+	}
+	var empty []string
+	for _, dir := range dirs {
+		if !notEmpty[dir] && !hasSubdir[dir] {
+			empty = append(empty, dir)
 		}
+	}
+	return files, empty, nil
+}
 
-		// Skip directories
-		if info.IsDir() {
-			return nil
+// walkLocalFiles calls visit with each file under rootDir that would be
+// synced, unhashed and in lexical order, as the tree is walked. An error
+// from visit stops the walk and is returned.
+func walkLocalFiles(rootDir string, visit func(LocalFile) error) error {
+	return walkLocalTree(rootDir, nil, visit)
+}
+
+// walkLocalTree is walkLocalFiles, also calling visitDir, if it's not nil,
+// with the slash-separated path of each directory below rootDir that's
+// walked rather than skipped
+func walkLocalTree(rootDir string, visitDir func(string), visit func(LocalFile) error) error {
+	ignore, err := loadIgnoreRules(rootDir)
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
 		}
 
-		// Skip hidden files and directories (starting with .)
 		relPath, err := filepath.Rel(rootDir, path)
 		if err != nil {
 			return err
 		}
 
-		// Check if any component of the path starts with .
-		parts := strings.Split(relPath, string(filepath.Separator))
-		for _, part := range parts {
-			if strings.HasPrefix(part, ".") {
-				// If it's a directory, skip the entire subtree
-				if info.IsDir() {
-					return filepath.SkipDir
-				}
-				// If it's a file, skip just this file
+		// Skip directories, pruning hidden ones and any listed in
+		// .efmrlignore
+		if info.IsDir() {
+			if relPath == "." {
 				return nil
 			}
+			if isHidden(info) || ignore.Match(filepath.ToSlash(relPath), true) {
+				return filepath.SkipDir
+			}
+			if visitDir != nil {
+				visitDir(filepath.ToSlash(relPath))
+			}
+			return nil
 		}
 
-		// Compute ETag — use multipart formula for large files so it matches
-		// what R2 stores after a multipart upload (md5(md5_p1+md5_p2+...)-N).
-		var etag string
-		if info.Size() > multipartThreshold {
-			etag, err = computeMultipartETag(path)
-		} else {
-			etag, err = computeFileETag(path)
-		}
-		if err != nil {
-			return fmt.Errorf("failed to compute ETag for %s: %w", relPath, err)
+		// Skip hidden files and files listed in .efmrlignore
+		if isHidden(info) || ignore.Match(filepath.ToSlash(relPath), false) {
+			return nil
 		}
 
 		// Convert to URL path (with leading slash, forward slashes)
@@ -212,18 +726,79 @@ func scanLocalFiles(rootDir string) ([]LocalFile, error) {
 		// Detect content type
 		contentType := detectContentType(path)
 
-		files = append(files, LocalFile{
+		return visit(LocalFile{
 			Path:        urlPath,
 			AbsPath:     path,
-			ETag:        etag,
 			Size:        info.Size(),
 			ContentType: contentType,
+			inode:       fileIdentity(info),
+			modTime:     info.ModTime(),
 		})
+	})
+}
+
+// hashLocalFiles computes the ETag of each file that need picks and doesn't
+// have one yet. Large files get the multipart formula, so the ETag matches
+// what R2 stores after a multipart upload (md5(md5_p1+md5_p2+...)-N).
+func hashLocalFiles(files []LocalFile, need func(*LocalFile) bool) error {
+	return hashLocalFilesProgress(files, need, nil)
+}
 
+// hashLocalFilesProgress is hashLocalFiles, counting each file hashed in
+// progress, if it isn't nil. Files are hashed [performance] hash_workers at
+// a time, and hard links to the same file are only read once.
+func hashLocalFilesProgress(files []LocalFile, need func(*LocalFile) bool, progress *scanProgress) error {
+	// Each file to hash, by index, except the later links to a file, which
+	// get the ETag of the first
+	var hash []int
+	first := make(map[fileID]int)
+	links := make(map[int]int)
+	for i := range files {
+		file := &files[i]
+		if file.ETag != "" || !need(file) {
+			continue
+		}
+		if file.inode != (fileID{}) {
+			if j, ok := first[file.inode]; ok {
+				links[i] = j
+				continue
+			}
+			first[file.inode] = i
+		}
+		hash = append(hash, i)
+	}
+	if progress != nil {
+		progress.total.Store(int64(len(hash) + len(links)))
+	}
+
+	var firstErr error
+	runConcurrently(hash, performance.HashWorkers, false, func(i int) error {
+		file := &files[i]
+		var err error
+		if file.Size > multipartThreshold {
+			file.ETag, err = computeMultipartETag(file.AbsPath)
+		} else {
+			file.ETag, err = computeFileETag(file.AbsPath)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to compute ETag for %s: %w", file.Path, err)
+		}
+		progress.add(file.Size)
 		return nil
+	}, func(_ int, err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
 	})
+	if firstErr != nil {
+		return firstErr
+	}
 
-	return files, err
+	for i, j := range links {
+		files[i].ETag = files[j].ETag
+		progress.add(files[i].Size)
+	}
+	return nil
 }
 
 // computeMultipartETag computes the ETag that R2 (and S3) assign after a
@@ -345,11 +920,11 @@ func validateQuota(localFiles []LocalFile, quota *QuotaInfo) error {
 
 	// Check if total local size exceeds max quota
 	if totalLocalSize > quota.MaxSpace {
-		return fmt.Errorf(
-			"local directory size (%s) exceeds efmrl quota (%s)",
+		return &QuotaError{Err: fmt.Errorf(
+			"local directory size (%s) exceeds efmrl quota (%s); see 'efmrl3 plan show' for larger plans",
 			formatBytes(totalLocalSize),
 			formatBytes(quota.MaxSpace),
-		)
+		)}
 	}
 
 	return nil
@@ -390,6 +965,8 @@ func computeSyncPlan(local []LocalFile, remote []RemoteFile, force bool, deleteR
 		ToUpload:  []LocalFile{},
 		ToDelete:  []RemoteFile{},
 		Unchanged: []string{},
+		Replacing: map[string]string{},
+		Existing:  map[string]string{},
 	}
 
 	// Build a map of remote files for quick lookup
@@ -405,9 +982,15 @@ func computeSyncPlan(local []LocalFile, remote []RemoteFile, force bool, deleteR
 		if !existsRemote || force || lf.ETag != rf.ETag {
 			// File doesn't exist remotely, or --force flag, or ETags differ
 			plan.ToUpload = append(plan.ToUpload, lf)
+			if existsRemote {
+				plan.Replacing[lf.Path] = rf.ETag
+			}
 		} else {
 			// File exists and ETags match
 			plan.Unchanged = append(plan.Unchanged, lf.Path)
+			if _, ok := plan.Existing[lf.ETag]; !ok {
+				plan.Existing[lf.ETag] = lf.Path
+			}
 		}
 
 		// Remove from remote map (we've processed it)
@@ -424,41 +1007,220 @@ func computeSyncPlan(local []LocalFile, remote []RemoteFile, force bool, deleteR
 	return plan
 }
 
-// executeSyncPlan performs the delete and upload operations
-func executeSyncPlan(client *APIClient, siteID string, plan SyncPlan) error {
+// executeSyncPlan performs the delete and upload operations. Normally the
+// first failure stops it; with keepGoing, failed changes are set aside and
+// retried once everything else is done, and any that fail again are
+// reported together in a SyncFailuresError. Each change made is recorded in
+// journal, if it isn't nil, so an interrupted sync can be resumed.
+func executeSyncPlan(client *APIClient, siteID string, plan SyncPlan, keepGoing bool, journal *syncJournal) error {
 	totalOps := len(plan.ToUpload) + len(plan.ToDelete)
 	currentOp := 0
+	var failedDeletes []RemoteFile
+	var failedUploads []LocalFile
 
 	// Delete files first to free up space
-	for _, rf := range plan.ToDelete {
+	deleted, err := deleteRemoteFiles(client, siteID, plan.ToDelete, keepGoing, func(rf RemoteFile, err error) {
 		currentOp++
-		fmt.Printf("[%d/%d] Deleting %s... ", currentOp, totalOps, rf.Path)
-
-		if err := deleteFile(client, siteID, rf.Path); err != nil {
-			fmt.Printf("FAILED\n")
-			return fmt.Errorf("failed to delete %s: %w", rf.Path, err)
+		reportDelete(rf, err, currentOp, totalOps)
+		if err != nil {
+			failedDeletes = append(failedDeletes, rf)
+		} else {
+			journal.done("delete", rf.Path)
 		}
+	})
+	if err != nil && !keepGoing {
+		return partialSyncError(deleted, totalOps, err)
+	}
+
+	// Upload files after deletes complete, the small ones in batches and
+	// changes to big ones as deltas if the server takes them. Duplicates of
+	// content already on the site are copied there if the server can, which
+	// means hashing the uploads that aren't yet.
+	var caps ServerCapabilities
+	if len(plan.ToUpload) > 1 || len(plan.Replacing) > 0 || (len(plan.ToUpload) > 0 && len(plan.Existing) > 0) {
+		caps = fetchCapabilities(client)
+	}
+	uploads := plan.ToUpload
+	var copies []LocalFile
+	var copyFrom map[string]string
+	if caps.CopyFiles {
+		if err := hashLocalFiles(plan.ToUpload, func(lf *LocalFile) bool { return lf.Size >= minCopySize }); err != nil {
+			return partialSyncError(deleted, totalOps, err)
+		}
+		uploads, copies, copyFrom = planCopies(plan)
+	}
+	if len(uploads) > 1 {
+		if caps.BatchUpload != nil {
+			var batches [][]LocalFile
+			batches, uploads = planBatches(uploads, caps.BatchUpload.withDefaults())
+			for _, batch := range batches {
+				first := currentOp + 1
+				currentOp += len(batch)
+				outf("[%d-%d/%d] Uploading %d small files... ", first, currentOp, totalOps, len(batch))
+
+				if err := uploadBatch(client, siteID, batch); err != nil {
+					outf("%s\n", red("FAILED"))
+					emitEvent("op_failed", map[string]any{"path": batch[0].Path, "current": first, "total": totalOps, "error": err.Error()})
+					annotateError("Failed to upload a batch of files", "", err)
+					if keepGoing {
+						failedUploads = append(failedUploads, batch...)
+						continue
+					}
+					return partialSyncError(first-1, totalOps, fmt.Errorf("failed to upload %d files (%s, ...): %w", len(batch), batch[0].Path, err))
+				}
 
-		fmt.Printf("OK\n")
+				outf("%s\n", green("OK"))
+				for i, lf := range batch {
+					journal.done("upload", lf.Path)
+					emitEvent("file_uploaded", map[string]any{"path": lf.Path, "bytes": lf.Size, "current": first + i, "total": totalOps})
+				}
+			}
+		}
 	}
 
-	// Upload files after deletes complete
-	for _, lf := range plan.ToUpload {
+	// The rest go one at a time, each reported as it starts, or [performance]
+	// upload_concurrency at a time, each reported as it finishes
+	upload := func(lf LocalFile) error {
+		return copyOrUpload(client, siteID, lf, copyFrom[lf.Path], plan.Replacing[lf.Path], caps.DeltaUpload)
+	}
+	start := func(lf LocalFile) {
 		currentOp++
-		fmt.Printf("[%d/%d] Uploading %s... ", currentOp, totalOps, lf.Path)
-
-		if err := uploadFile(client, siteID, lf); err != nil {
-			fmt.Printf("FAILED\n")
-			return fmt.Errorf("failed to upload %s: %w", lf.Path, err)
+		if from := copyFrom[lf.Path]; from != "" {
+			outf("[%d/%d] Copying %s from %s... ", currentOp, totalOps, lf.Path, from)
+		} else {
+			outf("[%d/%d] Uploading %s... ", currentOp, totalOps, lf.Path)
 		}
+	}
+	var uploadErr error
+	finish := func(lf LocalFile, err error) {
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			emitEvent("op_failed", map[string]any{"path": lf.Path, "current": currentOp, "total": totalOps, "error": err.Error()})
+			annotateError("Failed to upload "+lf.Path, lf.AbsPath, err)
+			failedUploads = append(failedUploads, lf)
+			if uploadErr == nil {
+				uploadErr = fmt.Errorf("failed to upload %s: %w", lf.Path, err)
+			}
+			return
+		}
+		outf("%s\n", green("OK"))
+		journal.done("upload", lf.Path)
+		emitEvent("file_uploaded", map[string]any{"path": lf.Path, "bytes": lf.Size, "current": currentOp, "total": totalOps})
+	}
+	singles := append(uploads, copies...)
+	if performance.UploadConcurrency <= 1 {
+		for _, lf := range singles {
+			start(lf)
+			err := upload(lf)
+			finish(lf, err)
+			if err != nil && !keepGoing {
+				break
+			}
+		}
+	} else {
+		runConcurrently(singles, performance.UploadConcurrency, keepGoing, upload, func(lf LocalFile, err error) {
+			start(lf)
+			finish(lf, err)
+		})
+	}
+	if uploadErr != nil && !keepGoing {
+		return partialSyncError(currentOp-len(failedUploads), totalOps, uploadErr)
+	}
 
-		fmt.Printf("OK\n")
+	if len(failedDeletes) > 0 || len(failedUploads) > 0 {
+		return retryFailed(client, siteID, plan, caps, failedDeletes, failedUploads, journal)
 	}
 
-	fmt.Println("\n✓ Sync complete")
+	outf("\n%s Sync complete\n", green("✓"))
 	return nil
 }
 
+// executeSyncPlan executes plan as the flags ask: carrying on past
+// failures, or taking a snapshot first and rolling back to it on failure.
+// Changes made are recorded in journal, unless it's nil; a sync that's
+// rolled back has nothing to resume, so it isn't journaled.
+func (s *SyncCmd) executeSyncPlan(client *APIClient, siteID string, plan SyncPlan, journal *syncJournal) error {
+	if !s.RollbackOnFailure {
+		return executeSyncPlan(client, siteID, plan, s.KeepGoing, journal)
+	}
+
+	snap, err := snapshotRemote(client, siteID, plan)
+	if err != nil {
+		return err
+	}
+	defer snap.cleanup()
+
+	err = executeSyncPlan(client, siteID, plan, false, nil)
+	var partial *PartialSyncError
+	if !errors.As(err, &partial) {
+		return err
+	}
+	return rolledBack(err, snap.restore(client, siteID))
+}
+
+// retryFailed tries the changes that failed in a sync with --keep-going
+// once more, one at a time, and returns a SyncFailuresError listing those
+// that fail again
+func retryFailed(client *APIClient, siteID string, plan SyncPlan, caps ServerCapabilities, deletes []RemoteFile, uploads []LocalFile, journal *syncJournal) error {
+	total := len(deletes) + len(uploads)
+	outf("\nRetrying %d failed change(s)...\n", total)
+
+	var failures []SyncFailure
+	current := 0
+	for _, rf := range deletes {
+		current++
+		outf("[%d/%d] Deleting %s... ", current, total, rf.Path)
+		if err := deleteFile(client, siteID, rf.Path); err != nil {
+			outf("%s\n", red("FAILED"))
+			failures = append(failures, SyncFailure{Op: "delete", Path: rf.Path, Error: err.Error()})
+			continue
+		}
+		outf("%s\n", green("OK"))
+		journal.done("delete", rf.Path)
+		emitEvent("delete_done", map[string]any{"path": rf.Path, "retried": true})
+	}
+	for _, lf := range uploads {
+		current++
+		outf("[%d/%d] Uploading %s... ", current, total, lf.Path)
+		if err := uploadChanged(client, siteID, lf, plan.Replacing[lf.Path], caps.DeltaUpload); err != nil {
+			outf("%s\n", red("FAILED"))
+			failures = append(failures, SyncFailure{Op: "upload", Path: lf.Path, Error: err.Error()})
+			continue
+		}
+		outf("%s\n", green("OK"))
+		journal.done("upload", lf.Path)
+		emitEvent("file_uploaded", map[string]any{"path": lf.Path, "bytes": lf.Size, "retried": true})
+	}
+
+	if len(failures) == 0 {
+		outf("\n%s Sync complete (after retrying %d change(s))\n", green("✓"), total)
+		return nil
+	}
+	return &SyncFailuresError{Failures: failures, Total: len(plan.ToUpload) + len(plan.ToDelete)}
+}
+
+// reportDelete prints how deleting a file went, as the current'th of total
+// operations
+func reportDelete(rf RemoteFile, err error, current, total int) {
+	if err != nil {
+		outf("[%d/%d] Deleting %s... %s\n", current, total, rf.Path, red("FAILED"))
+		emitEvent("op_failed", map[string]any{"path": rf.Path, "current": current, "total": total, "error": err.Error()})
+		annotateError("Failed to delete "+rf.Path, "", err)
+		return
+	}
+	outf("[%d/%d] Deleting %s... %s\n", current, total, rf.Path, green("OK"))
+	emitEvent("delete_done", map[string]any{"path": rf.Path, "current": current, "total": total})
+}
+
+// partialSyncError wraps err in a PartialSyncError if the remote site was
+// already changed; a failure before anything changed is an ordinary error
+func partialSyncError(completed, total int, err error) error {
+	if completed == 0 {
+		return err
+	}
+	return &PartialSyncError{Completed: completed, Total: total, Err: err}
+}
+
 const (
 	// multipartThreshold is the file size above which multipart upload is used.
 	// Cloudflare enforces a 100 MB hard limit on request bodies at the edge,
@@ -482,16 +1244,11 @@ func uploadFile(client *APIClient, siteID string, file LocalFile) error {
 		return uploadLargeFile(client, siteID, file)
 	}
 
-	// Open the file
-	f, err := os.Open(file.AbsPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	// Create the request
-	url := fmt.Sprintf("%s/admin/efmrls/%s/files%s", client.BaseURL, siteID, file.Path)
-	req, err := http.NewRequest("PUT", url, f)
+	// Create the request, with a deadline for the file's size
+	ctx, cancel := uploadContext(file.Size)
+	defer cancel()
+	url := fmt.Sprintf("%s/admin/efmrls/%s/files%s", client.BaseURL, siteID, fileURLPath(file.Path))
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, nil)
 	if err != nil {
 		return err
 	}
@@ -508,18 +1265,23 @@ func uploadFile(client *APIClient, siteID string, file LocalFile) error {
 	// Add Authorization header
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
 
+	// Open the file last, since sending the request closes it
+	if err := setUploadBody(req, file); err != nil {
+		return err
+	}
+
 	// Send request
-	httpClient := &http.Client{}
+	httpClient := newHTTPClient()
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return err
+		return uploadTimeoutError(ctx, file.Size, err)
 	}
 	defer resp.Body.Close()
 
 	// Handle 401 with token refresh (similar to APIClient.doRequest)
 	if resp.StatusCode == http.StatusUnauthorized {
 		// Try to refresh token
-		if err := client.refreshTokenIfNeeded(); err != nil {
+		if err := client.refreshAfter401(accessToken); err != nil {
 			return fmt.Errorf("failed to refresh credentials: %w", err)
 		}
 
@@ -529,20 +1291,16 @@ func uploadFile(client *APIClient, siteID string, file LocalFile) error {
 			return err
 		}
 
-		// Reopen file (previous one was consumed)
-		f.Close()
-		f, err = os.Open(file.AbsPath)
-		if err != nil {
+		// Send the file again from the start
+		if err := rewindBody(req); err != nil {
 			return err
 		}
-		defer f.Close()
 
-		req.Body = f
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
 
 		resp, err = httpClient.Do(req)
 		if err != nil {
-			return err
+			return uploadTimeoutError(ctx, file.Size, err)
 		}
 		defer resp.Body.Close()
 	}
@@ -555,11 +1313,60 @@ func uploadFile(client *APIClient, siteID string, file LocalFile) error {
 	return nil
 }
 
+// setUploadBody opens a file as the body of an upload request, which
+// closes it once it's sent. req.GetBody opens it again, so the request can
+// be resent. The server gets the file's MD5 to check it against: a file
+// hashed during the scan sends it up front in a Content-MD5 header, and any
+// other is hashed as it's sent, with the MD5 following the body as a
+// Content-MD5 trailer, so the file is only read once per attempt.
+func setUploadBody(req *http.Request, file LocalFile) error {
+	sum, err := hex.DecodeString(file.ETag)
+	upfront := err == nil && len(sum) == md5.Size
+	if upfront {
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum))
+	} else {
+		req.Header.Del("Content-MD5")
+		req.Trailer = http.Header{"Content-Md5": nil}
+	}
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		f, err := os.Open(file.AbsPath)
+		if err != nil {
+			return nil, err
+		}
+		if upfront {
+			return f, nil
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{&md5TrailerReader{r: f, hash: md5.New(), trailer: req.Trailer}, f}, nil
+	}
+	return rewindBody(req)
+}
+
+// md5TrailerReader hashes what's read through it, and sets the Content-MD5
+// trailer once it reaches the end
+type md5TrailerReader struct {
+	r       io.Reader
+	hash    hash.Hash
+	trailer http.Header
+}
+
+func (m *md5TrailerReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.hash.Write(p[:n])
+	if err == io.EOF {
+		m.trailer.Set("Content-MD5", base64.StdEncoding.EncodeToString(m.hash.Sum(nil)))
+	}
+	return n, err
+}
+
 // uploadLargeFile uploads a file that exceeds the single-request size limit
 // using R2 multipart upload: begin → upload parts → complete.
 func uploadLargeFile(client *APIClient, siteID string, file LocalFile) error {
 	numParts := int((file.Size + multipartChunkSize - 1) / multipartChunkSize)
-	fmt.Printf("(multipart: %d parts)\n", numParts)
+	outf("(multipart: %d parts)\n", numParts)
 
 	// 1. Begin
 	uploadID, err := beginMultipartUpload(client, siteID, file.Path, file.ContentType, file.Size)
@@ -589,16 +1396,16 @@ func uploadLargeFile(client *APIClient, siteID string, file LocalFile) error {
 		}
 
 		chunk := buf[:n]
-		fmt.Printf("  part %d/%d (%s)... ", partNum, numParts, formatBytes(int64(n)))
+		outf("  part %d/%d (%s)... ", partNum, numParts, formatBytes(int64(n)))
 
 		part, err := doUploadPart(client, siteID, uploadID, file.Path, partNum, chunk)
 		if err != nil {
-			fmt.Printf("FAILED\n")
+			outf("%s\n", red("FAILED"))
 			abortMultipartUpload(client, siteID, uploadID, file.Path)
 			return fmt.Errorf("failed to upload part %d: %w", partNum, err)
 		}
 
-		fmt.Printf("OK\n")
+		outf("%s\n", green("OK"))
 		uploadedParts = append(uploadedParts, part)
 	}
 
@@ -691,18 +1498,24 @@ func abortMultipartUpload(client *APIClient, siteID, uploadID, filePath string)
 	path := fmt.Sprintf("/admin/efmrls/%s/multipart/%s?filePath=%s", siteID, uploadID, url.QueryEscape(filePath))
 	resp, err := client.doRequest("DELETE", path, nil)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to abort multipart upload %s: %v\n", uploadID, err)
+		warnf("failed to abort multipart upload %s: %v\n", uploadID, err)
 		return
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		fmt.Fprintf(os.Stderr, "Warning: server returned %d when aborting multipart upload %s\n", resp.StatusCode, uploadID)
+		warnf("server returned %d when aborting multipart upload %s\n", resp.StatusCode, uploadID)
 	}
 }
 
+// fileURLPath percent-encodes a site path for the file API's URLs, so that
+// names with spaces, '#', '?', '%', or non-ASCII characters arrive intact
+func fileURLPath(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
 // deleteFile deletes a single file from the server
 func deleteFile(client *APIClient, siteID string, path string) error {
-	url := fmt.Sprintf("/admin/efmrls/%s/files%s", siteID, path)
+	url := fmt.Sprintf("/admin/efmrls/%s/files%s", siteID, fileURLPath(path))
 	resp, err := client.Delete(url)
 	if err != nil {
 		return err
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -382,3 +388,237 @@ func TestValidateQuota(t *testing.T) {
 		t.Errorf("Expected no error for empty file list, got: %v", err)
 	}
 }
+
+// TestSyncResultSummary tests the one-line summary printed with --quiet
+func TestSyncResultSummary(t *testing.T) {
+	result := SyncResult{
+		SiteID:    "abc123",
+		Uploaded:  []string{"index.html", "style.css"},
+		Deleted:   []string{"old.html"},
+		Unchanged: 4,
+	}
+
+	expected := "Synced abc123: 2 uploaded, 1 deleted, 4 unchanged"
+	if got := result.summary(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	result.DryRun = true
+	expected = "Dry run for abc123: would upload 2, delete 1 (4 unchanged)"
+	if got := result.summary(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+// TestComputeSyncImpact tests the storage impact of a sync plan
+func TestComputeSyncImpact(t *testing.T) {
+	remote := []RemoteFile{
+		{Path: "/index.html", Size: 100},
+		{Path: "/old.html", Size: 300},
+		{Path: "/same.css", Size: 600},
+	}
+	plan := SyncPlan{
+		ToUpload:  []LocalFile{{Path: "/index.html", Size: 250}, {Path: "/new.js", Size: 50}},
+		ToDelete:  []RemoteFile{remote[1]},
+		Unchanged: []string{"/same.css"},
+	}
+
+	impact := computeSyncImpact(plan, remote, 1000)
+	if impact.UploadBytes != 300 {
+		t.Errorf("Expected 300 upload bytes, got %d", impact.UploadBytes)
+	}
+	if impact.DeleteBytes != 300 {
+		t.Errorf("Expected 300 deleted bytes, got %d", impact.DeleteBytes)
+	}
+	// 1000 + 300 uploaded - 100 replaced - 300 deleted
+	if impact.ProjectedSpace != 900 {
+		t.Errorf("Expected 900 projected bytes, got %d", impact.ProjectedSpace)
+	}
+
+	summary := impact.describe(plan, &QuotaInfo{MaxSpace: 1800})
+	expected := "2 upload(s) (300 bytes), 1 deletion(s) (300 bytes freed), projected storage 50% of quota (900 bytes of 1.76 KB)"
+	if summary != expected {
+		t.Errorf("Expected %q, got %q", expected, summary)
+	}
+}
+
+// TestConfirmDeletes tests that large deletions need confirmation
+func TestConfirmDeletes(t *testing.T) {
+	defer func() { nonInteractive = false }()
+	nonInteractive = true
+
+	plan := SyncPlan{ToDelete: make([]RemoteFile, 3)}
+	config := &Config{Sync: SyncConfig{ConfirmDeletes: 2}}
+
+	if err := (&SyncCmd{}).confirmDeletes(config, plan); err == nil {
+		t.Errorf("Expected confirmation to be required for 3 deletions")
+	}
+	if err := (&SyncCmd{Yes: true}).confirmDeletes(config, plan); err != nil {
+		t.Errorf("Expected --yes to skip confirmation, got %v", err)
+	}
+
+	config.Sync.ConfirmDeletes = -1
+	if err := (&SyncCmd{}).confirmDeletes(config, plan); err != nil {
+		t.Errorf("Expected -1 to never ask, got %v", err)
+	}
+
+	config.Sync.ConfirmDeletes = 0
+	if err := (&SyncCmd{}).confirmDeletes(config, plan); err != nil {
+		t.Errorf("Expected 3 deletions to be under the default, got %v", err)
+	}
+}
+
+// TestHashLocalFiles tests that only the files picked are hashed, once
+func TestHashLocalFiles(t *testing.T) {
+	_, files := writeSite(t, map[string]string{"a.html": "a", "b.html": "b"})
+	for i := range files {
+		files[i].ETag = ""
+	}
+
+	err := hashLocalFiles(files, func(lf *LocalFile) bool { return lf.Path == "/a.html" })
+	if err != nil {
+		t.Fatalf("hashLocalFiles failed: %v", err)
+	}
+	for _, f := range files {
+		want := ""
+		if f.Path == "/a.html" {
+			want = md5Hex("a")
+		}
+		if f.ETag != want {
+			t.Errorf("Expected ETag %q for %s, got %q", want, f.Path, f.ETag)
+		}
+	}
+}
+
+// TestUploadFileChecksum tests that uploads carry their MD5: in a header
+// when the file was hashed during the scan, or in a trailer computed while
+// it's sent
+func TestUploadFileChecksum(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var header, trailer, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		header = r.Header.Get("Content-MD5")
+		trailer = r.Trailer.Get("Content-MD5")
+	}))
+	defer server.Close()
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, files := writeSite(t, map[string]string{"index.html": "<h1>Hi</h1>"})
+	sum, _ := hex.DecodeString(md5Hex("<h1>Hi</h1>"))
+	want := base64.StdEncoding.EncodeToString(sum)
+
+	if err := uploadFile(client, "abc", files[0]); err != nil {
+		t.Fatalf("uploadFile failed: %v", err)
+	}
+	if header != want || trailer != "" || body != "<h1>Hi</h1>" {
+		t.Errorf("Expected header %s and no trailer, got header %q, trailer %q, body %q", want, header, trailer, body)
+	}
+
+	unhashed := files[0]
+	unhashed.ETag = ""
+	if err := uploadFile(client, "abc", unhashed); err != nil {
+		t.Fatalf("uploadFile failed: %v", err)
+	}
+	if trailer != want || header != "" || body != "<h1>Hi</h1>" {
+		t.Errorf("Expected trailer %s and no header, got header %q, trailer %q, body %q", want, header, trailer, body)
+	}
+}
+
+// TestUploadFileResent tests that an upload's body can be sent again, as
+// when following a redirect
+func TestUploadFileResent(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if !strings.HasPrefix(r.URL.Path, "/moved") {
+			http.Redirect(w, r, "/moved"+r.URL.Path, http.StatusTemporaryRedirect)
+		}
+	}))
+	defer server.Close()
+	client, _ := NewAPIClient(server.URL)
+
+	_, files := writeSite(t, map[string]string{"index.html": "<h1>Hi</h1>"})
+	unhashed := files[0]
+	unhashed.ETag = ""
+	for _, file := range []LocalFile{files[0], unhashed} {
+		bodies = nil
+		if err := uploadFile(client, "abc", file); err != nil {
+			t.Fatalf("uploadFile failed: %v", err)
+		}
+		if len(bodies) != 2 || bodies[1] != "<h1>Hi</h1>" {
+			t.Errorf("Expected the body sent again after the redirect, got %q", bodies)
+		}
+	}
+}
+
+func TestFileURLPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/index.html", "/index.html"},
+		{"/my file.html", "/my%20file.html"},
+		{"/notes #1.txt", "/notes%20%231.txt"},
+		{"/what?.html", "/what%3F.html"},
+		{"/100%.txt", "/100%25.txt"},
+		{"/café/ünïcode.txt", "/caf%C3%A9/%C3%BCn%C3%AFcode.txt"},
+	}
+
+	for _, tt := range tests {
+		if got := fileURLPath(tt.path); got != tt.want {
+			t.Errorf("fileURLPath(%q): Expected %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
+
+// TestFileAPIEncodesPaths tests that uploads, deletes, and downloads of
+// files with awkward names reach the server with the right path
+func TestFileAPIEncodesPaths(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/admin/efmrls/abc/files"))
+		if r.URL.RawQuery != "" {
+			t.Errorf("Expected no query string, got %q", r.URL.RawQuery)
+		}
+	}))
+	defer server.Close()
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	names := []string{"my file #1?.html", "100%.txt", "ünïcode.txt"}
+	contents := map[string]string{}
+	for _, name := range names {
+		contents[name] = name
+	}
+	dir, files := writeSite(t, contents)
+
+	var want []string
+	for _, lf := range files {
+		if err := uploadFile(client, "abc", lf); err != nil {
+			t.Fatalf("uploadFile failed: %v", err)
+		}
+		if err := deleteFile(client, "abc", lf.Path); err != nil {
+			t.Fatalf("deleteFile failed: %v", err)
+		}
+		if _, err := downloadFile(client, "abc", RemoteFile{Path: lf.Path}, dir, nil); err != nil {
+			t.Fatalf("downloadFile failed: %v", err)
+		}
+		want = append(want, "PUT "+lf.Path, "DELETE "+lf.Path, "GET "+lf.Path)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SiteSyncResult is how syncing one site went, in the JSON form of
+// sync --all
+type SiteSyncResult struct {
+	Site   string      `json:"site,omitempty"` // the [sites.<name>] profile; empty for [site]
+	SiteID string      `json:"siteId"`
+	OK     bool        `json:"ok"`
+	Error  string      `json:"error,omitempty"`
+	Result *SyncResult `json:"result,omitempty"`
+}
+
+// syncAll syncs every site in the config file, one after another or up to
+// s.Parallel at once, then summarizes how each went. A site that fails
+// doesn't stop the others, but makes the whole command fail.
+func (s *SyncCmd) syncAll() error {
+	if siteOverride != "" || siteIDOverride != "" {
+		return fmt.Errorf("--all syncs every configured site, so it can't be combined with --site or --site-id")
+	}
+	if s.ProgressJSON {
+		return fmt.Errorf("--progress-json can't be combined with --all")
+	}
+	if s.Parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+
+	configs, err := LoadSiteConfigs()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	// Several sites syncing the same directory is almost certainly a
+	// mistake, and "." would publish the whole repository
+	if len(configs) > 1 {
+		for _, config := range configs {
+			if config.Site.Dir == "" && config.Build.OutputDir == "" {
+				return fmt.Errorf("%s has no dir; with several sites, set dir under %s for each", siteLabel(config), siteTable(config.FileName(), config.SiteName()))
+			}
+		}
+	}
+
+	unlock, err := acquireSyncLock(s.ForceUnlock)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer watchInterrupts()()
+	// Upload speed and performance are a matter of the connection and the
+	// machine, not the site, so the first site's settings serve for all
+	setUploadTimeouts(configs[0])
+	setPerformance(configs[0])
+
+	results := make([]SiteSyncResult, len(configs))
+	syncOne := func(i int) {
+		config := configs[i]
+		result, err := s.syncDir(config, config.SyncDir())
+		if !s.DryRun {
+			notifySync(config, "Sync", result, err)
+		}
+		results[i] = SiteSyncResult{Site: config.SiteName(), SiteID: config.Site.SiteID, OK: err == nil, Result: result}
+		if err != nil {
+			results[i].Error = err.Error()
+		} else if err := writeStepSummary(result); err != nil {
+			warnf("%v\n", err)
+		}
+	}
+
+	if s.Parallel == 1 || len(configs) == 1 {
+		for i, config := range configs {
+			outf("==> %s\n\n", siteLabel(config))
+			syncOne(i)
+			endGroup()
+			outln()
+		}
+	} else {
+		s.syncParallel(configs, results, syncOne)
+	}
+
+	return printSiteSyncResults(results)
+}
+
+// syncParallel runs syncOne for every site, s.Parallel at a time. Several
+// syncs' output interleaved would be unreadable, and their prompts
+// unanswerable, so the sites sync quietly and without prompting, and only
+// each one's outcome is printed as it finishes.
+func (s *SyncCmd) syncParallel(configs []*Config, results []SiteSyncResult, syncOne func(int)) {
+	out := humanOutput()
+	savedQuiet, savedNonInteractive := quietOutput, nonInteractive
+	quietOutput, nonInteractive = true, true
+	defer func() { quietOutput, nonInteractive = savedQuiet, savedNonInteractive }()
+
+	fmt.Fprintf(out, "Syncing %d sites, %d at a time...\n", len(configs), s.Parallel)
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, s.Parallel)
+	)
+	for i := range configs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			syncOne(i)
+			mu.Lock()
+			fmt.Fprintln(out, results[i].describe())
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	endGroup()
+	fmt.Fprintln(out)
+}
+
+// printSiteSyncResults prints the combined summary of sync --all, or its
+// JSON form, and returns an error naming the sites that failed
+func printSiteSyncResults(results []SiteSyncResult) error {
+	var failed []string
+	for _, r := range results {
+		if !r.OK {
+			failed = append(failed, r.label())
+		}
+	}
+
+	switch {
+	case jsonOutput:
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	case quietOutput:
+		// Like the one-line summary of a single sync under --quiet
+		for _, r := range results {
+			fmt.Println(r.describe())
+		}
+	default:
+		outln("Sites")
+		outln("=====")
+		for _, r := range results {
+			outln(r.describe())
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d site(s) failed to sync: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// describe is the one-line outcome of syncing a site
+func (r SiteSyncResult) describe() string {
+	if !r.OK {
+		return fmt.Sprintf("%s %s: %s", red("✗"), r.label(), r.Error)
+	}
+	verb := ""
+	if r.Result.DryRun {
+		verb = "would be "
+	}
+	return fmt.Sprintf("%s %s: %d %suploaded, %d %sdeleted, %d unchanged", green("✓"), r.label(),
+		len(r.Result.Uploaded), verb, len(r.Result.Deleted), verb, r.Result.Unchanged)
+}
+
+// label names the site in the summary, e.g. "docs (abc123)"
+func (r SiteSyncResult) label() string {
+	name := r.Site
+	if name == "" {
+		name = "[site]"
+	}
+	return fmt.Sprintf("%s (%s)", name, r.SiteID)
+}
+
+// siteLabel names a config's site the way the summary does
+func siteLabel(config *Config) string {
+	return SiteSyncResult{Site: config.SiteName(), SiteID: config.Site.SiteID}.label()
+}
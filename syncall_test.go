@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestLoadSiteConfigs tests loading the config once per configured site
+func TestLoadSiteConfigs(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	content := `
+[site]
+site_id = "main-id"
+dir = "public"
+base_host = "shared.test"
+
+[sites.docs]
+site_id = "docs-id"
+dir = "docs/public"
+
+[sites.blog]
+site_id = "blog-id"
+dir = "blog/public"
+`
+	if err := os.WriteFile(ConfigFileName, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	configs, err := LoadSiteConfigs()
+	if err != nil {
+		t.Fatalf("LoadSiteConfigs failed: %v", err)
+	}
+	var got []string
+	for _, config := range configs {
+		got = append(got, siteLabel(config)+" "+config.SyncDir()+" "+config.GetBaseHost())
+	}
+	want := []string{
+		"[site] (main-id) public shared.test",
+		"blog (blog-id) blog/public shared.test",
+		"docs (docs-id) docs/public shared.test",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestSiteSyncResultDescribe(t *testing.T) {
+	tests := []struct {
+		result SiteSyncResult
+		want   string
+	}{
+		{
+			SiteSyncResult{Site: "blog", SiteID: "b1", OK: true, Result: &SyncResult{Uploaded: []string{"/a"}, Unchanged: 4}},
+			"✓ blog (b1): 1 uploaded, 0 deleted, 4 unchanged",
+		},
+		{
+			SiteSyncResult{SiteID: "m1", OK: true, Result: &SyncResult{DryRun: true, Deleted: []string{"/a", "/b"}}},
+			"✓ [site] (m1): 0 would be uploaded, 2 would be deleted, 0 unchanged",
+		},
+		{
+			SiteSyncResult{Site: "docs", SiteID: "d1", Error: "quota exceeded"},
+			"✗ docs (d1): quota exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.result.describe(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+// TestPrintSiteSyncResults tests that any failed site fails the command
+func TestPrintSiteSyncResults(t *testing.T) {
+	results := []SiteSyncResult{
+		{Site: "blog", SiteID: "b1", OK: true, Result: &SyncResult{}},
+		{Site: "docs", SiteID: "d1", Error: "boom"},
+	}
+
+	var err error
+	output := captureStdout(t, func() { err = printSiteSyncResults(results) })
+	if err == nil || err.Error() != "1 of 2 site(s) failed to sync: docs (d1)" {
+		t.Errorf("Expected the failed site to be named, got %v", err)
+	}
+	if !strings.Contains(output, "✓ blog (b1)") || !strings.Contains(output, "✗ docs (d1): boom") {
+		t.Errorf("Expected both sites in the summary, got:\n%s", output)
+	}
+
+	captureStdout(t, func() { err = printSiteSyncResults(results[:1]) })
+	if err != nil {
+		t.Errorf("Expected no error when every site synced, got %v", err)
+	}
+}
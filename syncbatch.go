@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path"
+)
+
+// ServerCapabilities is what the server says it supports beyond the basic
+// file API. A server without the capabilities endpoint supports none of it.
+type ServerCapabilities struct {
+	BatchUpload *BatchUploadLimits `json:"batchUpload,omitempty"`
+	DeltaUpload *DeltaUploadLimits `json:"deltaUpload,omitempty"`
+	CopyFiles   bool               `json:"copyFiles,omitempty"`
+}
+
+// BatchUploadLimits bounds the batch upload requests a server accepts. A
+// zero limit means the server leaves it to the client's default.
+type BatchUploadLimits struct {
+	MaxFiles    int   `json:"maxFiles,omitempty"`
+	MaxBytes    int64 `json:"maxBytes,omitempty"`
+	MaxFileSize int64 `json:"maxFileSize,omitempty"`
+}
+
+const (
+	// Defaults for the batch upload limits the server doesn't set
+	defaultBatchMaxFiles    = 200
+	defaultBatchMaxBytes    = 10 * 1024 * 1024 // 10 MB
+	defaultBatchMaxFileSize = 256 * 1024       // 256 KB
+)
+
+// fetchCapabilities asks the server what it supports. Any failure, including
+// a server that predates the endpoint, means no optional capabilities.
+func fetchCapabilities(client *APIClient) ServerCapabilities {
+	var caps ServerCapabilities
+	if err := getJSON(client, "/admin/capabilities", &caps); err != nil {
+		return ServerCapabilities{}
+	}
+	return caps
+}
+
+// withDefaults fills in the limits the server left to the client. Batches
+// are kept under the size at which uploads go multipart, which the edge
+// would otherwise reject.
+func (l BatchUploadLimits) withDefaults() BatchUploadLimits {
+	if l.MaxFiles <= 0 {
+		l.MaxFiles = defaultBatchMaxFiles
+	}
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = defaultBatchMaxBytes
+	}
+	l.MaxBytes = min(l.MaxBytes, multipartThreshold)
+	if l.MaxFileSize <= 0 {
+		l.MaxFileSize = defaultBatchMaxFileSize
+	}
+	l.MaxFileSize = min(l.MaxFileSize, l.MaxBytes)
+	return l
+}
+
+// planBatches splits files into batches within limits, in their original
+// order, and returns the files too big to batch separately. A batch of one
+// file is no better than uploading it on its own, so it's returned with
+// the single uploads too.
+func planBatches(files []LocalFile, limits BatchUploadLimits) (batches [][]LocalFile, single []LocalFile) {
+	var batch []LocalFile
+	var batchBytes int64
+	flush := func() {
+		if len(batch) == 1 {
+			single = append(single, batch[0])
+		} else if len(batch) > 1 {
+			batches = append(batches, batch)
+		}
+		batch, batchBytes = nil, 0
+	}
+
+	for _, lf := range files {
+		if lf.Size > limits.MaxFileSize {
+			single = append(single, lf)
+			continue
+		}
+		if len(batch) == limits.MaxFiles || batchBytes+lf.Size > limits.MaxBytes {
+			flush()
+		}
+		batch = append(batch, lf)
+		batchBytes += lf.Size
+	}
+	flush()
+	return batches, single
+}
+
+// uploadBatch uploads several small files in one multipart/form-data
+// request, each part named by the file's site path (not its filename, which
+// readers commonly strip to a base name), with its content type and MD5 for
+// the server to check
+func uploadBatch(client *APIClient, siteID string, files []LocalFile) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, lf := range files {
+		data, err := os.ReadFile(lf.AbsPath)
+		if err != nil {
+			return err
+		}
+		sum := md5.Sum(data)
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", multipart.FileContentDisposition(lf.Path, path.Base(lf.Path)))
+		header.Set("Content-Type", lf.ContentType)
+		header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	resp, err := client.doBinaryRequest("POST", fmt.Sprintf("/admin/efmrls/%s/files/batch", siteID),
+		map[string]string{"Content-Type": mw.FormDataContentType()}, body.Bytes())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPlanBatches(t *testing.T) {
+	file := func(path string, size int64) LocalFile { return LocalFile{Path: path, Size: size} }
+	limits := BatchUploadLimits{MaxFiles: 3, MaxBytes: 100, MaxFileSize: 50}
+
+	tests := []struct {
+		name        string
+		files       []LocalFile
+		wantBatches string
+		wantSingle  string
+	}{
+		{
+			name:        "limited by count",
+			files:       []LocalFile{file("/a", 1), file("/b", 1), file("/c", 1), file("/d", 1), file("/e", 1)},
+			wantBatches: "/a /b /c | /d /e",
+		},
+		{
+			name:        "limited by bytes",
+			files:       []LocalFile{file("/a", 40), file("/b", 40), file("/c", 40), file("/d", 10)},
+			wantBatches: "/a /b | /c /d",
+		},
+		{
+			name:        "large files go alone",
+			files:       []LocalFile{file("/a", 10), file("/big", 51), file("/b", 10)},
+			wantBatches: "/a /b",
+			wantSingle:  "/big",
+		},
+		{
+			name:       "a batch of one isn't a batch",
+			files:      []LocalFile{file("/a", 10), file("/big", 51)},
+			wantSingle: "/big /a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, single := planBatches(tt.files, limits)
+			var got []string
+			for _, batch := range batches {
+				got = append(got, describePaths(batch))
+			}
+			if strings.Join(got, " | ") != tt.wantBatches {
+				t.Errorf("Expected batches %q, got %q", tt.wantBatches, strings.Join(got, " | "))
+			}
+			if describePaths(single) != tt.wantSingle {
+				t.Errorf("Expected single uploads %q, got %q", tt.wantSingle, describePaths(single))
+			}
+		})
+	}
+}
+
+// describePaths joins the files' paths, for comparing
+func describePaths(files []LocalFile) string {
+	var paths []string
+	for _, lf := range files {
+		paths = append(paths, lf.Path)
+	}
+	return strings.Join(paths, " ")
+}
+
+// TestExecuteSyncPlanBatches tests that small files are uploaded in a batch
+// when the server supports it, and the rest one by one
+func TestExecuteSyncPlanBatches(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var batched, single []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/admin/capabilities":
+			json.NewEncoder(w).Encode(ServerCapabilities{BatchUpload: &BatchUploadLimits{MaxFileSize: 8}})
+		case r.URL.Path == "/admin/efmrls/abc/files/batch":
+			mr, err := r.MultipartReader()
+			if err != nil {
+				t.Fatalf("Expected a multipart body, got %v", err)
+			}
+			for part, err := mr.NextPart(); err == nil; part, err = mr.NextPart() {
+				data, _ := io.ReadAll(part)
+				sum := md5.Sum(data)
+				if part.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+					t.Errorf("Expected the MD5 of %s in its part", part.FormName())
+				}
+				batched = append(batched, part.FormName()+"="+string(data))
+			}
+		case r.Method == http.MethodPut:
+			single = append(single, strings.TrimPrefix(r.URL.Path, "/admin/efmrls/abc/files"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client, _ := NewAPIClient(server.URL)
+
+	_, files := writeSite(t, map[string]string{
+		"a.txt":   "aaa",
+		"b.txt":   "bbb",
+		"big.txt": "more than eight bytes",
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	var err error
+	captureStdout(t, func() { err = executeSyncPlan(client, "abc", SyncPlan{ToUpload: files}, false, nil) })
+	if err != nil {
+		t.Fatalf("executeSyncPlan failed: %v", err)
+	}
+	if got := strings.Join(batched, ","); got != "/a.txt=aaa,/b.txt=bbb" {
+		t.Errorf("Expected a.txt and b.txt in a batch, got %s", got)
+	}
+	if got := strings.Join(single, ","); got != "/big.txt" {
+		t.Errorf("Expected big.txt on its own, got %s", got)
+	}
+}
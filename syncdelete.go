@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// deleteBatchSize is how many paths go in each bulk delete request
+const deleteBatchSize = 1000
+
+// deleteRemoteFiles deletes files from the site, in bulk if the server
+// supports it and otherwise several at a time, calling report as each one
+// is deleted or fails. report is only ever called from the calling
+// goroutine. It returns how many files were deleted, and stops at the first
+// failure unless keepGoing is set, in which case a failed bulk delete is
+// tried again file by file, and the first error is returned at the end.
+func deleteRemoteFiles(client *APIClient, siteID string, files []RemoteFile, keepGoing bool, report func(RemoteFile, error)) (int, error) {
+	deleted := 0
+	for deleted < len(files) {
+		batch := files[deleted:min(deleted+deleteBatchSize, len(files))]
+		supported, err := bulkDeleteFiles(client, siteID, batch)
+		if !supported || (err != nil && keepGoing) {
+			break
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete %d file(s): %w", len(batch), err)
+		}
+		for _, rf := range batch {
+			report(rf, nil)
+		}
+		deleted += len(batch)
+	}
+
+	n, err := deleteConcurrently(client, siteID, files[deleted:], keepGoing, report)
+	return deleted + n, err
+}
+
+// bulkDeleteFiles deletes files in one request. supported is false when the
+// server has no bulk delete endpoint, in which case nothing was deleted.
+func bulkDeleteFiles(client *APIClient, siteID string, files []RemoteFile) (supported bool, err error) {
+	paths := make([]string, len(files))
+	for i, rf := range files {
+		paths[i] = rf.Path
+	}
+	resp, err := client.Post(fmt.Sprintf("/admin/efmrls/%s/files/delete", siteID), map[string][]string{"paths": paths})
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return true, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// deleteConcurrently deletes files one request each, [performance]
+// delete_concurrency at a time. After a failure no more deletes are started,
+// unless keepGoing is set, but those already under way finish and are
+// reported.
+func deleteConcurrently(client *APIClient, siteID string, files []RemoteFile, keepGoing bool, report func(RemoteFile, error)) (int, error) {
+	var firstErr error
+	deleted := runConcurrently(files, performance.DeleteConcurrency, keepGoing, func(rf RemoteFile) error {
+		return deleteFile(client, siteID, rf.Path)
+	}, func(rf RemoteFile, err error) {
+		report(rf, err)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete %s: %w", rf.Path, err)
+		}
+	})
+	return deleted, firstErr
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// remoteFiles returns n remote files named /f0.html, /f1.html, ...
+func remoteFiles(n int) []RemoteFile {
+	files := make([]RemoteFile, n)
+	for i := range files {
+		files[i] = RemoteFile{Path: fmt.Sprintf("/f%d.html", i)}
+	}
+	return files
+}
+
+// TestDeleteRemoteFilesBulk tests that a server with bulk delete gets the
+// paths in batches
+func TestDeleteRemoteFilesBulk(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/admin/efmrls/abc/files/delete" {
+			t.Errorf("Expected only bulk deletes, got %s %s", r.Method, r.URL.Path)
+			return
+		}
+		var body struct {
+			Paths []string `json:"paths"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		batches = append(batches, len(body.Paths))
+	}))
+	defer server.Close()
+	client, _ := NewAPIClient(server.URL)
+
+	reported := 0
+	deleted, err := deleteRemoteFiles(client, "abc", remoteFiles(deleteBatchSize+5), false, func(rf RemoteFile, err error) {
+		reported++
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if deleted != deleteBatchSize+5 || reported != deleted {
+		t.Errorf("Expected %d deleted and reported, got %d and %d", deleteBatchSize+5, deleted, reported)
+	}
+	if fmt.Sprint(batches) != fmt.Sprintf("[%d 5]", deleteBatchSize) {
+		t.Errorf("Expected batches of %d and 5, got %v", deleteBatchSize, batches)
+	}
+}
+
+// TestDeleteRemoteFilesConcurrently tests the fallback for servers without
+// bulk delete, and that a failure stops it with the failed path named
+func TestDeleteRemoteFilesConcurrently(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var (
+		mu      sync.Mutex
+		failing string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failing != "" && strings.HasSuffix(r.URL.Path, failing) {
+			http.Error(w, "nope", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	client, _ := NewAPIClient(server.URL)
+
+	deleted, err := deleteRemoteFiles(client, "abc", remoteFiles(20), false, func(RemoteFile, error) {})
+	if deleted != 20 || err != nil {
+		t.Errorf("Expected 20 deleted, got %d (%v)", deleted, err)
+	}
+
+	mu.Lock()
+	failing = "/f3.html"
+	mu.Unlock()
+	deleted, err = deleteRemoteFiles(client, "abc", remoteFiles(4), false, func(RemoteFile, error) {})
+	if err == nil || !strings.Contains(err.Error(), "failed to delete /f3.html") {
+		t.Errorf("Expected the failed path in the error, got %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("Expected the other 3 files deleted, got %d", deleted)
+	}
+}
+
+// TestExecuteSyncPlanKeepGoing tests that failures don't stop a sync with
+// keepGoing, are retried, and are reported if they fail again
+func TestExecuteSyncPlanKeepGoing(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var (
+		mu       sync.Mutex
+		attempts = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet || r.Method == http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/admin/efmrls/abc/files")
+		attempts[path]++
+		switch {
+		case path == "/broken.html", path == "/stuck.html":
+			// Always fails
+		case path == "/flaky.html" && attempts[path] == 1:
+			// Fails once
+		default:
+			return
+		}
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	client, _ := NewAPIClient(server.URL)
+
+	_, files := writeSite(t, map[string]string{
+		"a.html":      "a",
+		"broken.html": "broken",
+		"flaky.html":  "flaky",
+		"z.html":      "z",
+	})
+	plan := SyncPlan{ToUpload: files, ToDelete: []RemoteFile{{Path: "/stuck.html"}, {Path: "/old.html"}}}
+
+	var err error
+	output := captureStdout(t, func() { err = executeSyncPlan(client, "abc", plan, true, nil) })
+	var failures *SyncFailuresError
+	if !errors.As(err, &failures) {
+		t.Fatalf("Expected a SyncFailuresError, got %v", err)
+	}
+	if got := fmt.Sprint(failures.Failures); !strings.Contains(got, "delete /stuck.html") || !strings.Contains(got, "upload /broken.html") || len(failures.Failures) != 2 {
+		t.Errorf("Expected /stuck.html and /broken.html to fail, got %s", got)
+	}
+	if failures.Total != 6 || exitCode(err) != ExitPartialSync {
+		t.Errorf("Expected 6 changes and the partial sync exit code, got %d and %d", failures.Total, exitCode(err))
+	}
+	if attempts["/z.html"] != 1 || attempts["/flaky.html"] != 2 || attempts["/broken.html"] != 2 {
+		t.Errorf("Expected later files uploaded and failures retried once, got %v", attempts)
+	}
+	if !strings.Contains(output, "Retrying 3 failed change(s)") {
+		t.Errorf("Expected the retry in the output, got:\n%s", output)
+	}
+}
+
+// TestDeleteConcurrentlyExpiredSession tests that concurrent deletes that
+// are all refused share one attempt to refresh the session
+func TestDeleteConcurrentlyExpiredSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	withPerformance(t, PerformanceConfig{DeleteConcurrency: 8})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	client, _ := NewAPIClient(server.URL)
+
+	// No refresh token, so the refresh fails
+	err := updateGlobalConfig(func(config *GlobalConfig) error {
+		config.SetHostCredentials(client.host, HostCredentials{AccessToken: "expired"})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var deleted int
+	output := captureStderr(t, func() {
+		deleted, err = deleteConcurrently(client, "abc", remoteFiles(16), true, func(RemoteFile, error) {})
+	})
+	if !errors.Is(err, errSessionExpired) || deleted != 0 {
+		t.Errorf("Expected an expired session and nothing deleted, got %d deleted and %v", deleted, err)
+	}
+	if n := strings.Count(output, "Access token expired, refreshing..."); n != 1 {
+		t.Errorf("Expected one refresh, got %d:\n%s", n, output)
+	}
+	if !client.AuthFailed() {
+		t.Errorf("Expected the failed refresh to be remembered")
+	}
+}
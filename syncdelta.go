@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// DeltaUploadLimits says when a server takes delta uploads, which send only
+// the parts of a changed file that differ from the copy already on the
+// site. A zero limit means the server leaves it to the client's default.
+type DeltaUploadLimits struct {
+	MinFileSize int64 `json:"minFileSize,omitempty"`
+	BlockSize   int   `json:"blockSize,omitempty"`
+}
+
+const (
+	// Defaults for the delta upload limits the server doesn't set
+	defaultDeltaMinFileSize = 8 * 1024 * 1024 // 8 MB
+	defaultDeltaBlockSize   = 64 * 1024       // 64 KB
+
+	// deltaLiteralChunk is the most unmatched data held before it's added to
+	// the delta, which keeps a file with few matches from filling memory
+	deltaLiteralChunk = 1024 * 1024 // 1 MB
+)
+
+// Delta operations: copy a run of blocks of the remote file, or insert
+// literal bytes. Each is a one-byte tag followed by big-endian uint32s:
+// 'C' start count, or 'L' length and that many bytes.
+const (
+	deltaOpCopy    = 'C'
+	deltaOpLiteral = 'L'
+)
+
+// errDeltaNotWorthwhile stops a delta once it's clear that it would save too
+// little over sending the whole file
+var errDeltaNotWorthwhile = errors.New("delta not worthwhile")
+
+func (l DeltaUploadLimits) withDefaults() DeltaUploadLimits {
+	if l.MinFileSize <= 0 {
+		l.MinFileSize = defaultDeltaMinFileSize
+	}
+	if l.BlockSize <= 0 {
+		l.BlockSize = defaultDeltaBlockSize
+	}
+	return l
+}
+
+// BlockChecksum identifies one block of a remote file: the rsync-style
+// rolling checksum, cheap to slide along the local file, and its MD5 to
+// confirm a match
+type BlockChecksum struct {
+	Weak   uint32 `json:"weak"`
+	Strong string `json:"strong"`
+}
+
+// RemoteBlocks is the block checksums of a remote file, as the server
+// reports them for the version of the file with ETag
+type RemoteBlocks struct {
+	ETag      string          `json:"etag"`
+	Size      int64           `json:"size"`
+	BlockSize int             `json:"blockSize"`
+	Blocks    []BlockChecksum `json:"blocks"`
+}
+
+// uploadChanged uploads a file, sending only what changed when the server
+// takes delta uploads and the file is big and already on the site with
+// baseETag. Otherwise, or if a delta turns out not to help, the whole file
+// is uploaded.
+func uploadChanged(client *APIClient, siteID string, file LocalFile, baseETag string, delta *DeltaUploadLimits) error {
+	if delta != nil && baseETag != "" {
+		limits := delta.withDefaults()
+		if file.Size >= limits.MinFileSize {
+			sent, err := uploadDelta(client, siteID, file, baseETag, limits.BlockSize)
+			if sent || err != nil {
+				return err
+			}
+		}
+	}
+	return uploadFile(client, siteID, file)
+}
+
+// uploadDelta sends the differences between a local file and the version on
+// the site with baseETag. sent is false, with no error, when the whole file
+// should be uploaded instead: the remote file has changed since it was
+// listed, or too little of it is reusable to be worth it.
+func uploadDelta(client *APIClient, siteID string, file LocalFile, baseETag string, blockSize int) (sent bool, err error) {
+	var blocks RemoteBlocks
+	path := fmt.Sprintf("/admin/efmrls/%s/blocks%s?blockSize=%d", siteID, fileURLPath(file.Path), blockSize)
+	if err := getJSON(client, path, &blocks); err != nil || blocks.ETag != baseETag || blocks.BlockSize <= 0 {
+		return false, nil
+	}
+
+	f, err := os.Open(file.AbsPath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	// Past half the file, or the most one request can carry, a full upload
+	// is as good
+	body, literal, err := computeDelta(f, blocks, min(file.Size/2, multipartThreshold))
+	if errors.Is(err, errDeltaNotWorthwhile) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := client.doBinaryRequest("POST", fmt.Sprintf("/admin/efmrls/%s/delta%s", siteID, fileURLPath(file.Path)), map[string]string{
+		"Content-Type":         "application/vnd.efmrl.delta",
+		"X-Efmrl-Base-ETag":    baseETag,
+		"X-Efmrl-ETag":         file.ETag,
+		"X-Efmrl-Block-Size":   strconv.Itoa(blocks.BlockSize),
+		"X-Efmrl-Content-Type": file.ContentType,
+	}, body)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		outf("(delta: sent %s of %s) ", formatBytes(literal), formatBytes(file.Size))
+		return true, nil
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		// The remote file changed in the meantime
+		return false, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// computeDelta encodes the local file read from r as operations on the
+// remote file's blocks, returning them and how many literal bytes they
+// carry. It gives up with errDeltaNotWorthwhile past maxLiteral of them.
+func computeDelta(r io.Reader, blocks RemoteBlocks, maxLiteral int64) ([]byte, int64, error) {
+	n := blocks.BlockSize
+	d := &deltaWriter{maxLiteral: maxLiteral, next: -1}
+
+	// Only whole blocks can match a sliding window; a short last block can
+	// only match the end of the file
+	index := make(map[uint32][]int)
+	last := len(blocks.Blocks) - 1
+	lastLen := int(blocks.Size - int64(last)*int64(n))
+	for i, b := range blocks.Blocks {
+		if i < last || lastLen == n {
+			index[b.Weak] = append(index[b.Weak], i)
+		}
+	}
+
+	br := bufio.NewReaderSize(r, deltaLiteralChunk)
+	// buf[:lit] is unmatched data not yet added to the delta, and buf[lit:]
+	// the window of n bytes being matched
+	buf := make([]byte, 0, deltaLiteralChunk+n)
+	lit := 0
+	var a, b uint32
+	readWindow := func() (bool, error) {
+		buf = buf[:n]
+		m, err := io.ReadFull(br, buf)
+		buf = buf[:m]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		a, b = weakSums(buf)
+		return true, nil
+	}
+
+	full, err := readWindow()
+	for full && err == nil {
+		if i, ok := matchBlock(blocks.Blocks, index[a|b<<16], buf[lit:]); ok {
+			if err := d.literal(buf[:lit]); err != nil {
+				return nil, 0, err
+			}
+			d.copyBlock(i)
+			lit = 0
+			full, err = readWindow()
+			continue
+		}
+
+		c, readErr := br.ReadByte()
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, 0, readErr
+		}
+		out := buf[lit]
+		buf = append(buf, c)
+		lit++
+		a = (a - uint32(out) + uint32(c)) & 0xffff
+		b = (b - uint32(n)*uint32(out) + a) & 0xffff
+
+		if lit >= deltaLiteralChunk {
+			if err := d.literal(buf[:lit]); err != nil {
+				return nil, 0, err
+			}
+			buf = append(buf[:0], buf[lit:]...)
+			lit = 0
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// What's left is a window that didn't match, or a short end of the file,
+	// which may still be the remote file's short last block
+	tail := buf[lit:]
+	if last >= 0 && len(tail) > 0 && len(tail) == lastLen && lastLen < n && blockMatches(blocks.Blocks[last], tail) {
+		if err := d.literal(buf[:lit]); err != nil {
+			return nil, 0, err
+		}
+		d.copyBlock(last)
+	} else if err := d.literal(buf); err != nil {
+		return nil, 0, err
+	}
+	d.flushCopy()
+	return d.buf.Bytes(), d.literalBytes, nil
+}
+
+// weakSums returns the two halves of the rolling checksum of p
+func weakSums(p []byte) (a, b uint32) {
+	for i, c := range p {
+		a += uint32(c)
+		b += uint32(len(p)-i) * uint32(c)
+	}
+	return a & 0xffff, b & 0xffff
+}
+
+// weakChecksum is the rolling checksum of a block, as the server reports it
+func weakChecksum(p []byte) uint32 {
+	a, b := weakSums(p)
+	return a | b<<16
+}
+
+// matchBlock returns which of the candidate blocks, whose weak checksums
+// match, really holds window
+func matchBlock(blocks []BlockChecksum, candidates []int, window []byte) (int, bool) {
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	sum := md5.Sum(window)
+	strong := hex.EncodeToString(sum[:])
+	for _, i := range candidates {
+		if blocks[i].Strong == strong {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// blockMatches reports whether p is the content of block
+func blockMatches(block BlockChecksum, p []byte) bool {
+	_, ok := matchBlock([]BlockChecksum{block}, []int{0}, p)
+	return ok && block.Weak == weakChecksum(p)
+}
+
+// deltaWriter encodes delta operations, merging copies of consecutive blocks
+// into one
+type deltaWriter struct {
+	buf          bytes.Buffer
+	literalBytes int64
+	maxLiteral   int64
+	start, next  int // the run of blocks to copy is start up to next; next is -1 if there's none
+}
+
+func (d *deltaWriter) copyBlock(i int) {
+	if i == d.next {
+		d.next++
+		return
+	}
+	d.flushCopy()
+	d.start, d.next = i, i+1
+}
+
+func (d *deltaWriter) flushCopy() {
+	if d.next < 0 {
+		return
+	}
+	d.buf.WriteByte(deltaOpCopy)
+	d.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(d.start)))
+	d.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(d.next-d.start)))
+	d.next = -1
+}
+
+func (d *deltaWriter) literal(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	d.literalBytes += int64(len(p))
+	if d.literalBytes > d.maxLiteral {
+		return errDeltaNotWorthwhile
+	}
+	d.flushCopy()
+	d.buf.WriteByte(deltaOpLiteral)
+	d.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(p))))
+	d.buf.Write(p)
+	return nil
+}
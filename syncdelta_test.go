@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// blocksOf computes the block checksums of data the way the server does
+func blocksOf(data []byte, blockSize int) RemoteBlocks {
+	blocks := RemoteBlocks{ETag: md5Hex(string(data)), Size: int64(len(data)), BlockSize: blockSize}
+	for start := 0; start < len(data); start += blockSize {
+		block := data[start:min(start+blockSize, len(data))]
+		sum := md5.Sum(block)
+		blocks.Blocks = append(blocks.Blocks, BlockChecksum{Weak: weakChecksum(block), Strong: hex.EncodeToString(sum[:])})
+	}
+	return blocks
+}
+
+// applyDelta rebuilds a file from the base the delta was computed against,
+// as the server does
+func applyDelta(t *testing.T, base []byte, blockSize int, delta []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	for len(delta) > 0 {
+		op := delta[0]
+		switch op {
+		case deltaOpCopy:
+			start := int(binary.BigEndian.Uint32(delta[1:]))
+			count := int(binary.BigEndian.Uint32(delta[5:]))
+			out.Write(base[start*blockSize : min((start+count)*blockSize, len(base))])
+			delta = delta[9:]
+		case deltaOpLiteral:
+			n := int(binary.BigEndian.Uint32(delta[1:]))
+			out.Write(delta[5 : 5+n])
+			delta = delta[5+n:]
+		default:
+			t.Fatalf("Unknown delta operation %q", op)
+		}
+	}
+	return out.Bytes()
+}
+
+func TestComputeDelta(t *testing.T) {
+	const blockSize = 64
+	random := rand.New(rand.NewSource(1))
+	base := make([]byte, 20*blockSize+17)
+	random.Read(base)
+
+	splice := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	tests := []struct {
+		name        string
+		local       []byte
+		wantLiteral int64
+	}{
+		{"unchanged", base, 0},
+		{"bytes changed in one block", splice(base[:300], []byte("XYZ"), base[303:]), blockSize},
+		{"bytes inserted", splice(base[:300], []byte("inserted"), base[300:]), blockSize + 8},
+		{"appended", splice(base, []byte("more")), 17 + 4},
+		{"truncated", base[:10*blockSize], 0},
+		{"empty", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta, literal, err := computeDelta(bytes.NewReader(tt.local), blocksOf(base, blockSize), int64(len(base)))
+			if err != nil {
+				t.Fatalf("computeDelta failed: %v", err)
+			}
+			if got := applyDelta(t, base, blockSize, delta); !bytes.Equal(got, tt.local) {
+				t.Errorf("Expected the delta to rebuild the local file (%d bytes), got %d bytes", len(tt.local), len(got))
+			}
+			if literal != tt.wantLiteral {
+				t.Errorf("Expected %d literal bytes, got %d", tt.wantLiteral, literal)
+			}
+		})
+	}
+}
+
+func TestComputeDeltaNotWorthwhile(t *testing.T) {
+	base := bytes.Repeat([]byte("a"), 1024)
+	local := bytes.Repeat([]byte("b"), 1024)
+
+	_, _, err := computeDelta(bytes.NewReader(local), blocksOf(base, 64), 512)
+	if !errors.Is(err, errDeltaNotWorthwhile) {
+		t.Errorf("Expected errDeltaNotWorthwhile, got %v", err)
+	}
+}
+
+// TestUploadChanged tests that a delta is sent when the server's copy
+// matches, and the whole file otherwise
+func TestUploadChanged(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	base := bytes.Repeat([]byte("0123456789abcdef"), 64)
+	local := append(bytes.Clone(base), "tail"...)
+	var blocks RemoteBlocks
+	var rebuilt []byte
+	var puts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/admin/efmrls/abc/blocks/"):
+			json.NewEncoder(w).Encode(blocks)
+		case strings.HasPrefix(r.URL.Path, "/admin/efmrls/abc/delta/"):
+			if r.Header.Get("X-Efmrl-Base-ETag") != blocks.ETag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			delta, _ := io.ReadAll(r.Body)
+			rebuilt = applyDelta(t, base, blocks.BlockSize, delta)
+		case r.Method == http.MethodPut:
+			puts++
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client, _ := NewAPIClient(server.URL)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.bin"), local, 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := LocalFile{Path: "/data.bin", AbsPath: filepath.Join(dir, "data.bin"), ETag: md5Hex(string(local)), Size: int64(len(local))}
+	limits := &DeltaUploadLimits{MinFileSize: 1, BlockSize: 128}
+	blocks = blocksOf(base, 128)
+
+	captureStdout(t, func() {
+		if err := uploadChanged(client, "abc", file, blocks.ETag, limits); err != nil {
+			t.Fatalf("uploadChanged failed: %v", err)
+		}
+	})
+	if !bytes.Equal(rebuilt, local) || puts != 0 {
+		t.Errorf("Expected the file sent as a delta, got %d bytes rebuilt and %d PUT(s)", len(rebuilt), puts)
+	}
+
+	// The remote file isn't the version that was listed
+	if err := uploadChanged(client, "abc", file, md5Hex("stale"), limits); err != nil {
+		t.Fatalf("uploadChanged failed: %v", err)
+	}
+	if puts != 1 {
+		t.Errorf("Expected a full upload for a stale base, got %d PUT(s)", puts)
+	}
+
+	// The server doesn't take deltas
+	if err := uploadChanged(client, "abc", file, blocks.ETag, nil); err != nil {
+		t.Fatalf("uploadChanged failed: %v", err)
+	}
+	if puts != 2 {
+		t.Errorf("Expected a full upload without delta support, got %d PUT(s)", puts)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// SyncLockFileName is the lock file written next to the project config while
+// a sync is running. It starts with a dot so it's never uploaded.
+const SyncLockFileName = ".efmrl.lock"
+
+// SyncLock records which process holds the project sync lock
+type SyncLock struct {
+	PID     int       `toml:"pid"`
+	Started time.Time `toml:"started"`
+}
+
+// acquireSyncLock creates the project lock file, failing if another sync
+// already holds it. If forceUnlock is set, any existing lock is removed first.
+// The returned function releases the lock.
+func acquireSyncLock(forceUnlock bool) (func(), error) {
+	lockPath := filepath.Join(".", SyncLockFileName)
+
+	if forceUnlock {
+		if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale lock: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		var held SyncLock
+		if _, err := toml.DecodeFile(lockPath, &held); err != nil {
+			return nil, fmt.Errorf("sync already in progress (unreadable %s); use --force-unlock if it is stale", SyncLockFileName)
+		}
+		return nil, fmt.Errorf("sync already in progress (pid %d, started %s); use --force-unlock if it is stale",
+			held.PID, held.Started.Local().Format(time.DateTime))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", SyncLockFileName, err)
+	}
+	defer file.Close()
+
+	lock := SyncLock{PID: os.Getpid(), Started: time.Now().UTC()}
+	if err := toml.NewEncoder(file).Encode(lock); err != nil {
+		os.Remove(lockPath)
+		return nil, fmt.Errorf("failed to write %s: %w", SyncLockFileName, err)
+	}
+
+	return func() { os.Remove(lockPath) }, nil
+}
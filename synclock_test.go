@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestAcquireSyncLock tests that a held lock blocks a second sync until released
+func TestAcquireSyncLock(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	unlock, err := acquireSyncLock(false)
+	if err != nil {
+		t.Fatalf("acquireSyncLock failed: %v", err)
+	}
+
+	_, err = acquireSyncLock(false)
+	if err == nil {
+		t.Fatal("Expected error while lock is held, got nil")
+	}
+	if !strings.Contains(err.Error(), "sync already in progress") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	unlock()
+	if _, err := os.Stat(SyncLockFileName); !os.IsNotExist(err) {
+		t.Errorf("Expected lock file to be removed after unlock")
+	}
+
+	// A stale lock can be broken with forceUnlock
+	os.WriteFile(SyncLockFileName, []byte("pid = 1\n"), 0644)
+	unlock, err = acquireSyncLock(true)
+	if err != nil {
+		t.Fatalf("acquireSyncLock with forceUnlock failed: %v", err)
+	}
+	unlock()
+}
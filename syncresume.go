@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SyncPlanFileName is the file in the sync directory where a sync saves its
+// plan, and then each change as it's made, so an interrupted sync can be
+// resumed. It starts with a dot so it's never uploaded.
+const SyncPlanFileName = ".efmrl-sync-plan.json"
+
+// SavedSyncPlan is the plan of a sync, as saved for resuming it
+type SavedSyncPlan struct {
+	SiteID    string            `json:"siteId"`
+	Created   time.Time         `json:"created"`
+	ToUpload  []LocalFile       `json:"toUpload"`
+	ToDelete  []RemoteFile      `json:"toDelete"`
+	Replacing map[string]string `json:"replacing,omitempty"`
+	Unchanged int               `json:"unchanged"`
+}
+
+// syncJournalEntry is a change a sync has made: an "upload" or "delete"
+type syncJournalEntry struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+}
+
+// syncJournal records a sync's progress in the sync directory: the plan on
+// the first line, then a line for each change made. Lines are appended as
+// changes are made, so an interrupted sync leaves at most a partial last
+// line. A nil journal records nothing.
+type syncJournal struct {
+	path string
+	file *os.File
+}
+
+// startSyncJournal saves plan in dir, replacing any plan left by an earlier
+// sync
+func startSyncJournal(dir string, plan SavedSyncPlan) (*syncJournal, error) {
+	path := filepath.Join(dir, SyncPlanFileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(file).Encode(plan); err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return &syncJournal{path: path, file: file}, nil
+}
+
+// startJournal saves plan in dir so the sync can be resumed if it's
+// interrupted. A sync that would be rolled back isn't journaled, nor is one
+// that uploads generated files, which are gone by the time it could be
+// resumed, and one whose plan can't be saved goes ahead with a warning. A
+// nil journal is returned in each case.
+func (s *SyncCmd) startJournal(config *Config, dir string, plan SyncPlan) *syncJournal {
+	if s.RollbackOnFailure {
+		return nil
+	}
+	if steps := s.transformSteps(config); len(steps) > 0 {
+		// A plan left by an earlier sync is out of date now
+		os.Remove(filepath.Join(dir, SyncPlanFileName))
+		outln(dim(fmt.Sprintf("Not saving the sync plan: %s upload files made for this sync, so it can't be resumed if it's interrupted",
+			strings.Join(steps, ", "))))
+		return nil
+	}
+	journal, err := startSyncJournal(dir, SavedSyncPlan{
+		SiteID:    config.Site.SiteID,
+		Created:   time.Now().UTC(),
+		ToUpload:  plan.ToUpload,
+		ToDelete:  plan.ToDelete,
+		Replacing: plan.Replacing,
+		Unchanged: len(plan.Unchanged),
+	})
+	if err != nil {
+		warnf("Couldn't save the sync plan, so this sync can't be resumed if it's interrupted: %v\n", err)
+		return nil
+	}
+	return journal
+}
+
+// reopenSyncJournal continues the journal of an interrupted sync
+func reopenSyncJournal(dir string) (*syncJournal, error) {
+	path := filepath.Join(dir, SyncPlanFileName)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &syncJournal{path: path, file: file}, nil
+}
+
+// done records a change made. The journal is a convenience, so a failure
+// to write to it doesn't fail the sync; resuming checks the site anyway.
+func (j *syncJournal) done(op, path string) {
+	if j == nil {
+		return
+	}
+	line, _ := json.Marshal(syncJournalEntry{Op: op, Path: path})
+	j.file.Write(append(line, '\n'))
+}
+
+// finish closes the journal, removing it if the sync it records is complete
+func (j *syncJournal) finish(complete bool) {
+	if j == nil {
+		return
+	}
+	j.file.Close()
+	if complete {
+		os.Remove(j.path)
+	}
+}
+
+// loadSyncJournal reads the plan and changes made by an interrupted sync of
+// dir. The changes are keyed by "op path".
+func loadSyncJournal(dir string) (*SavedSyncPlan, map[string]bool, error) {
+	file, err := os.Open(filepath.Join(dir, SyncPlanFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 256*1024*1024)
+	if !scanner.Scan() {
+		return nil, nil, fmt.Errorf("%s is empty", SyncPlanFileName)
+	}
+	var plan SavedSyncPlan
+	if err := json.Unmarshal(scanner.Bytes(), &plan); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", SyncPlanFileName, err)
+	}
+
+	done := map[string]bool{}
+	for scanner.Scan() {
+		// A partial last line is a change that may not have been recorded
+		// in full; the site is checked for it anyway
+		var entry syncJournalEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			done[entry.Op+" "+entry.Path] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", SyncPlanFileName, err)
+	}
+	return &plan, done, nil
+}
+
+// warnSavedPlan warns that starting a new sync of dir discards the plan
+// left by an interrupted one
+func warnSavedPlan(dir string) {
+	plan, _, err := loadSyncJournal(dir)
+	if err != nil {
+		return
+	}
+	warnf("an interrupted sync from %s left its plan in %s; this sync replaces it.\n"+
+		"         Use 'efmrl3 sync --resume' to finish the interrupted sync instead.\n",
+		plan.Created.Local().Format(time.DateTime), dir)
+}
+
+// remainingPlan works out what's left of a saved plan, going by the site as
+// it is now rather than only the journal, since the last change made may
+// not have been recorded, and a recorded one may have been undone since.
+// Files left to upload are hashed again, so a file changed since the plan
+// was made is uploaded as it is now. skipped counts the changes already
+// made.
+func remainingPlan(saved *SavedSyncPlan, done map[string]bool, remoteFiles []RemoteFile) (plan SyncPlan, skipped int, err error) {
+	remote := make(map[string]string, len(remoteFiles))
+	for _, rf := range remoteFiles {
+		remote[rf.Path] = rf.ETag
+	}
+
+	for _, rf := range saved.ToDelete {
+		if _, exists := remote[rf.Path]; !exists {
+			skipped++
+			continue
+		}
+		if done["delete "+rf.Path] {
+			warnf("%s was deleted, but is back on the site; deleting it again\n", rf.Path)
+		}
+		plan.ToDelete = append(plan.ToDelete, rf)
+	}
+
+	uploads := make([]LocalFile, 0, len(saved.ToUpload))
+	for _, lf := range saved.ToUpload {
+		if _, err := os.Stat(lf.AbsPath); err != nil {
+			return SyncPlan{}, 0, fmt.Errorf("%s is gone since the sync was interrupted (it may have been made by a build step); run sync without --resume", lf.AbsPath)
+		}
+		lf.ETag = ""
+		uploads = append(uploads, lf)
+	}
+	if err := hashLocalFiles(uploads, func(*LocalFile) bool { return true }); err != nil {
+		return SyncPlan{}, 0, err
+	}
+
+	plan.Replacing = map[string]string{}
+	for _, lf := range uploads {
+		etag, exists := remote[lf.Path]
+		if exists && etag == lf.ETag {
+			skipped++
+			continue
+		}
+		if done["upload "+lf.Path] {
+			warnf("%s was uploaded, but the site has a different version; uploading it again\n", lf.Path)
+		}
+		if exists {
+			plan.Replacing[lf.Path] = etag
+		}
+		plan.ToUpload = append(plan.ToUpload, lf)
+	}
+	return plan, skipped, nil
+}
+
+// resumeSync finishes the interrupted sync of absDir from its saved plan,
+// instead of scanning and comparing everything again
+func (s *SyncCmd) resumeSync(config *Config, absDir string) (*SyncResult, error) {
+	saved, done, err := loadSyncJournal(absDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("there's no interrupted sync of %s to resume", absDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if saved.SiteID != config.Site.SiteID {
+		return nil, fmt.Errorf("the interrupted sync of %s was to site %s, not %s; run sync without --resume", absDir, saved.SiteID, config.Site.SiteID)
+	}
+
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API client: %w", err)
+	}
+	spin := startSpinner("Fetching remote file list...")
+	remoteFiles, err := fetchRemoteFiles(apiClient, config.Site.SiteID)
+	spin.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+
+	startGroup("Plan")
+	plan, skipped, err := remainingPlan(saved, done, remoteFiles)
+	if err != nil {
+		return nil, err
+	}
+	remaining := len(plan.ToUpload) + len(plan.ToDelete)
+	outf("Resuming the sync from %s: %d of %d change(s) already made, %d left\n",
+		saved.Created.Local().Format(time.DateTime), skipped, skipped+remaining, remaining)
+	for _, f := range plan.ToUpload {
+		outf("  + %s\n", f.Path)
+	}
+	for _, f := range plan.ToDelete {
+		outf("  - %s\n", f.Path)
+	}
+
+	result := SyncResult{
+		SiteID:    config.Site.SiteID,
+		Dir:       absDir,
+		DryRun:    s.DryRun,
+		Uploaded:  []string{},
+		Deleted:   []string{},
+		Unchanged: saved.Unchanged,
+	}
+	for _, f := range saved.ToUpload {
+		result.Uploaded = append(result.Uploaded, f.Path)
+	}
+	for _, f := range saved.ToDelete {
+		result.Deleted = append(result.Deleted, f.Path)
+	}
+
+	switch {
+	case s.DryRun:
+		outln("\n--dry-run mode: no changes made")
+		return &result, nil
+	case remaining == 0:
+		outf("\n%s Sync complete\n", green("✓"))
+		os.Remove(filepath.Join(absDir, SyncPlanFileName))
+	default:
+		outln()
+		startGroup("Sync")
+		journal, err := reopenSyncJournal(absDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", SyncPlanFileName, err)
+		}
+		err = s.executeSyncPlan(apiClient, config.Site.SiteID, plan, journal)
+		journal.finish(err == nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.verifyAfterSync(config, apiClient, &result); err != nil {
+		return nil, err
+	}
+	endGroup()
+	return &result, nil
+}
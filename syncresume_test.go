@@ -0,0 +1,140 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSyncJournal tests reading back a saved plan and the changes made,
+// ignoring a partial last line
+func TestSyncJournal(t *testing.T) {
+	dir := t.TempDir()
+	plan := SavedSyncPlan{SiteID: "abc", ToUpload: []LocalFile{{Path: "/a.html"}}, ToDelete: []RemoteFile{{Path: "/old.html"}}}
+
+	journal, err := startSyncJournal(dir, plan)
+	if err != nil {
+		t.Fatalf("startSyncJournal failed: %v", err)
+	}
+	journal.done("delete", "/old.html")
+	journal.finish(false)
+
+	journal, err = reopenSyncJournal(dir)
+	if err != nil {
+		t.Fatalf("reopenSyncJournal failed: %v", err)
+	}
+	journal.file.WriteString(`{"op":"upload","pa`)
+	journal.finish(false)
+
+	saved, done, err := loadSyncJournal(dir)
+	if err != nil {
+		t.Fatalf("loadSyncJournal failed: %v", err)
+	}
+	if saved.SiteID != "abc" || len(saved.ToUpload) != 1 || saved.ToUpload[0].Path != "/a.html" {
+		t.Errorf("Unexpected plan %+v", saved)
+	}
+	if len(done) != 1 || !done["delete /old.html"] {
+		t.Errorf("Expected only the delete to be done, got %v", done)
+	}
+
+	journal, _ = reopenSyncJournal(dir)
+	journal.finish(true)
+	if _, err := os.Stat(filepath.Join(dir, SyncPlanFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the plan removed once the sync is complete")
+	}
+}
+
+// TestResumeSync tests that resuming skips the changes the site shows were
+// made, journaled or not, and redoes the rest
+func TestResumeSync(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	// /a.html was uploaded and journaled, /old.html deleted but not
+	// journaled, and /b.html not uploaded yet
+	site := map[string]string{"/a.html": "a", "/keep.html": "keep"}
+	server := fakeSite(t, site)
+	serverURL, _ := url.Parse(server.URL)
+
+	dir, files := writeSite(t, map[string]string{"a.html": "a", "b.html": "b"})
+	journal, err := startSyncJournal(dir, SavedSyncPlan{SiteID: "abc", ToUpload: files, ToDelete: []RemoteFile{{Path: "/old.html"}}, Unchanged: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.done("upload", "/a.html")
+	journal.finish(false)
+
+	config := &Config{Site: SiteConfig{SiteID: "abc", BaseHost: "localhost:" + serverURL.Port()}}
+	s := &SyncCmd{Resume: true}
+	var result *SyncResult
+	output := captureStdout(t, func() { result, err = s.resumeSync(config, dir) })
+	if err != nil {
+		t.Fatalf("resumeSync failed: %v", err)
+	}
+
+	if !strings.Contains(output, "2 of 3 change(s) already made, 1 left") {
+		t.Errorf("Expected 1 change left, got:\n%s", output)
+	}
+	if site["/b.html"] != "b" || len(site) != 3 {
+		t.Errorf("Expected /b.html uploaded, got %v", site)
+	}
+	if len(result.Uploaded) != 2 || len(result.Deleted) != 1 || result.Unchanged != 1 {
+		t.Errorf("Expected the whole sync in the result, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, SyncPlanFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the plan removed after resuming")
+	}
+
+	if _, err := s.resumeSync(config, dir); err == nil || !strings.Contains(err.Error(), "no interrupted sync") {
+		t.Errorf("Expected nothing left to resume, got %v", err)
+	}
+}
+
+// TestResumeAfterTransformedSync tests that a sync uploading minified
+// copies, which are deleted when it ends, leaves no plan to resume from,
+// not even one from an earlier sync
+func TestResumeAfterTransformedSync(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+	t.Setenv("HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/admin/efmrls/abc/quota":
+			w.Write([]byte(`{"currentSpace": 0, "maxSpace": 1000000}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/admin/efmrls/abc/files":
+			w.Write([]byte(`{"files": []}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/admin/efmrls/abc/files/b.html":
+			http.Error(w, "nope", http.StatusInternalServerError)
+		case r.Method == http.MethodPut:
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	dir, _ := writeSite(t, map[string]string{"a.html": "<p>  a  </p>", "b.html": "<p>  b  </p>"})
+	if err := os.WriteFile(filepath.Join(dir, SyncPlanFileName), []byte(`{"siteId":"abc"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{Site: SiteConfig{SiteID: "abc", BaseHost: "localhost:" + serverURL.Port()}}
+	s := &SyncCmd{Minify: []string{"html"}, Yes: true}
+	var err error
+	output := captureStdout(t, func() { _, err = s.syncDir(config, dir) })
+	if err == nil {
+		t.Fatalf("Expected the sync to fail")
+	}
+	if !strings.Contains(output, "Not saving the sync plan: minify") || strings.Contains(output, "The plan was saved") {
+		t.Errorf("Expected the sync not to be resumable, got:\n%s", output)
+	}
+
+	s = &SyncCmd{Resume: true}
+	captureStdout(t, func() { _, err = s.syncDir(config, dir) })
+	if err == nil || !strings.Contains(err.Error(), "no interrupted sync") {
+		t.Errorf("Expected nothing to resume, got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// syncSnapshot is what a sync with --rollback-on-failure needs to undo it:
+// copies of the remote files it's about to delete or overwrite, downloaded
+// before anything changes, and the paths of the files it adds
+type syncSnapshot struct {
+	dir   string
+	files []LocalFile
+	added []string
+}
+
+// snapshotRemote downloads the remote files plan deletes or overwrites, so
+// a failed sync can put them back. A failure here leaves the site as it was.
+func snapshotRemote(client *APIClient, siteID string, plan SyncPlan) (*syncSnapshot, error) {
+	dir, err := os.MkdirTemp("", "efmrl-rollback-")
+	if err != nil {
+		return nil, err
+	}
+	snap := &syncSnapshot{dir: dir}
+
+	var previous []RemoteFile
+	previous = append(previous, plan.ToDelete...)
+	for _, lf := range plan.ToUpload {
+		etag, replacing := plan.Replacing[lf.Path]
+		if !replacing {
+			snap.added = append(snap.added, lf.Path)
+			continue
+		}
+		previous = append(previous, RemoteFile{Path: lf.Path, ETag: etag})
+	}
+
+	if len(previous) > 0 {
+		spin := startSpinner(fmt.Sprintf("Backing up %d remote file(s) for rollback...", len(previous)))
+		for _, rf := range previous {
+			lf, err := downloadFile(client, siteID, rf, dir, nil)
+			if err != nil {
+				spin.Stop()
+				snap.cleanup()
+				return nil, fmt.Errorf("failed to back up %s for --rollback-on-failure: %w", rf.Path, err)
+			}
+			snap.files = append(snap.files, *lf)
+		}
+		spin.Stop()
+	}
+	return snap, nil
+}
+
+func (snap *syncSnapshot) cleanup() {
+	os.RemoveAll(snap.dir)
+}
+
+// restore puts the site back as it was when the snapshot was taken. Only
+// what the failed sync actually changed is undone, going by the remote file
+// list as it is now: added files that made it are deleted, and backed-up
+// files that are missing or different are uploaded again. It carries on
+// past failures and reports how many changes it couldn't undo.
+func (snap *syncSnapshot) restore(client *APIClient, siteID string) error {
+	remoteFiles, err := fetchRemoteFiles(client, siteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+	remote := make(map[string]string, len(remoteFiles))
+	for _, rf := range remoteFiles {
+		remote[rf.Path] = rf.ETag
+	}
+
+	var remove []string
+	for _, p := range snap.added {
+		if _, ok := remote[p]; ok {
+			remove = append(remove, p)
+		}
+	}
+	var reupload []LocalFile
+	for _, lf := range snap.files {
+		if etag, ok := remote[lf.Path]; !ok || etag != lf.ETag {
+			reupload = append(reupload, lf)
+		}
+	}
+
+	total := len(remove) + len(reupload)
+	outf("\nRolling back %d change(s)...\n", total)
+	current, failed := 0, 0
+	for _, p := range remove {
+		current++
+		outf("[%d/%d] Removing %s... ", current, total, p)
+		if err := deleteFile(client, siteID, p); err != nil {
+			outf("%s\n", red("FAILED"))
+			annotateError("Failed to remove "+p+" while rolling back", "", err)
+			failed++
+			continue
+		}
+		outf("%s\n", green("OK"))
+	}
+	for _, lf := range reupload {
+		current++
+		outf("[%d/%d] Restoring %s... ", current, total, lf.Path)
+		if err := uploadFile(client, siteID, lf); err != nil {
+			outf("%s\n", red("FAILED"))
+			annotateError("Failed to restore "+lf.Path+" while rolling back", "", err)
+			failed++
+			continue
+		}
+		outf("%s\n", green("OK"))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d change(s) couldn't be undone", failed, total)
+	}
+	return nil
+}
+
+// rolledBack describes the failure of a sync that restore was run for. A
+// sync that was undone left the site unchanged, so it's no longer partial;
+// one that couldn't be undone still is.
+func rolledBack(syncErr, restoreErr error) error {
+	if restoreErr != nil {
+		return fmt.Errorf("%w\nRolling back failed too, so the site is partly updated: %v", syncErr, restoreErr)
+	}
+	var partial *PartialSyncError
+	if errors.As(syncErr, &partial) {
+		syncErr = partial.Err
+	}
+	outf("%s Rolled back; the site is as it was before the sync\n", yellow("!"))
+	return fmt.Errorf("%w (the sync was rolled back)", syncErr)
+}
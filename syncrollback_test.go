@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSite serves a site's files from memory, failing uploads of the paths
+// in failing
+func fakeSite(t *testing.T, files map[string]string, failing ...string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path, ok := strings.CutPrefix(r.URL.Path, "/admin/efmrls/abc/files")
+		switch {
+		case !ok:
+			http.NotFound(w, r)
+		case r.Method == http.MethodGet && path == "":
+			var list []RemoteFile
+			for p, content := range files {
+				list = append(list, RemoteFile{Path: p, ETag: md5Hex(content), Size: int64(len(content))})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"files": list})
+		case r.Method == http.MethodGet:
+			content, exists := files[path]
+			if !exists {
+				http.NotFound(w, r)
+				return
+			}
+			io.WriteString(w, content)
+		case r.Method == http.MethodPut:
+			for _, p := range failing {
+				if p == path {
+					http.Error(w, "nope", http.StatusInternalServerError)
+					return
+				}
+			}
+			body, _ := io.ReadAll(r.Body)
+			files[path] = string(body)
+		case r.Method == http.MethodDelete:
+			delete(files, path)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRollbackOnFailure(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	site := map[string]string{
+		"/index.html": "old index",
+		"/old.html":   "old page",
+		"/keep.html":  "kept",
+	}
+	before := fmt.Sprint(site)
+	server := fakeSite(t, site, "/broken.html")
+	client, _ := NewAPIClient(server.URL)
+
+	_, files := writeSite(t, map[string]string{
+		"index.html":  "new index",
+		"new.html":    "new page",
+		"broken.html": "broken",
+	})
+	// /broken.html last, after the others are uploaded
+	sort.Slice(files, func(i, j int) bool { return files[i].Path > files[j].Path })
+	plan := SyncPlan{
+		ToUpload:  files,
+		ToDelete:  []RemoteFile{{Path: "/old.html", ETag: md5Hex("old page")}},
+		Replacing: map[string]string{"/index.html": md5Hex("old index")},
+	}
+
+	s := &SyncCmd{RollbackOnFailure: true}
+	var err error
+	output := captureStdout(t, func() { err = s.executeSyncPlan(client, "abc", plan, nil) })
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("Expected a rolled back error, got %v", err)
+	}
+	if exitCode(err) == ExitPartialSync {
+		t.Errorf("Expected a rolled back sync not to be partial, got exit code %d", exitCode(err))
+	}
+	if after := fmt.Sprint(site); after != before {
+		t.Errorf("Expected the site restored to %s, got %s", before, after)
+	}
+	if !strings.Contains(output, "Rolling back 3 change(s)") {
+		t.Errorf("Expected the rollback in the output, got:\n%s", output)
+	}
+}
+
+func TestRollbackFailed(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	site := map[string]string{"/old.html": "old page"}
+	// The restore of /old.html fails too
+	server := fakeSite(t, site, "/broken.html", "/old.html")
+	client, _ := NewAPIClient(server.URL)
+
+	_, files := writeSite(t, map[string]string{"broken.html": "broken"})
+	plan := SyncPlan{ToUpload: files, ToDelete: []RemoteFile{{Path: "/old.html", ETag: md5Hex("old page")}}}
+
+	s := &SyncCmd{RollbackOnFailure: true}
+	var err error
+	captureStdout(t, func() { err = s.executeSyncPlan(client, "abc", plan, nil) })
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 change(s) couldn't be undone") {
+		t.Fatalf("Expected a failed rollback, got %v", err)
+	}
+	if exitCode(err) != ExitPartialSync {
+		t.Errorf("Expected the partial sync exit code, got %d", exitCode(err))
+	}
+}
+
+func TestRollbackSnapshotFails(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	// /gone.html is listed in the plan but can't be downloaded
+	site := map[string]string{"/old.html": "old page"}
+	server := fakeSite(t, site)
+	client, _ := NewAPIClient(server.URL)
+
+	plan := SyncPlan{ToDelete: []RemoteFile{{Path: "/old.html"}, {Path: "/gone.html"}}}
+	s := &SyncCmd{RollbackOnFailure: true}
+	var err error
+	captureStdout(t, func() { err = s.executeSyncPlan(client, "abc", plan, nil) })
+	if err == nil || !strings.Contains(err.Error(), "failed to back up /gone.html") {
+		t.Fatalf("Expected the backup to fail, got %v", err)
+	}
+	if _, ok := site["/old.html"]; !ok {
+		t.Errorf("Expected nothing deleted when the backup fails")
+	}
+}
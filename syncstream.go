@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// errStreamStopped stops the scan of a streaming sync that has failed
+var errStreamStopped = errors.New("sync stopped")
+
+// syncStreaming is syncDir for very large sites. The directory is scanned,
+// hashed and compared, and uploaded as a pipeline: uploads begin before the
+// scan finishes, and only the remote file list is held in memory, not the
+// local one. So there's no plan shown up front, and remote files are
+// deleted last rather than first, once the whole directory has been seen.
+func (s *SyncCmd) syncStreaming(config *Config, absDir string) (*SyncResult, error) {
+	if s.KeepGoing {
+		return nil, fmt.Errorf("--keep-going can't be combined with streaming")
+	}
+	if s.RollbackOnFailure {
+		return nil, fmt.Errorf("--rollback-on-failure can't be combined with streaming")
+	}
+	if steps := s.wholeSiteSteps(config); len(steps) > 0 {
+		return nil, fmt.Errorf("streaming can't be combined with %s, which need every file up front", strings.Join(steps, ", "))
+	}
+
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API client: %w", err)
+	}
+	warnIfExpiring(config, apiClient)
+
+	quota, err := fetchQuota(apiClient, config.Site.SiteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quota: %w", err)
+	}
+	remoteFiles, err := fetchRemoteFiles(apiClient, config.Site.SiteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+	outf("Found %d remote file(s)\n\n", len(remoteFiles))
+
+	// The compare stage removes each local file from remote as it sees it,
+	// so what's left at the end is what to delete
+	remote := make(map[string]RemoteFile, len(remoteFiles))
+	for _, rf := range remoteFiles {
+		remote[rf.Path] = rf
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	scanned := make(chan LocalFile, performance.StreamBuffer)
+	changed := make(chan LocalFile, performance.StreamBuffer)
+	errs := make(chan error, 2)
+
+	startGroup("Sync")
+	emitEvent("scan_started", map[string]any{"dir": absDir})
+
+	// Scan
+	go func() {
+		defer close(scanned)
+		form := config.UnicodeNormalization()
+		err := walkLocalFiles(absDir, func(lf LocalFile) error {
+			lf.Path = normalizePath(lf.Path, form)
+			select {
+			case scanned <- lf:
+				return nil
+			case <-done:
+				return errStreamStopped
+			}
+		})
+		if err != nil && !errors.Is(err, errStreamStopped) {
+			errs <- fmt.Errorf("failed to scan local files: %w", err)
+		}
+	}()
+
+	// Hash and compare. Only files also on the site need hashing; the rest
+	// are hashed as they're uploaded.
+	var scannedFiles, unchanged int
+	var localSize int64
+	compare := config.CompareMode()
+	go func() {
+		defer close(changed)
+		for lf := range scanned {
+			scannedFiles++
+			localSize += lf.Size
+			if localSize > quota.MaxSpace {
+				errs <- &QuotaError{Err: fmt.Errorf("local directory size exceeds efmrl quota (%s); see 'efmrl3 plan show' for larger plans",
+					formatBytes(quota.MaxSpace))}
+				return
+			}
+
+			rf, exists := remote[lf.Path]
+			delete(remote, lf.Path)
+			if exists && !s.Force && comparesByMetadata(lf, rf, compare) {
+				if metadataUnchanged(lf, rf) {
+					unchanged++
+					continue
+				}
+			} else if exists && !s.Force {
+				file := []LocalFile{lf}
+				if err := hashLocalFiles(file, func(*LocalFile) bool { return true }); err != nil {
+					errs <- err
+					return
+				}
+				if file[0].ETag == rf.ETag {
+					unchanged++
+					continue
+				}
+				lf = file[0]
+			}
+
+			select {
+			case changed <- lf:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Upload
+	result := SyncResult{
+		SiteID:   config.Site.SiteID,
+		Dir:      absDir,
+		DryRun:   s.DryRun,
+		Uploaded: []string{},
+		Deleted:  []string{},
+	}
+	for lf := range changed {
+		current := len(result.Uploaded) + 1
+		if s.DryRun {
+			outf("  + %s\n", lf.Path)
+			result.Uploaded = append(result.Uploaded, lf.Path)
+			continue
+		}
+
+		outf("[%d] Uploading %s... ", current, lf.Path)
+		if err := uploadFile(apiClient, config.Site.SiteID, lf); err != nil {
+			outf("%s\n", red("FAILED"))
+			emitEvent("op_failed", map[string]any{"path": lf.Path, "current": current, "error": err.Error()})
+			annotateError("Failed to upload "+lf.Path, lf.AbsPath, err)
+			return nil, partialSyncError(current-1, current, fmt.Errorf("failed to upload %s: %w", lf.Path, err))
+		}
+		outf("%s\n", green("OK"))
+		emitEvent("file_uploaded", map[string]any{"path": lf.Path, "bytes": lf.Size, "current": current})
+		result.Uploaded = append(result.Uploaded, lf.Path)
+	}
+
+	// The stages report failures before closing their channels, so one is
+	// waiting by now if there was any. A directory that wasn't seen in full
+	// mustn't lead to deletions.
+	select {
+	case err := <-errs:
+		if s.DryRun {
+			return nil, err
+		}
+		return nil, partialSyncError(len(result.Uploaded), len(result.Uploaded), err)
+	default:
+	}
+	result.Unchanged = unchanged
+	emitEvent("scan_complete", map[string]any{"files": scannedFiles, "bytes": localSize})
+
+	if s.Delete && len(remote) > 0 {
+		if err := s.deleteRemaining(config, apiClient, remote, &result); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case len(result.Uploaded) == 0 && len(result.Deleted) == 0:
+		outf("%s Everything is up to date (%d file(s) unchanged)\n", green("✓"), unchanged)
+	case s.DryRun:
+		outln(dim(fmt.Sprintf("Files unchanged: %d", unchanged)))
+		outln("\n--dry-run mode: no changes made")
+	default:
+		outln(dim(fmt.Sprintf("Files unchanged: %d", unchanged)))
+		outf("\n%s Sync complete\n", green("✓"))
+	}
+
+	if err := s.verifyAfterSync(config, apiClient, &result); err != nil {
+		return nil, err
+	}
+	endGroup()
+	return &result, nil
+}
+
+// deleteRemaining deletes the remote files a streaming sync didn't find
+// locally, after confirming if there are many of them
+func (s *SyncCmd) deleteRemaining(config *Config, client *APIClient, remote map[string]RemoteFile, result *SyncResult) error {
+	toDelete := make([]RemoteFile, 0, len(remote))
+	for _, rf := range remote {
+		toDelete = append(toDelete, rf)
+	}
+	sort.Slice(toDelete, func(i, j int) bool { return toDelete[i].Path < toDelete[j].Path })
+
+	if s.DryRun {
+		for _, rf := range toDelete {
+			outf("  - %s\n", rf.Path)
+			result.Deleted = append(result.Deleted, rf.Path)
+		}
+		return nil
+	}
+
+	if err := s.confirmDeletes(config, SyncPlan{ToDelete: toDelete}); err != nil {
+		return partialSyncError(len(result.Uploaded), len(result.Uploaded)+len(toDelete), err)
+	}
+	completed, current := len(result.Uploaded), 0
+	deleted, err := deleteRemoteFiles(client, config.Site.SiteID, toDelete, false, func(rf RemoteFile, err error) {
+		current++
+		if err == nil {
+			result.Deleted = append(result.Deleted, rf.Path)
+		}
+		reportDelete(rf, err, current, len(toDelete))
+	})
+	if err != nil {
+		return partialSyncError(completed+deleted, completed+len(toDelete), err)
+	}
+	return nil
+}
+
+// wholeSiteSteps lists the sync steps enabled, by flag or config, that look
+// at every local file before anything is uploaded, and so can't stream
+func (s *SyncCmd) wholeSiteSteps(config *Config) []string {
+	var steps []string
+	if s.CheckLinks || config.Sync.CheckLinks != "" {
+		steps = append(steps, "check_links")
+	}
+	return append(steps, s.transformSteps(config)...)
+}
+
+// transformSteps lists the sync steps enabled that upload files generated
+// for the sync, which are only kept until it ends
+func (s *SyncCmd) transformSteps(config *Config) []string {
+	var steps []string
+	if s.Sitemap || config.Sync.Sitemap {
+		steps = append(steps, "sitemap")
+	}
+	if s.OptimizeImages || config.Sync.OptimizeImages || len(s.ImageVariants) > 0 || len(config.Sync.ImageVariants) > 0 {
+		steps = append(steps, "optimize_images")
+	}
+	if len(s.Minify) > 0 || len(config.Sync.Minify) > 0 {
+		steps = append(steps, "minify")
+	}
+	if s.Fingerprint || config.Sync.Fingerprint {
+		steps = append(steps, "fingerprint")
+	}
+	if s.Integrity || config.Sync.Integrity {
+		steps = append(steps, "integrity")
+	}
+	return steps
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestSyncStreaming tests a streaming sync against a fake server: changed
+// and new files are uploaded, unchanged ones skipped, and remote files not
+// found locally deleted once the scan is done
+func TestSyncStreaming(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+
+	var (
+		mu       sync.Mutex
+		uploaded []string
+		deleted  []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/quota"):
+			json.NewEncoder(w).Encode(QuotaInfo{MaxSpace: 1 << 20})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/files"):
+			json.NewEncoder(w).Encode(map[string]any{"files": []RemoteFile{
+				{Path: "/index.html", ETag: md5Hex("<h1>Hi</h1>")},
+				{Path: "/about.html", ETag: md5Hex("old")},
+				{Path: "/gone.html", ETag: md5Hex("gone")},
+			}})
+		case r.Method == http.MethodPut:
+			uploaded = append(uploaded, strings.TrimPrefix(r.URL.Path, "/admin/efmrls/abc/files"))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/admin/efmrls/abc/files"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	dir, _ := writeSite(t, map[string]string{
+		"index.html":     "<h1>Hi</h1>",
+		"about.html":     "new",
+		"css/style.css":  "body {}",
+		"img/photo.webp": "webp",
+	})
+	config := &Config{Site: SiteConfig{SiteID: "abc", BaseHost: "localhost:" + serverURL.Port()}}
+	config.Sync.ExpiryWarningDays = -1
+
+	s := &SyncCmd{Stream: true, Delete: true, Yes: true}
+	var result *SyncResult
+	var err error
+	captureStdout(t, func() { result, err = s.syncStreaming(config, dir) })
+	if err != nil {
+		t.Fatalf("syncStreaming failed: %v", err)
+	}
+
+	sort.Strings(uploaded)
+	if got, want := strings.Join(uploaded, ","), "/about.html,/css/style.css,/img/photo.webp"; got != want {
+		t.Errorf("Expected uploads %s, got %s", want, got)
+	}
+	if got := strings.Join(deleted, ","); got != "/gone.html" {
+		t.Errorf("Expected /gone.html to be deleted, got %s", got)
+	}
+	if result.Unchanged != 1 || len(result.Uploaded) != 3 || len(result.Deleted) != 1 {
+		t.Errorf("Expected 3 uploaded, 1 deleted, 1 unchanged, got %+v", result)
+	}
+}
+
+func TestSyncStreamingWholeSiteSteps(t *testing.T) {
+	config := &Config{}
+	config.Sync.Sitemap = true
+	s := &SyncCmd{Stream: true, Fingerprint: true}
+
+	_, err := s.syncStreaming(config, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "sitemap, fingerprint") {
+		t.Errorf("Expected an error naming sitemap and fingerprint, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// maxCellWidth is how wide a table cell can get before it's truncated,
+// unless --wide is given
+const maxCellWidth = 48
+
+// TableFlags are the display options shared by every list command
+type TableFlags struct {
+	Wide     bool `help:"Don't truncate long values"`
+	NoHeader bool `help:"Omit the title and column headers, for scripts"`
+}
+
+// Table is a list of rows rendered as aligned columns
+type Table struct {
+	Title   string   // e.g. "Domains"; rendered with the row count
+	Empty   string   // printed instead of the table when there are no rows
+	Columns []string // column headers
+	rows    [][]string
+}
+
+// AddRow appends a row. Empty cells are rendered as "-".
+func (t *Table) AddRow(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// Render writes the table to w. With NoHeader, only the bare rows are
+// written, without indentation, so they're easy to pipe into other tools.
+func (t *Table) Render(w io.Writer, flags TableFlags) error {
+	if len(t.rows) == 0 {
+		if !flags.NoHeader && t.Empty != "" {
+			fmt.Fprintln(w, t.Empty)
+		}
+		return nil
+	}
+
+	indent := ""
+	if !flags.NoHeader {
+		indent = "  "
+		if t.Title != "" {
+			fmt.Fprintf(w, "%s (%d):\n", t.Title, len(t.rows))
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if !flags.NoHeader && len(t.Columns) > 0 {
+		fmt.Fprintln(tw, indent+strings.Join(t.Columns, "\t"))
+	}
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if !flags.Wide {
+				cell = truncateCell(cell, maxCellWidth)
+			}
+			cells[i] = orDash(cell)
+		}
+		fmt.Fprintln(tw, indent+strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// truncateCell shortens s to at most width characters, ending in "…" if
+// anything was cut off
+func truncateCell(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
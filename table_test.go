@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTableRender tests the title, headers, and dashes for empty cells
+func TestTableRender(t *testing.T) {
+	table := &Table{Title: "Domains", Columns: []string{"DOMAIN", "ROLE"}}
+	table.AddRow("example.com", "primary")
+	table.AddRow("www.example.com", "")
+
+	var b strings.Builder
+	if err := table.Render(&b, TableFlags{}); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	expected := "Domains (2):\n" +
+		"  DOMAIN           ROLE\n" +
+		"  example.com      primary\n" +
+		"  www.example.com  -\n"
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+// TestTableRenderNoHeader tests that --no-header prints only bare rows
+func TestTableRenderNoHeader(t *testing.T) {
+	table := &Table{Title: "Rewrites", Empty: "No rewrites configured", Columns: []string{"FILENAME"}}
+
+	var b strings.Builder
+	table.Render(&b, TableFlags{NoHeader: true})
+	if b.String() != "" {
+		t.Errorf("Expected no output for an empty table, got %q", b.String())
+	}
+
+	table.AddRow("index.html")
+	b.Reset()
+	table.Render(&b, TableFlags{NoHeader: true})
+	if b.String() != "index.html\n" {
+		t.Errorf("Expected bare row, got %q", b.String())
+	}
+}
+
+// TestTruncateCell tests truncation of long cells
+func TestTruncateCell(t *testing.T) {
+	tests := []struct {
+		input    string
+		width    int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"this is too long", 10, "this is t…"},
+		{"héllo wörld", 6, "héllo…"},
+	}
+
+	for _, tt := range tests {
+		if got := truncateCell(tt.input, tt.width); got != tt.expected {
+			t.Errorf("truncateCell(%q, %d): expected %q, got %q", tt.input, tt.width, tt.expected, got)
+		}
+	}
+}
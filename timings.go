@@ -0,0 +1,63 @@
+package main
+
+import "time"
+
+// syncPhases are the phases of a sync that --timings reports, in order
+var syncPhases = []string{"scan", "hash", "remote list", "plan", "upload", "verify"}
+
+// syncTimings records how long each phase of a sync took, for --timings
+type syncTimings struct {
+	start  time.Time
+	phases map[string]time.Duration
+}
+
+func newSyncTimings() *syncTimings {
+	return &syncTimings{start: time.Now(), phases: make(map[string]time.Duration)}
+}
+
+// since adds the time since start to phase
+func (t *syncTimings) since(phase string, start time.Time) {
+	t.phases[phase] += time.Since(start)
+}
+
+// milliseconds returns the phases and the total, in milliseconds, for JSON
+func (t *syncTimings) milliseconds() map[string]int64 {
+	ms := make(map[string]int64, len(t.phases)+1)
+	for phase, d := range t.phases {
+		ms[phase] = d.Milliseconds()
+	}
+	ms["total"] = time.Since(t.start).Milliseconds()
+	return ms
+}
+
+// print prints the breakdown, leaving out the phases the sync didn't reach.
+// What's left of the total went to the quota check, confirmations, and the
+// like.
+func (t *syncTimings) print() {
+	outln("\nTimings")
+	outln("=======")
+	for _, phase := range syncPhases {
+		if d, ok := t.phases[phase]; ok {
+			outf("  %-12s %s\n", phase, formatPhase(d))
+		}
+	}
+	outf("  %-12s %s\n", "total", formatPhase(time.Since(t.start)))
+}
+
+// formatPhase rounds a phase's duration to what's worth reading
+func formatPhase(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// reportTimings prints the breakdown and adds it to result, if --timings
+// asks for it
+func (s *SyncCmd) reportTimings(t *syncTimings, result *SyncResult) {
+	if !s.Timings {
+		return
+	}
+	t.print()
+	result.TimingsMs = t.milliseconds()
+}
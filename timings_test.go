@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncTimings(t *testing.T) {
+	timings := newSyncTimings()
+	timings.start = time.Now().Add(-3 * time.Second)
+	timings.phases["scan"] = 120 * time.Millisecond
+	timings.phases["upload"] = 2345 * time.Millisecond
+	timings.phases["remote list"] = 40 * time.Millisecond
+
+	s := &SyncCmd{Timings: true}
+	var result SyncResult
+	output := captureStdout(t, func() { s.reportTimings(timings, &result) })
+
+	want := []string{"  scan         120ms", "  remote list  40ms", "  upload       2.35s", "  total        3"}
+	last := -1
+	for _, line := range want {
+		i := strings.Index(output, line)
+		if i < 0 || i < last {
+			t.Errorf("Expected %q in order in the output, got:\n%s", line, output)
+		}
+		last = i
+	}
+	if strings.Contains(output, "hash") || strings.Contains(output, "verify") {
+		t.Errorf("Expected phases that didn't run left out, got:\n%s", output)
+	}
+	if result.TimingsMs["upload"] != 2345 || result.TimingsMs["total"] < 3000 {
+		t.Errorf("Expected the timings in the result, got %v", result.TimingsMs)
+	}
+
+	s.Timings = false
+	result = SyncResult{}
+	if output := captureStdout(t, func() { s.reportTimings(timings, &result) }); output != "" || result.TimingsMs != nil {
+		t.Errorf("Expected nothing without --timings, got %q and %v", output, result.TimingsMs)
+	}
+}
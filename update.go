@@ -0,0 +1,392 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// releasesURL is the GitHub API endpoint for the latest release
+	releasesURL = "https://api.github.com/repos/efmrl/cli3/releases/latest"
+
+	// updateCheckFileName caches the result of the last release check, in
+	// the global config directory
+	updateCheckFileName = "update-check.json"
+
+	// updateCheckInterval is how often the "new version available" notice
+	// checks for a release
+	updateCheckInterval = 24 * time.Hour
+
+	// NoUpdateCheckEnvVar turns off the background release check
+	NoUpdateCheckEnvVar = "EFMRL_NO_UPDATE_CHECK"
+)
+
+// UpdateCmd replaces the running binary with the latest release
+type UpdateCmd struct {
+	Check bool `help:"Only check whether a newer release is available"`
+	Force bool `help:"Reinstall even if already on the latest release"`
+}
+
+// githubRelease is the subset of a GitHub release we use
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the named release asset
+func (r *githubRelease) assetURL(name string) (string, error) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL, nil
+		}
+	}
+	return "", fmt.Errorf("release %s has no %s", r.TagName, name)
+}
+
+func (u *UpdateCmd) Run() error {
+	release, err := fetchLatestRelease(30 * time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to check for updates: %w", err)
+	}
+	saveUpdateCheck(release.TagName)
+
+	latest := strings.TrimPrefix(release.TagName, "v")
+	newer := compareVersions(latest, version) > 0
+
+	if jsonOutput && u.Check {
+		return printJSON(map[string]any{"current": version, "latest": latest, "updateAvailable": newer})
+	}
+
+	if !newer && !u.Force {
+		outf("%s efmrl3 %s is the latest release\n", green("✓"), version)
+		return nil
+	}
+	if u.Check {
+		outf("efmrl3 %s is available (you have %s)\n", latest, version)
+		outf("  %s\n", release.HTMLURL)
+		outln("Run 'efmrl3 update' to install it")
+		return nil
+	}
+
+	if version == "dev" && !u.Force {
+		return fmt.Errorf("this is a development build; use --force to replace it with release %s", latest)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the running binary: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("failed to find the running binary: %w", err)
+	}
+	if strings.Contains(exe, "/Cellar/") {
+		return fmt.Errorf("efmrl3 was installed with Homebrew; run 'brew upgrade efmrl3' instead")
+	}
+
+	archiveName := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+	archiveURL, err := release.assetURL(archiveName)
+	if err != nil {
+		return err
+	}
+	checksumsURL, err := release.assetURL("checksums.txt")
+	if err != nil {
+		return err
+	}
+
+	outf("Downloading %s... ", archiveName)
+	binary, err := downloadRelease(archiveURL, checksumsURL, archiveName)
+	if err != nil {
+		outf("%s\n", red("FAILED"))
+		return err
+	}
+	outf("%s\n", green("OK"))
+
+	if err := replaceExecutable(exe, binary); err != nil {
+		return err
+	}
+
+	outf("%s Updated efmrl3 from %s to %s\n", green("✓"), version, latest)
+	return nil
+}
+
+// releaseArchiveName returns the goreleaser archive name for a platform,
+// matching archives.name_template in .goreleaser.yaml
+func releaseArchiveName(goos, goarch string) string {
+	arch := goarch
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+	return fmt.Sprintf("cli3_%s_%s.tar.gz", strings.ToUpper(goos[:1])+goos[1:], arch)
+}
+
+// fetchLatestRelease asks GitHub for the latest release
+func fetchLatestRelease(timeout time.Duration) (*githubRelease, error) {
+	client := &http.Client{Timeout: timeout}
+	req, err := http.NewRequest("GET", releasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GitHub returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	return &release, nil
+}
+
+// downloadRelease downloads a release archive, checks it against the
+// release's SHA-256 checksums, and returns the efmrl3 binary inside it
+func downloadRelease(archiveURL, checksumsURL, archiveName string) ([]byte, error) {
+	checksums, err := httpGetBytes(checksumsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download checksums: %w", err)
+	}
+	expected, err := findChecksum(string(checksums), archiveName)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := httpGetBytes(archiveURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", archiveName, err)
+	}
+	sum := sha256.Sum256(archive)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s (expected %s, got %s)", archiveName, expected, actual)
+	}
+
+	return extractBinary(archive, "efmrl3")
+}
+
+// httpGetBytes downloads url into memory
+func httpGetBytes(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// findChecksum finds name's SHA-256 in a checksums.txt file, which has
+// lines of the form "<hex>  <name>"
+func findChecksum(checksums, name string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in checksums.txt", name)
+}
+
+// extractBinary returns the named file from a .tar.gz archive
+func extractBinary(archive []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive does not contain %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// replaceExecutable atomically replaces the binary at exe. The new binary
+// is written next to it first so the final rename stays on one filesystem.
+func replaceExecutable(exe string, binary []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".efmrl3-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to write new binary (do you have permission to modify %s?): %w", exe, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("failed to make new binary executable: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+	return nil
+}
+
+// compareVersions compares two versions like "1.4.2" (a leading "v" is
+// ignored), returning -1, 0, or 1. A pre-release ("1.5.0-rc1") sorts before
+// its release. Unparseable parts compare as zero.
+func compareVersions(a, b string) int {
+	a, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	b, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	default:
+		return strings.Compare(aPre, bPre)
+	}
+}
+
+// updateCheck is the cached result of the last release check
+type updateCheck struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	Latest    string    `json:"latest"`
+}
+
+// updateCheckPath returns the path of the release check cache
+func updateCheckPath() (string, error) {
+	configPath, err := GetGlobalConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), updateCheckFileName), nil
+}
+
+// loadUpdateCheck reads the cached release check, if any
+func loadUpdateCheck() (updateCheck, error) {
+	var check updateCheck
+	path, err := updateCheckPath()
+	if err != nil {
+		return check, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return check, err
+	}
+	err = json.Unmarshal(data, &check)
+	return check, err
+}
+
+// saveUpdateCheck caches the latest release tag. Failures are ignored; the
+// cache only exists to rate-limit checks.
+func saveUpdateCheck(tag string) {
+	path, err := updateCheckPath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(updateCheck{CheckedAt: time.Now(), Latest: strings.TrimPrefix(tag, "v")})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	os.WriteFile(path, data, 0600)
+}
+
+// updateNoticeEnabled reports whether to check for and announce new
+// releases. Development builds, scripts, and CI are left alone.
+func updateNoticeEnabled() bool {
+	if version == "dev" || os.Getenv(NoUpdateCheckEnvVar) != "" {
+		return false
+	}
+	return !jsonOutput && !quietOutput && !nonInteractive
+}
+
+// startUpdateCheck refreshes the release check cache in the background if
+// it's stale. It never delays the command: if the check hasn't finished by
+// the time the command exits, it's simply abandoned.
+func startUpdateCheck() {
+	if !updateNoticeEnabled() {
+		return
+	}
+	if check, err := loadUpdateCheck(); err == nil && time.Since(check.CheckedAt) < updateCheckInterval {
+		return
+	}
+	go func() {
+		if release, err := fetchLatestRelease(5 * time.Second); err == nil {
+			saveUpdateCheck(release.TagName)
+		}
+	}()
+}
+
+// printUpdateNotice tells the user about a newer release found by an
+// earlier check
+func printUpdateNotice() {
+	if !updateNoticeEnabled() {
+		return
+	}
+	check, err := loadUpdateCheck()
+	if err != nil || compareVersions(check.Latest, version) <= 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%s efmrl3 %s is available (you have %s); run 'efmrl3 update' to install it\n",
+		yellow("Notice:"), check.Latest, version)
+}
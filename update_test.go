@@ -0,0 +1,73 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+// TestCompareVersions tests release version ordering
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"2.0.0-rc1", "2.0.0", -1},
+		{"2.0.0", "1.9.0-rc1", 1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.expected {
+			t.Errorf("compareVersions(%q, %q): expected %d, got %d", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+// TestReleaseArchiveName tests that archive names match .goreleaser.yaml
+func TestReleaseArchiveName(t *testing.T) {
+	if got := releaseArchiveName("darwin", "arm64"); got != "cli3_Darwin_arm64.tar.gz" {
+		t.Errorf("Expected cli3_Darwin_arm64.tar.gz, got %s", got)
+	}
+	if got := releaseArchiveName("linux", "amd64"); got != "cli3_Linux_x86_64.tar.gz" {
+		t.Errorf("Expected cli3_Linux_x86_64.tar.gz, got %s", got)
+	}
+}
+
+// TestFindChecksum tests parsing goreleaser's checksums.txt
+func TestFindChecksum(t *testing.T) {
+	checksums := "aaa111  cli3_Darwin_arm64.tar.gz\nBBB222  cli3_Linux_x86_64.tar.gz\n"
+
+	sum, err := findChecksum(checksums, "cli3_Linux_x86_64.tar.gz")
+	if err != nil || sum != "bbb222" {
+		t.Errorf("Expected bbb222, got %q (%v)", sum, err)
+	}
+	if _, err := findChecksum(checksums, "cli3_Linux_arm64.tar.gz"); err == nil {
+		t.Errorf("Expected an error for a missing archive")
+	}
+}
+
+// TestExtractBinary tests pulling the binary out of a release archive
+func TestExtractBinary(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"README.md": "readme", "efmrl3": "binary"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+
+	binary, err := extractBinary(buf.Bytes(), "efmrl3")
+	if err != nil {
+		t.Fatalf("extractBinary failed: %v", err)
+	}
+	if string(binary) != "binary" {
+		t.Errorf("Expected binary content, got %q", binary)
+	}
+}
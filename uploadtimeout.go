@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const (
+	// Defaults for [sync] min_upload_speed and upload_grace_seconds: an
+	// upload slower than 50 KB/s, after a minute's grace, is given up on
+	defaultMinUploadSpeed     = 50 // KB/s
+	defaultUploadGraceSeconds = 60
+)
+
+// uploadTimeouts are the limits on how long uploads may take, as set by
+// setUploadTimeouts. A zero minSpeed means no limit.
+var uploadTimeouts = struct {
+	minSpeed int64 // bytes per second
+	grace    time.Duration
+}{defaultMinUploadSpeed * 1024, defaultUploadGraceSeconds * time.Second}
+
+// interruptContext is what uploads are canceled through when they're
+// interrupted
+var interruptContext = context.Background()
+
+// errInterrupted is returned by an upload stopped by Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// watchInterrupts makes the first Ctrl-C, until the returned function is
+// called, stop the uploads under way rather than exit, so a sync can
+// release its locks and keep its saved plan. A second Ctrl-C exits at once.
+func watchInterrupts() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			fmt.Fprintln(os.Stderr, "\nInterrupted; stopping (press Ctrl-C again to quit at once)")
+			cancel()
+		case <-done:
+		}
+	}()
+
+	interruptContext = ctx
+	return func() {
+		close(done)
+		signal.Stop(signals)
+		cancel()
+		interruptContext = context.Background()
+	}
+}
+
+// MinUploadSpeed returns the slowest an upload may go, in KB/s, or -1 if
+// uploads never time out
+func (c *Config) MinUploadSpeed() int {
+	if c.Sync.MinUploadSpeed == 0 {
+		return defaultMinUploadSpeed
+	}
+	return c.Sync.MinUploadSpeed
+}
+
+// UploadGrace returns how long any upload gets on top of what its size
+// needs at the minimum speed
+func (c *Config) UploadGrace() time.Duration {
+	if c.Sync.UploadGraceSeconds <= 0 {
+		return defaultUploadGraceSeconds * time.Second
+	}
+	return time.Duration(c.Sync.UploadGraceSeconds) * time.Second
+}
+
+// setUploadTimeouts applies a project's upload speed settings to the
+// uploads that follow
+func setUploadTimeouts(config *Config) {
+	uploadTimeouts.minSpeed = max(int64(config.MinUploadSpeed())*1024, 0)
+	uploadTimeouts.grace = config.UploadGrace()
+}
+
+// uploadDeadline is how long an upload of size bytes may take
+func uploadDeadline(size int64) time.Duration {
+	if uploadTimeouts.minSpeed <= 0 {
+		return 0
+	}
+	return uploadTimeouts.grace + time.Duration(size)*time.Second/time.Duration(uploadTimeouts.minSpeed)
+}
+
+// uploadContext is the context for uploading size bytes: it's canceled by
+// an interrupt, or once the upload has taken longer than its size allows
+func uploadContext(size int64) (context.Context, context.CancelFunc) {
+	if d := uploadDeadline(size); d > 0 {
+		return context.WithTimeout(interruptContext, d)
+	}
+	return context.WithCancel(interruptContext)
+}
+
+// uploadTimeoutError explains an upload of size bytes that failed with err
+// because it ran out of time or was interrupted
+func uploadTimeoutError(ctx context.Context, size int64, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return errInterrupted
+	case !errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return err
+	}
+	return fmt.Errorf("upload of %s didn't finish in %s, which is slower than %s/s (raise min_upload_speed or upload_grace_seconds in [sync] for a slow connection)",
+		formatBytes(size), uploadDeadline(size).Round(time.Second), formatBytes(uploadTimeouts.minSpeed))
+}
+
+// cancelOnClose cancels an upload's context once its response is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
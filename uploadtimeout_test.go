@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setTestUploadTimeouts sets the upload limits for a test, restoring them
+// once it's done
+func setTestUploadTimeouts(t *testing.T, minSpeed int64, grace time.Duration) {
+	saved := uploadTimeouts
+	uploadTimeouts.minSpeed, uploadTimeouts.grace = minSpeed, grace
+	t.Cleanup(func() { uploadTimeouts = saved })
+}
+
+func TestUploadDeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   SyncConfig
+		size     int64
+		expected time.Duration
+	}{
+		{"defaults, small file", SyncConfig{}, 1024, 60*time.Second + 20*time.Millisecond},
+		{"defaults, 50 MB", SyncConfig{}, 50 * 1024 * 1024, 60*time.Second + 1024*time.Second},
+		{"faster minimum", SyncConfig{MinUploadSpeed: 1024, UploadGraceSeconds: 10}, 10 * 1024 * 1024, 20 * time.Second},
+		{"no limit", SyncConfig{MinUploadSpeed: -1}, 50 * 1024 * 1024, 0},
+	}
+
+	for _, tt := range tests {
+		setTestUploadTimeouts(t, 0, 0)
+		setUploadTimeouts(&Config{Sync: tt.config})
+		if got := uploadDeadline(tt.size); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+// TestUploadTimesOut tests that an upload slower than its deadline allows
+// is given up on, with an error saying why
+func TestUploadTimesOut(t *testing.T) {
+	t.Setenv(TokenEnvVar, "test-token")
+	setTestUploadTimeouts(t, 1024*1024, 100*time.Millisecond)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	client, _ := NewAPIClient(server.URL)
+
+	_, files := writeSite(t, map[string]string{"index.html": "hi"})
+	err := uploadFile(client, "abc", files[0])
+	if err == nil || !strings.Contains(err.Error(), "didn't finish in") {
+		t.Errorf("Expected the upload to time out, got %v", err)
+	}
+
+	_, err = client.doBinaryRequest("PUT", "/part", nil, []byte("hi"))
+	if err == nil || !strings.Contains(err.Error(), "didn't finish in") {
+		t.Errorf("Expected the binary upload to time out, got %v", err)
+	}
+}
+
+func TestUploadInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := uploadTimeoutError(ctx, 10, context.Canceled); !errors.Is(err, errInterrupted) {
+		t.Errorf("Expected errInterrupted, got %v", err)
+	}
+	if err := uploadTimeoutError(context.Background(), 10, errSessionExpired); err != errSessionExpired {
+		t.Errorf("Expected other errors to pass through, got %v", err)
+	}
+}
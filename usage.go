@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"text/tabwriter"
+	"time"
+)
+
+// UsageCmd reports storage, bandwidth, and request usage for the site
+type UsageCmd struct {
+	Since string `help:"How far back to look, e.g. 7d, 30d, 12w" default:"30d"`
+}
+
+// UsageReport is the server's usage totals for a period, with a daily breakdown
+type UsageReport struct {
+	Since          string     `json:"since"`
+	StorageBytes   int64      `json:"storageBytes"`
+	BandwidthBytes int64      `json:"bandwidthBytes"`
+	Requests       int64      `json:"requests"`
+	CacheHits      int64      `json:"cacheHits"`
+	Days           []UsageDay `json:"days"`
+}
+
+// UsageDay is one day of usage
+type UsageDay struct {
+	Date           string `json:"date"`
+	StorageBytes   int64  `json:"storageBytes"`
+	BandwidthBytes int64  `json:"bandwidthBytes"`
+	Requests       int64  `json:"requests"`
+	CacheHits      int64  `json:"cacheHits"`
+}
+
+func (u *UsageCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	since, err := parseSince(u.Since)
+	if err != nil {
+		return err
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("since", serverNow().Add(-since).UTC().Format(time.RFC3339))
+
+	var report UsageReport
+	if err := getJSON(apiClient, fmt.Sprintf("/admin/efmrls/%s/usage?%s", config.Site.SiteID, query.Encode()), &report); err != nil {
+		return fmt.Errorf("failed to fetch usage: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(report)
+	}
+
+	outf("Usage (last %s)\n", u.Since)
+	outln("===============")
+	outf("Storage:        %s\n", formatBytes(report.StorageBytes))
+	outf("Bandwidth:      %s\n", formatBytes(report.BandwidthBytes))
+	outf("Requests:       %d\n", report.Requests)
+	outf("Cache hit rate: %s\n", formatRatio(report.CacheHits, report.Requests))
+
+	if len(report.Days) == 0 {
+		return nil
+	}
+
+	outln("\nDaily:")
+	w := tabwriter.NewWriter(humanOutput(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  DATE\tSTORAGE\tBANDWIDTH\tREQUESTS\tCACHE HITS")
+	for _, day := range report.Days {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\n", day.Date, formatBytes(day.StorageBytes),
+			formatBytes(day.BandwidthBytes), day.Requests, formatRatio(day.CacheHits, day.Requests))
+	}
+	return w.Flush()
+}
+
+// formatRatio formats part/total as a percentage, or "-" when total is zero
+func formatRatio(part, total int64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(part)/float64(total))
+}
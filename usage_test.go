@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+// TestFormatRatio tests percentage formatting of cache hit rates
+func TestFormatRatio(t *testing.T) {
+	tests := []struct {
+		part, total int64
+		expected    string
+	}{
+		{0, 0, "-"},
+		{0, 10, "0.0%"},
+		{873, 1000, "87.3%"},
+		{1, 3, "33.3%"},
+		{5, 5, "100.0%"},
+	}
+
+	for _, tt := range tests {
+		if got := formatRatio(tt.part, tt.total); got != tt.expected {
+			t.Errorf("formatRatio(%d, %d) = %q, expected %q", tt.part, tt.total, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// maxVerifyUploads is how many of a sync's uploads are fetched when
+	// verifying it, on top of the configured paths
+	maxVerifyUploads = 20
+
+	// defaultVerifyTimeout is how long a sync keeps retrying paths that
+	// don't verify
+	defaultVerifyTimeout = 30 * time.Second
+
+	// verifyRetryInterval is how long to wait before fetching paths that
+	// didn't match again, while caches catch up
+	verifyRetryInterval = 2 * time.Second
+)
+
+// VerifyDeployCmd fetches pages from the live site and checks they're the
+// files that were synced
+type VerifyDeployCmd struct {
+	Paths   []string      `arg:"" optional:"" help:"Site paths to fetch (defaults to paths under [verify], and /)"`
+	Timeout time.Duration `help:"How long to keep retrying paths the live site doesn't serve correctly yet" default:"30s"`
+}
+
+// Examples are shown in 'efmrl3 verify-deploy --help'
+func (v *VerifyDeployCmd) Examples() []Example {
+	return []Example{
+		{"Check the home page and the paths under [verify]", "efmrl3 verify-deploy"},
+		{"Check particular pages", "efmrl3 verify-deploy / /about/ /app.js"},
+	}
+}
+
+// VerifyResult is the outcome of checking the live site
+type VerifyResult struct {
+	URL    string        `json:"url"`
+	Checks []VerifyCheck `json:"checks"`
+	Failed int           `json:"failed"`
+}
+
+// VerifyCheck is the outcome of fetching one path from the live site
+type VerifyCheck struct {
+	Path         string `json:"path"`
+	Status       int    `json:"status"`
+	ExpectedETag string `json:"expectedEtag,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+func (v *VerifyDeployCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	paths := verifyPaths(config, nil)
+	if len(v.Paths) > 0 {
+		paths = nil
+		for _, p := range v.Paths {
+			paths = append(paths, "/"+strings.TrimPrefix(p, "/"))
+		}
+	}
+	result, err := verifyDeploy(apiClient, config.Site.SiteID, paths, v.Timeout)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+	}
+	if result.Failed > 0 {
+		return fmt.Errorf("the live site doesn't serve %d of %d path(s) as synced", result.Failed, len(result.Checks))
+	}
+	outf("\n%s %s serves all %d path(s) as synced\n", green("✓"), result.URL, len(result.Checks))
+	return nil
+}
+
+// verifyPaths returns the paths to verify: /, those under [verify], and up
+// to maxVerifyUploads of the given uploads
+func verifyPaths(config *Config, uploaded []string) []string {
+	paths := []string{"/"}
+	for _, p := range config.Verify.Paths {
+		if !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+	for i, p := range uploaded {
+		if i == maxVerifyUploads {
+			break
+		}
+		if !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// verifyDeploy fetches each path from the live site, checking that it's
+// served successfully and, for paths that are files on the site, that the
+// content matches their ETag. Paths that fail are fetched again until
+// timeout passes, since caches in front of the site may lag behind a sync.
+func verifyDeploy(client *APIClient, siteID string, paths []string, timeout time.Duration) (*VerifyResult, error) {
+	baseURL, err := primarySiteURL(client, siteID)
+	if err != nil {
+		return nil, err
+	}
+	remoteFiles, err := fetchRemoteFiles(client, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote files: %w", err)
+	}
+	etags := make(map[string]string, len(remoteFiles))
+	for _, rf := range remoteFiles {
+		etags[rf.Path] = rf.ETag
+	}
+
+	result := checkLiveSite(baseURL, etags, paths, timeout)
+	for i, check := range result.Checks {
+		outf("[%d/%d] Verifying %s... ", i+1, len(result.Checks), check.Path)
+		if check.Error != "" {
+			outln(red("FAILED") + " " + dim("("+check.Error+")"))
+			annotateError("Failed to verify "+check.Path, "", errors.New(check.Error))
+		} else {
+			outln(green("OK"))
+		}
+	}
+	return result, nil
+}
+
+// checkLiveSite fetches paths from the site at baseURL, comparing them
+// with the ETags of the site's files, until they all match or timeout
+// passes
+func checkLiveSite(baseURL string, etags map[string]string, paths []string, timeout time.Duration) *VerifyResult {
+	httpClient := newHTTPClient()
+	httpClient.Timeout = 30 * time.Second
+
+	result := &VerifyResult{URL: baseURL, Checks: make([]VerifyCheck, len(paths))}
+	pending := make([]int, len(paths))
+	for i := range paths {
+		pending[i] = i
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		var failed []int
+		for _, i := range pending {
+			check := fetchAndVerify(httpClient, baseURL, paths[i], verifyExpectedETag(etags, paths[i]))
+			result.Checks[i] = check
+			if check.Error != "" {
+				failed = append(failed, i)
+			}
+		}
+		pending = failed
+		if len(pending) == 0 || time.Now().Add(verifyRetryInterval).After(deadline) {
+			break
+		}
+		time.Sleep(verifyRetryInterval)
+	}
+	result.Failed = len(pending)
+	return result
+}
+
+// verifyExpectedETag returns the ETag of the file a path is served from,
+// or "" if it isn't a file on the site (a rewrite, say)
+func verifyExpectedETag(etags map[string]string, p string) string {
+	if strings.HasSuffix(p, "/") {
+		return etags[p+"index.html"]
+	}
+	if etag, ok := etags[p]; ok {
+		return etag
+	}
+	return etags[p+"/index.html"]
+}
+
+// fetchAndVerify fetches one path from the live site, bypassing caches,
+// and compares the body with expectedETag if there is one
+func fetchAndVerify(client *http.Client, baseURL, p, expectedETag string) VerifyCheck {
+	check := VerifyCheck{Path: p, ExpectedETag: expectedETag}
+
+	u := (&url.URL{Path: p}).EscapedPath()
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(baseURL, "/")+u, nil)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	defer resp.Body.Close()
+	check.Status = resp.StatusCode
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		check.Error = fmt.Sprintf("failed to read response: %v", err)
+		return check
+	}
+	check.ETag = hex.EncodeToString(hash.Sum(nil))
+
+	switch {
+	case resp.StatusCode >= 400:
+		check.Error = fmt.Sprintf("status %d", resp.StatusCode)
+	case expectedETag != "" && check.ETag != expectedETag:
+		check.Error = "content doesn't match the synced file"
+	}
+	return check
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCheckLiveSite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte("<h1>home</h1>"))
+		case "/app.js":
+			w.Write([]byte("old script"))
+		case "/my page.html":
+			w.Write([]byte("spaced"))
+		case "/app/anything":
+			w.Write([]byte("rewritten"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	etags := map[string]string{
+		"/index.html":   md5Hex("<h1>home</h1>"),
+		"/app.js":       md5Hex("new script"),
+		"/my page.html": md5Hex("spaced"),
+		"/gone.css":     md5Hex("css"),
+	}
+	paths := []string{"/", "/app.js", "/my page.html", "/gone.css", "/app/anything"}
+
+	result := checkLiveSite(server.URL, etags, paths, 0)
+
+	want := map[string]string{
+		"/":             "",
+		"/app.js":       "content doesn't match the synced file",
+		"/my page.html": "",
+		"/gone.css":     "status 404",
+		"/app/anything": "",
+	}
+	for _, check := range result.Checks {
+		if check.Error != want[check.Path] {
+			t.Errorf("Expected %s to give %q, got %q", check.Path, want[check.Path], check.Error)
+		}
+	}
+	if result.Failed != 2 {
+		t.Errorf("Expected 2 failed paths, got %d", result.Failed)
+	}
+}
+
+func TestVerifyPaths(t *testing.T) {
+	config := &Config{Verify: VerifyConfig{Paths: []string{"/about/", "/"}}}
+	uploaded := make([]string, 0, maxVerifyUploads+5)
+	uploaded = append(uploaded, "/about/")
+	for range maxVerifyUploads + 4 {
+		uploaded = append(uploaded, "/f")
+	}
+
+	got := verifyPaths(config, uploaded)
+	if len(got) != 3 || got[0] != "/" || got[1] != "/about/" || got[2] != "/f" {
+		t.Errorf("Expected [/ /about/ /f], got %v", got)
+	}
+}
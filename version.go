@@ -1,38 +1,47 @@
 package main
 
 import (
-	"fmt"
 	"runtime/debug"
 )
 
 type VersionCmd struct{}
 
 func (v *VersionCmd) Run() error {
-	fmt.Printf("efmrl3 version %s", version)
+	var revision string
+	var modified bool
 
 	info, ok := debug.ReadBuildInfo()
 	if ok {
-		var revision, modified string
 		for _, s := range info.Settings {
 			switch s.Key {
 			case "vcs.revision":
 				revision = s.Value
 			case "vcs.modified":
-				modified = s.Value
+				modified = s.Value == "true"
 			}
 		}
-		if revision != "" {
-			if len(revision) > 12 {
-				revision = revision[:12]
-			}
-			fmt.Printf(" (%s", revision)
-			if modified == "true" {
-				fmt.Print(", modified")
-			}
-			fmt.Print(")")
+		if len(revision) > 12 {
+			revision = revision[:12]
+		}
+	}
+
+	if jsonOutput {
+		return printJSON(struct {
+			Version  string `json:"version"`
+			Revision string `json:"revision,omitempty"`
+			Modified bool   `json:"modified,omitempty"`
+		}{version, revision, modified})
+	}
+
+	outf("efmrl3 version %s", version)
+	if revision != "" {
+		outf(" (%s", revision)
+		if modified {
+			outf(", modified")
 		}
+		outf(")")
 	}
 
-	fmt.Println()
+	outln()
 	return nil
 }
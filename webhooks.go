@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WebhooksCmd manages webhooks notified when an efmrl changes
+type WebhooksCmd struct {
+	List   WebhooksListCmd   `cmd:"" help:"List all webhooks"`
+	Add    WebhooksAddCmd    `cmd:"" help:"Add a webhook"`
+	Remove WebhooksRemoveCmd `cmd:"" help:"Remove one or more webhooks"`
+	Test   WebhooksTestCmd   `cmd:"" help:"Send a test event to a webhook"`
+}
+
+// Webhook is a URL the server POSTs to when matching events happen
+type Webhook struct {
+	ID     int      `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"` // only returned when the webhook is created
+}
+
+// WebhooksListCmd lists all webhooks for the configured efmrl
+type WebhooksListCmd struct {
+	TableFlags `embed:""`
+}
+
+func (w *WebhooksListCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	webhooks, err := fetchWebhooks(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(webhooks)
+	}
+
+	table := &Table{
+		Title:   "Webhooks",
+		Empty:   "No webhooks configured",
+		Columns: []string{"ID", "URL", "EVENTS"},
+	}
+	for _, webhook := range webhooks {
+		table.AddRow(strconv.Itoa(webhook.ID), webhook.URL, strings.Join(webhook.Events, ","))
+	}
+	return table.Render(humanOutput(), w.TableFlags)
+}
+
+// fetchWebhooks retrieves the webhooks configured for an efmrl
+func fetchWebhooks(client *APIClient, siteID string) ([]Webhook, error) {
+	var result struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	if err := getJSON(client, fmt.Sprintf("/admin/efmrls/%s/webhooks", siteID), &result); err != nil {
+		return nil, err
+	}
+	return result.Webhooks, nil
+}
+
+// WebhooksAddCmd registers a new webhook
+type WebhooksAddCmd struct {
+	URL    string   `arg:"" name:"url" help:"URL to notify"`
+	Events []string `help:"Event(s) to send" name:"event" default:"deploy,delete" enum:"deploy,delete"`
+}
+
+func (w *WebhooksAddCmd) Run() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q (expected an http or https URL)", w.URL)
+	}
+	if u.Scheme == "http" {
+		warnf("%s is not HTTPS; event payloads will be sent unencrypted\n", w.URL)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	body := map[string]any{"url": w.URL, "events": w.Events}
+	resp, err := apiClient.Post(fmt.Sprintf("/admin/efmrls/%s/webhooks", config.Site.SiteID), body)
+	if err != nil {
+		return fmt.Errorf("failed to add webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Webhook Webhook `json:"webhook"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if jsonOutput {
+		return printJSON(result.Webhook)
+	}
+
+	outf("%s Added webhook %d for %s\n", green("✓"), result.Webhook.ID, strings.Join(w.Events, ", "))
+	if result.Webhook.Secret != "" {
+		outf("  Signing secret: %s\n", result.Webhook.Secret)
+		outln("  (shown only once; use it to verify the X-Efmrl-Signature header)")
+	}
+
+	return nil
+}
+
+// WebhooksRemoveCmd removes webhooks by ID or URL
+type WebhooksRemoveCmd struct {
+	Webhooks []string `arg:"" name:"webhook" help:"ID or URL of the webhook(s) to remove" required:""`
+}
+
+func (w *WebhooksRemoveCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	webhooks, err := fetchWebhooks(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+
+	removed := []Webhook{}
+	for _, ref := range w.Webhooks {
+		outf("Removing %s... ", ref)
+
+		webhook := findWebhook(webhooks, ref)
+		if webhook == nil {
+			outf("%s\n", yellow("NOT FOUND"))
+			continue
+		}
+
+		resp, err := apiClient.Delete(fmt.Sprintf("/admin/efmrls/%s/webhooks/%d", config.Site.SiteID, webhook.ID))
+		if err != nil {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("failed to remove webhook %s: %w", ref, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			outf("%s\n", red("FAILED"))
+			return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		outf("%s\n", green("OK"))
+		removed = append(removed, *webhook)
+	}
+
+	if jsonOutput {
+		return printJSON(map[string][]Webhook{"removed": removed})
+	}
+
+	return nil
+}
+
+// findWebhook finds a webhook by its numeric ID or its URL
+func findWebhook(webhooks []Webhook, ref string) *Webhook {
+	for i := range webhooks {
+		if fmt.Sprint(webhooks[i].ID) == ref || webhooks[i].URL == ref {
+			return &webhooks[i]
+		}
+	}
+	return nil
+}
+
+// WebhooksTestCmd asks the server to deliver a test event
+type WebhooksTestCmd struct {
+	Webhook string `arg:"" help:"ID or URL of the webhook to test"`
+	Event   string `help:"Event to simulate" default:"deploy" enum:"deploy,delete"`
+}
+
+func (w *WebhooksTestCmd) Run() error {
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if config.Site.SiteID == "" {
+		return errNoSiteID
+	}
+
+	// Create API client
+	apiClient, err := NewAPIClient(config.BaseURL())
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	webhooks, err := fetchWebhooks(apiClient, config.Site.SiteID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch webhooks: %w", err)
+	}
+	webhook := findWebhook(webhooks, w.Webhook)
+	if webhook == nil {
+		return fmt.Errorf("no webhook with ID or URL %q", w.Webhook)
+	}
+
+	outf("Sending test %s event to %s... ", w.Event, webhook.URL)
+	resp, err := apiClient.Post(fmt.Sprintf("/admin/efmrls/%s/webhooks/%d/test", config.Site.SiteID, webhook.ID),
+		map[string]string{"event": w.Event})
+	if err != nil {
+		outf("%s\n", red("FAILED"))
+		return fmt.Errorf("failed to test webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		outf("%s\n", red("FAILED"))
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	// The server reports how the receiving endpoint responded
+	var result struct {
+		Status     int    `json:"status"`
+		DurationMs int    `json:"durationMs"`
+		Error      string `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		outf("%s\n", red("FAILED"))
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if result.Error != "" || result.Status < 200 || result.Status >= 300 {
+		outf("%s\n", red("FAILED"))
+		if result.Error != "" {
+			return fmt.Errorf("delivery failed: %s", result.Error)
+		}
+		return fmt.Errorf("endpoint responded with status %d", result.Status)
+	}
+
+	outf("%s (%d in %dms)\n", green("OK"), result.Status, result.DurationMs)
+	if jsonOutput {
+		return printJSON(result)
+	}
+	return nil
+}
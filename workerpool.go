@@ -0,0 +1,61 @@
+package main
+
+import "sync"
+
+// runConcurrently calls do with each item, workers at a time, and report
+// with each item and how it went, as each finishes. report is only ever
+// called from the calling goroutine. After a failure no more items are
+// started, unless keepGoing is set, but those already under way finish and
+// are reported. It returns how many items succeeded.
+func runConcurrently[T any](items []T, workers int, keepGoing bool, do func(T) error, report func(T, error)) int {
+	if len(items) == 0 {
+		return 0
+	}
+
+	type outcome struct {
+		item T
+		err  error
+	}
+	jobs := make(chan T)
+	outcomes := make(chan outcome)
+	stop := make(chan struct{})
+
+	go func() {
+		defer close(jobs)
+		for _, item := range items {
+			select {
+			case jobs <- item:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range min(max(workers, 1), len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				outcomes <- outcome{item, do(item)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	succeeded := 0
+	stopped := false
+	for o := range outcomes {
+		report(o.item, o.err)
+		if o.err == nil {
+			succeeded++
+		} else if !keepGoing && !stopped {
+			stopped = true
+			close(stop)
+		}
+	}
+	return succeeded
+}
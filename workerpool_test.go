@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunConcurrently(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	fail := errors.New("odd")
+	do := func(n int) error {
+		if n == 3 {
+			return fail
+		}
+		return nil
+	}
+
+	var reported []int
+	succeeded := runConcurrently(items, 3, true, do, func(n int, err error) { reported = append(reported, n) })
+	if succeeded != 7 || len(reported) != 8 {
+		t.Errorf("Expected 7 of 8 to succeed with keepGoing, got %d of %d", succeeded, len(reported))
+	}
+
+	// Without keepGoing, nothing starts after the failure
+	var started atomic.Int32
+	runConcurrently(items, 1, false, func(n int) error {
+		started.Add(1)
+		return do(n)
+	}, func(int, error) {})
+	if got := started.Load(); got > 4 {
+		t.Errorf("Expected at most 4 items started after a failure at the 3rd, got %d", got)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WorkspaceCmd works with the sites in a monorepo
+type WorkspaceCmd struct {
+	Discover WorkspaceDiscoverCmd `cmd:"" help:"List the directories under the root that have their own efmrl config, and their sites"`
+}
+
+// WorkspaceDiscoverCmd finds the packages of a workspace: the directories
+// listed under [workspace] packages in the root config, or else every
+// directory below the root with a config file of its own
+type WorkspaceDiscoverCmd struct {
+	Root       string `arg:"" optional:"" help:"Root of the monorepo (defaults to the current directory)" type:"path"`
+	TableFlags `embed:""`
+}
+
+// Examples are shown in 'efmrl3 workspace discover --help'
+func (w *WorkspaceDiscoverCmd) Examples() []Example {
+	return []Example{
+		{"List the sites in this monorepo", "efmrl3 workspace discover"},
+		{"List their package paths, for scripting", "efmrl3 --format '{{.Path}}' workspace discover"},
+	}
+}
+
+// WorkspacePackage is a directory of a workspace with its own config file
+type WorkspacePackage struct {
+	Path   string          `json:"path"` // relative to the root, with forward slashes
+	Config string          `json:"config"`
+	Dir    string          `json:"dir"` // what sync publishes, relative to the package
+	Sites  []WorkspaceSite `json:"sites"`
+}
+
+// WorkspaceSite is one of the sites a package's config file sets up
+type WorkspaceSite struct {
+	Name   string `json:"name,omitempty"` // the [sites.<name>] profile; empty for [site]
+	SiteID string `json:"siteId"`
+}
+
+// skippedWorkspaceDirs are never searched for packages: they hold
+// dependencies, not sites of the workspace
+var skippedWorkspaceDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+}
+
+func (w *WorkspaceDiscoverCmd) Run() error {
+	root := w.Root
+	if root == "" {
+		root = "."
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("directory does not exist: %s", root)
+	}
+
+	packages, err := discoverPackages(root)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		return printJSON(packages)
+	}
+
+	table := &Table{
+		Title:   "Packages",
+		Empty:   "No efmrl packages found (add an efmrl.toml to each site's directory, or list them under [workspace] packages)",
+		Columns: []string{"PACKAGE", "CONFIG", "DIR", "SITES"},
+	}
+	for _, pkg := range packages {
+		var sites []string
+		for _, site := range pkg.Sites {
+			if site.Name == "" {
+				sites = append(sites, site.SiteID)
+			} else {
+				sites = append(sites, site.Name+"="+site.SiteID)
+			}
+		}
+		table.AddRow(pkg.Path, pkg.Config, pkg.Dir, strings.Join(sites, ", "))
+	}
+	if err := table.Render(humanOutput(), w.TableFlags); err != nil {
+		return err
+	}
+	if len(packages) > 0 && !w.NoHeader {
+		outln()
+		outf("Run a command in a package with 'efmrl3 --package <package> <command>', e.g. 'efmrl3 --package %s sync'\n", packages[0].Path)
+	}
+	return nil
+}
+
+// discoverPackages returns the packages of the workspace at root, sorted by
+// path. If the root config lists packages under [workspace], those are the
+// packages; otherwise every directory with a config file is one.
+func discoverPackages(root string) ([]WorkspacePackage, error) {
+	dirs, err := declaredPackages(root)
+	if err != nil {
+		return nil, err
+	}
+	if dirs == nil {
+		if dirs, err = findPackageDirs(root); err != nil {
+			return nil, err
+		}
+	}
+
+	packages := []WorkspacePackage{}
+	for _, dir := range dirs {
+		pkg, err := loadPackage(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		if pkg != nil {
+			packages = append(packages, *pkg)
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Path < packages[j].Path })
+	return packages, nil
+}
+
+// declaredPackages expands the [workspace] packages of the root config into
+// the directories they match, relative to root. It returns nil if the root
+// has no config, or its config doesn't declare any.
+func declaredPackages(root string) ([]string, error) {
+	fileName, err := findConfigFileIn(root)
+	if err != nil || fileName == "" {
+		return nil, err
+	}
+	var config Config
+	if err := decodeConfigFile(filepath.Join(root, fileName), &config); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", fileName, err)
+	}
+	if len(config.Workspace.Packages) == 0 {
+		return nil, nil
+	}
+
+	dirs := []string{}
+	seen := map[string]bool{}
+	for _, pattern := range config.Workspace.Packages {
+		matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid [workspace] package pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			warnf("[workspace] package %q matches no directory\n", pattern)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || !info.IsDir() {
+				continue
+			}
+			rel, err := filepath.Rel(root, match)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[pathKey(rel)] {
+				seen[pathKey(rel)] = true
+				dirs = append(dirs, rel)
+			}
+		}
+	}
+	return dirs, nil
+}
+
+// findPackageDirs returns every directory under root, root included, that
+// has a config file, relative to root. Hidden directories and dependency
+// directories aren't searched.
+func findPackageDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if isHidden(info) || skippedWorkspaceDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+		}
+		fileName, err := findConfigFileIn(path)
+		if err != nil {
+			return err
+		}
+		if fileName != "" {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", root, err)
+	}
+	return dirs, nil
+}
+
+// loadPackage reads the config file of the package in dir (relative to
+// root). A declared package without a config file is skipped with a
+// warning, and so is a root config that only declares the workspace.
+func loadPackage(root, dir string) (*WorkspacePackage, error) {
+	fileName, err := findConfigFileIn(filepath.Join(root, dir))
+	if err != nil {
+		return nil, err
+	}
+	if fileName == "" {
+		warnf("[workspace] package %s has no %s\n", filepath.ToSlash(dir), ConfigFileName)
+		return nil, nil
+	}
+
+	var config Config
+	if err := decodeConfigFile(filepath.Join(root, dir, fileName), &config); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filepath.ToSlash(filepath.Join(dir, fileName)), err)
+	}
+
+	pkg := &WorkspacePackage{
+		Path:   filepath.ToSlash(dir),
+		Config: fileName,
+		Dir:    config.SyncDir(),
+		Sites:  []WorkspaceSite{},
+	}
+	if config.Site.SiteID != "" {
+		pkg.Sites = append(pkg.Sites, WorkspaceSite{SiteID: config.Site.SiteID})
+	}
+	for _, name := range config.SiteNames() {
+		pkg.Sites = append(pkg.Sites, WorkspaceSite{Name: name, SiteID: config.Sites[name].SiteID})
+	}
+	if len(pkg.Sites) == 0 && dir == "." {
+		return nil, nil
+	}
+	return pkg, nil
+}
+
+// enterPackage makes the package at path, relative to the current
+// directory, the directory every command works in. It must have a config
+// file of its own.
+func enterPackage(path string) error {
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return fmt.Errorf("package %s does not exist", path)
+	}
+	fileName, err := findConfigFileIn(path)
+	if err != nil {
+		return err
+	}
+	if fileName == "" {
+		return fmt.Errorf("%s is not an efmrl package: it has no %s (see 'efmrl3 workspace discover')", path, ConfigFileName)
+	}
+	return os.Chdir(path)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeWorkspace writes config files into a new workspace and returns its
+// root
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		abs := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// describePackages renders packages one per line, for comparing
+func describePackages(packages []WorkspacePackage) string {
+	var lines []string
+	for _, pkg := range packages {
+		var sites []string
+		for _, site := range pkg.Sites {
+			sites = append(sites, site.Name+"="+site.SiteID)
+		}
+		lines = append(lines, pkg.Path+" "+pkg.Config+" "+pkg.Dir+" "+strings.Join(sites, ","))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestDiscoverPackages(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"efmrl.toml":                           "[site]\nsite_id = \"root-id\"\n",
+		"sites/blog/efmrl.toml":                "[site]\nsite_id = \"blog-id\"\ndir = \"public\"\n",
+		"sites/docs/efmrl.yaml":                "sites:\n  staging:\n    site_id: docs-staging\n  prod:\n    site_id: docs-prod\n",
+		"sites/blog/node_modules/x/efmrl.toml": "[site]\nsite_id = \"dependency\"\n",
+		".cache/efmrl.toml":                    "[site]\nsite_id = \"hidden\"\n",
+		"tools/README.md":                      "not a package",
+	})
+
+	packages, err := discoverPackages(root)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := `. efmrl.toml . =root-id
+sites/blog efmrl.toml public =blog-id
+sites/docs efmrl.yaml . prod=docs-prod,staging=docs-staging`
+	if got := describePackages(packages); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// TestDiscoverDeclaredPackages tests that [workspace] packages in the root
+// config decide which directories are packages
+func TestDiscoverDeclaredPackages(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"efmrl.toml":            "[workspace]\npackages = [\"apps/*\"]\n",
+		"apps/shop/efmrl.toml":  "[site]\nsite_id = \"shop-id\"\n",
+		"apps/admin/efmrl.toml": "[site]\nsite_id = \"admin-id\"\n",
+		"apps/empty/index.html": "no config",
+		"other/site/efmrl.toml": "[site]\nsite_id = \"undeclared\"\n",
+	})
+
+	var packages []WorkspacePackage
+	var err error
+	stderr := captureStderr(t, func() { packages, err = discoverPackages(root) })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := `apps/admin efmrl.toml . =admin-id
+apps/shop efmrl.toml . =shop-id`
+	if got := describePackages(packages); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+	if !strings.Contains(stderr, "apps/empty has no efmrl.toml") {
+		t.Errorf("Expected a warning about apps/empty, got %q", stderr)
+	}
+}
+
+func TestEnterPackage(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"sites/blog/efmrl.toml": "[site]\nsite_id = \"blog-id\"\n",
+	})
+	t.Chdir(root)
+
+	if err := enterPackage("sites"); err == nil || !strings.Contains(err.Error(), "not an efmrl package") {
+		t.Errorf("Expected an error for a directory without a config file, got %v", err)
+	}
+	if err := enterPackage("sites/missing"); err == nil {
+		t.Error("Expected an error for a missing package, got nil")
+	}
+	if err := enterPackage("sites/blog"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	config, err := LoadConfig()
+	if err != nil || config.Site.SiteID != "blog-id" {
+		t.Errorf("Expected the package's config to load, got %+v (%v)", config, err)
+	}
+}